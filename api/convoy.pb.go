@@ -21,6 +21,61 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type JobStatus_State int32
+
+const (
+	JobStatus_STATE_UNSPECIFIED JobStatus_State = 0
+	JobStatus_RUNNING           JobStatus_State = 1
+	JobStatus_EXITED            JobStatus_State = 2 // The command ran to completion; exit_code is set
+	JobStatus_FAILED            JobStatus_State = 3 // The command could not finish, e.g. it timed out; message says why
+	JobStatus_CANCELED          JobStatus_State = 4 // Stopped by CancelJob or the agent shutting down
+)
+
+// Enum value maps for JobStatus_State.
+var (
+	JobStatus_State_name = map[int32]string{
+		0: "STATE_UNSPECIFIED",
+		1: "RUNNING",
+		2: "EXITED",
+		3: "FAILED",
+		4: "CANCELED",
+	}
+	JobStatus_State_value = map[string]int32{
+		"STATE_UNSPECIFIED": 0,
+		"RUNNING":           1,
+		"EXITED":            2,
+		"FAILED":            3,
+		"CANCELED":          4,
+	}
+)
+
+func (x JobStatus_State) Enum() *JobStatus_State {
+	p := new(JobStatus_State)
+	*p = x
+	return p
+}
+
+func (x JobStatus_State) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobStatus_State) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_convoy_proto_enumTypes[0].Descriptor()
+}
+
+func (JobStatus_State) Type() protoreflect.EnumType {
+	return &file_api_convoy_proto_enumTypes[0]
+}
+
+func (x JobStatus_State) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobStatus_State.Descriptor instead.
+func (JobStatus_State) EnumDescriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{3, 0}
+}
+
 type ShellOutput_Stream int32
 
 const (
@@ -54,11 +109,11 @@ func (x ShellOutput_Stream) String() string {
 }
 
 func (ShellOutput_Stream) Descriptor() protoreflect.EnumDescriptor {
-	return file_api_convoy_proto_enumTypes[0].Descriptor()
+	return file_api_convoy_proto_enumTypes[1].Descriptor()
 }
 
 func (ShellOutput_Stream) Type() protoreflect.EnumType {
-	return &file_api_convoy_proto_enumTypes[0]
+	return &file_api_convoy_proto_enumTypes[1]
 }
 
 func (x ShellOutput_Stream) Number() protoreflect.EnumNumber {
@@ -67,7 +122,7 @@ func (x ShellOutput_Stream) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use ShellOutput_Stream.Descriptor instead.
 func (ShellOutput_Stream) EnumDescriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{6, 0}
+	return file_api_convoy_proto_rawDescGZIP(), []int{13, 0}
 }
 
 type HealthResponse_Status int32
@@ -106,11 +161,11 @@ func (x HealthResponse_Status) String() string {
 }
 
 func (HealthResponse_Status) Descriptor() protoreflect.EnumDescriptor {
-	return file_api_convoy_proto_enumTypes[1].Descriptor()
+	return file_api_convoy_proto_enumTypes[2].Descriptor()
 }
 
 func (HealthResponse_Status) Type() protoreflect.EnumType {
-	return &file_api_convoy_proto_enumTypes[1]
+	return &file_api_convoy_proto_enumTypes[2]
 }
 
 func (x HealthResponse_Status) Number() protoreflect.EnumNumber {
@@ -119,7 +174,7 @@ func (x HealthResponse_Status) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use HealthResponse_Status.Descriptor instead.
 func (HealthResponse_Status) EnumDescriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{9, 0}
+	return file_api_convoy_proto_rawDescGZIP(), []int{16, 0}
 }
 
 type CopyStart_Direction int32
@@ -155,11 +210,11 @@ func (x CopyStart_Direction) String() string {
 }
 
 func (CopyStart_Direction) Descriptor() protoreflect.EnumDescriptor {
-	return file_api_convoy_proto_enumTypes[2].Descriptor()
+	return file_api_convoy_proto_enumTypes[3].Descriptor()
 }
 
 func (CopyStart_Direction) Type() protoreflect.EnumType {
-	return &file_api_convoy_proto_enumTypes[2]
+	return &file_api_convoy_proto_enumTypes[3]
 }
 
 func (x CopyStart_Direction) Number() protoreflect.EnumNumber {
@@ -168,7 +223,7 @@ func (x CopyStart_Direction) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use CopyStart_Direction.Descriptor instead.
 func (CopyStart_Direction) EnumDescriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{11, 0}
+	return file_api_convoy_proto_rawDescGZIP(), []int{20, 0}
 }
 
 // CommandRequest describes a non-interactive command to execute.
@@ -178,6 +233,8 @@ type CommandRequest struct {
 	Env            map[string]string      `protobuf:"bytes,2,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	WorkDir        string                 `protobuf:"bytes,3,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`
 	TimeoutSeconds int32                  `protobuf:"varint,4,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	Stdin          []byte                 `protobuf:"bytes,5,opt,name=stdin,proto3" json:"stdin,omitempty"` // fed to the command's standard input, then closed
+	User           string                 `protobuf:"bytes,6,opt,name=user,proto3" json:"user,omitempty"`   // run as this user instead of the agent's: a name or uid, optionally with :group or :gid
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -240,6 +297,20 @@ func (x *CommandRequest) GetTimeoutSeconds() int32 {
 	return 0
 }
 
+func (x *CommandRequest) GetStdin() []byte {
+	if x != nil {
+		return x.Stdin
+	}
+	return nil
+}
+
+func (x *CommandRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
 // CommandResponse contains the execution result.
 type CommandResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -247,6 +318,7 @@ type CommandResponse struct {
 	Stderr        string                 `protobuf:"bytes,2,opt,name=stderr,proto3" json:"stderr,omitempty"`
 	ExitCode      int32                  `protobuf:"varint,3,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
 	ErrorMessage  string                 `protobuf:"bytes,4,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	Truncated     bool                   `protobuf:"varint,5,opt,name=truncated,proto3" json:"truncated,omitempty"` // stdout or stderr exceeded the agent's max_output_bytes and was cut short
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -309,6 +381,355 @@ func (x *CommandResponse) GetErrorMessage() string {
 	return ""
 }
 
+func (x *CommandResponse) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+// JobRequest names a background job started with StartJob.
+type JobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	IncludeOutput bool                   `protobuf:"varint,2,opt,name=include_output,json=includeOutput,proto3" json:"include_output,omitempty"` // Return the job's captured output
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobRequest) Reset() {
+	*x = JobRequest{}
+	mi := &file_api_convoy_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobRequest) ProtoMessage() {}
+
+func (x *JobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_convoy_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobRequest.ProtoReflect.Descriptor instead.
+func (*JobRequest) Descriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *JobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *JobRequest) GetIncludeOutput() bool {
+	if x != nil {
+		return x.IncludeOutput
+	}
+	return false
+}
+
+// JobStatus describes a background job.
+type JobStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	State         JobStatus_State        `protobuf:"varint,2,opt,name=state,proto3,enum=convoy.JobStatus_State" json:"state,omitempty"`
+	Args          []string               `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,4,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Message       string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	StartedUnix   int64                  `protobuf:"varint,6,opt,name=started_unix,json=startedUnix,proto3" json:"started_unix,omitempty"`
+	FinishedUnix  int64                  `protobuf:"varint,7,opt,name=finished_unix,json=finishedUnix,proto3" json:"finished_unix,omitempty"`
+	Output        []byte                 `protobuf:"bytes,8,opt,name=output,proto3" json:"output,omitempty"`        // Combined stdout and stderr, when requested
+	Truncated     bool                   `protobuf:"varint,9,opt,name=truncated,proto3" json:"truncated,omitempty"` // Output exceeded the agent's max_output_bytes and was cut short
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobStatus) Reset() {
+	*x = JobStatus{}
+	mi := &file_api_convoy_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobStatus) ProtoMessage() {}
+
+func (x *JobStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_convoy_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobStatus.ProtoReflect.Descriptor instead.
+func (*JobStatus) Descriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *JobStatus) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *JobStatus) GetState() JobStatus_State {
+	if x != nil {
+		return x.State
+	}
+	return JobStatus_STATE_UNSPECIFIED
+}
+
+func (x *JobStatus) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *JobStatus) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *JobStatus) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *JobStatus) GetStartedUnix() int64 {
+	if x != nil {
+		return x.StartedUnix
+	}
+	return 0
+}
+
+func (x *JobStatus) GetFinishedUnix() int64 {
+	if x != nil {
+		return x.FinishedUnix
+	}
+	return 0
+}
+
+func (x *JobStatus) GetOutput() []byte {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *JobStatus) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+// JobListRequest asks for the agent's background jobs.
+type JobListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobListRequest) Reset() {
+	*x = JobListRequest{}
+	mi := &file_api_convoy_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobListRequest) ProtoMessage() {}
+
+func (x *JobListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_convoy_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobListRequest.ProtoReflect.Descriptor instead.
+func (*JobListRequest) Descriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{4}
+}
+
+// JobListResponse lists background jobs, oldest first.
+type JobListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*JobStatus           `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobListResponse) Reset() {
+	*x = JobListResponse{}
+	mi := &file_api_convoy_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobListResponse) ProtoMessage() {}
+
+func (x *JobListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_convoy_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobListResponse.ProtoReflect.Descriptor instead.
+func (*JobListResponse) Descriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *JobListResponse) GetJobs() []*JobStatus {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+// CancelRequest names the job or shell session to stop.
+type CancelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"` // A job id from StartJob or a shell session id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRequest) Reset() {
+	*x = CancelRequest{}
+	mi := &file_api_convoy_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRequest) ProtoMessage() {}
+
+func (x *CancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_convoy_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
+func (*CancelRequest) Descriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CancelRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// CancelResponse reports what CancelJob stopped.
+type CancelResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Kind            string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`                                               // "job" or "shell"
+	AlreadyFinished bool                   `protobuf:"varint,2,opt,name=already_finished,json=alreadyFinished,proto3" json:"already_finished,omitempty"` // The job had finished before the request; nothing was stopped
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CancelResponse) Reset() {
+	*x = CancelResponse{}
+	mi := &file_api_convoy_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelResponse) ProtoMessage() {}
+
+func (x *CancelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_convoy_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelResponse.ProtoReflect.Descriptor instead.
+func (*CancelResponse) Descriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CancelResponse) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *CancelResponse) GetAlreadyFinished() bool {
+	if x != nil {
+		return x.AlreadyFinished
+	}
+	return false
+}
+
 // ShellRequest multiplexes shell session control over a bidi stream.
 type ShellRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -323,7 +744,7 @@ type ShellRequest struct {
 
 func (x *ShellRequest) Reset() {
 	*x = ShellRequest{}
-	mi := &file_api_convoy_proto_msgTypes[2]
+	mi := &file_api_convoy_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -335,7 +756,7 @@ func (x *ShellRequest) String() string {
 func (*ShellRequest) ProtoMessage() {}
 
 func (x *ShellRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[2]
+	mi := &file_api_convoy_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -348,7 +769,7 @@ func (x *ShellRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShellRequest.ProtoReflect.Descriptor instead.
 func (*ShellRequest) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{2}
+	return file_api_convoy_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ShellRequest) GetPayload() isShellRequest_Payload {
@@ -392,19 +813,29 @@ func (*ShellRequest_Start) isShellRequest_Payload() {}
 
 func (*ShellRequest_Input) isShellRequest_Payload() {}
 
-// ShellStart initiates a new shell session.
+// ShellStart initiates a new shell session, or reattaches to a running one.
 type ShellStart struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Args          []string               `protobuf:"bytes,1,rep,name=args,proto3" json:"args,omitempty"`
-	Env           map[string]string      `protobuf:"bytes,2,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	WorkDir       string                 `protobuf:"bytes,3,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Args    []string               `protobuf:"bytes,1,rep,name=args,proto3" json:"args,omitempty"`
+	Env     map[string]string      `protobuf:"bytes,2,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	WorkDir string                 `protobuf:"bytes,3,opt,name=work_dir,json=workDir,proto3" json:"work_dir,omitempty"`
+	// Reattach to the session with this id instead of starting a shell; the other
+	// fields are ignored. Fails with NOT_FOUND once the session has expired.
+	ResumeSessionId string `protobuf:"bytes,4,opt,name=resume_session_id,json=resumeSessionId,proto3" json:"resume_session_id,omitempty"`
+	// Run the command on a pseudo-terminal of rows x cols. Its stdout and stderr
+	// then both arrive as STDOUT output, with the terminal's line handling applied.
+	Tty  bool   `protobuf:"varint,5,opt,name=tty,proto3" json:"tty,omitempty"`
+	Rows uint32 `protobuf:"varint,6,opt,name=rows,proto3" json:"rows,omitempty"`
+	Cols uint32 `protobuf:"varint,7,opt,name=cols,proto3" json:"cols,omitempty"`
+	// Run the shell as this user, as CommandRequest.user.
+	User          string `protobuf:"bytes,8,opt,name=user,proto3" json:"user,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ShellStart) Reset() {
 	*x = ShellStart{}
-	mi := &file_api_convoy_proto_msgTypes[3]
+	mi := &file_api_convoy_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -416,7 +847,7 @@ func (x *ShellStart) String() string {
 func (*ShellStart) ProtoMessage() {}
 
 func (x *ShellStart) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[3]
+	mi := &file_api_convoy_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -429,7 +860,7 @@ func (x *ShellStart) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShellStart.ProtoReflect.Descriptor instead.
 func (*ShellStart) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{3}
+	return file_api_convoy_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ShellStart) GetArgs() []string {
@@ -453,6 +884,41 @@ func (x *ShellStart) GetWorkDir() string {
 	return ""
 }
 
+func (x *ShellStart) GetResumeSessionId() string {
+	if x != nil {
+		return x.ResumeSessionId
+	}
+	return ""
+}
+
+func (x *ShellStart) GetTty() bool {
+	if x != nil {
+		return x.Tty
+	}
+	return false
+}
+
+func (x *ShellStart) GetRows() uint32 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *ShellStart) GetCols() uint32 {
+	if x != nil {
+		return x.Cols
+	}
+	return 0
+}
+
+func (x *ShellStart) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
 // ShellInput provides stdin data or closes the stream.
 type ShellInput struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -464,7 +930,7 @@ type ShellInput struct {
 
 func (x *ShellInput) Reset() {
 	*x = ShellInput{}
-	mi := &file_api_convoy_proto_msgTypes[4]
+	mi := &file_api_convoy_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -476,7 +942,7 @@ func (x *ShellInput) String() string {
 func (*ShellInput) ProtoMessage() {}
 
 func (x *ShellInput) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[4]
+	mi := &file_api_convoy_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -489,7 +955,7 @@ func (x *ShellInput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShellInput.ProtoReflect.Descriptor instead.
 func (*ShellInput) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{4}
+	return file_api_convoy_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *ShellInput) GetData() []byte {
@@ -513,6 +979,7 @@ type ShellResponse struct {
 	//
 	//	*ShellResponse_Output
 	//	*ShellResponse_Exit
+	//	*ShellResponse_Session
 	Payload       isShellResponse_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -520,7 +987,7 @@ type ShellResponse struct {
 
 func (x *ShellResponse) Reset() {
 	*x = ShellResponse{}
-	mi := &file_api_convoy_proto_msgTypes[5]
+	mi := &file_api_convoy_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -532,7 +999,7 @@ func (x *ShellResponse) String() string {
 func (*ShellResponse) ProtoMessage() {}
 
 func (x *ShellResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[5]
+	mi := &file_api_convoy_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -545,7 +1012,7 @@ func (x *ShellResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShellResponse.ProtoReflect.Descriptor instead.
 func (*ShellResponse) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{5}
+	return file_api_convoy_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *ShellResponse) GetPayload() isShellResponse_Payload {
@@ -573,6 +1040,15 @@ func (x *ShellResponse) GetExit() *ShellExit {
 	return nil
 }
 
+func (x *ShellResponse) GetSession() *ShellSession {
+	if x != nil {
+		if x, ok := x.Payload.(*ShellResponse_Session); ok {
+			return x.Session
+		}
+	}
+	return nil
+}
+
 type isShellResponse_Payload interface {
 	isShellResponse_Payload()
 }
@@ -585,10 +1061,70 @@ type ShellResponse_Exit struct {
 	Exit *ShellExit `protobuf:"bytes,2,opt,name=exit,proto3,oneof"`
 }
 
+type ShellResponse_Session struct {
+	Session *ShellSession `protobuf:"bytes,3,opt,name=session,proto3,oneof"`
+}
+
 func (*ShellResponse_Output) isShellResponse_Payload() {}
 
 func (*ShellResponse_Exit) isShellResponse_Payload() {}
 
+func (*ShellResponse_Session) isShellResponse_Payload() {}
+
+// ShellSession is the first response on a shell stream and names the session
+// so a client whose stream drops can resume it.
+type ShellSession struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Resumed       bool                   `protobuf:"varint,2,opt,name=resumed,proto3" json:"resumed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShellSession) Reset() {
+	*x = ShellSession{}
+	mi := &file_api_convoy_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShellSession) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShellSession) ProtoMessage() {}
+
+func (x *ShellSession) ProtoReflect() protoreflect.Message {
+	mi := &file_api_convoy_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShellSession.ProtoReflect.Descriptor instead.
+func (*ShellSession) Descriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ShellSession) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ShellSession) GetResumed() bool {
+	if x != nil {
+		return x.Resumed
+	}
+	return false
+}
+
 // ShellOutput identifies stdout vs stderr data chunks.
 type ShellOutput struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -600,7 +1136,7 @@ type ShellOutput struct {
 
 func (x *ShellOutput) Reset() {
 	*x = ShellOutput{}
-	mi := &file_api_convoy_proto_msgTypes[6]
+	mi := &file_api_convoy_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -612,7 +1148,7 @@ func (x *ShellOutput) String() string {
 func (*ShellOutput) ProtoMessage() {}
 
 func (x *ShellOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[6]
+	mi := &file_api_convoy_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -625,7 +1161,7 @@ func (x *ShellOutput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShellOutput.ProtoReflect.Descriptor instead.
 func (*ShellOutput) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{6}
+	return file_api_convoy_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ShellOutput) GetStream() ShellOutput_Stream {
@@ -653,7 +1189,7 @@ type ShellExit struct {
 
 func (x *ShellExit) Reset() {
 	*x = ShellExit{}
-	mi := &file_api_convoy_proto_msgTypes[7]
+	mi := &file_api_convoy_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -665,7 +1201,7 @@ func (x *ShellExit) String() string {
 func (*ShellExit) ProtoMessage() {}
 
 func (x *ShellExit) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[7]
+	mi := &file_api_convoy_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -678,7 +1214,7 @@ func (x *ShellExit) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShellExit.ProtoReflect.Descriptor instead.
 func (*ShellExit) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{7}
+	return file_api_convoy_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *ShellExit) GetExitCode() int32 {
@@ -697,15 +1233,17 @@ func (x *ShellExit) GetMessage() string {
 
 // HealthRequest probes agent readiness/liveness.
 type HealthRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Probe         string                 `protobuf:"bytes,1,opt,name=probe,proto3" json:"probe,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Probe string                 `protobuf:"bytes,1,opt,name=probe,proto3" json:"probe,omitempty"`
+	// The caller's ProtocolVersion, major.minor.
+	ProtocolVersion string `protobuf:"bytes,2,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *HealthRequest) Reset() {
 	*x = HealthRequest{}
-	mi := &file_api_convoy_proto_msgTypes[8]
+	mi := &file_api_convoy_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -717,7 +1255,7 @@ func (x *HealthRequest) String() string {
 func (*HealthRequest) ProtoMessage() {}
 
 func (x *HealthRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[8]
+	mi := &file_api_convoy_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -730,7 +1268,7 @@ func (x *HealthRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
 func (*HealthRequest) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{8}
+	return file_api_convoy_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *HealthRequest) GetProbe() string {
@@ -740,18 +1278,28 @@ func (x *HealthRequest) GetProbe() string {
 	return ""
 }
 
+func (x *HealthRequest) GetProtocolVersion() string {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return ""
+}
+
 // HealthResponse reports agent status.
 type HealthResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        HealthResponse_Status  `protobuf:"varint,1,opt,name=status,proto3,enum=convoy.HealthResponse_Status" json:"status,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Status  HealthResponse_Status  `protobuf:"varint,1,opt,name=status,proto3,enum=convoy.HealthResponse_Status" json:"status,omitempty"`
+	Message string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// The agent's ProtocolVersion, major.minor. Agents that leave it empty
+	// predate versioning and speak 1.0.
+	ProtocolVersion string `protobuf:"bytes,3,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *HealthResponse) Reset() {
 	*x = HealthResponse{}
-	mi := &file_api_convoy_proto_msgTypes[9]
+	mi := &file_api_convoy_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -763,7 +1311,7 @@ func (x *HealthResponse) String() string {
 func (*HealthResponse) ProtoMessage() {}
 
 func (x *HealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[9]
+	mi := &file_api_convoy_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -776,7 +1324,7 @@ func (x *HealthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
 func (*HealthResponse) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{9}
+	return file_api_convoy_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *HealthResponse) GetStatus() HealthResponse_Status {
@@ -793,6 +1341,103 @@ func (x *HealthResponse) GetMessage() string {
 	return ""
 }
 
+func (x *HealthResponse) GetProtocolVersion() string {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return ""
+}
+
+// InfoRequest asks the agent to describe itself.
+type InfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InfoRequest) Reset() {
+	*x = InfoRequest{}
+	mi := &file_api_convoy_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoRequest) ProtoMessage() {}
+
+func (x *InfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_convoy_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoRequest.ProtoReflect.Descriptor instead.
+func (*InfoRequest) Descriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{17}
+}
+
+// InfoResponse reports the agent identity.
+type InfoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InfoResponse) Reset() {
+	*x = InfoResponse{}
+	mi := &file_api_convoy_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoResponse) ProtoMessage() {}
+
+func (x *InfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_convoy_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoResponse.ProtoReflect.Descriptor instead.
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return file_api_convoy_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *InfoResponse) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
 // CopyRequest streams file data to/from the agent.
 type CopyRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -807,7 +1452,7 @@ type CopyRequest struct {
 
 func (x *CopyRequest) Reset() {
 	*x = CopyRequest{}
-	mi := &file_api_convoy_proto_msgTypes[10]
+	mi := &file_api_convoy_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -819,7 +1464,7 @@ func (x *CopyRequest) String() string {
 func (*CopyRequest) ProtoMessage() {}
 
 func (x *CopyRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[10]
+	mi := &file_api_convoy_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -832,7 +1477,7 @@ func (x *CopyRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CopyRequest.ProtoReflect.Descriptor instead.
 func (*CopyRequest) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{10}
+	return file_api_convoy_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *CopyRequest) GetPayload() isCopyRequest_Payload {
@@ -880,15 +1525,26 @@ func (*CopyRequest_Chunk) isCopyRequest_Payload() {}
 type CopyStart struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Direction     CopyStart_Direction    `protobuf:"varint,1,opt,name=direction,proto3,enum=convoy.CopyStart_Direction" json:"direction,omitempty"`
-	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`            // Destination path (TO_AGENT) or source path (FROM_AGENT)
-	Overwrite     bool                   `protobuf:"varint,3,opt,name=overwrite,proto3" json:"overwrite,omitempty"` // Whether to overwrite existing files
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`                                         // Destination path (TO_AGENT) or source path (FROM_AGENT)
+	Overwrite     bool                   `protobuf:"varint,3,opt,name=overwrite,proto3" json:"overwrite,omitempty"`                              // Whether to overwrite existing files
+	Exclude       []string               `protobuf:"bytes,4,rep,name=exclude,proto3" json:"exclude,omitempty"`                                   // Gitignore-style patterns skipped when packing (FROM_AGENT)
+	Raw           bool                   `protobuf:"varint,5,opt,name=raw,proto3" json:"raw,omitempty"`                                          // Stream a single regular file without tar framing
+	Offset        int64                  `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"`                                    // Byte offset to resume a raw transfer from
+	PreserveTimes bool                   `protobuf:"varint,7,opt,name=preserve_times,json=preserveTimes,proto3" json:"preserve_times,omitempty"` // Restore access/modification times on extracted entries (TO_AGENT)
+	PreserveOwner bool                   `protobuf:"varint,8,opt,name=preserve_owner,json=preserveOwner,proto3" json:"preserve_owner,omitempty"` // Restore uid/gid on extracted entries when the agent runs as root (TO_AGENT)
+	SkipNewer     bool                   `protobuf:"varint,9,opt,name=skip_newer,json=skipNewer,proto3" json:"skip_newer,omitempty"`             // Keep existing files whose mtime is newer than the incoming entry (TO_AGENT)
+	Atomic        bool                   `protobuf:"varint,10,opt,name=atomic,proto3" json:"atomic,omitempty"`                                   // Extract into a staging directory and move it into place only after the whole tar arrived (TO_AGENT)
+	NoFollow      bool                   `protobuf:"varint,11,opt,name=no_follow,json=noFollow,proto3" json:"no_follow,omitempty"`               // Reject symlink entries that are absolute or point outside the destination (TO_AGENT)
+	Size          int64                  `protobuf:"varint,12,opt,name=size,proto3" json:"size,omitempty"`                                       // Total size of a raw pushed file; partial uploads are kept per path and size (TO_AGENT)
+	Name          string                 `protobuf:"bytes,13,opt,name=name,proto3" json:"name,omitempty"`                                        // Base name of a raw pushed file, used when path is a directory (TO_AGENT)
+	Sha256        string                 `protobuf:"bytes,14,opt,name=sha256,proto3" json:"sha256,omitempty"`                                    // Hex SHA-256 of the offset bytes a resumed raw pull already holds; the agent restarts from zero when its file no longer starts with them (FROM_AGENT)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CopyStart) Reset() {
 	*x = CopyStart{}
-	mi := &file_api_convoy_proto_msgTypes[11]
+	mi := &file_api_convoy_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -900,7 +1556,7 @@ func (x *CopyStart) String() string {
 func (*CopyStart) ProtoMessage() {}
 
 func (x *CopyStart) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[11]
+	mi := &file_api_convoy_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -913,7 +1569,7 @@ func (x *CopyStart) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CopyStart.ProtoReflect.Descriptor instead.
 func (*CopyStart) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{11}
+	return file_api_convoy_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *CopyStart) GetDirection() CopyStart_Direction {
@@ -937,18 +1593,96 @@ func (x *CopyStart) GetOverwrite() bool {
 	return false
 }
 
+func (x *CopyStart) GetExclude() []string {
+	if x != nil {
+		return x.Exclude
+	}
+	return nil
+}
+
+func (x *CopyStart) GetRaw() bool {
+	if x != nil {
+		return x.Raw
+	}
+	return false
+}
+
+func (x *CopyStart) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *CopyStart) GetPreserveTimes() bool {
+	if x != nil {
+		return x.PreserveTimes
+	}
+	return false
+}
+
+func (x *CopyStart) GetPreserveOwner() bool {
+	if x != nil {
+		return x.PreserveOwner
+	}
+	return false
+}
+
+func (x *CopyStart) GetSkipNewer() bool {
+	if x != nil {
+		return x.SkipNewer
+	}
+	return false
+}
+
+func (x *CopyStart) GetAtomic() bool {
+	if x != nil {
+		return x.Atomic
+	}
+	return false
+}
+
+func (x *CopyStart) GetNoFollow() bool {
+	if x != nil {
+		return x.NoFollow
+	}
+	return false
+}
+
+func (x *CopyStart) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *CopyStart) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CopyStart) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
 // CopyChunk contains a chunk of tar data.
 type CopyChunk struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
-	Eof           bool                   `protobuf:"varint,2,opt,name=eof,proto3" json:"eof,omitempty"` // Signals end of tar stream
+	Eof           bool                   `protobuf:"varint,2,opt,name=eof,proto3" json:"eof,omitempty"`      // Signals end of tar stream
+	Sha256        string                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"` // Hex SHA-256 of all tar data, sent with eof; an atomic copy is rolled back on mismatch
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CopyChunk) Reset() {
 	*x = CopyChunk{}
-	mi := &file_api_convoy_proto_msgTypes[12]
+	mi := &file_api_convoy_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -960,7 +1694,7 @@ func (x *CopyChunk) String() string {
 func (*CopyChunk) ProtoMessage() {}
 
 func (x *CopyChunk) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[12]
+	mi := &file_api_convoy_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -973,7 +1707,7 @@ func (x *CopyChunk) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CopyChunk.ProtoReflect.Descriptor instead.
 func (*CopyChunk) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{12}
+	return file_api_convoy_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *CopyChunk) GetData() []byte {
@@ -990,6 +1724,13 @@ func (x *CopyChunk) GetEof() bool {
 	return false
 }
 
+func (x *CopyChunk) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
 // CopyResponse streams status and data back from the agent.
 type CopyResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -1005,7 +1746,7 @@ type CopyResponse struct {
 
 func (x *CopyResponse) Reset() {
 	*x = CopyResponse{}
-	mi := &file_api_convoy_proto_msgTypes[13]
+	mi := &file_api_convoy_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1017,7 +1758,7 @@ func (x *CopyResponse) String() string {
 func (*CopyResponse) ProtoMessage() {}
 
 func (x *CopyResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[13]
+	mi := &file_api_convoy_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1030,7 +1771,7 @@ func (x *CopyResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CopyResponse.ProtoReflect.Descriptor instead.
 func (*CopyResponse) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{13}
+	return file_api_convoy_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *CopyResponse) GetPayload() isCopyResponse_Payload {
@@ -1094,13 +1835,14 @@ type CopyProgress struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
 	BytesTransferred int64                  `protobuf:"varint,1,opt,name=bytes_transferred,json=bytesTransferred,proto3" json:"bytes_transferred,omitempty"`
 	CurrentFile      string                 `protobuf:"bytes,2,opt,name=current_file,json=currentFile,proto3" json:"current_file,omitempty"`
+	Sha256           string                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"` // Hex SHA-256 of the bytes already held when a raw push can resume
 	unknownFields    protoimpl.UnknownFields
 	sizeCache        protoimpl.SizeCache
 }
 
 func (x *CopyProgress) Reset() {
 	*x = CopyProgress{}
-	mi := &file_api_convoy_proto_msgTypes[14]
+	mi := &file_api_convoy_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1112,7 +1854,7 @@ func (x *CopyProgress) String() string {
 func (*CopyProgress) ProtoMessage() {}
 
 func (x *CopyProgress) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[14]
+	mi := &file_api_convoy_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1125,7 +1867,7 @@ func (x *CopyProgress) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CopyProgress.ProtoReflect.Descriptor instead.
 func (*CopyProgress) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{14}
+	return file_api_convoy_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *CopyProgress) GetBytesTransferred() int64 {
@@ -1142,20 +1884,29 @@ func (x *CopyProgress) GetCurrentFile() string {
 	return ""
 }
 
+func (x *CopyProgress) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
 // CopyResult indicates the final outcome of the copy operation.
 type CopyResult struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	TotalBytes    int64                  `protobuf:"varint,3,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
-	FileCount     int32                  `protobuf:"varint,4,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	TotalBytes      int64                  `protobuf:"varint,3,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	FileCount       int32                  `protobuf:"varint,4,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"`
+	SkippedNewer    int32                  `protobuf:"varint,5,opt,name=skipped_newer,json=skippedNewer,proto3" json:"skipped_newer,omitempty"`          // Files left untouched because the destination was newer
+	SkippedExisting int32                  `protobuf:"varint,6,opt,name=skipped_existing,json=skippedExisting,proto3" json:"skipped_existing,omitempty"` // Files left untouched because they existed and overwrite was disabled
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *CopyResult) Reset() {
 	*x = CopyResult{}
-	mi := &file_api_convoy_proto_msgTypes[15]
+	mi := &file_api_convoy_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1167,7 +1918,7 @@ func (x *CopyResult) String() string {
 func (*CopyResult) ProtoMessage() {}
 
 func (x *CopyResult) ProtoReflect() protoreflect.Message {
-	mi := &file_api_convoy_proto_msgTypes[15]
+	mi := &file_api_convoy_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1180,7 +1931,7 @@ func (x *CopyResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CopyResult.ProtoReflect.Descriptor instead.
 func (*CopyResult) Descriptor() ([]byte, []int) {
-	return file_api_convoy_proto_rawDescGZIP(), []int{15}
+	return file_api_convoy_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *CopyResult) GetSuccess() bool {
@@ -1211,44 +1962,101 @@ func (x *CopyResult) GetFileCount() int32 {
 	return 0
 }
 
+func (x *CopyResult) GetSkippedNewer() int32 {
+	if x != nil {
+		return x.SkippedNewer
+	}
+	return 0
+}
+
+func (x *CopyResult) GetSkippedExisting() int32 {
+	if x != nil {
+		return x.SkippedExisting
+	}
+	return 0
+}
+
 var File_api_convoy_proto protoreflect.FileDescriptor
 
 const file_api_convoy_proto_rawDesc = "" +
 	"\n" +
-	"\x10api/convoy.proto\x12\x06convoy\"\xd3\x01\n" +
+	"\x10api/convoy.proto\x12\x06convoy\"\xfd\x01\n" +
 	"\x0eCommandRequest\x12\x12\n" +
 	"\x04args\x18\x01 \x03(\tR\x04args\x121\n" +
 	"\x03env\x18\x02 \x03(\v2\x1f.convoy.CommandRequest.EnvEntryR\x03env\x12\x19\n" +
 	"\bwork_dir\x18\x03 \x01(\tR\aworkDir\x12'\n" +
-	"\x0ftimeout_seconds\x18\x04 \x01(\x05R\x0etimeoutSeconds\x1a6\n" +
+	"\x0ftimeout_seconds\x18\x04 \x01(\x05R\x0etimeoutSeconds\x12\x14\n" +
+	"\x05stdin\x18\x05 \x01(\fR\x05stdin\x12\x12\n" +
+	"\x04user\x18\x06 \x01(\tR\x04user\x1a6\n" +
 	"\bEnvEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x83\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa1\x01\n" +
 	"\x0fCommandResponse\x12\x16\n" +
 	"\x06stdout\x18\x01 \x01(\tR\x06stdout\x12\x16\n" +
 	"\x06stderr\x18\x02 \x01(\tR\x06stderr\x12\x1b\n" +
 	"\texit_code\x18\x03 \x01(\x05R\bexitCode\x12#\n" +
-	"\rerror_message\x18\x04 \x01(\tR\ferrorMessage\"q\n" +
+	"\rerror_message\x18\x04 \x01(\tR\ferrorMessage\x12\x1c\n" +
+	"\ttruncated\x18\x05 \x01(\bR\ttruncated\"J\n" +
+	"\n" +
+	"JobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12%\n" +
+	"\x0einclude_output\x18\x02 \x01(\bR\rincludeOutput\"\xed\x02\n" +
+	"\tJobStatus\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12-\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x17.convoy.JobStatus.StateR\x05state\x12\x12\n" +
+	"\x04args\x18\x03 \x03(\tR\x04args\x12\x1b\n" +
+	"\texit_code\x18\x04 \x01(\x05R\bexitCode\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\x12!\n" +
+	"\fstarted_unix\x18\x06 \x01(\x03R\vstartedUnix\x12#\n" +
+	"\rfinished_unix\x18\a \x01(\x03R\ffinishedUnix\x12\x16\n" +
+	"\x06output\x18\b \x01(\fR\x06output\x12\x1c\n" +
+	"\ttruncated\x18\t \x01(\bR\ttruncated\"Q\n" +
+	"\x05State\x12\x15\n" +
+	"\x11STATE_UNSPECIFIED\x10\x00\x12\v\n" +
+	"\aRUNNING\x10\x01\x12\n" +
+	"\n" +
+	"\x06EXITED\x10\x02\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x03\x12\f\n" +
+	"\bCANCELED\x10\x04\"\x10\n" +
+	"\x0eJobListRequest\"8\n" +
+	"\x0fJobListResponse\x12%\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x11.convoy.JobStatusR\x04jobs\"\x1f\n" +
+	"\rCancelRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"O\n" +
+	"\x0eCancelResponse\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12)\n" +
+	"\x10already_finished\x18\x02 \x01(\bR\x0falreadyFinished\"q\n" +
 	"\fShellRequest\x12*\n" +
 	"\x05start\x18\x01 \x01(\v2\x12.convoy.ShellStartH\x00R\x05start\x12*\n" +
 	"\x05input\x18\x02 \x01(\v2\x12.convoy.ShellInputH\x00R\x05inputB\t\n" +
-	"\apayload\"\xa2\x01\n" +
+	"\apayload\"\x9c\x02\n" +
 	"\n" +
 	"ShellStart\x12\x12\n" +
 	"\x04args\x18\x01 \x03(\tR\x04args\x12-\n" +
 	"\x03env\x18\x02 \x03(\v2\x1b.convoy.ShellStart.EnvEntryR\x03env\x12\x19\n" +
-	"\bwork_dir\x18\x03 \x01(\tR\aworkDir\x1a6\n" +
+	"\bwork_dir\x18\x03 \x01(\tR\aworkDir\x12*\n" +
+	"\x11resume_session_id\x18\x04 \x01(\tR\x0fresumeSessionId\x12\x10\n" +
+	"\x03tty\x18\x05 \x01(\bR\x03tty\x12\x12\n" +
+	"\x04rows\x18\x06 \x01(\rR\x04rows\x12\x12\n" +
+	"\x04cols\x18\a \x01(\rR\x04cols\x12\x12\n" +
+	"\x04user\x18\b \x01(\tR\x04user\x1a6\n" +
 	"\bEnvEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"2\n" +
 	"\n" +
 	"ShellInput\x12\x12\n" +
 	"\x04data\x18\x01 \x01(\fR\x04data\x12\x10\n" +
-	"\x03eof\x18\x02 \x01(\bR\x03eof\"r\n" +
+	"\x03eof\x18\x02 \x01(\bR\x03eof\"\xa4\x01\n" +
 	"\rShellResponse\x12-\n" +
 	"\x06output\x18\x01 \x01(\v2\x13.convoy.ShellOutputH\x00R\x06output\x12'\n" +
-	"\x04exit\x18\x02 \x01(\v2\x11.convoy.ShellExitH\x00R\x04exitB\t\n" +
-	"\apayload\"\x8f\x01\n" +
+	"\x04exit\x18\x02 \x01(\v2\x11.convoy.ShellExitH\x00R\x04exit\x120\n" +
+	"\asession\x18\x03 \x01(\v2\x14.convoy.ShellSessionH\x00R\asessionB\t\n" +
+	"\apayload\"G\n" +
+	"\fShellSession\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x18\n" +
+	"\aresumed\x18\x02 \x01(\bR\aresumed\"\x8f\x01\n" +
 	"\vShellOutput\x122\n" +
 	"\x06stream\x18\x01 \x01(\x0e2\x1a.convoy.ShellOutput.StreamR\x06stream\x12\x12\n" +
 	"\x04data\x18\x02 \x01(\fR\x04data\"8\n" +
@@ -1260,41 +2068,62 @@ const file_api_convoy_proto_rawDesc = "" +
 	"\x06STDERR\x10\x02\"B\n" +
 	"\tShellExit\x12\x1b\n" +
 	"\texit_code\x18\x01 \x01(\x05R\bexitCode\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"%\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"P\n" +
 	"\rHealthRequest\x12\x14\n" +
-	"\x05probe\x18\x01 \x01(\tR\x05probe\"\xbe\x01\n" +
+	"\x05probe\x18\x01 \x01(\tR\x05probe\x12)\n" +
+	"\x10protocol_version\x18\x02 \x01(\tR\x0fprotocolVersion\"\xe9\x01\n" +
 	"\x0eHealthResponse\x125\n" +
 	"\x06status\x18\x01 \x01(\x0e2\x1d.convoy.HealthResponse.StatusR\x06status\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"[\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12)\n" +
+	"\x10protocol_version\x18\x03 \x01(\tR\x0fprotocolVersion\"[\n" +
 	"\x06Status\x12\x12\n" +
 	"\x0eSTATUS_UNKNOWN\x10\x00\x12\x12\n" +
 	"\x0eSTATUS_HEALTHY\x10\x01\x12\x13\n" +
 	"\x0fSTATUS_DEGRADED\x10\x02\x12\x14\n" +
-	"\x10STATUS_UNHEALTHY\x10\x03\"n\n" +
+	"\x10STATUS_UNHEALTHY\x10\x03\"\r\n" +
+	"\vInfoRequest\"E\n" +
+	"\fInfoResponse\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\"n\n" +
 	"\vCopyRequest\x12)\n" +
 	"\x05start\x18\x01 \x01(\v2\x11.convoy.CopyStartH\x00R\x05start\x12)\n" +
 	"\x05chunk\x18\x02 \x01(\v2\x11.convoy.CopyChunkH\x00R\x05chunkB\t\n" +
-	"\apayload\"\xbe\x01\n" +
+	"\apayload\"\xe4\x03\n" +
 	"\tCopyStart\x129\n" +
 	"\tdirection\x18\x01 \x01(\x0e2\x1b.convoy.CopyStart.DirectionR\tdirection\x12\x12\n" +
 	"\x04path\x18\x02 \x01(\tR\x04path\x12\x1c\n" +
-	"\toverwrite\x18\x03 \x01(\bR\toverwrite\"D\n" +
+	"\toverwrite\x18\x03 \x01(\bR\toverwrite\x12\x18\n" +
+	"\aexclude\x18\x04 \x03(\tR\aexclude\x12\x10\n" +
+	"\x03raw\x18\x05 \x01(\bR\x03raw\x12\x16\n" +
+	"\x06offset\x18\x06 \x01(\x03R\x06offset\x12%\n" +
+	"\x0epreserve_times\x18\a \x01(\bR\rpreserveTimes\x12%\n" +
+	"\x0epreserve_owner\x18\b \x01(\bR\rpreserveOwner\x12\x1d\n" +
+	"\n" +
+	"skip_newer\x18\t \x01(\bR\tskipNewer\x12\x16\n" +
+	"\x06atomic\x18\n" +
+	" \x01(\bR\x06atomic\x12\x1b\n" +
+	"\tno_follow\x18\v \x01(\bR\bnoFollow\x12\x12\n" +
+	"\x04size\x18\f \x01(\x03R\x04size\x12\x12\n" +
+	"\x04name\x18\r \x01(\tR\x04name\x12\x16\n" +
+	"\x06sha256\x18\x0e \x01(\tR\x06sha256\"D\n" +
 	"\tDirection\x12\x19\n" +
 	"\x15DIRECTION_UNSPECIFIED\x10\x00\x12\f\n" +
 	"\bTO_AGENT\x10\x01\x12\x0e\n" +
 	"\n" +
-	"FROM_AGENT\x10\x02\"1\n" +
+	"FROM_AGENT\x10\x02\"I\n" +
 	"\tCopyChunk\x12\x12\n" +
 	"\x04data\x18\x01 \x01(\fR\x04data\x12\x10\n" +
-	"\x03eof\x18\x02 \x01(\bR\x03eof\"\xa6\x01\n" +
+	"\x03eof\x18\x02 \x01(\bR\x03eof\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\tR\x06sha256\"\xa6\x01\n" +
 	"\fCopyResponse\x122\n" +
 	"\bprogress\x18\x01 \x01(\v2\x14.convoy.CopyProgressH\x00R\bprogress\x12)\n" +
 	"\x05chunk\x18\x02 \x01(\v2\x11.convoy.CopyChunkH\x00R\x05chunk\x12,\n" +
 	"\x06result\x18\x03 \x01(\v2\x12.convoy.CopyResultH\x00R\x06resultB\t\n" +
-	"\apayload\"^\n" +
+	"\apayload\"v\n" +
 	"\fCopyProgress\x12+\n" +
 	"\x11bytes_transferred\x18\x01 \x01(\x03R\x10bytesTransferred\x12!\n" +
-	"\fcurrent_file\x18\x02 \x01(\tR\vcurrentFile\"\x80\x01\n" +
+	"\fcurrent_file\x18\x02 \x01(\tR\vcurrentFile\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\tR\x06sha256\"\xd0\x01\n" +
 	"\n" +
 	"CopyResult\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
@@ -1302,12 +2131,20 @@ const file_api_convoy_proto_rawDesc = "" +
 	"\vtotal_bytes\x18\x03 \x01(\x03R\n" +
 	"totalBytes\x12\x1d\n" +
 	"\n" +
-	"file_count\x18\x04 \x01(\x05R\tfileCount2\x90\x02\n" +
+	"file_count\x18\x04 \x01(\x05R\tfileCount\x12#\n" +
+	"\rskipped_newer\x18\x05 \x01(\x05R\fskippedNewer\x12)\n" +
+	"\x10skipped_existing\x18\x06 \x01(\x05R\x0fskippedExisting2\xfc\x04\n" +
 	"\rConvoyService\x12C\n" +
-	"\x0eExecuteCommand\x12\x16.convoy.CommandRequest\x1a\x17.convoy.CommandResponse\"\x00\x12A\n" +
+	"\x0eExecuteCommand\x12\x16.convoy.CommandRequest\x1a\x17.convoy.CommandResponse\"\x00\x12I\n" +
+	"\x14ExecuteCommandStream\x12\x16.convoy.CommandRequest\x1a\x15.convoy.ShellResponse\"\x000\x01\x12A\n" +
 	"\fExecuteShell\x12\x14.convoy.ShellRequest\x1a\x15.convoy.ShellResponse\"\x00(\x010\x01\x12>\n" +
 	"\vCheckHealth\x12\x15.convoy.HealthRequest\x1a\x16.convoy.HealthResponse\"\x00\x127\n" +
-	"\x04Copy\x12\x13.convoy.CopyRequest\x1a\x14.convoy.CopyResponse\"\x00(\x010\x01B\fZ\n" +
+	"\x04Copy\x12\x13.convoy.CopyRequest\x1a\x14.convoy.CopyResponse\"\x00(\x010\x01\x126\n" +
+	"\aGetInfo\x12\x13.convoy.InfoRequest\x1a\x14.convoy.InfoResponse\"\x00\x127\n" +
+	"\bStartJob\x12\x16.convoy.CommandRequest\x1a\x11.convoy.JobStatus\"\x00\x121\n" +
+	"\x06GetJob\x12\x12.convoy.JobRequest\x1a\x11.convoy.JobStatus\"\x00\x12=\n" +
+	"\bListJobs\x12\x16.convoy.JobListRequest\x1a\x17.convoy.JobListResponse\"\x00\x12<\n" +
+	"\tCancelJob\x12\x15.convoy.CancelRequest\x1a\x16.convoy.CancelResponse\"\x00B\fZ\n" +
 	"convoy/apib\x06proto3"
 
 var (
@@ -1322,59 +2159,84 @@ func file_api_convoy_proto_rawDescGZIP() []byte {
 	return file_api_convoy_proto_rawDescData
 }
 
-var file_api_convoy_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
-var file_api_convoy_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_api_convoy_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_api_convoy_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
 var file_api_convoy_proto_goTypes = []any{
-	(ShellOutput_Stream)(0),    // 0: convoy.ShellOutput.Stream
-	(HealthResponse_Status)(0), // 1: convoy.HealthResponse.Status
-	(CopyStart_Direction)(0),   // 2: convoy.CopyStart.Direction
-	(*CommandRequest)(nil),     // 3: convoy.CommandRequest
-	(*CommandResponse)(nil),    // 4: convoy.CommandResponse
-	(*ShellRequest)(nil),       // 5: convoy.ShellRequest
-	(*ShellStart)(nil),         // 6: convoy.ShellStart
-	(*ShellInput)(nil),         // 7: convoy.ShellInput
-	(*ShellResponse)(nil),      // 8: convoy.ShellResponse
-	(*ShellOutput)(nil),        // 9: convoy.ShellOutput
-	(*ShellExit)(nil),          // 10: convoy.ShellExit
-	(*HealthRequest)(nil),      // 11: convoy.HealthRequest
-	(*HealthResponse)(nil),     // 12: convoy.HealthResponse
-	(*CopyRequest)(nil),        // 13: convoy.CopyRequest
-	(*CopyStart)(nil),          // 14: convoy.CopyStart
-	(*CopyChunk)(nil),          // 15: convoy.CopyChunk
-	(*CopyResponse)(nil),       // 16: convoy.CopyResponse
-	(*CopyProgress)(nil),       // 17: convoy.CopyProgress
-	(*CopyResult)(nil),         // 18: convoy.CopyResult
-	nil,                        // 19: convoy.CommandRequest.EnvEntry
-	nil,                        // 20: convoy.ShellStart.EnvEntry
+	(JobStatus_State)(0),       // 0: convoy.JobStatus.State
+	(ShellOutput_Stream)(0),    // 1: convoy.ShellOutput.Stream
+	(HealthResponse_Status)(0), // 2: convoy.HealthResponse.Status
+	(CopyStart_Direction)(0),   // 3: convoy.CopyStart.Direction
+	(*CommandRequest)(nil),     // 4: convoy.CommandRequest
+	(*CommandResponse)(nil),    // 5: convoy.CommandResponse
+	(*JobRequest)(nil),         // 6: convoy.JobRequest
+	(*JobStatus)(nil),          // 7: convoy.JobStatus
+	(*JobListRequest)(nil),     // 8: convoy.JobListRequest
+	(*JobListResponse)(nil),    // 9: convoy.JobListResponse
+	(*CancelRequest)(nil),      // 10: convoy.CancelRequest
+	(*CancelResponse)(nil),     // 11: convoy.CancelResponse
+	(*ShellRequest)(nil),       // 12: convoy.ShellRequest
+	(*ShellStart)(nil),         // 13: convoy.ShellStart
+	(*ShellInput)(nil),         // 14: convoy.ShellInput
+	(*ShellResponse)(nil),      // 15: convoy.ShellResponse
+	(*ShellSession)(nil),       // 16: convoy.ShellSession
+	(*ShellOutput)(nil),        // 17: convoy.ShellOutput
+	(*ShellExit)(nil),          // 18: convoy.ShellExit
+	(*HealthRequest)(nil),      // 19: convoy.HealthRequest
+	(*HealthResponse)(nil),     // 20: convoy.HealthResponse
+	(*InfoRequest)(nil),        // 21: convoy.InfoRequest
+	(*InfoResponse)(nil),       // 22: convoy.InfoResponse
+	(*CopyRequest)(nil),        // 23: convoy.CopyRequest
+	(*CopyStart)(nil),          // 24: convoy.CopyStart
+	(*CopyChunk)(nil),          // 25: convoy.CopyChunk
+	(*CopyResponse)(nil),       // 26: convoy.CopyResponse
+	(*CopyProgress)(nil),       // 27: convoy.CopyProgress
+	(*CopyResult)(nil),         // 28: convoy.CopyResult
+	nil,                        // 29: convoy.CommandRequest.EnvEntry
+	nil,                        // 30: convoy.ShellStart.EnvEntry
 }
 var file_api_convoy_proto_depIdxs = []int32{
-	19, // 0: convoy.CommandRequest.env:type_name -> convoy.CommandRequest.EnvEntry
-	6,  // 1: convoy.ShellRequest.start:type_name -> convoy.ShellStart
-	7,  // 2: convoy.ShellRequest.input:type_name -> convoy.ShellInput
-	20, // 3: convoy.ShellStart.env:type_name -> convoy.ShellStart.EnvEntry
-	9,  // 4: convoy.ShellResponse.output:type_name -> convoy.ShellOutput
-	10, // 5: convoy.ShellResponse.exit:type_name -> convoy.ShellExit
-	0,  // 6: convoy.ShellOutput.stream:type_name -> convoy.ShellOutput.Stream
-	1,  // 7: convoy.HealthResponse.status:type_name -> convoy.HealthResponse.Status
-	14, // 8: convoy.CopyRequest.start:type_name -> convoy.CopyStart
-	15, // 9: convoy.CopyRequest.chunk:type_name -> convoy.CopyChunk
-	2,  // 10: convoy.CopyStart.direction:type_name -> convoy.CopyStart.Direction
-	17, // 11: convoy.CopyResponse.progress:type_name -> convoy.CopyProgress
-	15, // 12: convoy.CopyResponse.chunk:type_name -> convoy.CopyChunk
-	18, // 13: convoy.CopyResponse.result:type_name -> convoy.CopyResult
-	3,  // 14: convoy.ConvoyService.ExecuteCommand:input_type -> convoy.CommandRequest
-	5,  // 15: convoy.ConvoyService.ExecuteShell:input_type -> convoy.ShellRequest
-	11, // 16: convoy.ConvoyService.CheckHealth:input_type -> convoy.HealthRequest
-	13, // 17: convoy.ConvoyService.Copy:input_type -> convoy.CopyRequest
-	4,  // 18: convoy.ConvoyService.ExecuteCommand:output_type -> convoy.CommandResponse
-	8,  // 19: convoy.ConvoyService.ExecuteShell:output_type -> convoy.ShellResponse
-	12, // 20: convoy.ConvoyService.CheckHealth:output_type -> convoy.HealthResponse
-	16, // 21: convoy.ConvoyService.Copy:output_type -> convoy.CopyResponse
-	18, // [18:22] is the sub-list for method output_type
-	14, // [14:18] is the sub-list for method input_type
-	14, // [14:14] is the sub-list for extension type_name
-	14, // [14:14] is the sub-list for extension extendee
-	0,  // [0:14] is the sub-list for field type_name
+	29, // 0: convoy.CommandRequest.env:type_name -> convoy.CommandRequest.EnvEntry
+	0,  // 1: convoy.JobStatus.state:type_name -> convoy.JobStatus.State
+	7,  // 2: convoy.JobListResponse.jobs:type_name -> convoy.JobStatus
+	13, // 3: convoy.ShellRequest.start:type_name -> convoy.ShellStart
+	14, // 4: convoy.ShellRequest.input:type_name -> convoy.ShellInput
+	30, // 5: convoy.ShellStart.env:type_name -> convoy.ShellStart.EnvEntry
+	17, // 6: convoy.ShellResponse.output:type_name -> convoy.ShellOutput
+	18, // 7: convoy.ShellResponse.exit:type_name -> convoy.ShellExit
+	16, // 8: convoy.ShellResponse.session:type_name -> convoy.ShellSession
+	1,  // 9: convoy.ShellOutput.stream:type_name -> convoy.ShellOutput.Stream
+	2,  // 10: convoy.HealthResponse.status:type_name -> convoy.HealthResponse.Status
+	24, // 11: convoy.CopyRequest.start:type_name -> convoy.CopyStart
+	25, // 12: convoy.CopyRequest.chunk:type_name -> convoy.CopyChunk
+	3,  // 13: convoy.CopyStart.direction:type_name -> convoy.CopyStart.Direction
+	27, // 14: convoy.CopyResponse.progress:type_name -> convoy.CopyProgress
+	25, // 15: convoy.CopyResponse.chunk:type_name -> convoy.CopyChunk
+	28, // 16: convoy.CopyResponse.result:type_name -> convoy.CopyResult
+	4,  // 17: convoy.ConvoyService.ExecuteCommand:input_type -> convoy.CommandRequest
+	4,  // 18: convoy.ConvoyService.ExecuteCommandStream:input_type -> convoy.CommandRequest
+	12, // 19: convoy.ConvoyService.ExecuteShell:input_type -> convoy.ShellRequest
+	19, // 20: convoy.ConvoyService.CheckHealth:input_type -> convoy.HealthRequest
+	23, // 21: convoy.ConvoyService.Copy:input_type -> convoy.CopyRequest
+	21, // 22: convoy.ConvoyService.GetInfo:input_type -> convoy.InfoRequest
+	4,  // 23: convoy.ConvoyService.StartJob:input_type -> convoy.CommandRequest
+	6,  // 24: convoy.ConvoyService.GetJob:input_type -> convoy.JobRequest
+	8,  // 25: convoy.ConvoyService.ListJobs:input_type -> convoy.JobListRequest
+	10, // 26: convoy.ConvoyService.CancelJob:input_type -> convoy.CancelRequest
+	5,  // 27: convoy.ConvoyService.ExecuteCommand:output_type -> convoy.CommandResponse
+	15, // 28: convoy.ConvoyService.ExecuteCommandStream:output_type -> convoy.ShellResponse
+	15, // 29: convoy.ConvoyService.ExecuteShell:output_type -> convoy.ShellResponse
+	20, // 30: convoy.ConvoyService.CheckHealth:output_type -> convoy.HealthResponse
+	26, // 31: convoy.ConvoyService.Copy:output_type -> convoy.CopyResponse
+	22, // 32: convoy.ConvoyService.GetInfo:output_type -> convoy.InfoResponse
+	7,  // 33: convoy.ConvoyService.StartJob:output_type -> convoy.JobStatus
+	7,  // 34: convoy.ConvoyService.GetJob:output_type -> convoy.JobStatus
+	9,  // 35: convoy.ConvoyService.ListJobs:output_type -> convoy.JobListResponse
+	11, // 36: convoy.ConvoyService.CancelJob:output_type -> convoy.CancelResponse
+	27, // [27:37] is the sub-list for method output_type
+	17, // [17:27] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
 }
 
 func init() { file_api_convoy_proto_init() }
@@ -1382,19 +2244,20 @@ func file_api_convoy_proto_init() {
 	if File_api_convoy_proto != nil {
 		return
 	}
-	file_api_convoy_proto_msgTypes[2].OneofWrappers = []any{
+	file_api_convoy_proto_msgTypes[8].OneofWrappers = []any{
 		(*ShellRequest_Start)(nil),
 		(*ShellRequest_Input)(nil),
 	}
-	file_api_convoy_proto_msgTypes[5].OneofWrappers = []any{
+	file_api_convoy_proto_msgTypes[11].OneofWrappers = []any{
 		(*ShellResponse_Output)(nil),
 		(*ShellResponse_Exit)(nil),
+		(*ShellResponse_Session)(nil),
 	}
-	file_api_convoy_proto_msgTypes[10].OneofWrappers = []any{
+	file_api_convoy_proto_msgTypes[19].OneofWrappers = []any{
 		(*CopyRequest_Start)(nil),
 		(*CopyRequest_Chunk)(nil),
 	}
-	file_api_convoy_proto_msgTypes[13].OneofWrappers = []any{
+	file_api_convoy_proto_msgTypes[22].OneofWrappers = []any{
 		(*CopyResponse_Progress)(nil),
 		(*CopyResponse_Chunk)(nil),
 		(*CopyResponse_Result)(nil),
@@ -1404,8 +2267,8 @@ func file_api_convoy_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_convoy_proto_rawDesc), len(file_api_convoy_proto_rawDesc)),
-			NumEnums:      3,
-			NumMessages:   18,
+			NumEnums:      4,
+			NumMessages:   27,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
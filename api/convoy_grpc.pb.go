@@ -19,10 +19,16 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ConvoyService_ExecuteCommand_FullMethodName = "/convoy.ConvoyService/ExecuteCommand"
-	ConvoyService_ExecuteShell_FullMethodName   = "/convoy.ConvoyService/ExecuteShell"
-	ConvoyService_CheckHealth_FullMethodName    = "/convoy.ConvoyService/CheckHealth"
-	ConvoyService_Copy_FullMethodName           = "/convoy.ConvoyService/Copy"
+	ConvoyService_ExecuteCommand_FullMethodName       = "/convoy.ConvoyService/ExecuteCommand"
+	ConvoyService_ExecuteCommandStream_FullMethodName = "/convoy.ConvoyService/ExecuteCommandStream"
+	ConvoyService_ExecuteShell_FullMethodName         = "/convoy.ConvoyService/ExecuteShell"
+	ConvoyService_CheckHealth_FullMethodName          = "/convoy.ConvoyService/CheckHealth"
+	ConvoyService_Copy_FullMethodName                 = "/convoy.ConvoyService/Copy"
+	ConvoyService_GetInfo_FullMethodName              = "/convoy.ConvoyService/GetInfo"
+	ConvoyService_StartJob_FullMethodName             = "/convoy.ConvoyService/StartJob"
+	ConvoyService_GetJob_FullMethodName               = "/convoy.ConvoyService/GetJob"
+	ConvoyService_ListJobs_FullMethodName             = "/convoy.ConvoyService/ListJobs"
+	ConvoyService_CancelJob_FullMethodName            = "/convoy.ConvoyService/CancelJob"
 )
 
 // ConvoyServiceClient is the client API for ConvoyService service.
@@ -32,9 +38,20 @@ const (
 // ConvoyService exposes RPCs for executing commands and retrieving agent health.
 type ConvoyServiceClient interface {
 	ExecuteCommand(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandResponse, error)
+	// ExecuteCommandStream runs a command and streams its output as it is produced, ending with an exit message.
+	ExecuteCommandStream(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ShellResponse], error)
 	ExecuteShell(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ShellRequest, ShellResponse], error)
 	CheckHealth(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
 	Copy(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CopyRequest, CopyResponse], error)
+	GetInfo(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
+	// StartJob runs a command in the background and returns once it has started.
+	StartJob(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*JobStatus, error)
+	// GetJob reports a background job's state and, on request, its output.
+	GetJob(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*JobStatus, error)
+	// ListJobs reports every background job the agent still remembers.
+	ListJobs(ctx context.Context, in *JobListRequest, opts ...grpc.CallOption) (*JobListResponse, error)
+	// CancelJob stops a background job or shell session, killing every process it started.
+	CancelJob(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error)
 }
 
 type convoyServiceClient struct {
@@ -55,9 +72,28 @@ func (c *convoyServiceClient) ExecuteCommand(ctx context.Context, in *CommandReq
 	return out, nil
 }
 
+func (c *convoyServiceClient) ExecuteCommandStream(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ShellResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ConvoyService_ServiceDesc.Streams[0], ConvoyService_ExecuteCommandStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CommandRequest, ShellResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConvoyService_ExecuteCommandStreamClient = grpc.ServerStreamingClient[ShellResponse]
+
 func (c *convoyServiceClient) ExecuteShell(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ShellRequest, ShellResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &ConvoyService_ServiceDesc.Streams[0], ConvoyService_ExecuteShell_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &ConvoyService_ServiceDesc.Streams[1], ConvoyService_ExecuteShell_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +116,7 @@ func (c *convoyServiceClient) CheckHealth(ctx context.Context, in *HealthRequest
 
 func (c *convoyServiceClient) Copy(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CopyRequest, CopyResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &ConvoyService_ServiceDesc.Streams[1], ConvoyService_Copy_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &ConvoyService_ServiceDesc.Streams[2], ConvoyService_Copy_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -91,6 +127,56 @@ func (c *convoyServiceClient) Copy(ctx context.Context, opts ...grpc.CallOption)
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ConvoyService_CopyClient = grpc.BidiStreamingClient[CopyRequest, CopyResponse]
 
+func (c *convoyServiceClient) GetInfo(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InfoResponse)
+	err := c.cc.Invoke(ctx, ConvoyService_GetInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *convoyServiceClient) StartJob(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*JobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatus)
+	err := c.cc.Invoke(ctx, ConvoyService_StartJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *convoyServiceClient) GetJob(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*JobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatus)
+	err := c.cc.Invoke(ctx, ConvoyService_GetJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *convoyServiceClient) ListJobs(ctx context.Context, in *JobListRequest, opts ...grpc.CallOption) (*JobListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobListResponse)
+	err := c.cc.Invoke(ctx, ConvoyService_ListJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *convoyServiceClient) CancelJob(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelResponse)
+	err := c.cc.Invoke(ctx, ConvoyService_CancelJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ConvoyServiceServer is the server API for ConvoyService service.
 // All implementations must embed UnimplementedConvoyServiceServer
 // for forward compatibility.
@@ -98,9 +184,20 @@ type ConvoyService_CopyClient = grpc.BidiStreamingClient[CopyRequest, CopyRespon
 // ConvoyService exposes RPCs for executing commands and retrieving agent health.
 type ConvoyServiceServer interface {
 	ExecuteCommand(context.Context, *CommandRequest) (*CommandResponse, error)
+	// ExecuteCommandStream runs a command and streams its output as it is produced, ending with an exit message.
+	ExecuteCommandStream(*CommandRequest, grpc.ServerStreamingServer[ShellResponse]) error
 	ExecuteShell(grpc.BidiStreamingServer[ShellRequest, ShellResponse]) error
 	CheckHealth(context.Context, *HealthRequest) (*HealthResponse, error)
 	Copy(grpc.BidiStreamingServer[CopyRequest, CopyResponse]) error
+	GetInfo(context.Context, *InfoRequest) (*InfoResponse, error)
+	// StartJob runs a command in the background and returns once it has started.
+	StartJob(context.Context, *CommandRequest) (*JobStatus, error)
+	// GetJob reports a background job's state and, on request, its output.
+	GetJob(context.Context, *JobRequest) (*JobStatus, error)
+	// ListJobs reports every background job the agent still remembers.
+	ListJobs(context.Context, *JobListRequest) (*JobListResponse, error)
+	// CancelJob stops a background job or shell session, killing every process it started.
+	CancelJob(context.Context, *CancelRequest) (*CancelResponse, error)
 	mustEmbedUnimplementedConvoyServiceServer()
 }
 
@@ -114,6 +211,9 @@ type UnimplementedConvoyServiceServer struct{}
 func (UnimplementedConvoyServiceServer) ExecuteCommand(context.Context, *CommandRequest) (*CommandResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ExecuteCommand not implemented")
 }
+func (UnimplementedConvoyServiceServer) ExecuteCommandStream(*CommandRequest, grpc.ServerStreamingServer[ShellResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ExecuteCommandStream not implemented")
+}
 func (UnimplementedConvoyServiceServer) ExecuteShell(grpc.BidiStreamingServer[ShellRequest, ShellResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method ExecuteShell not implemented")
 }
@@ -123,6 +223,21 @@ func (UnimplementedConvoyServiceServer) CheckHealth(context.Context, *HealthRequ
 func (UnimplementedConvoyServiceServer) Copy(grpc.BidiStreamingServer[CopyRequest, CopyResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method Copy not implemented")
 }
+func (UnimplementedConvoyServiceServer) GetInfo(context.Context, *InfoRequest) (*InfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInfo not implemented")
+}
+func (UnimplementedConvoyServiceServer) StartJob(context.Context, *CommandRequest) (*JobStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartJob not implemented")
+}
+func (UnimplementedConvoyServiceServer) GetJob(context.Context, *JobRequest) (*JobStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedConvoyServiceServer) ListJobs(context.Context, *JobListRequest) (*JobListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (UnimplementedConvoyServiceServer) CancelJob(context.Context, *CancelRequest) (*CancelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelJob not implemented")
+}
 func (UnimplementedConvoyServiceServer) mustEmbedUnimplementedConvoyServiceServer() {}
 func (UnimplementedConvoyServiceServer) testEmbeddedByValue()                       {}
 
@@ -162,6 +277,17 @@ func _ConvoyService_ExecuteCommand_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ConvoyService_ExecuteCommandStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CommandRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConvoyServiceServer).ExecuteCommandStream(m, &grpc.GenericServerStream[CommandRequest, ShellResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConvoyService_ExecuteCommandStreamServer = grpc.ServerStreamingServer[ShellResponse]
+
 func _ConvoyService_ExecuteShell_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(ConvoyServiceServer).ExecuteShell(&grpc.GenericServerStream[ShellRequest, ShellResponse]{ServerStream: stream})
 }
@@ -194,6 +320,96 @@ func _ConvoyService_Copy_Handler(srv interface{}, stream grpc.ServerStream) erro
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ConvoyService_CopyServer = grpc.BidiStreamingServer[CopyRequest, CopyResponse]
 
+func _ConvoyService_GetInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConvoyServiceServer).GetInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConvoyService_GetInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConvoyServiceServer).GetInfo(ctx, req.(*InfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConvoyService_StartJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConvoyServiceServer).StartJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConvoyService_StartJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConvoyServiceServer).StartJob(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConvoyService_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConvoyServiceServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConvoyService_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConvoyServiceServer).GetJob(ctx, req.(*JobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConvoyService_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConvoyServiceServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConvoyService_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConvoyServiceServer).ListJobs(ctx, req.(*JobListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConvoyService_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConvoyServiceServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConvoyService_CancelJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConvoyServiceServer).CancelJob(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ConvoyService_ServiceDesc is the grpc.ServiceDesc for ConvoyService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -209,8 +425,33 @@ var ConvoyService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CheckHealth",
 			Handler:    _ConvoyService_CheckHealth_Handler,
 		},
+		{
+			MethodName: "GetInfo",
+			Handler:    _ConvoyService_GetInfo_Handler,
+		},
+		{
+			MethodName: "StartJob",
+			Handler:    _ConvoyService_StartJob_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _ConvoyService_GetJob_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _ConvoyService_ListJobs_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _ConvoyService_CancelJob_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExecuteCommandStream",
+			Handler:       _ConvoyService_ExecuteCommandStream_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "ExecuteShell",
 			Handler:       _ConvoyService_ExecuteShell_Handler,
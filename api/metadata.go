@@ -0,0 +1,10 @@
+package api
+
+// RequestIDMetadataKey is the gRPC metadata key carrying the ID that correlates
+// a CLI operation with the agent's log lines for it.
+const RequestIDMetadataKey = "x-request-id"
+
+// ProtocolVersion is the major.minor version of the agent RPC protocol. The
+// minor version grows with backwards-compatible additions; the major version
+// changes when old CLIs and agents can no longer work together.
+const ProtocolVersion = "1.0"
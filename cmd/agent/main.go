@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os/signal"
 	"syscall"
@@ -10,7 +11,10 @@ import (
 )
 
 func main() {
-	cfg, err := agent.LoadConfig("")
+	configPath := flag.String("config", "", "Agent config file (default ~/.config/convoy/agent.yaml, or agent.yaml in $CONVOY_CONFIG_DIR); only a missing default file falls back to the built-in defaults")
+	flag.Parse()
+
+	cfg, err := agent.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("load config: %v", err)
 	}
@@ -13,6 +13,9 @@ import (
 type Application struct {
 	cfgPath string
 
+	// dockerHost overrides Config.DockerHost when non-empty.
+	dockerHost string
+
 	configMu sync.Mutex
 	config   *app.Config
 
@@ -51,6 +54,10 @@ func (a *Application) Config() (*app.Config, error) {
 		return nil, err
 	}
 
+	if a.dockerHost != "" {
+		cfg.DockerHost = a.dockerHost
+	}
+
 	a.config = cfg
 	return a.config, nil
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -43,3 +44,33 @@ func TestApplicationManagerErrorsBubblesUp(t *testing.T) {
 		t.Fatalf("expected error from runtime factory")
 	}
 }
+
+func TestApplicationConfigDockerHostOverride(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configYAML := "image: test\ndocker_host: unix:///tmp/from-config.sock\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	t.Setenv("DOCKER_HOST", "tcp://from-env:2375")
+
+	application := newApplication(configPath, nil)
+	application.dockerHost = resolveDockerHost("tcp://from-flag:2376")
+	cfg, err := application.Config()
+	if err != nil {
+		t.Fatalf("Config error: %v", err)
+	}
+	if cfg.DockerHost != "tcp://from-flag:2376" {
+		t.Fatalf("expected flag to win, got %s", cfg.DockerHost)
+	}
+
+	application = newApplication(configPath, nil)
+	application.dockerHost = resolveDockerHost("")
+	cfg, err = application.Config()
+	if err != nil {
+		t.Fatalf("Config error: %v", err)
+	}
+	if cfg.DockerHost != "tcp://from-env:2375" {
+		t.Fatalf("expected DOCKER_HOST to win over config, got %s", cfg.DockerHost)
+	}
+}
@@ -0,0 +1,85 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"convoy/internal/orchestrator"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewAPICmd creates the api command for listing the RPCs an agent exposes.
+func NewAPICmd() *cobra.Command {
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "api [container-id|name]",
+		Short: "List the gRPC services and methods an agent exposes",
+		Long: `List the gRPC services and methods a container's agent exposes, using gRPC
+reflection. This helps spot protocol skew between the CLI and an agent.
+
+Reflection is off by default; enable it on the agent with enable_reflection: true
+in its config or CONVOY_AGENT_REFLECTION=true.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containers, err := LoadContainers()
+			if err != nil {
+				return err
+			}
+			container, err := containers.ResolveWithEndpoint(args[0])
+			if err != nil {
+				return err
+			}
+
+			rpc := NewRPCClientWithTimeout(timeout)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			services, err := rpc.Services(context.Background(), container.Endpoint)
+			if status.Code(err) == codes.Unimplemented {
+				return fmt.Errorf("agent for %s does not expose gRPC reflection (set enable_reflection: true in its config)", ContainerLabel(container))
+			}
+			if err != nil {
+				return fmt.Errorf("list services: %w", err)
+			}
+
+			return writeServices(cmd.OutOrStdout(), services)
+		},
+	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "Timeout for the reflection call")
+
+	return cmd
+}
+
+func writeServices(w io.Writer, services []orchestrator.ServiceInfo) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, svc := range services {
+		_, _ = fmt.Fprintf(writer, "%s (%s)\n", svc.Name, svc.File)
+		for _, method := range svc.Methods {
+			_, _ = fmt.Fprintf(writer, "  %s\t%s\n", method.Name, methodKind(method))
+		}
+	}
+	return writer.Flush()
+}
+
+func methodKind(method orchestrator.MethodInfo) string {
+	switch {
+	case method.ClientStreaming && method.ServerStreaming:
+		return "bidi-streaming"
+	case method.ClientStreaming:
+		return "client-streaming"
+	case method.ServerStreaming:
+		return "server-streaming"
+	default:
+		return "unary"
+	}
+}
@@ -0,0 +1,61 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCancelCmd creates the cancel command for stopping background jobs and shell sessions.
+func NewCancelCmd() *cobra.Command {
+	var (
+		endpoint string
+		timeout  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cancel [container-id|name] ID",
+		Short: "Cancel a background job or shell session",
+		Long: `Stop a background job started with "convoy exec --detach", or a shell session,
+on a container's agent. The agent kills the command together with every
+process it started.
+
+  convoy cancel web 5d41402abc4b2a76b9719d911017c592`,
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if endpoint == "" && len(args) != 2 {
+				return errors.New("requires a container and a job or session ID")
+			}
+			id := args[len(args)-1]
+			target, err := jobEndpoint(args[:len(args)-1], endpoint)
+			if err != nil {
+				return err
+			}
+
+			rpc := NewRPCClientWithTimeout(timeout)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			resp, err := rpc.CancelJob(context.Background(), target, id)
+			if err != nil {
+				return fmt.Errorf("cancel %s: %w", id, err)
+			}
+			if resp.GetAlreadyFinished() {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s %s had already finished\n", resp.GetKind(), id)
+				return nil
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Canceled %s %s\n", resp.GetKind(), id)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "Dial the agent at this host:port or unix:///socket instead of resolving a container (omit the container argument)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for reaching the agent and waiting for the job to stop")
+
+	return cmd
+}
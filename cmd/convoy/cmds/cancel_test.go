@@ -0,0 +1,45 @@
+package cmds
+
+import (
+	"strings"
+	"testing"
+
+	"convoy/internal/agent"
+	"convoy/internal/orchestrator"
+)
+
+func TestCancelCmd_StopsDetachedJob(t *testing.T) {
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Endpoint: endpoint}}})
+
+	stdout, stderr, err := runQuiet(t, NewExecCmd(), "web", "--detach", "--no-stdin", "--", "sleep 30")
+	if err != nil {
+		t.Fatalf("exec --detach: %v (stderr %q)", err, stderr)
+	}
+	jobID := strings.TrimSpace(stdout)
+
+	stdout, _, err = runQuiet(t, NewCancelCmd(), "web", jobID)
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if stdout != "Canceled job "+jobID+"\n" {
+		t.Fatalf("cancel printed %q", stdout)
+	}
+
+	_, stderr, err = runQuiet(t, NewLogsCmd(), "web", "--job", jobID)
+	if err != nil {
+		t.Fatalf("logs: %v", err)
+	}
+	if stderr != "Job "+jobID+" was canceled\n" {
+		t.Fatalf("logs reported %q, want the job canceled", stderr)
+	}
+
+	stdout, _, err = runQuiet(t, NewCancelCmd(), "web", jobID)
+	if err != nil || !strings.Contains(stdout, "already finished") {
+		t.Fatalf("second cancel = %q, %v", stdout, err)
+	}
+
+	if _, _, err := runQuiet(t, NewCancelCmd(), jobID); err == nil {
+		t.Fatalf("cancel without a container should fail")
+	}
+}
@@ -1,6 +1,7 @@
 package cmds
 
 import (
+	"errors"
 	"fmt"
 
 	"convoy/internal/app"
@@ -32,26 +33,38 @@ func NewConfigCmd() *cobra.Command {
 		},
 	}
 
-	cmd.AddCommand(newConfigInitCmd())
+	cmd.AddCommand(newConfigInitCmd(), newConfigValidateCmd())
 
 	return cmd
 }
 
 func newConfigInitCmd() *cobra.Command {
+	var force bool
+
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Create the default configuration file",
+		Long: `Create the default configuration file, or with --profile NAME a new profile
+that can be selected with convoy context use NAME. An existing file is left
+untouched unless --force is given.`,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			cfgPath := CLIOpts.ConfigPath
 			if cfgPath == "" {
 				var err error
-				cfgPath, err = app.DefaultConfigPath()
+				if CLIOpts.Profile != "" {
+					cfgPath, err = app.ProfilePath(CLIOpts.Profile)
+				} else {
+					cfgPath, err = app.DefaultConfigPath()
+				}
 				if err != nil {
 					return err
 				}
 			}
 
-			createdPath, err := app.InitializeConfig(cfgPath)
+			createdPath, err := app.InitializeConfig(cfgPath, force)
+			if errors.Is(err, app.ErrConfigExists) {
+				return fmt.Errorf("config already exists at %s; edit it directly or run convoy config init --force to replace it with the defaults", cfgPath)
+			}
 			if err != nil {
 				return err
 			}
@@ -61,5 +74,47 @@ func newConfigInitCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing config file with the defaults")
+
 	return cmd
 }
+
+func newConfigValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration file without using it",
+		Long: `Load the configuration selected by --config, --profile or the current context
+and check it, printing OK or every problem found. Exits non-zero when the file
+cannot be read or is invalid, so it can gate config changes in CI.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfgPath, err := app.ResolveConfigPath(CLIOpts.ConfigPath, CLIOpts.Profile)
+			if err != nil {
+				return err
+			}
+			if cfgPath == "" {
+				if cfgPath, err = app.DefaultConfigPath(); err != nil {
+					return err
+				}
+			}
+
+			_, err = app.LoadConfig(cfgPath)
+			var invalid *app.ValidationError
+			if errors.As(err, &invalid) {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %d problem(s)\n", cfgPath, len(invalid.Problems))
+				for _, problem := range invalid.Problems {
+					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "  - %s\n", problem)
+				}
+				cmd.SilenceErrors = true
+				return &ExitError{Code: 1}
+			}
+			if err != nil {
+				return err
+			}
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: OK\n", cfgPath)
+			return nil
+		},
+	}
+}
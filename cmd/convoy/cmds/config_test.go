@@ -0,0 +1,90 @@
+package cmds
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runConfigValidate(t *testing.T, path string) (string, error) {
+	t.Helper()
+	prev := CLIOpts
+	CLIOpts.ConfigPath = path
+	t.Cleanup(func() { CLIOpts = prev })
+
+	var out strings.Builder
+	cmd := NewConfigCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"validate"})
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestConfigValidateCmd(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(valid, []byte("image: alpine\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := os.WriteFile(invalid, []byte("grpc_port: 70000\npull_timeout_sec: -1\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	out, err := runConfigValidate(t, valid)
+	if err != nil || out != valid+": OK\n" {
+		t.Fatalf("valid config: out=%q err=%v", out, err)
+	}
+
+	out, err = runConfigValidate(t, invalid)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("invalid config: expected exit code 1, got %v", err)
+	}
+	for _, want := range []string{"3 problem(s)", "  - image is required", "  - grpc_port must be between 1 and 65535", "  - pull_timeout_sec cannot be negative"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := runConfigValidate(t, filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+}
+
+func TestConfigInitCmd_RefusesExistingFileWithoutForce(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("image: custom\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	prev := CLIOpts
+	CLIOpts.ConfigPath = cfgPath
+	t.Cleanup(func() { CLIOpts = prev })
+
+	run := func(args ...string) error {
+		cmd := NewConfigCmd()
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		cmd.SetArgs(append([]string{"init"}, args...))
+		return cmd.Execute()
+	}
+
+	err := run()
+	if err == nil || !strings.Contains(err.Error(), cfgPath) || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected a refusal naming the path and --force, got %v", err)
+	}
+
+	if err := run("--force"); err != nil {
+		t.Fatalf("init --force: %v", err)
+	}
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if strings.Contains(string(data), "custom") {
+		t.Fatalf("expected config to be replaced, got %s", data)
+	}
+}
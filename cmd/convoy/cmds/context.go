@@ -0,0 +1,74 @@
+package cmds
+
+import (
+	"fmt"
+
+	"convoy/internal/app"
+
+	"github.com/spf13/cobra"
+)
+
+// NewContextCmd creates the context command for switching between config profiles.
+func NewContextCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "context",
+		Short: "Switch between saved config profiles",
+		Long: `Switch between saved config profiles. A profile is a config file stored as
+profiles/NAME.yaml in the convoy config directory; create one with
+convoy config init --profile NAME.
+
+The selected context is remembered across invocations and applies whenever
+neither --config nor --profile is given. The "default" context uses the main
+config file.`,
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(newContextUseCmd(), newContextListCmd())
+
+	return cmd
+}
+
+func newContextUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "use NAME",
+		Short:        "Make a profile the current context",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := app.UseContext(args[0]); err != nil {
+				return err
+			}
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Switched to context %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+func newContextListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "list",
+		Short:        "List contexts, marking the current one",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			current, err := app.CurrentContext()
+			if err != nil {
+				return err
+			}
+			profiles, err := app.ListProfiles()
+			if err != nil {
+				return err
+			}
+
+			for _, name := range append([]string{app.DefaultContext}, profiles...) {
+				marker := " "
+				if name == current {
+					marker = "*"
+				}
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", marker, name)
+			}
+			return nil
+		},
+	}
+}
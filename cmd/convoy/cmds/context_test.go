@@ -0,0 +1,60 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"convoy/internal/app"
+)
+
+func runContextCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out strings.Builder
+	cmd := NewContextCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestContextCmd_UsePersistsSelection(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CONVOY_CONFIG_DIR", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "profiles"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "profiles", "staging.yaml"), []byte("image: staging-image\n"), 0o600); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+
+	if _, err := runContextCmd(t, "use", "nope"); err == nil {
+		t.Fatalf("expected an error switching to a missing profile")
+	}
+
+	if _, err := runContextCmd(t, "use", "staging"); err != nil {
+		t.Fatalf("context use: %v", err)
+	}
+
+	out, err := runContextCmd(t, "list")
+	if err != nil {
+		t.Fatalf("context list: %v", err)
+	}
+	if want := "  default\n* staging\n"; out != want {
+		t.Fatalf("context list = %q, want %q", out, want)
+	}
+
+	path, err := app.ResolveConfigPath("", "")
+	if err != nil {
+		t.Fatalf("ResolveConfigPath: %v", err)
+	}
+	cfg, err := app.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Image != "staging-image" {
+		t.Fatalf("config resolved to image %q, want the staging profile", cfg.Image)
+	}
+}
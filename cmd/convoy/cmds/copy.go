@@ -2,16 +2,25 @@ package cmds
 
 import (
 	"archive/tar"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	pathpkg "path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	convoypb "convoy/api"
 	"convoy/internal/orchestrator"
+	"convoy/internal/transfer"
 
 	"github.com/spf13/cobra"
 )
@@ -23,15 +32,24 @@ type copyEndpoint struct {
 	path        string
 }
 
+// options returns opts adjusted for the --overwrite-on or --no-overwrite-on
+// override naming this endpoint's container, if any.
+func (e copyEndpoint) options(opts copyOptions) copyOptions {
+	if overwrite, ok := opts.overwriteOn[e.container]; ok && e.isContainer {
+		opts.overwrite = overwrite
+	}
+	return opts
+}
+
 // NewCopyCmd creates the copy command for transferring files between host and containers.
 func NewCopyCmd() *cobra.Command {
 	var (
-		timeout   time.Duration
-		overwrite bool
+		endpoint string
+		flags    copyFlags
 	)
 
 	cmd := &cobra.Command{
-		Use:   "copy <source> <destination> [destination...]",
+		Use:   "copy <source> [source...] <destination> [destination...]",
 		Short: "Copy files/folders to or from containers",
 		Long: `Copy files or folders between host and containers.
 
@@ -39,6 +57,11 @@ func NewCopyCmd() *cobra.Command {
 				  - Local path: /path/to/file or ./relative/path
 				  - Container path: container-name:/path/in/container
 				
+				Sources may contain shell-style globs which are expanded on the side that
+				owns the files. Several local sources may be given before the destinations;
+				all matches are packed into a single transfer relative to the directory
+				that precedes the first wildcard.
+				
 				Examples:
 				  # Copy from host to single container
 				  convoy copy ./myfile.txt mycontainer:/tmp/myfile.txt
@@ -46,81 +69,151 @@ func NewCopyCmd() *cobra.Command {
 				  # Copy from host to multiple containers
 				  convoy copy ./config.yaml c1:/etc/app/config.yaml c2:/etc/app/config.yaml
 				
+				  # Keep existing files on c2 only
+				  convoy copy --no-overwrite-on c2 ./config.yaml c1:/etc/app c2:/etc/app
+				
 				  # Copy from container to host
 				  convoy copy mycontainer:/var/log/app.log ./app.log
 				
 				  # Copy directory from host to container
 				  convoy copy ./mydir mycontainer:/opt/mydir
 				
+				  # Copy several local files and globs to a container
+				  convoy copy ./logs/*.log ./notes.txt mycontainer:/tmp/upload
+				
+				  # Copy matching files out of a container
+				  convoy copy 'mycontainer:/var/log/*.log' ./logs
+				
+				  # Export a container path as a local tarball (no extraction)
+				  convoy copy --archive mycontainer:/var/log ./logs.tar
+				
+				  # Push a local tarball and extract it in the container
+				  convoy copy --extract ./bundle.tar mycontainer:/opt/app
+				
+				  # Replace a release directory all at once, leaving it untouched if the copy fails
+				  convoy copy --atomic ./release mycontainer:/opt/app
+				
+				  # Upload a large file, continuing where an interrupted attempt stopped
+				  convoy copy --resume ./disk.img mycontainer:/data/disk.img
+				
 				  # Copy between containers (uses host as relay)
-				  convoy copy c1:/data/file.txt c2:/backup/file.txt`,
+				  convoy copy c1:/data/file.txt c2:/backup/file.txt
+				
+				  # Dial an agent address directly; the container name is then only a label
+				  convoy copy --endpoint 10.0.0.5:6000 ./myfile.txt agent:/tmp/myfile.txt
+				
+				  # Report progress as JSON lines, e.g. {"event":"copy_start","container":"c1",...}
+				  convoy copy --json ./config.yaml c1:/etc/app/config.yaml`,
 		Args:         cobra.MinimumNArgs(2),
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			source, err := parseEndpoint(args[0])
+			sources, destinations, err := parseCopyArgs(args)
 			if err != nil {
-				return fmt.Errorf("invalid source: %w", err)
-			}
-
-			var destinations []copyEndpoint
-			for _, arg := range args[1:] {
-				dest, err := parseEndpoint(arg)
-				if err != nil {
-					return fmt.Errorf("invalid destination %q: %w", arg, err)
-				}
-				destinations = append(destinations, dest)
-			}
-
-			hasContainer := source.isContainer
-			for _, d := range destinations {
-				if d.isContainer {
-					hasContainer = true
-					break
-				}
-			}
-			if !hasContainer {
-				return fmt.Errorf("at least one endpoint must be a container")
+				return err
 			}
-
-			hostDestCount := 0
-			for _, d := range destinations {
-				if !d.isContainer {
-					hostDestCount++
-				}
+			opts, err := flags.options(cmd)
+			if err != nil {
+				return err
 			}
-			if hostDestCount > 1 {
-				return fmt.Errorf("only one host destination allowed per invocation")
+			if err := checkOverwriteOn(opts, destinations); err != nil {
+				return err
 			}
 
-			containers, err := LoadContainers()
+			containers, err := LoadContainersOrEndpoint(endpoint)
 			if err != nil {
 				return err
 			}
 
-			rpc := NewRPCClient(timeout, 0)
+			rpc := NewRPCClient(flags.timeout, 0)
 			defer func() {
 				_ = rpc.Close()
 			}()
 
-			ctx := context.Background()
-
-			switch {
-			case !source.isContainer:
-				return copyHostToContainers(ctx, cmd, rpc.RPC, containers, source, destinations, overwrite)
-			case len(destinations) == 1 && !destinations[0].isContainer:
-				return copyContainerToHost(ctx, cmd, rpc.RPC, containers, source, destinations[0], overwrite)
-			default:
-				return copyContainerToContainers(ctx, cmd, rpc.RPC, containers, source, destinations, overwrite)
-			}
+			return runCopy(context.Background(), cmd, rpc.RPC, containers, sources, destinations, opts)
 		},
 	}
 
-	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Timeout for copy operations")
-	cmd.Flags().BoolVar(&overwrite, "overwrite", true, "Overwrite existing files")
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "Dial the agent at this host:port or unix:///socket for every container path instead of looking containers up")
+	flags.register(cmd)
 
 	return cmd
 }
 
+// parseCopyArgs parses copy endpoints and checks they describe a supported transfer.
+func parseCopyArgs(args []string) (sources, destinations []copyEndpoint, err error) {
+	if len(args) < 2 {
+		return nil, nil, fmt.Errorf("a copy needs a source and a destination")
+	}
+
+	endpoints := make([]copyEndpoint, 0, len(args))
+	for _, arg := range args {
+		endpoint, err := parseEndpoint(arg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid endpoint %q: %w", arg, err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	sources, destinations = splitEndpoints(endpoints)
+
+	hasContainer := sources[0].isContainer
+	for _, d := range destinations {
+		if d.isContainer {
+			hasContainer = true
+			break
+		}
+	}
+	if !hasContainer {
+		return nil, nil, fmt.Errorf("at least one endpoint must be a container")
+	}
+
+	hostDestCount := 0
+	for _, d := range destinations {
+		if !d.isContainer {
+			hostDestCount++
+		}
+	}
+	if hostDestCount > 1 {
+		return nil, nil, fmt.Errorf("only one host destination allowed per invocation")
+	}
+
+	return sources, destinations, nil
+}
+
+// runCopy performs one parsed transfer, picking the direction from its endpoints.
+func runCopy(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, sources, destinations []copyEndpoint, opts copyOptions) error {
+	source := sources[0]
+	if opts.extract && source.isContainer {
+		return fmt.Errorf("--extract only applies to a local tar file source")
+	}
+	if opts.archive && (!source.isContainer || len(destinations) != 1 || destinations[0].isContainer) {
+		return fmt.Errorf("--archive only applies to copying from a container to a local file")
+	}
+	switch {
+	case !source.isContainer:
+		if opts.resume {
+			if len(sources) != 1 || len(destinations) != 1 || !destinations[0].isContainer || transfer.HasGlobMeta(source.path) {
+				return fmt.Errorf("--resume only supports copying a single file between the host and one container")
+			}
+			return copyFileToContainer(ctx, cmd, rpc, containers, source, destinations[0], opts)
+		}
+		return copyHostToContainers(ctx, cmd, rpc, containers, sources, destinations, opts)
+	case len(destinations) == 1 && !destinations[0].isContainer:
+		if opts.resume {
+			return copyContainerFileToHost(ctx, cmd, rpc, containers, source, destinations[0], opts)
+		}
+		if opts.archive {
+			return copyContainerToArchive(ctx, cmd, rpc, containers, source, destinations[0], opts)
+		}
+		return copyContainerToHost(ctx, cmd, rpc, containers, source, destinations[0], opts)
+	default:
+		if opts.resume {
+			return fmt.Errorf("--resume only supports copying a single file between the host and one container")
+		}
+		return copyContainerToContainers(ctx, cmd, rpc, containers, source, destinations, opts)
+	}
+}
+
 // parseEndpoint parses a string like "container:/path" or "/local/path".
 func parseEndpoint(s string) (copyEndpoint, error) {
 	if s == "" {
@@ -148,16 +241,240 @@ func parseEndpoint(s string) (copyEndpoint, error) {
 	}, nil
 }
 
+// splitEndpoints separates the leading sources from the destinations. Only local
+// sources may be repeated; a container source is always a single endpoint whose
+// path may carry a glob expanded by the agent.
+func splitEndpoints(endpoints []copyEndpoint) (sources, destinations []copyEndpoint) {
+	n := 1
+	if !endpoints[0].isContainer {
+		for n < len(endpoints)-1 && !endpoints[n].isContainer {
+			n++
+		}
+	}
+	return endpoints[:n], endpoints[n:]
+}
+
+// copyOptions carries the flags shared by every copy direction.
+type copyOptions struct {
+	overwrite bool
+	// overwriteOn overrides overwrite for the destination containers it names.
+	overwriteOn   map[string]bool
+	exclude       []string
+	resume        bool
+	preserveTimes bool
+	preserveOwner bool
+	skipNewer     bool
+	archive       bool
+	extract       bool
+	atomic        bool
+	noFollow      bool
+	// relaySpillDir, when set, buffers container-to-container relays instead of
+	// streaming them, moving them to disk past relaySpillThreshold.
+	relaySpillDir       string
+	relaySpillThreshold int64
+	// events, set by --json, receives progress instead of the human-readable lines.
+	events *eventEmitter
+}
+
+// say prints a human-readable progress line to w, or emits ev instead with --json.
+func (o copyOptions) say(w io.Writer, ev event, format string, args ...any) {
+	if o.events != nil {
+		o.events.emit(ev)
+		return
+	}
+	_, _ = fmt.Fprintf(w, format, args...)
+}
+
+// emit writes ev with --json only, for steps the human-readable output does not mention.
+func (o copyOptions) emit(ev event) {
+	if o.events != nil {
+		o.events.emit(ev)
+	}
+}
+
+// failed emits a copy_error event for container with --json and returns err unchanged.
+func (o copyOptions) failed(container string, err error) error {
+	if o.events != nil {
+		o.events.emit(event{Event: "copy_error", Container: container, Error: err.Error()})
+	}
+	return err
+}
+
+// agent returns the options the agent applies to a transfer.
+func (o copyOptions) agent() orchestrator.CopyOptions {
+	return orchestrator.CopyOptions{
+		Overwrite:     o.overwrite,
+		PreserveTimes: o.preserveTimes,
+		PreserveOwner: o.preserveOwner,
+		SkipNewer:     o.skipNewer,
+		Atomic:        o.atomic,
+		NoFollow:      o.noFollow,
+		Exclude:       o.exclude,
+	}
+}
+
+// metadata is what extraction restores onto each entry.
+func (o copyOptions) metadata() transfer.Metadata {
+	return transfer.Metadata{Times: o.preserveTimes, Owner: o.preserveOwner}
+}
+
+// copyFlags are the flags copy and copy-batch share, so both take the same
+// options and neither falls behind when one is added.
+type copyFlags struct {
+	timeout       time.Duration
+	jsonEvents    bool
+	overwriteOn   []string
+	noOverwriteOn []string
+	opts          copyOptions
+}
+
+// register adds the shared copy flags to cmd.
+func (f *copyFlags) register(cmd *cobra.Command) {
+	f.opts.relaySpillThreshold = defaultRelaySpillThreshold
+	flags := cmd.Flags()
+	flags.DurationVar(&f.timeout, "timeout", 5*time.Minute, "Timeout for each copy operation")
+	flags.BoolVar(&f.opts.overwrite, "overwrite", true, "Overwrite existing files")
+	flags.StringSliceVar(&f.overwriteOn, "overwrite-on", nil, "Overwrite existing files on these destination containers even with --overwrite=false (can be repeated)")
+	flags.StringSliceVar(&f.noOverwriteOn, "no-overwrite-on", nil, "Keep existing files on these destination containers (can be repeated)")
+	flags.StringArrayVar(&f.opts.exclude, "exclude", nil, "Skip paths matching a gitignore-style pattern (can be repeated)")
+	flags.BoolVar(&f.opts.preserveTimes, "preserve-times", true, "Preserve access and modification times")
+	flags.BoolVar(&f.opts.preserveOwner, "preserve-owner", false, "Preserve uid/gid when the receiving side runs as root")
+	flags.BoolVarP(&f.opts.skipNewer, "update", "u", false, "Skip files that are newer at the destination")
+	flags.BoolVar(&f.opts.skipNewer, "no-overwrite-newer", false, "Alias for --update")
+	flags.BoolVar(&f.opts.archive, "archive", false, "Write the data pulled from a container to the local destination as a tar file instead of extracting it")
+	flags.BoolVar(&f.opts.extract, "extract", false, "Unpack a local tar file source at the destination instead of copying the file itself")
+	flags.StringVar(&f.opts.relaySpillDir, "relay-spill", "", "Buffer container-to-container relays in this directory and replay them to each destination instead of streaming")
+	flags.BoolVar(&f.opts.atomic, "atomic", false, "Stage files pushed to containers and move them into place only once the whole transfer succeeded")
+	flags.BoolVar(&f.opts.noFollow, "no-follow", false, "Reject symlinks that are absolute or point outside the destination instead of recreating them")
+	flags.BoolVar(&f.opts.resume, "resume", false, "Copy a single file without tar framing, resuming a previous partial transfer in either direction (not with --atomic, --preserve-*, --update or --exclude)")
+	flags.BoolVar(&f.jsonEvents, "json", false, "Print one JSON event per line (copy_start, copy_progress, copy_done, copy_error) instead of human-readable output")
+}
+
+// options checks the flags set on cmd and returns the options to copy with,
+// sending --json events to its output.
+func (f *copyFlags) options(cmd *cobra.Command) (copyOptions, error) {
+	if f.opts.resume {
+		if err := checkResumeFlags(cmd); err != nil {
+			return copyOptions{}, err
+		}
+	}
+	opts := f.opts
+	if len(f.overwriteOn)+len(f.noOverwriteOn) > 0 {
+		opts.overwriteOn = make(map[string]bool)
+		for _, name := range f.overwriteOn {
+			opts.overwriteOn[name] = true
+		}
+		for _, name := range f.noOverwriteOn {
+			if opts.overwriteOn[name] {
+				return copyOptions{}, fmt.Errorf("%s is given to both --overwrite-on and --no-overwrite-on", name)
+			}
+			opts.overwriteOn[name] = false
+		}
+	}
+	opts.events = newEventEmitter(cmd.OutOrStdout(), f.jsonEvents)
+	return opts, nil
+}
+
+// checkOverwriteOn rejects --overwrite-on and --no-overwrite-on names that are
+// not a destination container, which would otherwise be silently ignored.
+func checkOverwriteOn(opts copyOptions, destinations []copyEndpoint) error {
+	for name := range opts.overwriteOn {
+		if !slices.ContainsFunc(destinations, func(d copyEndpoint) bool { return d.isContainer && d.container == name }) {
+			return fmt.Errorf("--overwrite-on/--no-overwrite-on: %s is not a destination container", name)
+		}
+	}
+	return nil
+}
+
+// checkResumeFlags rejects the flags a --resume copy cannot honour: it moves
+// one file without tar framing, so there are no entries to stage, filter or
+// restore metadata on.
+func checkResumeFlags(cmd *cobra.Command) error {
+	var set []string
+	for _, name := range []string{"atomic", "preserve-times", "preserve-owner", "update", "no-overwrite-newer", "exclude", "archive", "extract"} {
+		if cmd.Flags().Changed(name) {
+			set = append(set, "--"+name)
+		}
+	}
+	if len(set) > 0 {
+		return fmt.Errorf("--resume cannot be combined with %s", strings.Join(set, ", "))
+	}
+	return nil
+}
+
+// defaultRelaySpillThreshold is the relay size above which --relay-spill moves data to disk.
+const defaultRelaySpillThreshold = 64 << 20
+
+// copySource is a resolved local path together with its name inside the tar stream.
+// An empty name means a directory whose contents are packed at the tar root.
+type copySource struct {
+	path string
+	name string
+	info os.FileInfo
+}
+
+// expandSources resolves local source patterns into concrete paths. A single literal
+// source keeps the historical layout; otherwise each match is named relative to the
+// directory preceding the first wildcard of its pattern.
+func expandSources(patterns []string) ([]copySource, error) {
+	if len(patterns) == 1 && !transfer.HasGlobMeta(patterns[0]) {
+		info, err := os.Stat(patterns[0])
+		if err != nil {
+			return nil, fmt.Errorf("source not found: %w", err)
+		}
+		name := filepath.Base(patterns[0])
+		if info.IsDir() {
+			name = ""
+		}
+		return []copySource{{path: patterns[0], name: name, info: info}}, nil
+	}
+
+	var sources []copySource
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if transfer.HasGlobMeta(pattern) {
+			var err error
+			matches, err = filepath.Glob(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", pattern)
+			}
+		}
+
+		root := transfer.GlobRoot(pattern)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("source not found: %w", err)
+			}
+			name, err := filepath.Rel(root, match)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, copySource{path: match, name: filepath.ToSlash(name), info: info})
+		}
+	}
+
+	return sources, nil
+}
+
 // copyHostToContainers copies from local filesystem to one or more containers.
-func copyHostToContainers(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, destinations []copyEndpoint, overwrite bool) error {
-	srcPath := source.path
+func copyHostToContainers(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, sources []copyEndpoint, destinations []copyEndpoint, opts copyOptions) error {
+	patterns := make([]string, 0, len(sources))
+	for _, source := range sources {
+		patterns = append(patterns, source.path)
+	}
+	srcPath := strings.Join(patterns, " ")
 
-	srcInfo, err := os.Stat(srcPath)
-	if err != nil {
-		return fmt.Errorf("source not found: %w", err)
+	type target struct {
+		dest     copyEndpoint
+		endpoint string
 	}
 
 	var failed bool
+	var targets []target
 	for _, dest := range destinations {
 		if !dest.isContainer {
 			continue
@@ -165,21 +482,76 @@ func copyHostToContainers(ctx context.Context, cmd *cobra.Command, rpc *orchestr
 
 		container, err := containers.ResolveWithEndpoint(dest.container)
 		if err != nil {
-			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			opts.say(cmd.ErrOrStderr(), event{Event: "copy_error", Container: dest.container, Error: err.Error()}, "%v\n", err)
 			failed = true
 			continue
 		}
+		targets = append(targets, target{dest: dest, endpoint: container.Endpoint})
+	}
+	if len(targets) == 0 {
+		if failed {
+			return fmt.Errorf("one or more copy operations failed")
+		}
+		return nil
+	}
 
-		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Copying %s to %s:%s\n", srcPath, dest.container, dest.path)
-
-		if err := pushToContainer(ctx, rpc, container.Endpoint, srcPath, srcInfo, dest.path, overwrite); err != nil {
-			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "failed to copy to %s: %v\n", dest.container, err)
-			failed = true
-			continue
+	// The tar is built once and fanned out; each destination reads at its own
+	// pace, with a lagging one's backlog spilled to disk rather than held in memory.
+	var src io.ReadCloser
+	if opts.extract {
+		if len(patterns) != 1 {
+			return fmt.Errorf("--extract accepts a single local tar file as source")
+		}
+		file, err := os.Open(patterns[0])
+		if err != nil {
+			return fmt.Errorf("read archive: %w", err)
+		}
+		src = file
+	} else {
+		resolved, err := expandSources(patterns)
+		if err != nil {
+			return err
 		}
+		src = sourcesTarReader(resolved, opts.exclude)
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	readers := newFanOut(src, len(targets))
+
+	var mu sync.Mutex
+	report := func(w io.Writer, ev event, format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		opts.say(w, ev, format, args...)
+	}
 
-		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Successfully copied to %s\n", dest.container)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(r io.ReadCloser, t target) {
+			defer wg.Done()
+			defer func() {
+				_ = r.Close()
+			}()
+
+			report(cmd.OutOrStdout(), event{Event: "copy_start", Container: t.dest.container, Source: srcPath, Destination: t.dest.path},
+				"Copying %s to %s:%s\n", srcPath, t.dest.container, t.dest.path)
+			result, err := rpc.PushTar(ctx, t.endpoint, r, t.dest.path, t.dest.options(opts).agent())
+			if err != nil {
+				report(cmd.ErrOrStderr(), event{Event: "copy_error", Container: t.dest.container, Error: err.Error()},
+					"failed to copy to %s: %v\n", t.dest.container, err)
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+				return
+			}
+			report(cmd.OutOrStdout(), event{Event: "copy_done", Container: t.dest.container, Destination: t.dest.path, Skipped: result.GetSkippedNewer(), SkippedExisting: result.GetSkippedExisting()},
+				"Successfully copied to %s%s\n", t.dest.container, skippedSuffix(result))
+		}(readers[i], t)
 	}
+	wg.Wait()
 
 	if failed {
 		return fmt.Errorf("one or more copy operations failed")
@@ -187,212 +559,562 @@ func copyHostToContainers(ctx context.Context, cmd *cobra.Command, rpc *orchestr
 	return nil
 }
 
-// copyContainerToHost copies from a container to local filesystem.
-func copyContainerToHost(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, dest copyEndpoint, overwrite bool) error {
-	container, err := containers.ResolveWithEndpoint(source.container)
+// fanOutReader is one consumer's view of a fanOut.
+type fanOutReader struct {
+	f *fanOut
+	i int
+}
+
+// fanOutQueueChunks is how many chunks a fanOut keeps in memory per reader.
+// Chunks for a reader that falls further behind go to a spill file on disk, so
+// a fan-out holds at most this many chunks per destination in memory.
+const fanOutQueueChunks = 4
+
+// fanOutChunkSize is the size of each chunk the pump reads from the source.
+const fanOutChunkSize = 32 * 1024
+
+// fanOut tees a source reader to several consumers. A pump goroutine reads the
+// source and queues each chunk for every open consumer, so each consumer runs
+// at its own pace and a fast one is never held back by a slow one. A consumer
+// whose memory queue is full has later chunks spilled to a temporary file
+// until it catches up.
+type fanOut struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queues []fanOutQueue
+	closed []bool
+	done   bool
+	err    error
+}
+
+// fanOutQueue is the backlog of one reader: chunks in memory, followed by any
+// bytes spilled to disk once the memory queue was full.
+type fanOutQueue struct {
+	chunks [][]byte
+	spill  *os.File
+	// spilled and read are the spill file's write and read offsets.
+	spilled, read int64
+	// err fails this reader alone, when its backlog could not be spilled.
+	err error
+}
+
+// newFanOut starts pumping src and returns n independent readers over it. Each
+// reader must be closed; the pump stops early once every reader is closed.
+func newFanOut(src io.Reader, n int) []io.ReadCloser {
+	f := &fanOut{queues: make([]fanOutQueue, n), closed: make([]bool, n)}
+	f.cond = sync.NewCond(&f.mu)
+
+	readers := make([]io.ReadCloser, n)
+	for i := range readers {
+		readers[i] = &fanOutReader{f: f, i: i}
+	}
+
+	go f.pump(src)
+	return readers
+}
+
+func (f *fanOut) pump(src io.Reader) {
+	for {
+		f.mu.Lock()
+		if f.open() == 0 {
+			f.done = true
+			f.cond.Broadcast()
+			f.mu.Unlock()
+			return
+		}
+		f.mu.Unlock()
+
+		// A fresh buffer per chunk: queued chunks are shared by every consumer.
+		buf := make([]byte, fanOutChunkSize)
+		n, err := src.Read(buf)
+
+		f.mu.Lock()
+		if n > 0 {
+			for i := range f.queues {
+				if !f.closed[i] {
+					f.queues[i].push(buf[:n])
+				}
+			}
+		}
+		if err != nil {
+			f.done = true
+			if err != io.EOF {
+				f.err = err
+			}
+		}
+		f.cond.Broadcast()
+		done := f.done
+		f.mu.Unlock()
+
+		if done {
+			return
+		}
+	}
+}
+
+// push queues chunk in memory, or appends it to the spill file once the memory
+// queue is full or earlier chunks are already waiting on disk.
+func (q *fanOutQueue) push(chunk []byte) {
+	if q.err != nil {
+		return
+	}
+	if q.spill == nil && len(q.chunks) < fanOutQueueChunks {
+		q.chunks = append(q.chunks, chunk)
+		return
+	}
+	if q.spill == nil {
+		spill, err := os.CreateTemp("", "convoy-fanout-*")
+		if err != nil {
+			q.err = fmt.Errorf("spill copy backlog: %w", err)
+			return
+		}
+		q.spill = spill
+	}
+	n, err := q.spill.WriteAt(chunk, q.spilled)
+	q.spilled += int64(n)
 	if err != nil {
-		return err
+		q.err = fmt.Errorf("spill copy backlog: %w", err)
+	}
+}
+
+// discard drops the backlog and removes the spill file, if any.
+func (q *fanOutQueue) discard() {
+	q.chunks = nil
+	if q.spill != nil {
+		_ = q.spill.Close()
+		_ = os.Remove(q.spill.Name())
+		q.spill = nil
+	}
+	q.spilled, q.read = 0, 0
+}
+
+// open counts the readers that have not been closed. The caller holds f.mu.
+func (f *fanOut) open() int {
+	open := 0
+	for _, closed := range f.closed {
+		if !closed {
+			open++
+		}
 	}
+	return open
+}
 
-	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Copying %s:%s to %s\n", source.container, source.path, dest.path)
+func (r *fanOutReader) Read(p []byte) (int, error) {
+	f := r.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q := &f.queues[r.i]
+	for len(q.chunks) == 0 && q.read == q.spilled && q.err == nil && !f.done && !f.closed[r.i] {
+		f.cond.Wait()
+	}
+	if f.closed[r.i] {
+		return 0, io.ErrClosedPipe
+	}
 
-	if err := pullFromContainer(ctx, rpc, container.Endpoint, source.path, dest.path, overwrite); err != nil {
-		return fmt.Errorf("failed to copy from %s: %w", source.container, err)
+	if len(q.chunks) > 0 {
+		n := copy(p, q.chunks[0])
+		if n == len(q.chunks[0]) {
+			q.chunks = q.chunks[1:]
+		} else {
+			q.chunks[0] = q.chunks[0][n:]
+		}
+		return n, nil
+	}
+	if q.read < q.spilled {
+		if int64(len(p)) > q.spilled-q.read {
+			p = p[:q.spilled-q.read]
+		}
+		n, err := q.spill.ReadAt(p, q.read)
+		q.read += int64(n)
+		if err != nil && err != io.EOF {
+			return n, fmt.Errorf("read copy backlog: %w", err)
+		}
+		if q.read == q.spilled && q.err == nil {
+			// Caught up: go back to queueing in memory.
+			q.discard()
+		}
+		return n, nil
+	}
+	if q.err != nil {
+		return 0, q.err
 	}
+	if f.err != nil {
+		return 0, f.err
+	}
+	return 0, io.EOF
+}
 
-	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Successfully copied from %s\n", source.container)
+// Close drops the reader's backlog; the pump no longer queues data for it.
+func (r *fanOutReader) Close() error {
+	f := r.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed[r.i] = true
+	f.queues[r.i].discard()
+	f.cond.Broadcast()
 	return nil
 }
 
-// copyContainerToContainers copies from one container to other containers via host relay.
-func copyContainerToContainers(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, destinations []copyEndpoint, overwrite bool) error {
-	srcContainer, err := containers.ResolveWithEndpoint(source.container)
+// skippedSuffix describes files the agent kept because they already existed
+// without overwrite or were newer at the destination.
+func skippedSuffix(result *convoypb.CopyResult) string {
+	var reasons []string
+	if n := result.GetSkippedExisting(); n > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d skipped, already present", n))
+	}
+	if n := result.GetSkippedNewer(); n > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d skipped, destination newer", n))
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(reasons, "; ") + ")"
+}
+
+// copyContainerToHost copies from a container to local filesystem.
+func copyContainerToHost(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, dest copyEndpoint, opts copyOptions) error {
+	container, err := containers.ResolveWithEndpoint(source.container)
 	if err != nil {
-		return fmt.Errorf("source %w", err)
+		return opts.failed(source.container, err)
 	}
 
-	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Pulling %s:%s for relay...\n", source.container, source.path)
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_start", Container: source.container, Source: source.path, Destination: dest.path},
+		"Copying %s:%s to %s\n", source.container, source.path, dest.path)
 
-	tarData, err := pullTarFromContainer(ctx, rpc, srcContainer.Endpoint, source.path)
-	if err != nil {
-		return fmt.Errorf("failed to pull from source container: %w", err)
+	if err := pullFromContainer(ctx, rpc, container.Endpoint, source.path, dest.path, dest.options(opts)); err != nil {
+		return opts.failed(source.container, fmt.Errorf("failed to copy from %s: %w", source.container, err))
 	}
 
-	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Pulled %d bytes from %s\n", len(tarData), source.container)
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_done", Container: source.container, Destination: dest.path},
+		"Successfully copied from %s\n", source.container)
+	return nil
+}
 
-	var failed bool
-	for _, dest := range destinations {
-		if !dest.isContainer {
-			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Extracting to local path %s\n", dest.path)
-			if err := extractTarToLocal(tarData, dest.path, overwrite); err != nil {
-				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "failed to extract to %s: %v\n", dest.path, err)
-				failed = true
-			}
-			continue
-		}
+// copyContainerToArchive writes the tar stream for a container path to a local file without extracting it.
+func copyContainerToArchive(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, dest copyEndpoint, opts copyOptions) error {
+	container, err := containers.ResolveWithEndpoint(source.container)
+	if err != nil {
+		return opts.failed(source.container, err)
+	}
 
-		destContainer, err := containers.ResolveWithEndpoint(dest.container)
-		if err != nil {
-			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "destination %v\n", err)
-			failed = true
-			continue
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_start", Container: source.container, Source: source.path, Destination: dest.path},
+		"Archiving %s:%s to %s\n", source.container, source.path, dest.path)
+
+	if dir := filepath.Dir(dest.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return opts.failed(source.container, fmt.Errorf("failed to create destination directory: %w", err))
 		}
+	}
+	file, err := os.OpenFile(dest.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return opts.failed(source.container, fmt.Errorf("failed to write archive: %w", err))
+	}
 
-		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Pushing to %s:%s\n", dest.container, dest.path)
+	// Stream the tar into the file rather than holding the whole archive in memory.
+	written := &countingWriter{w: file}
+	err = rpc.PullTar(ctx, container.Endpoint, source.path, written, orchestrator.CopyOptions{Exclude: opts.exclude})
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+	if err != nil {
+		_ = os.Remove(dest.path)
+		return opts.failed(source.container, fmt.Errorf("failed to copy from %s: %w", source.container, err))
+	}
 
-		if err := pushTarToContainer(ctx, rpc, destContainer.Endpoint, tarData, dest.path, overwrite); err != nil {
-			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "failed to push to %s: %v\n", dest.container, err)
-			failed = true
-			continue
-		}
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_done", Container: source.container, Destination: dest.path, Bytes: written.n},
+		"Wrote %d bytes from %s\n", written.n, source.container)
+	return nil
+}
 
-		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Successfully copied to %s\n", dest.container)
+// copyContainerFileToHost pulls a single file from a container, resuming any partial download.
+func copyContainerFileToHost(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, dest copyEndpoint, opts copyOptions) error {
+	container, err := containers.ResolveWithEndpoint(source.container)
+	if err != nil {
+		return opts.failed(source.container, err)
 	}
 
-	if failed {
-		return fmt.Errorf("one or more copy operations failed")
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_start", Container: source.container, Source: source.path, Destination: dest.path},
+		"Copying %s:%s to %s\n", source.container, source.path, dest.path)
+
+	resumed, err := pullFileFromContainer(ctx, rpc, container.Endpoint, source.path, dest.path, dest.options(opts).overwrite)
+	if err != nil {
+		return opts.failed(source.container, fmt.Errorf("failed to copy from %s: %w", source.container, err))
+	}
+	if resumed > 0 {
+		opts.say(cmd.OutOrStdout(), event{Event: "copy_progress", Container: source.container, Bytes: resumed},
+			"Resumed after %d bytes\n", resumed)
 	}
+
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_done", Container: source.container, Destination: dest.path},
+		"Successfully copied from %s\n", source.container)
 	return nil
 }
 
-// pushToContainer streams a local file/directory as tar to a container.
-func pushToContainer(ctx context.Context, rpc *orchestrator.RPC, endpoint, srcPath string, srcInfo os.FileInfo, destPath string, overwrite bool) error {
-	stream, err := rpc.Copy(ctx, endpoint)
+// copyFileToContainer pushes a single local file, resuming a partial upload the agent kept.
+func copyFileToContainer(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, dest copyEndpoint, opts copyOptions) error {
+	container, err := containers.ResolveWithEndpoint(dest.container)
 	if err != nil {
-		return fmt.Errorf("failed to open copy stream: %w", err)
+		return opts.failed(dest.container, err)
 	}
 
-	if err := stream.Send(&convoypb.CopyRequest{
-		Payload: &convoypb.CopyRequest_Start{
-			Start: &convoypb.CopyStart{
-				Direction: convoypb.CopyStart_TO_AGENT,
-				Path:      destPath,
-				Overwrite: overwrite,
-			},
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to send start message: %w", err)
+	file, err := os.Open(source.path)
+	if err != nil {
+		return opts.failed(dest.container, fmt.Errorf("failed to open %s: %w", source.path, err))
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return opts.failed(dest.container, fmt.Errorf("failed to stat %s: %w", source.path, err))
+	}
+	if !info.Mode().IsRegular() {
+		return opts.failed(dest.container, fmt.Errorf("--resume requires a regular file: %s", source.path))
 	}
 
-	pr, pw := io.Pipe()
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_start", Container: dest.container, Source: source.path, Destination: dest.path},
+		"Copying %s to %s:%s\n", source.path, dest.container, dest.path)
 
-	go func() {
-		tw := tar.NewWriter(pw)
-		var tarErr error
+	resumed, _, err := rpc.PushFile(ctx, container.Endpoint, file, info.Size(), dest.path, filepath.Base(source.path), dest.options(opts).overwrite)
+	if resumed > 0 {
+		opts.say(cmd.OutOrStdout(), event{Event: "copy_progress", Container: dest.container, Bytes: resumed},
+			"Resumed after %d bytes\n", resumed)
+	}
+	if err != nil {
+		return opts.failed(dest.container, fmt.Errorf("failed to copy to %s: %w; rerun with --resume to continue", dest.container, err))
+	}
 
-		if srcInfo.IsDir() {
-			tarErr = filepath.Walk(srcPath, func(path string, info os.FileInfo, walkErr error) error {
-				if walkErr != nil {
-					return walkErr
-				}
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_done", Container: dest.container, Destination: dest.path, Bytes: info.Size()},
+		"Successfully copied to %s\n", dest.container)
+	return nil
+}
 
-				relPath, err := filepath.Rel(srcPath, path)
-				if err != nil {
-					return err
-				}
+// copyContainerToContainers copies from one container to other containers via host relay.
+// Without --relay-spill the tar is streamed straight through to every destination;
+// with it the tar is buffered once (on disk past the threshold) and replayed to
+// each destination in turn.
+func copyContainerToContainers(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, destinations []copyEndpoint, opts copyOptions) error {
+	srcContainer, err := containers.ResolveWithEndpoint(source.container)
+	if err != nil {
+		return opts.failed(source.container, fmt.Errorf("source %w", err))
+	}
 
-				if relPath == "." {
-					return nil
-				}
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_start", Container: source.container, Source: source.path},
+		"Pulling %s:%s for relay...\n", source.container, source.path)
 
-				return addFileToTar(tw, path, relPath, info)
-			})
-		} else {
-			tarErr = addFileToTar(tw, srcPath, filepath.Base(srcPath), srcInfo)
-		}
+	if opts.relaySpillDir == "" {
+		return relayStreamed(ctx, cmd, rpc, containers, source, srcContainer.Endpoint, destinations, opts)
+	}
+	return relayBuffered(ctx, cmd, rpc, containers, source, srcContainer.Endpoint, destinations, opts)
+}
 
-		_ = tw.Close()
-		if tarErr != nil {
-			_ = pw.CloseWithError(tarErr)
-		} else {
-			_ = pw.Close()
+// relayBuffered pulls the whole source tar into a spill buffer before pushing it
+// to each destination in turn.
+func relayBuffered(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, srcEndpoint string, destinations []copyEndpoint, opts copyOptions) error {
+	relay := &spillBuffer{dir: opts.relaySpillDir, limit: opts.relaySpillThreshold}
+	defer func() {
+		_ = relay.Close()
+	}()
+
+	if err := rpc.PullTar(ctx, srcEndpoint, source.path, relay, orchestrator.CopyOptions{Exclude: opts.exclude}); err != nil {
+		return opts.failed(source.container, fmt.Errorf("failed to pull from source container: %w", err))
+	}
+
+	spilled := ""
+	if relay.file != nil {
+		spilled = " (spilled to disk)"
+	}
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_progress", Container: source.container, Bytes: relay.size},
+		"Pulled %d bytes from %s%s\n", relay.size, source.container, spilled)
+
+	var failed bool
+	for _, dest := range destinations {
+		if !relayTo(ctx, cmd, rpc, containers, dest, relay.reader(), opts, opts.say) {
+			failed = true
 		}
+	}
+
+	if failed {
+		return fmt.Errorf("one or more copy operations failed")
+	}
+	return nil
+}
+
+// relayStreamed pipes the source tar to every destination as it arrives. The
+// fan-out holds a few chunks per destination in memory at most; a destination
+// that falls behind has the rest of its stream spilled to disk.
+func relayStreamed(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, source copyEndpoint, srcEndpoint string, destinations []copyEndpoint, opts copyOptions) error {
+	pr, pw := io.Pipe()
+	pulled := &countingWriter{w: pw}
+	pullDone := make(chan error, 1)
+	go func() {
+		err := rpc.PullTar(ctx, srcEndpoint, source.path, pulled, orchestrator.CopyOptions{Exclude: opts.exclude})
+		_ = pw.CloseWithError(err)
+		pullDone <- err
 	}()
 
-	buf := make([]byte, 32*1024)
-	for {
-		n, readErr := pr.Read(buf)
-		if n > 0 {
-			chunk := make([]byte, n)
-			copy(chunk, buf[:n])
-			if err := stream.Send(&convoypb.CopyRequest{
-				Payload: &convoypb.CopyRequest_Chunk{
-					Chunk: &convoypb.CopyChunk{
-						Data: chunk,
-						Eof:  false,
-					},
-				},
-			}); err != nil {
-				return fmt.Errorf("failed to send data chunk: %w", err)
+	var mu sync.Mutex
+	report := func(w io.Writer, ev event, format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		opts.say(w, ev, format, args...)
+	}
+
+	readers := newFanOut(pr, len(destinations))
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for i, dest := range destinations {
+		wg.Add(1)
+		go func(r io.ReadCloser, dest copyEndpoint) {
+			defer wg.Done()
+			defer func() {
+				_ = r.Close()
+			}()
+			if !relayTo(ctx, cmd, rpc, containers, dest, r, opts, report) {
+				failed.Store(true)
 			}
-		}
+		}(readers[i], dest)
+	}
+	wg.Wait()
 
-		if readErr == io.EOF {
-			break
+	// Every destination is done; stop a pull that nobody is reading anymore.
+	_ = pr.CloseWithError(errors.New("relay finished"))
+	if err := <-pullDone; err != nil {
+		return opts.failed(source.container, fmt.Errorf("failed to pull from source container: %w", err))
+	}
+	opts.say(cmd.OutOrStdout(), event{Event: "copy_progress", Container: source.container, Bytes: pulled.n},
+		"Pulled %d bytes from %s\n", pulled.n, source.container)
+
+	if failed.Load() {
+		return fmt.Errorf("one or more copy operations failed")
+	}
+	return nil
+}
+
+// relayTo extracts or pushes the relayed tar read from r to one destination,
+// reporting progress through report. It returns false if the copy failed.
+func relayTo(ctx context.Context, cmd *cobra.Command, rpc *orchestrator.RPC, containers *ContainerIndex, dest copyEndpoint, r io.Reader, opts copyOptions, report func(w io.Writer, ev event, format string, args ...any)) bool {
+	if !dest.isContainer {
+		report(cmd.OutOrStdout(), event{Event: "copy_start", Destination: dest.path},
+			"Extracting to local path %s\n", dest.path)
+		if err := os.MkdirAll(dest.path, 0o755); err != nil {
+			report(cmd.ErrOrStderr(), event{Event: "copy_error", Destination: dest.path, Error: err.Error()},
+				"failed to create %s: %v\n", dest.path, err)
+			return false
 		}
-		if readErr != nil {
-			return fmt.Errorf("tar read error: %w", readErr)
+		if err := extractTarFromReader(r, dest.path, dest.options(opts)); err != nil {
+			report(cmd.ErrOrStderr(), event{Event: "copy_error", Destination: dest.path, Error: err.Error()},
+				"failed to extract to %s: %v\n", dest.path, err)
+			return false
 		}
+		opts.emit(event{Event: "copy_done", Destination: dest.path})
+		return true
 	}
 
-	if err := stream.Send(&convoypb.CopyRequest{
-		Payload: &convoypb.CopyRequest_Chunk{
-			Chunk: &convoypb.CopyChunk{
-				Data: nil,
-				Eof:  true,
-			},
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to send EOF: %w", err)
+	destContainer, err := containers.ResolveWithEndpoint(dest.container)
+	if err != nil {
+		report(cmd.ErrOrStderr(), event{Event: "copy_error", Container: dest.container, Error: err.Error()},
+			"destination %v\n", err)
+		return false
 	}
 
-	if err := stream.CloseSend(); err != nil {
-		return fmt.Errorf("failed to close send: %w", err)
+	report(cmd.OutOrStdout(), event{Event: "copy_start", Container: dest.container, Destination: dest.path},
+		"Pushing to %s:%s\n", dest.container, dest.path)
+
+	result, err := rpc.PushTar(ctx, destContainer.Endpoint, r, dest.path, dest.options(opts).agent())
+	if err != nil {
+		report(cmd.ErrOrStderr(), event{Event: "copy_error", Container: dest.container, Error: err.Error()},
+			"failed to push to %s: %v\n", dest.container, err)
+		return false
 	}
 
-	for {
-		resp, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
+	report(cmd.OutOrStdout(), event{Event: "copy_done", Container: dest.container, Destination: dest.path, Skipped: result.GetSkippedNewer(), SkippedExisting: result.GetSkippedExisting()},
+		"Successfully copied to %s%s\n", dest.container, skippedSuffix(result))
+	return true
+}
+
+// countingWriter passes writes through to w and counts the bytes written.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// spillBuffer accumulates relayed tar data in memory and moves it to a temp file in
+// dir once it grows past limit. An empty dir keeps everything in memory.
+type spillBuffer struct {
+	dir   string
+	limit int64
+	mem   bytes.Buffer
+	file  *os.File
+	size  int64
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	if b.file == nil && b.dir != "" && b.size+int64(len(p)) > b.limit {
+		file, err := os.CreateTemp(b.dir, "convoy-relay-*.tar")
 		if err != nil {
-			return fmt.Errorf("receive error: %w", err)
+			return 0, fmt.Errorf("create spill file: %w", err)
 		}
-
-		if result := resp.GetResult(); result != nil {
-			if !result.GetSuccess() {
-				return fmt.Errorf("copy failed: %s", result.GetMessage())
-			}
-			return nil
+		b.file = file
+		if _, err := b.mem.WriteTo(file); err != nil {
+			return 0, fmt.Errorf("write spill file: %w", err)
 		}
+		b.mem = bytes.Buffer{}
 	}
 
-	return nil
+	var n int
+	var err error
+	if b.file != nil {
+		n, err = b.file.Write(p)
+	} else {
+		n, err = b.mem.Write(p)
+	}
+	b.size += int64(n)
+	return n, err
 }
 
-// pullFromContainer pulls data from a container and extracts to local filesystem.
-func pullFromContainer(ctx context.Context, rpc *orchestrator.RPC, endpoint, srcPath, destPath string, overwrite bool) error {
-	stream, err := rpc.Copy(ctx, endpoint)
-	if err != nil {
-		return fmt.Errorf("failed to open copy stream: %w", err)
+// reader returns a new reader over everything written so far.
+func (b *spillBuffer) reader() io.Reader {
+	if b.file != nil {
+		return io.NewSectionReader(b.file, 0, b.size)
 	}
+	return bytes.NewReader(b.mem.Bytes())
+}
 
-	if err := stream.Send(&convoypb.CopyRequest{
-		Payload: &convoypb.CopyRequest_Start{
-			Start: &convoypb.CopyStart{
-				Direction: convoypb.CopyStart_FROM_AGENT,
-				Path:      srcPath,
-				Overwrite: overwrite,
-			},
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to send start message: %w", err)
+// Close releases the buffer and removes any spill file.
+func (b *spillBuffer) Close() error {
+	if b.file == nil {
+		return nil
 	}
+	name := b.file.Name()
+	_ = b.file.Close()
+	b.file = nil
+	return os.Remove(name)
+}
 
-	if err := stream.CloseSend(); err != nil {
-		return fmt.Errorf("failed to close send: %w", err)
-	}
+// sourcesTarReader returns a reader producing a tar of sources. Closing it stops the writer.
+func sourcesTarReader(sources []copySource, exclude []string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		tarErr := writeSourcesTar(tw, sources, exclude)
+		if closeErr := tw.Close(); tarErr == nil {
+			tarErr = closeErr
+		}
+		_ = pw.CloseWithError(tarErr)
+	}()
+	return pr
+}
 
+// pullFromContainer pulls data from a container and extracts to local filesystem.
+func pullFromContainer(ctx context.Context, rpc *orchestrator.RPC, endpoint, srcPath, destPath string, opts copyOptions) error {
 	if err := os.MkdirAll(destPath, 0o755); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
@@ -401,48 +1123,60 @@ func pullFromContainer(ctx context.Context, rpc *orchestrator.RPC, endpoint, src
 	extractDone := make(chan error, 1)
 
 	go func() {
-		extractDone <- extractTarFromReader(pr, destPath, overwrite)
-	}()
-
-	for {
-		resp, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			_ = pw.CloseWithError(err)
-			return fmt.Errorf("receive error: %w", err)
+		err := extractTarFromReader(pr, destPath, opts)
+		if err == nil {
+			// Consume any padding after the end-of-archive marker.
+			_, _ = io.Copy(io.Discard, pr)
 		}
+		// Unblock the pull if extraction stopped early.
+		_ = pr.CloseWithError(err)
+		extractDone <- err
+	}()
 
-		if chunk := resp.GetChunk(); chunk != nil {
-			if len(chunk.GetData()) > 0 {
-				if _, err := pw.Write(chunk.GetData()); err != nil {
-					return fmt.Errorf("pipe write error: %w", err)
-				}
-			}
-			if chunk.GetEof() {
-				break
-			}
-		}
+	pullErr := rpc.PullTar(ctx, endpoint, srcPath, pw, opts.agent())
+	_ = pw.CloseWithError(pullErr)
+	extractErr := <-extractDone
+	if pullErr != nil {
+		return pullErr
+	}
+	return extractErr
+}
 
-		if result := resp.GetResult(); result != nil {
-			if !result.GetSuccess() {
-				_ = pw.CloseWithError(fmt.Errorf("copy failed: %s", result.GetMessage()))
-				return fmt.Errorf("copy failed: %s", result.GetMessage())
-			}
+// pullFileFromContainer downloads a single regular file without tar framing. Bytes are
+// appended to "<target>.partial", whose size and checksum are sent so a retried pull
+// continues where the previous one stopped. The agent restarts from zero when the
+// source no longer starts with the bytes held, and the partial is then truncated.
+// Without overwrite an existing target is left alone. Returns the offset the
+// transfer resumed from.
+func pullFileFromContainer(ctx context.Context, rpc *orchestrator.RPC, endpoint, srcPath, destPath string, overwrite bool) (int64, error) {
+	target := destPath
+	if info, err := os.Stat(destPath); err == nil && info.IsDir() {
+		target = filepath.Join(destPath, pathpkg.Base(srcPath))
+	}
+	if !overwrite {
+		if _, err := os.Lstat(target); err == nil {
+			return 0, fmt.Errorf("%s exists and overwrite is disabled", target)
 		}
 	}
+	partial := target + ".partial"
 
-	_ = pw.Close()
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open partial file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
 
-	return <-extractDone
-}
+	hash := sha256.New()
+	offset, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read partial file: %w", err)
+	}
 
-// pullTarFromContainer pulls data from a container and returns the raw tar bytes.
-func pullTarFromContainer(ctx context.Context, rpc *orchestrator.RPC, endpoint, srcPath string) ([]byte, error) {
 	stream, err := rpc.Copy(ctx, endpoint)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open copy stream: %w", err)
+		return offset, fmt.Errorf("failed to open copy stream: %w", err)
 	}
 
 	if err := stream.Send(&convoypb.CopyRequest{
@@ -450,140 +1184,107 @@ func pullTarFromContainer(ctx context.Context, rpc *orchestrator.RPC, endpoint,
 			Start: &convoypb.CopyStart{
 				Direction: convoypb.CopyStart_FROM_AGENT,
 				Path:      srcPath,
-				Overwrite: false,
+				Raw:       true,
+				Offset:    offset,
+				Sha256:    hex.EncodeToString(hash.Sum(nil)),
 			},
 		},
 	}); err != nil {
-		return nil, fmt.Errorf("failed to send start message: %w", err)
+		return offset, fmt.Errorf("failed to send start message: %w", err)
 	}
 
 	if err := stream.CloseSend(); err != nil {
-		return nil, fmt.Errorf("failed to close send: %w", err)
+		return offset, fmt.Errorf("failed to close send: %w", err)
 	}
 
-	var tarData []byte
+	complete := false
 	for {
 		resp, err := stream.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("receive error: %w", err)
+			return offset, fmt.Errorf("receive error: %w", err)
+		}
+
+		if progress := resp.GetProgress(); progress != nil && progress.GetBytesTransferred() != offset {
+			if progress.GetBytesTransferred() != 0 {
+				return offset, fmt.Errorf("agent resumed from %d bytes, but %d are held", progress.GetBytesTransferred(), offset)
+			}
+			// The source changed since the partial was written; start over.
+			if err := file.Truncate(0); err != nil {
+				return offset, fmt.Errorf("failed to discard %s: %w", partial, err)
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return offset, fmt.Errorf("failed to discard %s: %w", partial, err)
+			}
+			offset = 0
 		}
 
 		if chunk := resp.GetChunk(); chunk != nil {
 			if len(chunk.GetData()) > 0 {
-				tarData = append(tarData, chunk.GetData()...)
+				if _, err := file.Write(chunk.GetData()); err != nil {
+					return offset, fmt.Errorf("failed to write %s: %w", partial, err)
+				}
 			}
 			if chunk.GetEof() {
-				break
+				complete = true
 			}
 		}
 
 		if result := resp.GetResult(); result != nil {
 			if !result.GetSuccess() {
-				return nil, fmt.Errorf("copy failed: %s", result.GetMessage())
+				return offset, fmt.Errorf("copy failed: %s", result.GetMessage())
 			}
+			break
 		}
 	}
 
-	return tarData, nil
-}
-
-// pushTarToContainer sends pre-built tar data to a container.
-func pushTarToContainer(ctx context.Context, rpc *orchestrator.RPC, endpoint string, tarData []byte, destPath string, overwrite bool) error {
-	stream, err := rpc.Copy(ctx, endpoint)
-	if err != nil {
-		return fmt.Errorf("failed to open copy stream: %w", err)
+	if !complete {
+		return offset, fmt.Errorf("transfer interrupted; rerun with --resume to continue")
 	}
 
-	if err := stream.Send(&convoypb.CopyRequest{
-		Payload: &convoypb.CopyRequest_Start{
-			Start: &convoypb.CopyStart{
-				Direction: convoypb.CopyStart_TO_AGENT,
-				Path:      destPath,
-				Overwrite: overwrite,
-			},
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to send start message: %w", err)
+	if err := file.Close(); err != nil {
+		return offset, fmt.Errorf("failed to close %s: %w", partial, err)
 	}
-
-	chunkSize := 32 * 1024
-	for i := 0; i < len(tarData); i += chunkSize {
-		end := i + chunkSize
-		if end > len(tarData) {
-			end = len(tarData)
-		}
-
-		if err := stream.Send(&convoypb.CopyRequest{
-			Payload: &convoypb.CopyRequest_Chunk{
-				Chunk: &convoypb.CopyChunk{
-					Data: tarData[i:end],
-					Eof:  false,
-				},
-			},
-		}); err != nil {
-			return fmt.Errorf("failed to send data chunk: %w", err)
+	if !overwrite {
+		// Link fails if the target appeared meanwhile, where a rename would replace it.
+		if err := os.Link(partial, target); err != nil {
+			return offset, fmt.Errorf("failed to finalize %s: %w", target, err)
 		}
+		return offset, os.Remove(partial)
 	}
-
-	if err := stream.Send(&convoypb.CopyRequest{
-		Payload: &convoypb.CopyRequest_Chunk{
-			Chunk: &convoypb.CopyChunk{
-				Data: nil,
-				Eof:  true,
-			},
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to send EOF: %w", err)
-	}
-
-	if err := stream.CloseSend(); err != nil {
-		return fmt.Errorf("failed to close send: %w", err)
-	}
-
-	for {
-		resp, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("receive error: %w", err)
-		}
-
-		if result := resp.GetResult(); result != nil {
-			if !result.GetSuccess() {
-				return fmt.Errorf("copy failed: %s", result.GetMessage())
-			}
-			return nil
-		}
+	if err := os.Rename(partial, target); err != nil {
+		return offset, fmt.Errorf("failed to finalize %s: %w", target, err)
 	}
 
-	return nil
+	return offset, nil
 }
 
-// extractTarToLocal extracts tar data to a local directory.
-func extractTarToLocal(tarData []byte, destPath string, overwrite bool) error {
-	if err := os.MkdirAll(destPath, 0o755); err != nil {
-		return fmt.Errorf("failed to create destination directory: %w", err)
-	}
-
-	reader := tar.NewReader(strings.NewReader(string(tarData)))
-	return extractTarEntries(reader, destPath, overwrite)
+// pushTarToContainer sends pre-built tar data to a container.
+func pushTarToContainer(ctx context.Context, rpc *orchestrator.RPC, endpoint string, tarData []byte, destPath string, opts copyOptions) (*convoypb.CopyResult, error) {
+	return rpc.PushTar(ctx, endpoint, bytes.NewReader(tarData), destPath, opts.agent())
 }
 
 // extractTarFromReader extracts tar data from a reader to a local directory.
-func extractTarFromReader(r io.Reader, destPath string, overwrite bool) error {
+func extractTarFromReader(r io.Reader, destPath string, opts copyOptions) error {
 	reader := tar.NewReader(r)
-	return extractTarEntries(reader, destPath, overwrite)
+	return extractTarEntries(reader, destPath, opts)
 }
 
 // extractTarEntries extracts entries from a tar reader to a destination path.
-func extractTarEntries(reader *tar.Reader, destPath string, overwrite bool) error {
+// Directory timestamps are applied once all entries are written so that creating
+// their contents does not disturb them.
+func extractTarEntries(reader *tar.Reader, destPath string, opts copyOptions) error {
+	var dirs []*tar.Header
 	for {
 		header, err := reader.Next()
 		if err == io.EOF {
+			for i := len(dirs) - 1; i >= 0; i-- {
+				if err := transfer.ApplyTarMetadata(filepath.Join(destPath, dirs[i].Name), dirs[i], opts.metadata()); err != nil {
+					return err
+				}
+			}
 			return nil
 		}
 		if err != nil {
@@ -592,20 +1293,38 @@ func extractTarEntries(reader *tar.Reader, destPath string, overwrite bool) erro
 
 		targetPath := filepath.Join(destPath, header.Name)
 
-		if !strings.HasPrefix(filepath.Clean(targetPath), filepath.Clean(destPath)) {
+		if !transfer.InsideRoot(filepath.Clean(destPath), targetPath) {
 			return fmt.Errorf("invalid tar entry path: %s", header.Name)
 		}
+		if err := transfer.CheckParents(destPath, targetPath); err != nil {
+			return err
+		}
 
 		switch header.Typeflag {
 		case tar.TypeDir:
 			if err := os.MkdirAll(targetPath, os.FileMode(header.Mode)); err != nil {
 				return fmt.Errorf("failed to create directory %s: %w", targetPath, err)
 			}
+			dirs = append(dirs, header)
 		case tar.TypeReg:
+			if !opts.overwrite {
+				if _, err := os.Lstat(targetPath); err == nil {
+					continue
+				}
+			}
+			if opts.skipNewer && isNewerThan(targetPath, header.ModTime) {
+				continue
+			}
+
 			if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
 				return fmt.Errorf("failed to create parent directory: %w", err)
 			}
 
+			// Replace a symlink in the way rather than writing through it.
+			if info, err := os.Lstat(targetPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				_ = os.Remove(targetPath)
+			}
+
 			file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
 			if err != nil {
 				return fmt.Errorf("failed to create file %s: %w", targetPath, err)
@@ -617,17 +1336,90 @@ func extractTarEntries(reader *tar.Reader, destPath string, overwrite bool) erro
 			}
 			_ = file.Close()
 
+			if err := transfer.ApplyTarMetadata(targetPath, header, opts.metadata()); err != nil {
+				return err
+			}
+
 		case tar.TypeSymlink:
-			if overwrite {
+			if opts.noFollow && transfer.LinkEscapes(filepath.Clean(destPath), targetPath, header.Linkname) {
+				return fmt.Errorf("symlink %s points outside the destination: %s", header.Name, header.Linkname)
+			}
+			if opts.overwrite {
 				_ = os.Remove(targetPath)
 			}
 			if err := os.Symlink(header.Linkname, targetPath); err != nil {
 				return fmt.Errorf("failed to create symlink %s: %w", targetPath, err)
 			}
+			if opts.preserveOwner && os.Geteuid() == 0 {
+				if err := os.Lchown(targetPath, header.Uid, header.Gid); err != nil {
+					return fmt.Errorf("failed to set owner of %s: %w", targetPath, err)
+				}
+			}
+
+		case tar.TypeLink:
+			return fmt.Errorf("hardlink entries are not supported: %s", header.Name)
 		}
 	}
 }
 
+// isNewerThan reports whether an existing file at path was modified after modTime.
+func isNewerThan(path string, modTime time.Time) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.ModTime().After(modTime)
+}
+
+// writeSourcesTar packs each source into tw under its resolved name, skipping excluded paths.
+func writeSourcesTar(tw *tar.Writer, sources []copySource, exclude []string) error {
+	for _, source := range sources {
+		if source.name != "" && transfer.IsExcluded(exclude, source.name, source.info.IsDir()) {
+			continue
+		}
+
+		if !source.info.IsDir() {
+			if err := addFileToTar(tw, source.path, source.name, source.info); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := filepath.Walk(source.path, func(path string, info os.FileInfo, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+
+			relPath, err := filepath.Rel(source.path, path)
+			if err != nil {
+				return err
+			}
+
+			if relPath == "." {
+				if source.name == "" {
+					return nil
+				}
+				return addFileToTar(tw, path, source.name, info)
+			}
+
+			name := filepath.ToSlash(filepath.Join(source.name, relPath))
+			if transfer.IsExcluded(exclude, name, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			return addFileToTar(tw, path, name, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // addFileToTar adds a single file or directory to a tar writer.
 func addFileToTar(tw *tar.Writer, srcPath, relPath string, info os.FileInfo) error {
 	header, err := tar.FileInfoHeader(info, "")
@@ -635,6 +1427,8 @@ func addFileToTar(tw *tar.Writer, srcPath, relPath string, info os.FileInfo) err
 		return err
 	}
 	header.Name = relPath
+	// PAX keeps sub-second modification times and access times.
+	header.Format = tar.FormatPAX
 
 	if info.Mode()&os.ModeSymlink != 0 {
 		linkTarget, err := os.Readlink(srcPath)
@@ -0,0 +1,1300 @@
+package cmds
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+	"convoy/internal/agent"
+	"convoy/internal/orchestrator"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func tarEntryNames(t *testing.T, sources []copySource, exclude []string) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := writeSourcesTar(tw, sources, exclude); err != nil {
+		t.Fatalf("writeSourcesTar: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+
+	var names []string
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read tar: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSplitEndpoints(t *testing.T) {
+	endpoints := []copyEndpoint{
+		{path: "./a"},
+		{path: "./b/*.log"},
+		{isContainer: true, container: "c1", path: "/tmp"},
+		{isContainer: true, container: "c2", path: "/tmp"},
+	}
+	sources, destinations := splitEndpoints(endpoints)
+	if len(sources) != 2 || len(destinations) != 2 {
+		t.Fatalf("expected 2 sources and 2 destinations, got %d and %d", len(sources), len(destinations))
+	}
+
+	relay := []copyEndpoint{
+		{isContainer: true, container: "c1", path: "/data"},
+		{isContainer: true, container: "c2", path: "/data"},
+	}
+	sources, destinations = splitEndpoints(relay)
+	if len(sources) != 1 || len(destinations) != 1 {
+		t.Fatalf("container source must be single, got %d sources", len(sources))
+	}
+}
+
+func TestCopyFlags_OverwriteOn(t *testing.T) {
+	var flags copyFlags
+	cmd := &cobra.Command{}
+	flags.register(cmd)
+	if err := cmd.ParseFlags([]string{"--overwrite=false", "--overwrite-on", "c1", "--no-overwrite-on", "c2,c3"}); err != nil {
+		t.Fatalf("ParseFlags: %v", err)
+	}
+	opts, err := flags.options(cmd)
+	if err != nil {
+		t.Fatalf("options: %v", err)
+	}
+
+	for _, tc := range []struct {
+		endpoint string
+		want     bool
+	}{
+		{"c1:/etc/app", true},
+		{"c2:/etc/app", false},
+		{"c4:/etc/app", false},
+		{"./c1", false},
+	} {
+		dest, err := parseEndpoint(tc.endpoint)
+		if err != nil {
+			t.Fatalf("parseEndpoint: %v", err)
+		}
+		if got := dest.options(opts).overwrite; got != tc.want {
+			t.Errorf("overwrite for %s = %v, want %v", tc.endpoint, got, tc.want)
+		}
+	}
+
+	destinations := []copyEndpoint{{isContainer: true, container: "c1"}, {isContainer: true, container: "c2"}}
+	if err := checkOverwriteOn(opts, destinations); err == nil || !strings.Contains(err.Error(), "c3 is not a destination") {
+		t.Fatalf("checkOverwriteOn = %v, want c3 rejected", err)
+	}
+	if err := checkOverwriteOn(opts, append(destinations, copyEndpoint{isContainer: true, container: "c3"})); err != nil {
+		t.Fatalf("checkOverwriteOn: %v", err)
+	}
+
+	if err := cmd.ParseFlags([]string{"--overwrite-on", "c2"}); err != nil {
+		t.Fatalf("ParseFlags: %v", err)
+	}
+	if _, err := flags.options(cmd); err == nil {
+		t.Fatalf("expected a container given to both flags to be rejected")
+	}
+}
+
+func TestParseEndpoint_KeepsBangInPath(t *testing.T) {
+	for _, arg := range []string{"c1:/tmp/a!no-overwrite", "./out!overwrite"} {
+		endpoint, err := parseEndpoint(arg)
+		if err != nil {
+			t.Fatalf("parseEndpoint: %v", err)
+		}
+		if !strings.HasSuffix(endpoint.path, "overwrite") {
+			t.Fatalf("parseEndpoint(%q) path = %q, want it kept verbatim", arg, endpoint.path)
+		}
+	}
+}
+
+func TestExpandSources_Globs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "logs", "a.log"), "a")
+	writeTestFile(t, filepath.Join(dir, "logs", "b.log"), "b")
+	writeTestFile(t, filepath.Join(dir, "logs", "c.txt"), "c")
+	writeTestFile(t, filepath.Join(dir, "svc", "api", "app.log"), "api")
+	writeTestFile(t, filepath.Join(dir, "svc", "web", "app.log"), "web")
+	writeTestFile(t, filepath.Join(dir, "notes.txt"), "notes")
+
+	sources, err := expandSources([]string{
+		filepath.Join(dir, "logs", "*.log"),
+		filepath.Join(dir, "svc", "*", "app.log"),
+		filepath.Join(dir, "notes.txt"),
+	})
+	if err != nil {
+		t.Fatalf("expandSources: %v", err)
+	}
+
+	got := strings.Join(tarEntryNames(t, sources, nil), ",")
+	want := "a.log,api/app.log,b.log,notes.txt,web/app.log"
+	if got != want {
+		t.Fatalf("unexpected tar entries:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestExpandSources_SingleDirectoryKeepsLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "tree", "x.txt"), "x")
+
+	sources, err := expandSources([]string{filepath.Join(dir, "tree")})
+	if err != nil {
+		t.Fatalf("expandSources: %v", err)
+	}
+
+	if got := strings.Join(tarEntryNames(t, sources, nil), ","); got != "x.txt" {
+		t.Fatalf("expected directory contents at tar root, got %s", got)
+	}
+}
+
+func TestExpandSources_NoMatch(t *testing.T) {
+	dir := t.TempDir()
+	_, err := expandSources([]string{filepath.Join(dir, "*.missing")})
+	if err == nil || !strings.Contains(err.Error(), "no files match") {
+		t.Fatalf("expected no-match error, got %v", err)
+	}
+}
+
+func TestWriteSourcesTar_Exclude(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "project")
+	writeTestFile(t, filepath.Join(root, "main.go"), "package main")
+	writeTestFile(t, filepath.Join(root, ".git", "HEAD"), "ref")
+	writeTestFile(t, filepath.Join(root, "web", "node_modules", "dep", "index.js"), "js")
+	writeTestFile(t, filepath.Join(root, "web", "app.js"), "app")
+	writeTestFile(t, filepath.Join(root, "build", "out.bin"), "bin")
+	writeTestFile(t, filepath.Join(root, "docs", "build", "keep.md"), "keep")
+	writeTestFile(t, filepath.Join(root, "tmp.log"), "log")
+
+	sources, err := expandSources([]string{root})
+	if err != nil {
+		t.Fatalf("expandSources: %v", err)
+	}
+
+	exclude := []string{".git", "node_modules/", "/build", "*.log"}
+	got := strings.Join(tarEntryNames(t, sources, exclude), ",")
+	want := "docs,docs/build,docs/build/keep.md,main.go,web,web/app.js"
+	if got != want {
+		t.Fatalf("unexpected tar entries:\n got %s\nwant %s", got, want)
+	}
+}
+
+// rawPullServer serves a byte slice over raw copy streams, optionally dropping the
+// first stream after cutAfter bytes.
+type rawPullServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	content  []byte
+	cutAfter int
+
+	mu      sync.Mutex
+	offsets []int64
+}
+
+func (f *rawPullServer) Copy(stream convoypb.ConvoyService_CopyServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	start := req.GetStart()
+
+	f.mu.Lock()
+	f.offsets = append(f.offsets, start.GetOffset())
+	first := len(f.offsets) == 1
+	f.mu.Unlock()
+
+	data := f.content[start.GetOffset():]
+	if first && f.cutAfter > 0 {
+		_ = stream.Send(&convoypb.CopyResponse{Payload: &convoypb.CopyResponse_Chunk{Chunk: &convoypb.CopyChunk{Data: data[:f.cutAfter]}}})
+		return status.Error(codes.Unavailable, "connection dropped")
+	}
+
+	_ = stream.Send(&convoypb.CopyResponse{Payload: &convoypb.CopyResponse_Chunk{Chunk: &convoypb.CopyChunk{Data: data}}})
+	_ = stream.Send(&convoypb.CopyResponse{Payload: &convoypb.CopyResponse_Chunk{Chunk: &convoypb.CopyChunk{Eof: true}}})
+	return stream.Send(&convoypb.CopyResponse{Payload: &convoypb.CopyResponse_Result{Result: &convoypb.CopyResult{Success: true}}})
+}
+
+// startFakeAgent serves srv on a loopback listener and returns its address.
+func startFakeAgent(t testing.TB, srv convoypb.ConvoyServiceServer) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	convoypb.RegisterConvoyServiceServer(server, srv)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestPullFileFromContainer_Resume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	fake := &rawPullServer{content: content, cutAfter: 4000}
+	endpoint := startFakeAgent(t, fake)
+
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	dest := filepath.Join(t.TempDir(), "big.bin")
+	if _, err := pullFileFromContainer(context.Background(), rpc, endpoint, "/data/big.bin", dest, true); err == nil {
+		t.Fatalf("expected interrupted pull to fail")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("destination should not exist before completion: %v", err)
+	}
+
+	resumed, err := pullFileFromContainer(context.Background(), rpc, endpoint, "/data/big.bin", dest, true)
+	if err != nil {
+		t.Fatalf("resumed pull: %v", err)
+	}
+	if resumed != 4000 {
+		t.Fatalf("expected resume from 4000, got %d", resumed)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("read result: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("resumed file does not match source (%d vs %d bytes)", len(got), len(content))
+	}
+	if _, err := os.Stat(dest + ".partial"); !os.IsNotExist(err) {
+		t.Fatalf("partial file should be removed: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.offsets) != 2 || fake.offsets[0] != 0 || fake.offsets[1] != 4000 {
+		t.Fatalf("unexpected requested offsets: %v", fake.offsets)
+	}
+}
+
+func TestPullFileFromContainer_RestartsWhenSourceChanged(t *testing.T) {
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	base := t.TempDir()
+	src := filepath.Join(base, "src.bin")
+	dest := filepath.Join(base, "dest.bin")
+	writeTestFile(t, src, "new contents of the source")
+
+	for name, held := range map[string]string{
+		"stale prefix":       "old c",
+		"longer than source": strings.Repeat("x", 100),
+	} {
+		writeTestFile(t, dest+".partial", held)
+		resumed, err := pullFileFromContainer(context.Background(), rpc, endpoint, src, dest, true)
+		if err != nil {
+			t.Fatalf("%s: pull: %v", name, err)
+		}
+		if resumed != 0 {
+			t.Fatalf("%s: resumed after %d bytes, want a restart", name, resumed)
+		}
+		if got, _ := os.ReadFile(dest); string(got) != "new contents of the source" {
+			t.Fatalf("%s: destination = %q", name, got)
+		}
+	}
+
+	// A partial that still matches is resumed.
+	writeTestFile(t, dest+".partial", "new con")
+	if resumed, err := pullFileFromContainer(context.Background(), rpc, endpoint, src, dest, true); err != nil || resumed != 7 {
+		t.Fatalf("matching partial: resumed %d, %v; want 7", resumed, err)
+	}
+
+	// Without overwrite an existing destination is kept.
+	writeTestFile(t, src, "changed again")
+	_, err := pullFileFromContainer(context.Background(), rpc, endpoint, src, dest, false)
+	if err == nil || !strings.Contains(err.Error(), "overwrite is disabled") {
+		t.Fatalf("pull without overwrite = %v", err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "new contents of the source" {
+		t.Fatalf("destination replaced without overwrite: %q", got)
+	}
+}
+
+func TestCopyRoundTripPreservesTimes(t *testing.T) {
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	base := t.TempDir()
+	src := filepath.Join(base, "src")
+	writeTestFile(t, filepath.Join(src, "nested", "file.txt"), "hello")
+
+	fileTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	dirTime := time.Date(2019, 6, 7, 8, 9, 10, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(src, "nested", "file.txt"), fileTime, fileTime); err != nil {
+		t.Fatalf("chtimes file: %v", err)
+	}
+	if err := os.Chtimes(filepath.Join(src, "nested"), dirTime, dirTime); err != nil {
+		t.Fatalf("chtimes dir: %v", err)
+	}
+
+	sources, err := expandSources([]string{src})
+	if err != nil {
+		t.Fatalf("expandSources: %v", err)
+	}
+
+	opts := copyOptions{overwrite: true, preserveTimes: true}
+	remote := filepath.Join(base, "remote")
+	if _, err := rpc.PushTar(context.Background(), endpoint, sourcesTarReader(sources, nil), remote, opts.agent()); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	local := filepath.Join(base, "local")
+	if err := pullFromContainer(context.Background(), rpc, endpoint, remote, local, opts); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+
+	for _, root := range []string{remote, local} {
+		info, err := os.Stat(filepath.Join(root, "nested", "file.txt"))
+		if err != nil {
+			t.Fatalf("stat file: %v", err)
+		}
+		if !info.ModTime().Equal(fileTime) {
+			t.Fatalf("%s: file mtime %v, want %v", root, info.ModTime(), fileTime)
+		}
+
+		info, err = os.Stat(filepath.Join(root, "nested"))
+		if err != nil {
+			t.Fatalf("stat dir: %v", err)
+		}
+		if !info.ModTime().Equal(dirTime) {
+			t.Fatalf("%s: dir mtime %v, want %v", root, info.ModTime(), dirTime)
+		}
+	}
+}
+
+func TestPushSkipsNewerDestinationFiles(t *testing.T) {
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	base := t.TempDir()
+	src := filepath.Join(base, "src")
+	remote := filepath.Join(base, "remote")
+	writeTestFile(t, filepath.Join(src, "stale.txt"), "incoming")
+	writeTestFile(t, filepath.Join(src, "fresh.txt"), "incoming")
+	writeTestFile(t, filepath.Join(remote, "stale.txt"), "edited remotely")
+	writeTestFile(t, filepath.Join(remote, "fresh.txt"), "old remote")
+
+	older := time.Now().Add(-2 * time.Hour)
+	newer := time.Now().Add(-1 * time.Hour)
+	_ = os.Chtimes(filepath.Join(src, "stale.txt"), older, older)
+	_ = os.Chtimes(filepath.Join(remote, "stale.txt"), newer, newer)
+	_ = os.Chtimes(filepath.Join(src, "fresh.txt"), newer, newer)
+	_ = os.Chtimes(filepath.Join(remote, "fresh.txt"), older, older)
+
+	sources, err := expandSources([]string{src})
+	if err != nil {
+		t.Fatalf("expandSources: %v", err)
+	}
+
+	opts := copyOptions{overwrite: true, skipNewer: true}
+	result, err := rpc.PushTar(context.Background(), endpoint, sourcesTarReader(sources, nil), remote, opts.agent())
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if result.GetSkippedNewer() != 1 {
+		t.Fatalf("expected 1 skipped file, got %d", result.GetSkippedNewer())
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(remote, "stale.txt")); string(got) != "edited remotely" {
+		t.Fatalf("newer destination file was overwritten: %q", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(remote, "fresh.txt")); string(got) != "incoming" {
+		t.Fatalf("older destination file was not updated: %q", got)
+	}
+}
+
+func TestArchiveModeRoundTrip(t *testing.T) {
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	containers := NewContainerIndex([]*orchestrator.Container{{ID: "c1", Name: "c1", Endpoint: endpoint}})
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+
+	base := t.TempDir()
+	remote := filepath.Join(base, "remote")
+	writeTestFile(t, filepath.Join(remote, "a.txt"), "alpha")
+	writeTestFile(t, filepath.Join(remote, "sub", "b.txt"), "beta")
+
+	archive := filepath.Join(base, "out", "export.tar")
+	source := copyEndpoint{isContainer: true, container: "c1", path: remote}
+	if err := copyContainerToArchive(context.Background(), cmd, rpc, containers, source, copyEndpoint{path: archive}, copyOptions{}); err != nil {
+		t.Fatalf("pull archive: %v", err)
+	}
+
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("archive is not a valid tar: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "a.txt,sub,sub/b.txt" {
+		t.Fatalf("unexpected archive entries: %s", got)
+	}
+
+	restored := filepath.Join(base, "restored")
+	dest := copyEndpoint{isContainer: true, container: "c1", path: restored}
+	opts := copyOptions{overwrite: true, extract: true}
+	if err := copyHostToContainers(context.Background(), cmd, rpc, containers, []copyEndpoint{{path: archive}}, []copyEndpoint{dest}, opts); err != nil {
+		t.Fatalf("push archive: %v", err)
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(restored, "sub", "b.txt")); string(got) != "beta" {
+		t.Fatalf("archive not extracted as-is, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(restored, "export.tar")); !os.IsNotExist(err) {
+		t.Fatalf("archive was re-packed instead of pushed as-is")
+	}
+
+	// Without --extract a tar file is copied like any other file.
+	verbatim := filepath.Join(base, "verbatim")
+	dest = copyEndpoint{isContainer: true, container: "c1", path: verbatim}
+	if err := runCopy(context.Background(), cmd, rpc, containers, []copyEndpoint{{path: archive}}, []copyEndpoint{dest}, copyOptions{overwrite: true}); err != nil {
+		t.Fatalf("push tar file: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(verbatim, "export.tar")); !bytes.Equal(got, data) {
+		t.Fatalf("tar file was not copied verbatim")
+	}
+	if err := runCopy(context.Background(), cmd, rpc, containers, []copyEndpoint{{path: archive}}, []copyEndpoint{dest}, copyOptions{archive: true}); err == nil {
+		t.Fatalf("expected --archive to be rejected for a push")
+	}
+}
+
+func TestCopyHonorsPerDestinationOverwrite(t *testing.T) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	containers := NewContainerIndex([]*orchestrator.Container{
+		{ID: "c1", Name: "c1", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+		{ID: "c2", Name: "c2", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+	})
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+
+	base := t.TempDir()
+	src := filepath.Join(base, "src")
+	writeTestFile(t, filepath.Join(src, "app.conf"), "new")
+	writeTestFile(t, filepath.Join(src, "extra.conf"), "extra")
+	for _, dir := range []string{"one", "two"} {
+		writeTestFile(t, filepath.Join(base, dir, "app.conf"), "old")
+	}
+
+	destinations := []copyEndpoint{
+		{isContainer: true, container: "c1", path: filepath.Join(base, "one")},
+		{isContainer: true, container: "c2", path: filepath.Join(base, "two")},
+	}
+
+	opts := copyOptions{overwrite: true, overwriteOn: map[string]bool{"c2": false}}
+	err := copyHostToContainers(context.Background(), cmd, rpc, containers, []copyEndpoint{{path: src}}, destinations, opts)
+	if err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if !strings.Contains(out.String(), "Successfully copied to c2 (1 skipped, already present)") || !strings.Contains(out.String(), "Successfully copied to c1\n") {
+		t.Fatalf("expected the skipped file reported for c2 only:\n%s", out.String())
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(base, "one", "app.conf")); string(got) != "new" {
+		t.Fatalf("c1 should overwrite, got %q", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(base, "two", "app.conf")); string(got) != "old" {
+		t.Fatalf("c2 should keep existing file, got %q", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(base, "two", "extra.conf")); string(got) != "extra" {
+		t.Fatalf("c2 should still receive new files, got %q", got)
+	}
+}
+
+func TestRelaySpillsLargeTransfersToDisk(t *testing.T) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	containers := NewContainerIndex([]*orchestrator.Container{
+		{ID: "c1", Name: "c1", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+		{ID: "c2", Name: "c2", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+	})
+
+	base := t.TempDir()
+	payload := strings.Repeat("relay data ", 20000)
+	writeTestFile(t, filepath.Join(base, "src", "big.txt"), payload)
+	spillDir := filepath.Join(base, "spill")
+	if err := os.MkdirAll(spillDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+
+	opts := copyOptions{overwrite: true, relaySpillDir: spillDir, relaySpillThreshold: 64 * 1024}
+	source := copyEndpoint{isContainer: true, container: "c1", path: filepath.Join(base, "src")}
+	destinations := []copyEndpoint{
+		{isContainer: true, container: "c2", path: filepath.Join(base, "remote")},
+		{path: filepath.Join(base, "local")},
+	}
+	if err := copyContainerToContainers(context.Background(), cmd, rpc, containers, source, destinations, opts); err != nil {
+		t.Fatalf("relay: %v\n%s", err, out.String())
+	}
+
+	if !strings.Contains(out.String(), "spilled to disk") {
+		t.Fatalf("expected relay to spill, output:\n%s", out.String())
+	}
+	for _, dir := range []string{"remote", "local"} {
+		if got, _ := os.ReadFile(filepath.Join(base, dir, "big.txt")); string(got) != payload {
+			t.Fatalf("%s copy mismatch (%d bytes)", dir, len(got))
+		}
+	}
+	if entries, _ := os.ReadDir(spillDir); len(entries) != 0 {
+		t.Fatalf("spill file not removed: %v", entries)
+	}
+}
+
+func TestSpillBuffer_SmallDataStaysInMemory(t *testing.T) {
+	buf := &spillBuffer{dir: t.TempDir(), limit: 1024}
+	defer func() {
+		_ = buf.Close()
+	}()
+
+	if _, err := buf.Write([]byte("small")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if buf.file != nil {
+		t.Fatalf("small relay should not spill")
+	}
+	data, _ := io.ReadAll(buf.reader())
+	if string(data) != "small" {
+		t.Fatalf("reader returned %q", data)
+	}
+}
+
+// pacedCopyServer accepts pushes, optionally holding each chunk until release is closed.
+type pacedCopyServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	release  chan struct{}
+	received chan int64
+	// delay, when set, is slept before taking each chunk.
+	delay time.Duration
+}
+
+func (s *pacedCopyServer) Copy(stream convoypb.ConvoyService_CopyServer) error {
+	var total int64
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		chunk := req.GetChunk()
+		if chunk == nil {
+			continue
+		}
+		if s.release != nil {
+			<-s.release
+		}
+		time.Sleep(s.delay)
+		total += int64(len(chunk.GetData()))
+		if chunk.GetEof() {
+			s.received <- total
+			return stream.Send(&convoypb.CopyResponse{Payload: &convoypb.CopyResponse_Result{Result: &convoypb.CopyResult{Success: true}}})
+		}
+	}
+}
+
+func TestCopyFanOut_FastDestinationNotGatedBySlow(t *testing.T) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	fast := &pacedCopyServer{received: make(chan int64, 1)}
+	slow := &pacedCopyServer{release: make(chan struct{}), received: make(chan int64, 1)}
+	containers := NewContainerIndex([]*orchestrator.Container{
+		{ID: "fast", Name: "fast", Endpoint: startFakeAgent(t, fast)},
+		{ID: "slow", Name: "slow", Endpoint: startFakeAgent(t, slow)},
+	})
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+
+	// Large enough to overrun gRPC flow-control windows, so a stalled destination blocks its sender.
+	archive := filepath.Join(t.TempDir(), "payload.tar")
+	if err := os.WriteFile(archive, bytes.Repeat([]byte("x"), 8<<20), 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	// The slow destination comes first so a sequential push would never reach the fast one.
+	destinations := []copyEndpoint{
+		{isContainer: true, container: "slow", path: "/dst"},
+		{isContainer: true, container: "fast", path: "/dst"},
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- copyHostToContainers(context.Background(), cmd, rpc, containers, []copyEndpoint{{path: archive}}, destinations, copyOptions{extract: true})
+	}()
+
+	select {
+	case got := <-fast.received:
+		if got != 8<<20 {
+			t.Fatalf("fast destination received %d bytes", got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("fast destination was held back by the slow one")
+	}
+
+	close(slow.release)
+	if err := <-errc; err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if got := <-slow.received; got != 8<<20 {
+		t.Fatalf("slow destination received %d bytes", got)
+	}
+}
+
+func TestFanOut_StalledReaderSpillsToDisk(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	readers := newFanOut(bytes.NewReader(payload), 2)
+	f := readers[0].(*fanOutReader).f
+
+	// Reader 1 reads everything while reader 0 has not read at all.
+	got, err := io.ReadAll(readers[1])
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("fast reader: %d bytes, err %v", len(got), err)
+	}
+
+	f.mu.Lock()
+	queued, spilled := len(f.queues[0].chunks), f.queues[0].spilled
+	f.mu.Unlock()
+	if queued > fanOutQueueChunks {
+		t.Fatalf("stalled reader holds %d chunks in memory, want at most %d", queued, fanOutQueueChunks)
+	}
+	if want := int64(len(payload) - fanOutQueueChunks*fanOutChunkSize); spilled != want {
+		t.Fatalf("spilled %d bytes, want %d", spilled, want)
+	}
+
+	got, err = io.ReadAll(readers[0])
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("stalled reader: %d bytes, err %v", len(got), err)
+	}
+	f.mu.Lock()
+	spill := f.queues[0].spill
+	f.mu.Unlock()
+	if spill != nil {
+		t.Fatalf("spill file %s kept after the reader caught up", spill.Name())
+	}
+	for _, r := range readers {
+		_ = r.Close()
+	}
+}
+
+func TestFanOut_ReadersAreIndependent(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 100000)
+	readers := newFanOut(bytes.NewReader(payload), 3)
+
+	// Reader 2 is closed early; the others must still see everything.
+	_ = readers[2].Close()
+
+	got, err := io.ReadAll(readers[0])
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("first reader: %d bytes, err %v", len(got), err)
+	}
+	got, err = io.ReadAll(readers[1])
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("second reader: %d bytes, err %v", len(got), err)
+	}
+	if _, err := readers[2].Read(make([]byte, 1)); err == nil {
+		t.Fatalf("expected an error reading a closed reader")
+	}
+}
+
+func TestCopyCmd_JSONEvents(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "c1", Name: "c1", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+	}})
+
+	base := t.TempDir()
+	src := filepath.Join(base, "app.conf")
+	writeTestFile(t, src, "config")
+
+	stdout, _, err := runQuiet(t, NewCopyCmd(), "--json", src, "c1:"+filepath.Join(base, "out"), "ghost:/tmp")
+	if err == nil {
+		t.Fatalf("expected an error for the unknown container")
+	}
+
+	events := decodeEvents(t, stdout)
+	if got, want := eventNames(events), "copy_error,copy_start,copy_done"; got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+	if events[0].Container != "ghost" || events[0].Error == "" {
+		t.Fatalf("copy_error = %+v", events[0])
+	}
+	if start := events[1]; start.Container != "c1" || start.Source != src {
+		t.Fatalf("copy_start = %+v", start)
+	}
+}
+
+func TestExtractTarEntries_SymlinkSafety(t *testing.T) {
+	build := func(headers ...*tar.Header) io.Reader {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, header := range headers {
+			body := ""
+			if header.Typeflag == tar.TypeReg {
+				body = "pwned"
+				header.Size = int64(len(body))
+			}
+			header.Mode = 0o644
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatalf("write header: %v", err)
+			}
+			if _, err := tw.Write([]byte(body)); err != nil {
+				t.Fatalf("write body: %v", err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("close tar: %v", err)
+		}
+		return &buf
+	}
+
+	base := t.TempDir()
+	outside := filepath.Join(base, "a", "etc")
+	if err := os.MkdirAll(outside, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	escape := &tar.Header{Name: "etc", Typeflag: tar.TypeSymlink, Linkname: "../../etc"}
+
+	tests := []struct {
+		name    string
+		opts    copyOptions
+		headers []*tar.Header
+		want    string
+	}{
+		{"write through link", copyOptions{overwrite: true}, []*tar.Header{escape, {Name: "etc/passwd", Typeflag: tar.TypeReg}}, "through a symlink"},
+		{"no-follow", copyOptions{overwrite: true, noFollow: true}, []*tar.Header{escape}, "points outside the destination"},
+		{"hardlink", copyOptions{overwrite: true}, []*tar.Header{{Name: "shadow", Typeflag: tar.TypeLink, Linkname: "../../etc/shadow"}}, "hardlink"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(base, "a", "b", strings.ReplaceAll(tt.name, " ", "-"))
+			if err := os.MkdirAll(dest, 0o755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			err := extractTarFromReader(build(tt.headers...), dest, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q error, got %v", tt.want, err)
+			}
+			if _, err := os.Stat(filepath.Join(outside, "passwd")); !os.IsNotExist(err) {
+				t.Fatalf("file was written outside the destination: %v", err)
+			}
+		})
+	}
+}
+
+func TestRelayStreamsWithoutSpillDir(t *testing.T) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	containers := NewContainerIndex([]*orchestrator.Container{
+		{ID: "c1", Name: "c1", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+		{ID: "c2", Name: "c2", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+	})
+
+	base := t.TempDir()
+	payload := strings.Repeat("relay data ", 20000)
+	writeTestFile(t, filepath.Join(base, "src", "big.txt"), payload)
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+
+	source := copyEndpoint{isContainer: true, container: "c1", path: filepath.Join(base, "src")}
+	destinations := []copyEndpoint{
+		{isContainer: true, container: "c2", path: filepath.Join(base, "remote")},
+		{path: filepath.Join(base, "local")},
+		{isContainer: true, container: "ghost", path: "/tmp"},
+	}
+	err := copyContainerToContainers(context.Background(), cmd, rpc, containers, source, destinations, copyOptions{overwrite: true})
+	if err == nil {
+		t.Fatalf("expected the unknown destination to fail the relay")
+	}
+
+	for _, dir := range []string{"remote", "local"} {
+		if got, _ := os.ReadFile(filepath.Join(base, dir, "big.txt")); string(got) != payload {
+			t.Fatalf("%s copy mismatch (%d bytes)\n%s", dir, len(got), out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "Pulled ") {
+		t.Fatalf("expected the pulled size to be reported:\n%s", out.String())
+	}
+}
+
+// BenchmarkRelay compares the peak heap of a buffered relay against a streamed
+// one for a large tar; run with -benchtime=3x to keep it short.
+func BenchmarkRelay(b *testing.B) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	containers := NewContainerIndex([]*orchestrator.Container{
+		{ID: "c1", Name: "c1", Endpoint: startFakeAgent(b, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+		{ID: "c2", Name: "c2", Endpoint: startFakeAgent(b, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+	})
+
+	base := b.TempDir()
+	src := filepath.Join(base, "src")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		b.Fatalf("mkdir: %v", err)
+	}
+	chunk := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	for i := range 64 {
+		if err := os.WriteFile(filepath.Join(src, fmt.Sprintf("part-%02d", i)), chunk, 0o644); err != nil {
+			b.Fatalf("write: %v", err)
+		}
+	}
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	source := copyEndpoint{isContainer: true, container: "c1", path: src}
+	destinations := []copyEndpoint{{isContainer: true, container: "c2", path: filepath.Join(base, "dest")}}
+
+	for _, mode := range []struct {
+		name  string
+		relay func(context.Context, *cobra.Command, *orchestrator.RPC, *ContainerIndex, copyEndpoint, string, []copyEndpoint, copyOptions) error
+	}{
+		{"buffered", relayBuffered},
+		{"streamed", relayStreamed},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			opts := copyOptions{overwrite: true, relaySpillThreshold: defaultRelaySpillThreshold}
+			endpoint := containers.Resolve("c1").Endpoint
+			var peak uint64
+			for b.Loop() {
+				runtime.GC()
+				stop := samplePeakHeap(&peak)
+				if err := mode.relay(context.Background(), cmd, rpc, containers, source, endpoint, destinations, opts); err != nil {
+					b.Fatalf("relay: %v", err)
+				}
+				stop()
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MiB")
+		})
+	}
+}
+
+// samplePeakHeap records the largest HeapInuse seen in peak until stop is called.
+func samplePeakHeap(peak *uint64) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		var ms runtime.MemStats
+		for {
+			runtime.ReadMemStats(&ms)
+			*peak = max(*peak, ms.HeapInuse)
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// rejectingCopyServer fails every copy as soon as it starts.
+type rejectingCopyServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+}
+
+func (rejectingCopyServer) Copy(stream convoypb.ConvoyService_CopyServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+	return status.Error(codes.PermissionDenied, "read-only filesystem")
+}
+
+func TestRelayStreamed_FailingDestinationDoesNotStallOthers(t *testing.T) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	containers := NewContainerIndex([]*orchestrator.Container{
+		{ID: "c1", Name: "c1", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+		{ID: "c2", Name: "c2", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+		{ID: "c3", Name: "c3", Endpoint: startFakeAgent(t, rejectingCopyServer{})},
+		{ID: "c4", Name: "c4", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+	})
+
+	base := t.TempDir()
+	// Larger than the gRPC window so the failing destination stops reading mid-stream.
+	payload := strings.Repeat("0123456789abcdef", 1<<16)
+	writeTestFile(t, filepath.Join(base, "src", "big.txt"), payload)
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+
+	source := copyEndpoint{isContainer: true, container: "c1", path: filepath.Join(base, "src")}
+	destinations := []copyEndpoint{
+		{isContainer: true, container: "c2", path: filepath.Join(base, "two")},
+		{isContainer: true, container: "c3", path: filepath.Join(base, "three")},
+		{isContainer: true, container: "c4", path: filepath.Join(base, "four")},
+		{path: filepath.Join(base, "local")},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- copyContainerToContainers(context.Background(), cmd, rpc, containers, source, destinations, copyOptions{overwrite: true})
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("relay stalled behind the failing destination")
+	}
+	if err == nil {
+		t.Fatalf("expected the failing destination to fail the relay")
+	}
+
+	for _, dir := range []string{"two", "four", "local"} {
+		if got, _ := os.ReadFile(filepath.Join(base, dir, "big.txt")); string(got) != payload {
+			t.Fatalf("%s copy mismatch (%d bytes)\n%s", dir, len(got), out.String())
+		}
+	}
+	for _, want := range []string{"Successfully copied to c2", "Successfully copied to c4", "failed to push to c3"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// failingReader fails reads once it reaches limit, like a source lost mid-upload.
+type failingReader struct {
+	*bytes.Reader
+	limit int64
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	pos := r.Size() - int64(r.Len())
+	if pos >= r.limit {
+		return 0, errors.New("connection lost")
+	}
+	if remaining := r.limit - pos; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	return r.Reader.Read(p)
+}
+
+func TestCopyFileToContainer_ResumesAfterInterruption(t *testing.T) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	containers := NewContainerIndex([]*orchestrator.Container{{ID: "c1", Name: "c1", Endpoint: endpoint}})
+
+	base := t.TempDir()
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	src := filepath.Join(base, "disk.img")
+	writeTestFile(t, src, string(content))
+	remote := filepath.Join(base, "remote", "disk.img")
+	partial := fmt.Sprintf("%s.%d.partial", remote, len(content))
+
+	// The first attempt dies after 300 KiB; the agent keeps what arrived.
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := &failingReader{Reader: bytes.NewReader(content), limit: 300 * 1024}
+	if _, _, err := rpc.PushFile(ctx, endpoint, reader, int64(len(content)), remote, "disk.img", true); err == nil {
+		t.Fatalf("expected the interrupted upload to fail")
+	}
+	cancel()
+	// Chunks in flight when the stream was cancelled may be lost; wait for the size to settle.
+	var held int64
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		time.Sleep(50 * time.Millisecond)
+		info, err := os.Stat(partial)
+		if err == nil && info.Size() > 0 && info.Size() == held {
+			break
+		}
+		if err == nil {
+			held = info.Size()
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("agent did not keep the partial upload")
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	if err := copyFileToContainer(context.Background(), cmd, rpc, containers, copyEndpoint{path: src}, copyEndpoint{isContainer: true, container: "c1", path: remote}, copyOptions{}); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if !strings.Contains(out.String(), fmt.Sprintf("Resumed after %d bytes", held)) {
+		t.Fatalf("upload did not resume:\n%s", out.String())
+	}
+	if got, _ := os.ReadFile(remote); !bytes.Equal(got, content) {
+		t.Fatalf("resumed file differs (%d bytes)", len(got))
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Fatalf("partial file left behind: %v", err)
+	}
+
+	// A partial that does not match the source is discarded instead of resumed.
+	writeTestFile(t, partial, strings.Repeat("x", 1024))
+	out.Reset()
+	if err := copyFileToContainer(context.Background(), cmd, rpc, containers, copyEndpoint{path: src}, copyEndpoint{isContainer: true, container: "c1", path: filepath.Dir(remote)}, copyOptions{overwrite: true}); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if strings.Contains(out.String(), "Resumed") {
+		t.Fatalf("mismatched partial was resumed:\n%s", out.String())
+	}
+	if got, _ := os.ReadFile(remote); !bytes.Equal(got, content) {
+		t.Fatalf("restarted file differs (%d bytes)", len(got))
+	}
+}
+
+func TestCopyFileToContainer_HonoursOverwriteAndRejectsTarFlags(t *testing.T) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	containers := NewContainerIndex([]*orchestrator.Container{{ID: "c1", Name: "c1", Endpoint: endpoint}})
+
+	base := t.TempDir()
+	src := filepath.Join(base, "disk.img")
+	writeTestFile(t, src, "new contents")
+	remote := filepath.Join(base, "remote", "disk.img")
+	writeTestFile(t, remote, "existing")
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	err := copyFileToContainer(context.Background(), cmd, rpc, containers, copyEndpoint{path: src}, copyEndpoint{isContainer: true, container: "c1", path: remote}, copyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "overwrite is disabled") {
+		t.Fatalf("copy without overwrite = %v, want the existing file refused", err)
+	}
+	if got, _ := os.ReadFile(remote); string(got) != "existing" {
+		t.Fatalf("existing file replaced with %q", got)
+	}
+
+	if err := copyFileToContainer(context.Background(), cmd, rpc, containers, copyEndpoint{path: src}, copyEndpoint{isContainer: true, container: "c1", path: remote}, copyOptions{overwrite: true}); err != nil {
+		t.Fatalf("copy with overwrite: %v", err)
+	}
+	if got, _ := os.ReadFile(remote); string(got) != "new contents" {
+		t.Fatalf("file = %q after an overwriting copy", got)
+	}
+
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "c1", Name: "c1", Endpoint: endpoint}}})
+	_, _, err = runQuiet(t, NewCopyCmd(), "--resume", "--atomic", "--preserve-times=false", src, "c1:"+remote)
+	if err == nil || !strings.Contains(err.Error(), "--resume cannot be combined with --atomic, --preserve-times") {
+		t.Fatalf("copy --resume --atomic = %v, want the tar-only flags rejected", err)
+	}
+}
+
+func TestRawPush_ConcurrentUploadsOfOneFileAreRefused(t *testing.T) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	remote := filepath.Join(t.TempDir(), "disk.img")
+	content := bytes.Repeat([]byte("a"), 1<<20)
+
+	// The first upload stalls after announcing itself, holding the partial file.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stalled := io.MultiReader(bytes.NewReader(content[:1024]), blockingReader{ctx: ctx})
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := rpc.PushFile(ctx, endpoint, &seekable{Reader: stalled}, int64(len(content)), remote, "disk.img", true)
+		first <- err
+	}()
+
+	// Race the second upload only once the first holds the partial file, or
+	// the second may win and the first be the one refused.
+	partial := fmt.Sprintf("%s.%d.partial", remote, len(content))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(partial); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("first upload never wrote %s", partial)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for {
+		_, _, err := rpc.PushFile(context.Background(), endpoint, bytes.NewReader(content), int64(len(content)), remote, "disk.img", true)
+		if err != nil && strings.Contains(err.Error(), "in progress") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("second upload = %v, want it refused while the first is running", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	<-first
+}
+
+// blockingReader blocks until ctx is done.
+type blockingReader struct {
+	ctx context.Context
+}
+
+func (r blockingReader) Read([]byte) (int, error) {
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+// seekable adapts a reader that is only ever read from the start to io.ReadSeeker.
+type seekable struct {
+	io.Reader
+}
+
+func (seekable) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, errors.New("seek not supported")
+	}
+	return 0, nil
+}
+
+func TestRelayStreamed_SlowDestinationBoundsMemory(t *testing.T) {
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	slow := &pacedCopyServer{received: make(chan int64, 1), delay: 2 * time.Millisecond}
+	containers := NewContainerIndex([]*orchestrator.Container{
+		{ID: "c1", Name: "c1", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+		{ID: "c2", Name: "c2", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+		{ID: "slow", Name: "slow", Endpoint: startFakeAgent(t, slow)},
+	})
+
+	base := t.TempDir()
+	src := filepath.Join(base, "src")
+	chunk := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	for i := range 64 {
+		writeTestFile(t, filepath.Join(src, fmt.Sprintf("part-%02d", i)), string(chunk))
+	}
+	const sourceSize = 64 << 20
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	source := copyEndpoint{isContainer: true, container: "c1", path: src}
+	destinations := []copyEndpoint{
+		{isContainer: true, container: "c2", path: filepath.Join(base, "fast")},
+		{isContainer: true, container: "slow", path: "/dst"},
+	}
+
+	// Collect eagerly so the sampled heap tracks what the relay keeps alive
+	// rather than garbage waiting for the next cycle.
+	defer debug.SetGCPercent(debug.SetGCPercent(10))
+	runtime.GC()
+	var baseline runtime.MemStats
+	runtime.ReadMemStats(&baseline)
+	var peak uint64
+	stop := samplePeakHeap(&peak)
+	err := relayStreamed(context.Background(), cmd, rpc, containers, source, containers.Resolve("c1").Endpoint, destinations, copyOptions{overwrite: true})
+	stop()
+	if err != nil {
+		t.Fatalf("relay: %v", err)
+	}
+	if got := <-slow.received; got < sourceSize {
+		t.Fatalf("slow destination received %d bytes, want the whole %d byte tree", got, sourceSize)
+	}
+
+	// A relay that let the fast destination run ahead would queue most of the
+	// source for the slow one. The in-process agents' gRPC flow-control windows
+	// alone account for about a quarter of it, so allow up to half.
+	if grown := int64(peak) - int64(baseline.HeapInuse); grown > sourceSize/2 {
+		t.Fatalf("heap grew by %d MiB relaying %d MiB to a slow destination", grown>>20, sourceSize>>20)
+	}
+}
@@ -0,0 +1,271 @@
+package cmds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"convoy/internal/orchestrator"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// transferManifest is the file read by copy-batch.
+type transferManifest struct {
+	Transfers []manifestTransfer `yaml:"transfers"`
+}
+
+// manifestTransfer is one copy: the same endpoints the copy command takes.
+type manifestTransfer struct {
+	From endpointList `yaml:"from"`
+	To   endpointList `yaml:"to"`
+}
+
+// endpointList accepts either a single endpoint or a list of them.
+type endpointList []string
+
+func (l *endpointList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = endpointList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+func (t manifestTransfer) String() string {
+	return strings.Join(t.From, " ") + " -> " + strings.Join(t.To, " ")
+}
+
+// batchTransfer is a validated manifest entry ready to run.
+type batchTransfer struct {
+	label        string
+	desc         string
+	sources      []copyEndpoint
+	destinations []copyEndpoint
+}
+
+// NewCopyBatchCmd creates the copy-batch command for running many copies from a manifest.
+func NewCopyBatchCmd() *cobra.Command {
+	var (
+		file        string
+		concurrency int
+		flags       copyFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "copy-batch -f FILE",
+		Short: "Run many copies listed in a manifest",
+		Long: `Run the copies listed in a YAML manifest in one process, sharing connections
+to the agents and running up to --concurrency transfers at once. Each entry
+takes the same endpoints as convoy copy; from and to may be a single endpoint
+or a list:
+
+  transfers:
+    - from: ./dist
+      to: web:/srv/app
+    - from: [./config.yaml, ./secrets.env]
+      to: [web-1:/etc/app, web-2:/etc/app]
+    - from: db:/var/log/postgres
+      to: ./logs
+
+Every transfer reports its own result; the command fails if any of them did.
+Output is printed as it happens, each line prefixed with its transfer's number.
+Use "-f -" to read the manifest from standard input. The copy flags apply to
+every transfer.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if file == "" {
+				return errors.New("--file is required")
+			}
+			if concurrency < 1 {
+				return errors.New("--concurrency must be at least 1")
+			}
+			opts, err := flags.options(cmd)
+			if err != nil {
+				return err
+			}
+
+			manifest, err := readTransferManifest(cmd.InOrStdin(), file)
+			if err != nil {
+				return err
+			}
+			transfers, err := planTransfers(manifest)
+			if err != nil {
+				return err
+			}
+			var destinations []copyEndpoint
+			for _, t := range transfers {
+				destinations = append(destinations, t.destinations...)
+			}
+			if err := checkOverwriteOn(opts, destinations); err != nil {
+				return err
+			}
+
+			containers, err := LoadContainers()
+			if err != nil {
+				return err
+			}
+
+			rpc := NewRPCClient(flags.timeout, 0)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			failed := runTransfers(context.Background(), cmd.OutOrStdout(), cmd.ErrOrStderr(), rpc.RPC, containers, transfers, opts, concurrency)
+			// With --json, standard output carries only events.
+			summary := cmd.OutOrStdout()
+			if opts.events != nil {
+				summary = cmd.ErrOrStderr()
+			}
+			_, _ = fmt.Fprintf(summary, "%d of %d transfers succeeded\n", len(transfers)-failed, len(transfers))
+			if failed > 0 {
+				return fmt.Errorf("%d transfers failed", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Manifest listing the transfers (- for stdin)")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 4, "Maximum number of transfers to run at once")
+	flags.register(cmd)
+
+	return cmd
+}
+
+func readTransferManifest(stdin io.Reader, file string) (*transferManifest, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	var manifest transferManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", file, err)
+	}
+	if len(manifest.Transfers) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no transfers", file)
+	}
+	return &manifest, nil
+}
+
+// planTransfers validates every entry up front so a bad manifest fails before anything is copied.
+func planTransfers(manifest *transferManifest) ([]batchTransfer, error) {
+	transfers := make([]batchTransfer, 0, len(manifest.Transfers))
+	for i, entry := range manifest.Transfers {
+		if len(entry.From) == 0 || len(entry.To) == 0 {
+			return nil, fmt.Errorf("transfer %d: from and to are required", i+1)
+		}
+		sources, destinations, err := parseCopyArgs(append(append([]string(nil), entry.From...), entry.To...))
+		if err != nil {
+			return nil, fmt.Errorf("transfer %d (%s): %w", i+1, entry, err)
+		}
+		transfers = append(transfers, batchTransfer{
+			label:        fmt.Sprintf("%d", i+1),
+			desc:         entry.String(),
+			sources:      sources,
+			destinations: destinations,
+		})
+	}
+	return transfers, nil
+}
+
+// runTransfers runs the transfers with bounded concurrency, writing each one's
+// output as it is produced and its result as it finishes, and returns how many
+// failed.
+func runTransfers(ctx context.Context, stdout, stderr io.Writer, rpc *orchestrator.RPC, containers *ContainerIndex, transfers []batchTransfer, opts copyOptions, concurrency int) int {
+	var (
+		mu     sync.Mutex
+		failed int
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, concurrency)
+	)
+
+	for _, transfer := range transfers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Lines are passed on whole as they end, so concurrent transfers
+			// never split each other's lines.
+			prefix := "[" + transfer.label + "] "
+			out := &prefixedLineWriter{mu: &mu, w: stdout, prefix: prefix}
+			errOut := &prefixedLineWriter{mu: &mu, w: stderr, prefix: prefix}
+			sub := &cobra.Command{}
+			sub.SetOut(out)
+			sub.SetErr(errOut)
+			err := runCopy(ctx, sub, rpc, containers, transfer.sources, transfer.destinations, opts)
+			out.flush()
+			errOut.flush()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				_, _ = fmt.Fprintf(stderr, "%sfailed: %s: %v\n", prefix, transfer.desc, err)
+				return
+			}
+			if opts.events == nil {
+				_, _ = fmt.Fprintf(stdout, "%sdone: %s\n", prefix, transfer.desc)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return failed
+}
+
+// prefixedLineWriter writes each line to w with prefix as soon as it ends,
+// holding mu so lines written through other writers sharing it stay whole.
+type prefixedLineWriter struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	prefix  string
+	partial []byte
+}
+
+func (p *prefixedLineWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.partial = append(p.partial, data...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		_, _ = fmt.Fprintf(p.w, "%s%s", p.prefix, p.partial[:i+1])
+		p.partial = p.partial[i+1:]
+	}
+	return len(data), nil
+}
+
+// flush writes out a final line that did not end in a newline.
+func (p *prefixedLineWriter) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.partial) > 0 {
+		_, _ = fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.partial)
+		p.partial = nil
+	}
+}
@@ -0,0 +1,129 @@
+package cmds
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"convoy/internal/agent"
+	"convoy/internal/orchestrator"
+)
+
+func TestPlanTransfers_ParsesManifest(t *testing.T) {
+	manifest := `transfers:
+  - from: ./dist
+    to: web:/srv/app
+  - from: [./a.conf, ./b.conf]
+    to: [web-1:/etc/app, web-2:/etc/app]
+  - from: db:/var/log/app.log
+    to: ./logs
+`
+	parsed, err := readTransferManifest(strings.NewReader(manifest), "-")
+	if err != nil {
+		t.Fatalf("readTransferManifest: %v", err)
+	}
+	transfers, err := planTransfers(parsed)
+	if err != nil {
+		t.Fatalf("planTransfers: %v", err)
+	}
+	if len(transfers) != 3 {
+		t.Fatalf("got %d transfers, want 3", len(transfers))
+	}
+
+	second := transfers[1]
+	if len(second.sources) != 2 || len(second.destinations) != 2 {
+		t.Fatalf("transfer 2 = %+v", second)
+	}
+	if second.destinations[1].container != "web-2" || second.destinations[1].path != "/etc/app" {
+		t.Fatalf("transfer 2 destination = %+v", second.destinations[1])
+	}
+	if got := []string{transfers[2].sources[0].container, transfers[2].destinations[0].path}; !reflect.DeepEqual(got, []string{"db", "./logs"}) {
+		t.Fatalf("transfer 3 = %v", got)
+	}
+
+	bad := &transferManifest{Transfers: []manifestTransfer{{From: endpointList{"./a"}, To: endpointList{"./b"}}}}
+	if _, err := planTransfers(bad); err == nil || !strings.Contains(err.Error(), "transfer 1") {
+		t.Fatalf("expected an error naming transfer 1, got %v", err)
+	}
+}
+
+func TestCopyBatchCmd_RunsEachTransfer(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "web", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+		{ID: "id-2", Name: "db", Endpoint: startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))},
+	}})
+
+	base := t.TempDir()
+	writeTestFile(t, filepath.Join(base, "dist", "index.html"), "hello")
+	writeTestFile(t, filepath.Join(base, "remote", "app.log"), "log line")
+
+	manifest := filepath.Join(base, "transfers.yaml")
+	content := "transfers:\n" +
+		"  - from: " + filepath.Join(base, "dist") + "\n" +
+		"    to: web:" + filepath.Join(base, "pushed") + "\n" +
+		"  - from: [db:" + filepath.Join(base, "remote", "app.log") + "]\n" +
+		"    to: " + filepath.Join(base, "pulled") + "\n" +
+		"  - from: " + filepath.Join(base, "missing") + "\n" +
+		"    to: web:/tmp/missing\n"
+	if err := os.WriteFile(manifest, []byte(content), 0o600); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := NewCopyBatchCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"-f", manifest, "--concurrency", "2"})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected an error for the failed transfer")
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(base, "pushed", "index.html")); string(got) != "hello" {
+		t.Fatalf("pushed file = %q", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(base, "pulled", "app.log")); string(got) != "log line" {
+		t.Fatalf("pulled file = %q", got)
+	}
+
+	for _, want := range []string{"[1] done: ", "[2] done: ", "2 of 3 transfers succeeded"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("stdout missing %q:\n%s", want, stdout.String())
+		}
+	}
+	if !strings.Contains(stderr.String(), "[3] failed: ") {
+		t.Fatalf("stderr missing the failed transfer:\n%s", stderr.String())
+	}
+}
+
+func TestCopyBatchCmd_TakesEveryCopyFlag(t *testing.T) {
+	copyFlags, batch := NewCopyCmd().Flags(), NewCopyBatchCmd().Flags()
+	for _, name := range []string{"timeout", "overwrite", "overwrite-on", "no-overwrite-on", "exclude", "preserve-times", "preserve-owner", "update", "no-overwrite-newer", "archive", "extract", "relay-spill", "atomic", "no-follow", "resume", "json"} {
+		want, got := copyFlags.Lookup(name), batch.Lookup(name)
+		if want == nil || got == nil || got.Usage != want.Usage || got.DefValue != want.DefValue {
+			t.Errorf("--%s: copy has %v, copy-batch has %v", name, want, got)
+		}
+	}
+}
+
+func TestPrefixedLineWriter_WritesLinesAsTheyEnd(t *testing.T) {
+	var mu sync.Mutex
+	var out bytes.Buffer
+	w := &prefixedLineWriter{mu: &mu, w: &out, prefix: "[1] "}
+
+	_, _ = w.Write([]byte("Copying"))
+	if out.Len() != 0 {
+		t.Fatalf("wrote an unfinished line: %q", out.String())
+	}
+	_, _ = w.Write([]byte(" a\nCopying b\nDone"))
+	if got, want := out.String(), "[1] Copying a\n[1] Copying b\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	w.flush()
+	if got, want := out.String(), "[1] Copying a\n[1] Copying b\n[1] Done\n"; got != want {
+		t.Fatalf("output after flush = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"convoy/internal/app"
+	"convoy/internal/environ"
+	"convoy/internal/orchestrator"
+)
+
+// NewCreateCmd creates the create command for provisioning containers without starting them.
+func NewCreateCmd() *cobra.Command {
+	var (
+		name        string
+		image       string
+		envVars     []string
+		envFiles    []string
+		envPrefix   string
+		stripPrefix bool
+		labels      []string
+		volumes     []string
+		publish     []string
+		network     string
+		newNetwork  bool
+		restart     string
+		quietPull   bool
+		pull        string
+		start       bool
+		wait        time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create [--name NAME] [--image IMAGE]",
+		Short: "Create a container",
+		Long: `Create and register a container without starting it, printing its ID.
+
+Without --name a readable unused name such as brave-otter-042 is generated
+and reported on stderr. The image defaults to the one in the convoy config.
+Volumes use Docker's bind syntax (host-path:container-path[:ro]). Besides the
+agent port, --publish makes container ports reachable on the host as
+host:container[/tcp|udp|sctp], with host port 0 picking a free one.
+
+--network overrides the configured Docker network with host, none or a named
+network, which --create-network creates if needed so a group of containers can
+talk on a network of their own. --restart takes Docker's restart policies;
+containers are restarted unless stopped by default. With --start the container
+is started right away, and --wait additionally waits for its agent to report
+healthy:
+
+  convoy create --name web -v /srv/data:/data:ro -p 8080:80 --restart on-failure:5 --start --wait 30s`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			name = strings.TrimSpace(name)
+			if wait > 0 && !start {
+				return errors.New("--wait requires --start")
+			}
+			if newNetwork && strings.TrimSpace(network) == "" {
+				return errors.New("--create-network requires --network")
+			}
+			if err := validateVolumes(volumes); err != nil {
+				return err
+			}
+			ports, err := parsePublishFlag(publish)
+			if err != nil {
+				return err
+			}
+			restartPolicy, err := parseRestartFlag(cmd, restart)
+			if err != nil {
+				return err
+			}
+			pullPolicy, err := parsePullFlag(cmd, pull)
+			if err != nil {
+				return err
+			}
+			fileEnv, err := ParseEnvFiles(envFiles)
+			if err != nil {
+				return err
+			}
+
+			app, err := getApp()
+			if err != nil {
+				return err
+			}
+			cfg, err := app.Config()
+			if err != nil {
+				return err
+			}
+			mgr, err := app.Manager()
+			if err != nil {
+				return err
+			}
+			showPullProgress(cmd, mgr, quietPull)
+
+			if name == "" {
+				containers, err := LoadContainers()
+				if err != nil {
+					return err
+				}
+				if name, err = unusedContainerName(containers); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Generated name %s\n", name)
+			}
+
+			spec := orchestrator.ContainerSpec{
+				Name:          name,
+				Image:         strings.TrimSpace(image),
+				Environment:   environ.Merge(PrefixedEnvVars(os.Environ(), envPrefix, stripPrefix), fileEnv, ParseEnvVars(envVars)),
+				Labels:        ParseEnvVars(labels),
+				Volumes:       volumes,
+				Ports:         ports,
+				Network:       strings.TrimSpace(network),
+				CreateNetwork: newNetwork,
+				PullPolicy:    pullPolicy,
+				RestartPolicy: restartPolicy,
+			}
+			if spec.Image == "" {
+				spec.Image = cfg.Image
+			}
+			if spec.Image == "" {
+				return errors.New("--image is required when no default image is configured")
+			}
+			if warning := floatingTagWarning(cfg, spec.Image); warning != "" {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s\n", warning)
+			}
+
+			container, err := mgr.Create(spec)
+			if err != nil {
+				return fmt.Errorf("create %s: %w", name, err)
+			}
+			if err := app.Registry().Register(container); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to register %s: %v\n", container.ID, err)
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), container.ID)
+
+			if !start {
+				return nil
+			}
+			if err := mgr.Start(container.ID); err != nil {
+				return fmt.Errorf("start %s: %w", name, err)
+			}
+			if wait > 0 {
+				endpoint := startedEndpoint(mgr, container.ID)
+				if endpoint == "" {
+					return fmt.Errorf("started %s but it has no gRPC endpoint to wait on", name)
+				}
+
+				rpc := NewRPCClientWithTimeout(wait)
+				defer func() {
+					_ = rpc.Close()
+				}()
+				if err := waitForAgent(context.Background(), rpc.RPC, endpoint, wait, agentPollInterval); err != nil {
+					return fmt.Errorf("started %s but agent is not healthy: %w", name, err)
+				}
+			}
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Started %s\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Container name (generated when omitted)")
+	cmd.Flags().StringVar(&image, "image", "", "Image to run (defaults to the configured image)")
+	cmd.Flags().StringArrayVarP(&envVars, "env", "e", nil, "Set environment variables (can be repeated)")
+	cmd.Flags().StringArrayVar(&envFiles, "env-file", nil, "Read environment variables from a dotenv file; -e overrides them (can be repeated)")
+	cmd.Flags().StringVar(&envPrefix, "env-prefix", "", "Forward host environment variables with this prefix")
+	cmd.Flags().BoolVar(&stripPrefix, "strip-prefix", false, "Strip --env-prefix from forwarded variable names")
+	cmd.Flags().StringArrayVarP(&labels, "label", "l", nil, "Add a key=value label (can be repeated)")
+	cmd.Flags().StringArrayVarP(&volumes, "volume", "v", nil, "Bind mount host-path:container-path[:ro] (can be repeated)")
+	cmd.Flags().StringArrayVarP(&publish, "publish", "p", nil, "Publish a container port as host:container[/protocol] (can be repeated)")
+	cmd.Flags().StringVar(&network, "network", "", "Network to join: host, none or a network name (defaults to the configured network)")
+	cmd.Flags().BoolVar(&newNetwork, "create-network", false, "Create the --network network if it does not exist")
+	cmd.Flags().StringVar(&restart, "restart", "", "Restart policy: no, on-failure[:max-retries], always or unless-stopped (default unless-stopped)")
+	cmd.Flags().StringVar(&pull, "pull", "", "When to pull the image: always, missing or never (defaults to the pull_always setting)")
+	cmd.Flags().BoolVar(&quietPull, "quiet-pull", false, "Do not show image pull progress")
+	cmd.Flags().BoolVar(&start, "start", false, "Start the container after creating it")
+	cmd.Flags().DurationVar(&wait, "wait", 0, "With --start, wait up to this long for the agent to report healthy")
+
+	return cmd
+}
+
+// floatingTagWarning explains, before the create is attempted, that
+// verify_image_digest only accepts images pinned by digest. It returns "" when
+// verification is off or the image is already pinned.
+func floatingTagWarning(cfg *app.Config, image string) string {
+	if !cfg.VerifyImageDigest || orchestrator.PinnedDigest(image) != "" {
+		return ""
+	}
+	return fmt.Sprintf("image %s is not pinned by digest, which verify_image_digest requires; use image@sha256:... instead", image)
+}
+
+// parsePublishFlag parses the repeated --publish flag into port mappings.
+func parsePublishFlag(publish []string) ([]orchestrator.PortMapping, error) {
+	var ports []orchestrator.PortMapping
+	for _, p := range publish {
+		port, err := orchestrator.ParsePortMapping(p)
+		if err != nil {
+			return nil, fmt.Errorf("--publish: %w", err)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// parsePullFlag parses the --pull flag, returning "" when it was not given so
+// the configured pull_always applies.
+func parsePullFlag(cmd *cobra.Command, pull string) (orchestrator.PullPolicy, error) {
+	if !cmd.Flags().Changed("pull") {
+		return "", nil
+	}
+	policy, err := orchestrator.ParsePullPolicy(pull)
+	if err != nil {
+		return "", fmt.Errorf("--pull: %w", err)
+	}
+	return policy, nil
+}
+
+// parseRestartFlag parses the --restart flag, returning the zero policy when it was not given.
+func parseRestartFlag(cmd *cobra.Command, restart string) (orchestrator.RestartPolicy, error) {
+	if !cmd.Flags().Changed("restart") {
+		return orchestrator.RestartPolicy{}, nil
+	}
+	policy, err := orchestrator.ParseRestartPolicy(restart)
+	if err != nil {
+		return orchestrator.RestartPolicy{}, fmt.Errorf("--restart: %w", err)
+	}
+	return policy, nil
+}
+
+// validateVolumes checks each bind is host-path:container-path with an optional mode.
+func validateVolumes(volumes []string) error {
+	for _, volume := range volumes {
+		parts := strings.Split(volume, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid volume %q (want host-path:container-path[:ro])", volume)
+		}
+		if !path.IsAbs(parts[1]) {
+			return fmt.Errorf("invalid volume %q: container path must be absolute", volume)
+		}
+		if len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw" {
+			return fmt.Errorf("invalid volume %q: mode must be ro or rw", volume)
+		}
+	}
+	return nil
+}
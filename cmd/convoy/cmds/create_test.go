@@ -0,0 +1,194 @@
+package cmds
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"convoy/internal/orchestrator"
+)
+
+func TestCreateCmd_BuildsSpecFromFlags(t *testing.T) {
+	rt := &fakeRuntime{}
+	fake := useFakeApp(t, rt)
+
+	var out bytes.Buffer
+	cmd := NewCreateCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{
+		"--name", "web", "--image", "nginx:1.27",
+		"-e", "MODE=prod", "-l", "team=core",
+		"-v", "/srv/data:/data:ro", "-v", "/tmp/cache:/cache",
+		"-p", "8080:80", "--publish", "0:53/udp",
+		"--network", "team-a", "--create-network",
+		"--restart", "on-failure:3", "--pull", "never",
+	})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if len(rt.specs) != 1 {
+		t.Fatalf("expected one create call, got %d", len(rt.specs))
+	}
+	want := orchestrator.ContainerSpec{
+		Name:        "web",
+		Image:       "nginx:1.27",
+		Environment: map[string]string{"MODE": "prod"},
+		Labels:      map[string]string{"team": "core"},
+		Volumes:     []string{"/srv/data:/data:ro", "/tmp/cache:/cache"},
+		Ports: []orchestrator.PortMapping{
+			{HostPort: 8080, ContainerPort: 80},
+			{HostPort: 0, ContainerPort: 53, Protocol: "udp"},
+		},
+		Network:       "team-a",
+		CreateNetwork: true,
+		PullPolicy:    orchestrator.PullNever,
+		RestartPolicy: orchestrator.RestartPolicy{Name: orchestrator.RestartOnFailure, MaxRetries: 3},
+	}
+	if !reflect.DeepEqual(rt.specs[0], want) {
+		t.Fatalf("spec = %+v, want %+v", rt.specs[0], want)
+	}
+	if got := strings.TrimSpace(out.String()); got != "web-id" {
+		t.Fatalf("expected the new container ID on stdout, got %q", got)
+	}
+	if !reflect.DeepEqual(rt.calls, []string{"create:web"}) {
+		t.Fatalf("container should not be started without --start: %v", rt.calls)
+	}
+	if _, ok := fake.registry.Get("web-id"); !ok {
+		t.Fatalf("created container was not registered")
+	}
+}
+
+func TestCreateCmd_StartAndDefaults(t *testing.T) {
+	rt := &fakeRuntime{}
+	useFakeApp(t, rt)
+
+	cmd := NewCreateCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--name", "api", "--start"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("create --start: %v", err)
+	}
+
+	if rt.specs[0].Image != "convoy:test" {
+		t.Fatalf("expected the configured image, got %q", rt.specs[0].Image)
+	}
+	if !reflect.DeepEqual(rt.calls, []string{"create:api", "start:api-id"}) {
+		t.Fatalf("unexpected runtime calls: %v", rt.calls)
+	}
+}
+
+func TestCreateCmd_EnvFileUnderExplicitEnv(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "app.env")
+	if err := os.WriteFile(envFile, []byte("# app\nMODE=prod\nREGION='eu'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rt := &fakeRuntime{}
+	useFakeApp(t, rt)
+
+	cmd := NewCreateCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--name", "web", "--env-file", envFile, "-e", "MODE=debug"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	want := map[string]string{"MODE": "debug", "REGION": "eu"}
+	if len(rt.specs) != 1 || !reflect.DeepEqual(rt.specs[0].Environment, want) {
+		t.Fatalf("environment = %v, want %v", rt.specs, want)
+	}
+}
+
+func TestCreateCmd_GeneratesUnusedName(t *testing.T) {
+	taken, err := newNameGenerator(3).generate(nil)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	useNameSeed(t, 3)
+	rt := &fakeRuntime{containers: []*orchestrator.Container{{ID: "old-id", Name: taken}}}
+	useFakeApp(t, rt)
+
+	var out, errOut bytes.Buffer
+	cmd := NewCreateCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs([]string{"--image", "nginx"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if len(rt.specs) != 1 {
+		t.Fatalf("expected one create call, got %d", len(rt.specs))
+	}
+	name := rt.specs[0].Name
+	if name == taken || !generatedName.MatchString(name) {
+		t.Fatalf("generated name %q (taken %q)", name, taken)
+	}
+	if got := errOut.String(); got != "Generated name "+name+"\n" {
+		t.Fatalf("expected the generated name on stderr, got %q", got)
+	}
+	if got := strings.TrimSpace(out.String()); got != name+"-id" {
+		t.Fatalf("expected the new container ID on stdout, got %q", got)
+	}
+}
+
+func TestCreateCmd_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		rt   *fakeRuntime
+		args []string
+		want string
+	}{
+		{name: "bad volume", rt: &fakeRuntime{}, args: []string{"--name", "web", "-v", "/srv/data"}, want: "invalid volume"},
+		{name: "relative target", rt: &fakeRuntime{}, args: []string{"--name", "web", "-v", "/srv:data"}, want: "must be absolute"},
+		{name: "bad port", rt: &fakeRuntime{}, args: []string{"--name", "web", "-p", "80"}, want: "invalid port mapping"},
+		{name: "create network without a name", rt: &fakeRuntime{}, args: []string{"--name", "web", "--create-network"}, want: "--create-network requires --network"},
+		{name: "bad pull policy", rt: &fakeRuntime{}, args: []string{"--name", "web", "--pull", "sometimes"}, want: "invalid pull policy"},
+		{name: "wait without start", rt: &fakeRuntime{}, args: []string{"--name", "web", "--wait", "5s"}, want: "--wait requires --start"},
+		{name: "docker error", rt: &fakeRuntime{failCreate: errors.New("pull access denied for nope")}, args: []string{"--name", "web", "--image", "nope"}, want: "pull access denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeApp(t, tt.rt)
+			cmd := NewCreateCmd()
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tt.args)
+			err := cmd.Execute()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCreateCmd_WarnsOnlyForFloatingTagsWhenVerifying(t *testing.T) {
+	for _, tc := range []struct {
+		image  string
+		verify bool
+		warn   bool
+	}{
+		{image: "alpine:3.20", verify: true, warn: true},
+		{image: "alpine:3.20", verify: false, warn: false},
+		{image: "alpine@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", verify: true, warn: false},
+	} {
+		fake := useFakeApp(t, &fakeRuntime{})
+		fake.cfg.VerifyImageDigest = tc.verify
+		_, stderr, err := runQuiet(t, NewCreateCmd(), "--name", "web", "--image", tc.image)
+		if err != nil {
+			t.Fatalf("create %s: %v", tc.image, err)
+		}
+		if got := strings.Contains(stderr, "not pinned by digest"); got != tc.warn {
+			t.Fatalf("create %s (verify %v): stderr = %q, want warning %v", tc.image, tc.verify, stderr, tc.warn)
+		}
+	}
+}
@@ -20,6 +20,7 @@ var GetAppFunc func() (AppProvider, error)
 // CLIOpts holds CLI options accessible to commands.
 var CLIOpts struct {
 	ConfigPath string
+	Profile    string
 }
 
 func getApp() (AppProvider, error) {
@@ -0,0 +1,47 @@
+package cmds
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// event is one JSON line written by a command run with --json. Event names the
+// step (copy_start, start_done, stop_done, ...); the other fields are set
+// when they apply to it.
+type event struct {
+	Event           string `json:"event"`
+	Container       string `json:"container,omitempty"`
+	ID              string `json:"id,omitempty"`
+	Source          string `json:"source,omitempty"`
+	Destination     string `json:"destination,omitempty"`
+	Signal          string `json:"signal,omitempty"`
+	Bytes           int64  `json:"bytes,omitempty"`
+	Skipped         int32  `json:"skipped,omitempty"`
+	SkippedExisting int32  `json:"skipped_existing,omitempty"`
+	ForceKilled     bool   `json:"force_killed,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// eventEmitter writes events as JSON lines. It is safe for concurrent use, so
+// commands working on several containers at once never interleave lines.
+type eventEmitter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// newEventEmitter returns an emitter writing to w, or nil when disabled so
+// callers can fall back to their human-readable output.
+func newEventEmitter(w io.Writer, enabled bool) *eventEmitter {
+	if !enabled {
+		return nil
+	}
+	return &eventEmitter{enc: json.NewEncoder(w)}
+}
+
+// emit writes ev as a single line.
+func (e *eventEmitter) emit(ev event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(ev)
+}
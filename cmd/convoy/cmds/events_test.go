@@ -0,0 +1,60 @@
+package cmds
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// decodeEvents parses out as JSON lines, failing unless every line is one event object.
+func decodeEvents(t *testing.T, out string) []event {
+	t.Helper()
+	var events []event
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		var ev event
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("line %q is not valid JSON: %v", line, err)
+		}
+		if ev.Event == "" {
+			t.Fatalf("line %q has no event name", line)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// eventNames lists the event field of each event, in order.
+func eventNames(events []event) string {
+	names := make([]string, len(events))
+	for i, ev := range events {
+		names[i] = ev.Event
+	}
+	return strings.Join(names, ",")
+}
+
+func TestEventEmitter_ConcurrentLinesStayWhole(t *testing.T) {
+	var out bytes.Buffer
+	events := newEventEmitter(&out, true)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			events.emit(event{Event: "copy_progress", Container: "c1", Bytes: 1 << 20})
+		}()
+	}
+	wg.Wait()
+
+	if got := decodeEvents(t, out.String()); len(got) != 50 {
+		t.Fatalf("got %d events, want 50", len(got))
+	}
+}
+
+func TestNewEventEmitter_DisabledIsNil(t *testing.T) {
+	if newEventEmitter(&bytes.Buffer{}, false) != nil {
+		t.Fatalf("disabled emitter should be nil")
+	}
+}
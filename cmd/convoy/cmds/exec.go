@@ -2,50 +2,222 @@ package cmds
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	convoypb "convoy/api"
+	"convoy/internal/environ"
+	"convoy/internal/orchestrator"
 
 	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
 )
 
 // NewExecCmd creates the exec command for running commands inside containers.
 func NewExecCmd() *cobra.Command {
 	var (
-		envVars []string
-		workDir string
-		timeout time.Duration
+		envVars     []string
+		envFiles    []string
+		envPrefix   string
+		stripPrefix bool
+		workDir     string
+		timeout     time.Duration
+		noShell     bool
+		stream      bool
+		noStdin     bool
+		all         bool
+		dedupe      bool
+		concurrency int
+		expandLocal bool
+		output      string
+		endpoint    string
+		jsonEvents  bool
+		detach      bool
+		tty         bool
+		user        string
 	)
 
 	cmd := &cobra.Command{
-		Use:          "exec [container-id|name] [command] [args...]",
-		Short:        "Execute command in container",
-		Long:         "Execute a non-interactive command inside a container via the gRPC agent.",
-		Args:         cobra.MinimumNArgs(2),
+		Use:   "exec [container-id|name[,name...]] [command] [args...]",
+		Short: "Execute command in container",
+		Long: `Execute a non-interactive command inside a container via the gRPC agent.
+
+By default the arguments are joined and run with "sh -c". With --no-shell they
+are passed to the agent as argv exactly as given, which needs no shell in the
+image and keeps arguments containing spaces intact:
+
+  convoy exec web --no-shell -- grep -r "two words" /etc
+
+Containers may carry default environment variables as convoy.env.<KEY> labels;
+--env-prefix, --env-file and -e values take precedence over them, in that
+order. Env files hold dotenv-style KEY=value lines:
+
+  convoy exec web --env-file deploy.env -e MODE=debug -- ./migrate
+
+Variables in the command are normally expanded by the shell inside the
+container. --expand-local instead substitutes ${VAR} references with the -e,
+--env-file and --env-prefix values before the command is sent, so the remote
+side only sees the final text; bare $VAR and unknown names are left for the
+remote shell. Substituted values are quoted for the shell, so spaces or ;
+in them stay part of the value:
+
+  convoy exec web -e TAG=v2 --expand-local -- docker pull app:${TAG}
+
+Standard input redirected from a pipe or a file is forwarded to the command
+(disable with --no-stdin). It is sent with the request, so it is limited to
+3 MiB; copy larger inputs with 'convoy copy' instead:
+
+  cat dump.sql | convoy exec db --no-shell -- psql app
+
+With --all, or several comma-separated containers, the command runs on each of
+them concurrently (at most --concurrency at a time) and every output line is
+prefixed with the container name. --dedupe instead prints identical results once
+with the containers that produced them:
+
+  convoy exec web-1,web-2 -- uptime
+  convoy exec --all --dedupe cat /etc/app/version
+
+With -o json (or --json, as on the other commands) the output of a single
+container is streamed as JSON lines, one object per chunk tagged "stdout" or
+"stderr", ending with the exit status:
+
+  {"stream":"stdout","data":"building\n"}
+  {"stream":"stderr","data":"warning: ...\n"}
+  {"exit_code":0}
+
+--detach starts the command as a background job on the agent and prints its
+job ID straight away; --timeout still bounds how long the job may run. List
+jobs with "convoy jobs", read their output with "convoy logs --job" and stop
+them with "convoy cancel":
+
+  convoy exec web --detach --timeout 2h -- ./reindex.sh
+  convoy logs web --job <job-id>
+
+-t/--tty runs the command on a terminal allocated by the agent and relays the
+local terminal to it in raw mode, so full-screen and interactive programs such
+as top or vim work. It only takes effect when standard output is a terminal;
+piped or redirected output falls back to the normal buffered mode. On a
+terminal stdout and stderr arrive merged, and --timeout only bounds connecting:
+
+  convoy exec -t web -- top
+
+--user runs the command as another user, given as a name or uid with an
+optional :group or :gid, like docker exec. The agent must run as root to switch
+users:
+
+  convoy exec web --user postgres -- psql -c 'select 1'
+
+--endpoint dials an agent address directly instead of looking up a container,
+for custom networking or port forwards; the container argument is then omitted:
+
+  convoy exec --endpoint 127.0.0.1:16000 -- uptime`,
+		Args:         cobra.MinimumNArgs(1),
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			containerRef := args[0]
-			commandArgs := []string{"sh", "-c", strings.Join(args[1:], " ")}
+			if endpoint != "" && all {
+				return fmt.Errorf("--endpoint cannot be used with --all")
+			}
+			if !all && endpoint == "" && len(args) < 2 {
+				return fmt.Errorf("requires a container and a command (or --all and a command)")
+			}
+			if concurrency < 1 {
+				return fmt.Errorf("--concurrency must be at least 1")
+			}
+			if output != "text" && output != "json" {
+				return fmt.Errorf("unsupported output format %q (want text or json)", output)
+			}
+			if jsonEvents {
+				output = "json"
+			}
 
-			containers, err := LoadContainers()
+			containers, err := LoadContainersOrEndpoint(endpoint)
 			if err != nil {
 				return err
 			}
 
-			container, err := containers.ResolveWithEndpoint(containerRef)
+			var targets []*orchestrator.Container
+			if endpoint != "" {
+				targets = append(targets, containers.Resolve(endpoint))
+			} else if all {
+				for _, c := range containers.List() {
+					if c != nil && c.Endpoint != "" {
+						targets = append(targets, c)
+					}
+				}
+				if len(targets) == 0 {
+					return fmt.Errorf("no containers with a gRPC endpoint")
+				}
+			} else {
+				for _, ref := range strings.Split(args[0], ",") {
+					container, err := containers.ResolveWithEndpoint(strings.TrimSpace(ref))
+					if err != nil {
+						return err
+					}
+					targets = append(targets, container)
+				}
+				args = args[1:]
+			}
+			multi := all || len(targets) > 1
+			if multi && stream {
+				return fmt.Errorf("--stream cannot be used with several containers")
+			}
+			if multi && output == "json" {
+				return fmt.Errorf("--json and --output json cannot be used with several containers")
+			}
+			if output == "json" && stream {
+				return fmt.Errorf("--json and --output json cannot be used with --stream; they already stream")
+			}
+			if dedupe && !multi {
+				return fmt.Errorf("--dedupe requires --all or several containers")
+			}
+			if detach && (multi || stream || output == "json") {
+				return fmt.Errorf("--detach runs on a single container and cannot be used with --stream, --json or --output json")
+			}
+			if tty && (multi || stream || output == "json" || detach) {
+				return fmt.Errorf("--tty runs on a single container and cannot be used with --stream, --json, --output json or --detach")
+			}
+			fileEnv, err := ParseEnvFiles(envFiles)
 			if err != nil {
 				return err
 			}
+			env := environ.Merge(PrefixedEnvVars(os.Environ(), envPrefix, stripPrefix), fileEnv, ParseEnvVars(envVars))
+			if expandLocal {
+				args = ExpandLocal(args, env, !noShell)
+			}
+			commandArgs := shellCommand(args, noShell)
 
-			env := ParseEnvVars(envVars)
+			if tty && isTerminal(cmd.OutOrStdout()) {
+				container := targets[0]
+				start := &convoypb.ShellStart{
+					Args:    commandArgs,
+					Env:     environ.Merge(LabelEnv(container.Labels), env),
+					WorkDir: workDir,
+					Tty:     true,
+					User:    user,
+				}
+				start.Rows, start.Cols = terminalSize(cmd.OutOrStdout())
+				return execTTY(cmd, container.Endpoint, start, timeout)
+			}
+
+			var stdin []byte
+			if !noStdin {
+				if stdin, err = readPipedStdin(cmd.InOrStdin()); err != nil {
+					return fmt.Errorf("read stdin: %w", err)
+				}
+			}
 
 			req := &convoypb.CommandRequest{
 				Args:           commandArgs,
 				Env:            env,
 				WorkDir:        workDir,
 				TimeoutSeconds: int32(timeout.Seconds()),
+				Stdin:          stdin,
+				User:           user,
 			}
 
 			rpc := NewRPCClientWithTimeout(timeout)
@@ -53,6 +225,46 @@ func NewExecCmd() *cobra.Command {
 				_ = rpc.Close()
 			}()
 
+			if multi {
+				results := broadcastCommand(rpc.RPC, targets, req, concurrency)
+				if dedupe {
+					writeExecGroups(cmd.OutOrStdout(), groupExecResults(results))
+				} else {
+					for _, result := range results {
+						writePrefixedResult(cmd.OutOrStdout(), cmd.ErrOrStderr(), result)
+					}
+				}
+				for _, result := range results {
+					if result.exitCode != 0 {
+						cmd.SilenceErrors = true
+						return &ExitError{Code: 1}
+					}
+				}
+				return nil
+			}
+
+			container := targets[0]
+			req.Env = environ.Merge(LabelEnv(container.Labels), req.GetEnv())
+			if detach {
+				job, err := rpc.StartJob(context.Background(), container.Endpoint, req)
+				if err != nil {
+					return fmt.Errorf("start job: %w", err)
+				}
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), job.GetJobId())
+				ref := ContainerLabel(container)
+				if endpoint != "" {
+					ref = "--endpoint " + endpoint
+				}
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Started job on %s; read its output with: convoy logs %s --job %s\n", ContainerLabel(container), ref, job.GetJobId())
+				return nil
+			}
+			if output == "json" {
+				return streamCommandJSON(cmd, rpc.RPC, container.Endpoint, req)
+			}
+			if stream {
+				return streamCommand(cmd, rpc.RPC, container.Endpoint, req)
+			}
+
 			resp, err := rpc.ExecuteCommand(context.Background(), container.Endpoint, req)
 			if err != nil {
 				return fmt.Errorf("execute command: %w", err)
@@ -64,18 +276,317 @@ func NewExecCmd() *cobra.Command {
 			if stderr := resp.GetStderr(); stderr != "" {
 				_, _ = fmt.Fprint(cmd.ErrOrStderr(), stderr)
 			}
-
-			if resp.GetErrorMessage() != "" {
-				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", resp.GetErrorMessage())
+			if resp.GetTruncated() {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), truncatedNotice)
 			}
 
-			return nil
+			return remoteExit(cmd, resp.GetExitCode(), resp.GetErrorMessage())
 		},
 	}
 
 	cmd.Flags().StringArrayVarP(&envVars, "env", "e", nil, "Set environment variables (can be repeated)")
+	cmd.Flags().StringArrayVar(&envFiles, "env-file", nil, "Read environment variables from a dotenv file; -e overrides them (can be repeated)")
+	cmd.Flags().StringVar(&envPrefix, "env-prefix", "", "Forward host environment variables with this prefix")
+	cmd.Flags().BoolVar(&stripPrefix, "strip-prefix", false, "Strip --env-prefix from forwarded variable names")
 	cmd.Flags().StringVarP(&workDir, "workdir", "w", "", "Working directory inside the container")
+	cmd.Flags().StringVarP(&user, "user", "u", "", "Run the command as this user: name or uid, optionally with :group or :gid")
 	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Timeout for command execution")
+	cmd.Flags().BoolVar(&stream, "stream", false, "Print output as it is produced instead of after the command exits")
+	cmd.Flags().BoolVar(&noStdin, "no-stdin", false, "Do not forward piped standard input to the command")
+	cmd.Flags().BoolVarP(&all, "all", "a", false, "Run the command on every container")
+	cmd.Flags().BoolVar(&dedupe, "dedupe", false, "With several containers, print identical results once with the containers that produced them")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 8, "Maximum number of containers to run the command on at once")
+	cmd.Flags().BoolVar(&expandLocal, "expand-local", false, "Substitute ${VAR} in the command with -e/--env-file/--env-prefix values before sending it")
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json (JSON lines, streamed)")
+	cmd.Flags().BoolVar(&jsonEvents, "json", false, "Same as --output json")
+	cmd.Flags().BoolVar(&noShell, "no-shell", false, "Pass arguments directly as argv instead of wrapping them in sh -c")
+	cmd.Flags().BoolVar(&detach, "detach", false, "Start the command as a background job on the agent and print its job ID")
+	cmd.Flags().BoolVarP(&tty, "tty", "t", false, "Run the command on a terminal when stdout is one, for interactive programs")
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "Dial the agent at this host:port or unix:///socket instead of resolving a container (omit the container argument)")
 
 	return cmd
 }
+
+// shellCommand builds the argv sent to the agent: args verbatim when raw, otherwise joined under sh -c.
+func shellCommand(args []string, raw bool) []string {
+	if raw {
+		return append([]string(nil), args...)
+	}
+	return []string{"sh", "-c", strings.Join(args, " ")}
+}
+
+// execTTY runs start on a terminal on the agent, relaying the local terminal
+// in raw mode until the command exits. The session has no call timeout, as an
+// interactive program runs for as long as its user wants.
+func execTTY(cmd *cobra.Command, endpoint string, start *convoypb.ShellStart, dialTimeout time.Duration) error {
+	restore, err := rawTerminal(cmd.InOrStdin())
+	if err != nil {
+		return fmt.Errorf("set terminal to raw mode: %w", err)
+	}
+
+	rpc := NewRPCClient(dialTimeout, 0)
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	stdio := orchestrator.ShellIO{Stdin: cmd.InOrStdin(), Stdout: cmd.OutOrStdout(), Stderr: cmd.ErrOrStderr()}
+	exit, err := rpc.RunShell(context.Background(), endpoint, start, stdio, orchestrator.ShellOptions{})
+	restore()
+	if err != nil {
+		return fmt.Errorf("execute command: %w", err)
+	}
+	return remoteExit(cmd, exit.GetExitCode(), exit.GetMessage())
+}
+
+// truncatedNotice is printed when the agent cut output at its max_output_bytes limit.
+const truncatedNotice = "warning: output truncated by the agent (max_output_bytes); use --stream for the full output"
+
+// execResult is the outcome of a command on one container.
+type execResult struct {
+	label     string
+	exitCode  int32
+	stdout    string
+	stderr    string
+	message   string
+	truncated bool
+}
+
+// execGroup is a distinct result together with every container that produced it.
+type execGroup struct {
+	labels []string
+	result execResult
+}
+
+// broadcastCommand runs req on every target, at most concurrency at a time, and
+// returns results in target order.
+func broadcastCommand(rpc *orchestrator.RPC, targets []*orchestrator.Container, req *convoypb.CommandRequest, concurrency int) []execResult {
+	results := make([]execResult, len(targets))
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target *orchestrator.Container) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result := execResult{label: ContainerLabel(target)}
+			targetReq := proto.Clone(req).(*convoypb.CommandRequest)
+			targetReq.Env = environ.Merge(LabelEnv(target.Labels), req.GetEnv())
+			resp, err := rpc.ExecuteCommand(context.Background(), target.Endpoint, targetReq)
+			if err != nil {
+				result.exitCode = -1
+				result.message = err.Error()
+			} else {
+				result.exitCode = resp.GetExitCode()
+				result.stdout = resp.GetStdout()
+				result.stderr = resp.GetStderr()
+				result.truncated = resp.GetTruncated()
+				if result.exitCode <= 0 {
+					result.message = resp.GetErrorMessage()
+				}
+			}
+			results[i] = result
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// groupExecResults merges results with identical exit code and output, keeping first-seen order.
+func groupExecResults(results []execResult) []execGroup {
+	type key struct {
+		exitCode                int32
+		stdout, stderr, message string
+		truncated               bool
+	}
+
+	var groups []execGroup
+	index := make(map[key]int)
+	for _, result := range results {
+		k := key{result.exitCode, result.stdout, result.stderr, result.message, result.truncated}
+		if i, ok := index[k]; ok {
+			groups[i].labels = append(groups[i].labels, result.label)
+			continue
+		}
+		index[k] = len(groups)
+		groups = append(groups, execGroup{labels: []string{result.label}, result: result})
+	}
+	return groups
+}
+
+// writeExecGroups prints each group under a header naming its containers.
+func writeExecGroups(w io.Writer, groups []execGroup) {
+	for _, group := range groups {
+		header := strings.Join(group.labels, ", ")
+		if len(group.labels) > 1 {
+			header = fmt.Sprintf("%s (%d)", header, len(group.labels))
+		}
+		_, _ = fmt.Fprintf(w, "==> %s <==\n", header)
+
+		result := group.result
+		_, _ = fmt.Fprint(w, result.stdout)
+		_, _ = fmt.Fprint(w, result.stderr)
+		if result.truncated {
+			_, _ = fmt.Fprintln(w, truncatedNotice)
+		}
+		if result.message != "" {
+			_, _ = fmt.Fprintf(w, "error: %s\n", result.message)
+		}
+		if result.exitCode != 0 {
+			_, _ = fmt.Fprintf(w, "exit code %d\n", result.exitCode)
+		}
+	}
+}
+
+// writePrefixedResult prints a result with every line prefixed by its container label.
+func writePrefixedResult(stdout, stderr io.Writer, result execResult) {
+	prefix := "[" + result.label + "] "
+	writePrefixedLines(stdout, prefix, result.stdout)
+	writePrefixedLines(stderr, prefix, result.stderr)
+	if result.truncated {
+		_, _ = fmt.Fprintf(stderr, "%s%s\n", prefix, truncatedNotice)
+	}
+	if result.message != "" {
+		_, _ = fmt.Fprintf(stderr, "%serror: %s\n", prefix, result.message)
+	}
+	if result.exitCode != 0 {
+		_, _ = fmt.Fprintf(stderr, "%sexit code %d\n", prefix, result.exitCode)
+	}
+}
+
+func writePrefixedLines(w io.Writer, prefix, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		_, _ = fmt.Fprint(w, prefix+line)
+	}
+}
+
+// maxPipedStdin caps the standard input exec forwards. It travels inside the
+// CommandRequest, so it has to stay below gRPC's default 4 MiB message limit.
+const maxPipedStdin = 3 << 20
+
+// readPipedStdin returns in when it is redirected from a pipe or a regular
+// file. Terminals, sockets and devices such as /dev/null forward nothing, so
+// exec never blocks on input nobody meant to send. Input over maxPipedStdin
+// is an error rather than a request the agent would refuse.
+func readPipedStdin(in io.Reader) ([]byte, error) {
+	if f, ok := in.(*os.File); ok {
+		info, err := f.Stat()
+		if err != nil || (info.Mode()&os.ModeNamedPipe == 0 && !info.Mode().IsRegular()) {
+			return nil, nil
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(in, maxPipedStdin+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPipedStdin {
+		return nil, fmt.Errorf("piped input is larger than %d MiB; copy it with 'convoy copy' and read it from a file", maxPipedStdin>>20)
+	}
+	return data, nil
+}
+
+// streamCommand runs req via the streaming RPC, copying output to the command's writers as it arrives.
+func streamCommand(cmd *cobra.Command, rpc *orchestrator.RPC, endpoint string, req *convoypb.CommandRequest) error {
+	stream, err := rpc.ExecuteCommandStream(context.Background(), endpoint, req)
+	if err != nil {
+		return fmt.Errorf("execute command: %w", err)
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return fmt.Errorf("execute command: stream ended without exit status")
+		}
+		if err != nil {
+			return fmt.Errorf("execute command: %w", err)
+		}
+
+		if exit := resp.GetExit(); exit != nil {
+			return remoteExit(cmd, exit.GetExitCode(), exit.GetMessage())
+		}
+
+		output := resp.GetOutput()
+		if output == nil {
+			continue
+		}
+		w := cmd.OutOrStdout()
+		if output.GetStream() == convoypb.ShellOutput_STDERR {
+			w = cmd.ErrOrStderr()
+		}
+		_, _ = w.Write(output.GetData())
+	}
+}
+
+// execEvent is one JSON line written by exec -o json: an output chunk or the final exit status.
+type execEvent struct {
+	Stream   string `json:"stream,omitempty"`
+	Data     string `json:"data,omitempty"`
+	ExitCode *int32 `json:"exit_code,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// streamCommandJSON runs req via the streaming RPC, writing each chunk and the exit status as JSON lines.
+func streamCommandJSON(cmd *cobra.Command, rpc *orchestrator.RPC, endpoint string, req *convoypb.CommandRequest) error {
+	stream, err := rpc.ExecuteCommandStream(context.Background(), endpoint, req)
+	if err != nil {
+		return fmt.Errorf("execute command: %w", err)
+	}
+
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return fmt.Errorf("execute command: stream ended without exit status")
+		}
+		if err != nil {
+			return fmt.Errorf("execute command: %w", err)
+		}
+
+		if exit := resp.GetExit(); exit != nil {
+			code := exit.GetExitCode()
+			if err := enc.Encode(execEvent{ExitCode: &code, Message: exit.GetMessage()}); err != nil {
+				return err
+			}
+			// The message is already in the JSON output; only the exit status is left to report.
+			if code < 0 {
+				code = 1
+			}
+			return remoteExit(cmd, code, "")
+		}
+
+		if output := resp.GetOutput(); output != nil {
+			event := execEvent{Stream: "stdout", Data: string(output.GetData())}
+			if output.GetStream() == convoypb.ShellOutput_STDERR {
+				event.Stream = "stderr"
+			}
+			if err := enc.Encode(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// remoteExit turns a remote exit status into the CLI result. A command that could not
+// run at all (not found, timed out, ...) reports its message and exits with 1.
+func remoteExit(cmd *cobra.Command, exitCode int32, message string) error {
+	code := int(exitCode)
+	if code <= 0 && message != "" {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", message)
+		code = 1
+	}
+
+	if code != 0 {
+		cmd.SilenceErrors = true
+		return &ExitError{Code: code}
+	}
+	return nil
+}
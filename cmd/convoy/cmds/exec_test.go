@@ -0,0 +1,608 @@
+package cmds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+	"convoy/internal/orchestrator"
+)
+
+type execServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	resp      *convoypb.CommandResponse
+	echoStdin bool
+
+	mu    sync.Mutex
+	last  *convoypb.CommandRequest
+	shell *convoypb.ShellStart
+}
+
+func (s *execServer) ExecuteCommand(_ context.Context, req *convoypb.CommandRequest) (*convoypb.CommandResponse, error) {
+	s.mu.Lock()
+	s.last = req
+	s.mu.Unlock()
+	if s.echoStdin {
+		return &convoypb.CommandResponse{Stdout: string(req.GetStdin())}, nil
+	}
+	return s.resp, nil
+}
+
+func (s *execServer) ExecuteCommandStream(req *convoypb.CommandRequest, stream convoypb.ConvoyService_ExecuteCommandStreamServer) error {
+	s.mu.Lock()
+	s.last = req
+	s.mu.Unlock()
+	chunks := []*convoypb.ShellOutput{
+		{Stream: convoypb.ShellOutput_STDOUT, Data: []byte(s.resp.GetStdout())},
+		{Stream: convoypb.ShellOutput_STDERR, Data: []byte(s.resp.GetStderr())},
+	}
+	for _, chunk := range chunks {
+		if err := stream.Send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Output{Output: chunk}}); err != nil {
+			return err
+		}
+	}
+	return stream.Send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Exit{
+		Exit: &convoypb.ShellExit{ExitCode: s.resp.GetExitCode(), Message: s.resp.GetErrorMessage()},
+	}})
+}
+
+// ExecuteShell records the session's start and runs it as the buffered response would.
+func (s *execServer) ExecuteShell(stream convoypb.ConvoyService_ExecuteShellServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.shell = req.GetStart()
+	s.mu.Unlock()
+
+	responses := []*convoypb.ShellResponse{
+		{Payload: &convoypb.ShellResponse_Session{Session: &convoypb.ShellSession{SessionId: "s1"}}},
+		{Payload: &convoypb.ShellResponse_Output{Output: &convoypb.ShellOutput{Stream: convoypb.ShellOutput_STDOUT, Data: []byte(s.resp.GetStdout())}}},
+		{Payload: &convoypb.ShellResponse_Exit{Exit: &convoypb.ShellExit{ExitCode: s.resp.GetExitCode()}}},
+	}
+	for _, resp := range responses {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func useFakeExecAgent(t *testing.T, srv *execServer) {
+	t.Helper()
+	endpoint := startFakeAgent(t, srv)
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Endpoint: endpoint}}})
+}
+
+func TestExecCmd_PropagatesRemoteExitCode(t *testing.T) {
+	useFakeExecAgent(t, &execServer{resp: &convoypb.CommandResponse{Stderr: "boom\n", ExitCode: 3, ErrorMessage: "exit status 3"}})
+
+	var stdout, stderr bytes.Buffer
+	cmd := NewExecCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"web", "false"})
+
+	err := cmd.Execute()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	if stderr.String() != "boom\n" {
+		t.Fatalf("expected only remote stderr, got %q", stderr.String())
+	}
+}
+
+func TestExecCmd_SuccessReturnsNil(t *testing.T) {
+	useFakeExecAgent(t, &execServer{resp: &convoypb.CommandResponse{Stdout: "ok\n"}})
+
+	var stdout bytes.Buffer
+	cmd := NewExecCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"web", "true"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "ok\n" {
+		t.Fatalf("unexpected stdout %q", stdout.String())
+	}
+}
+
+func TestExecCmd_NotesTruncatedOutput(t *testing.T) {
+	useFakeExecAgent(t, &execServer{resp: &convoypb.CommandResponse{Stdout: "partial", Truncated: true}})
+
+	var stdout, stderr bytes.Buffer
+	cmd := NewExecCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"web", "yes"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if stdout.String() != "partial" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "output truncated") {
+		t.Fatalf("expected a truncation notice, got %q", stderr.String())
+	}
+}
+
+func TestExecCmd_EndpointBypassesContainerLookup(t *testing.T) {
+	direct := &execServer{resp: &convoypb.CommandResponse{Stdout: "from-direct\n"}}
+	endpoint := startFakeAgent(t, direct)
+
+	previous := GetAppFunc
+	GetAppFunc = func() (AppProvider, error) { return nil, errors.New("container lookup should be skipped") }
+	t.Cleanup(func() { GetAppFunc = previous })
+
+	var stdout bytes.Buffer
+	cmd := NewExecCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--endpoint", endpoint, "--", "uptime"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if stdout.String() != "from-direct\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	if got := direct.last.GetArgs(); len(got) != 3 || got[2] != "uptime" {
+		t.Fatalf("agent received args %q", got)
+	}
+}
+
+func TestExecCmd_ArgvModes(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "shell",
+			args: []string{"web", "echo", "two words"},
+			want: []string{"sh", "-c", "echo two words"},
+		},
+		{
+			name: "no-shell",
+			args: []string{"web", "--no-shell", "--", "grep", "-r", "two words", "/etc/a b"},
+			want: []string{"grep", "-r", "two words", "/etc/a b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &execServer{resp: &convoypb.CommandResponse{}}
+			useFakeExecAgent(t, srv)
+
+			cmd := NewExecCmd()
+			cmd.SetOut(io.Discard)
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("exec: %v", err)
+			}
+
+			if got := srv.last.GetArgs(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("argv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecCmd_StreamWritesOutputAndExitCode(t *testing.T) {
+	useFakeExecAgent(t, &execServer{resp: &convoypb.CommandResponse{Stdout: "out\n", Stderr: "err\n", ExitCode: 3, ErrorMessage: "exit status 3"}})
+
+	var stdout, stderr bytes.Buffer
+	cmd := NewExecCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"web", "--stream", "make"})
+
+	err := cmd.Execute()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Fatalf("unexpected output: stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+}
+
+func TestExecCmd_JSONOutputSeparatesStreams(t *testing.T) {
+	useFakeExecAgent(t, &execServer{resp: &convoypb.CommandResponse{Stdout: "out\n", Stderr: "err\n", ExitCode: 2, ErrorMessage: "exit status 2"}})
+
+	var stdout, stderr bytes.Buffer
+	cmd := NewExecCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"web", "-o", "json", "make"})
+
+	err := cmd.Execute()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Fatalf("expected exit code 2, got %v", err)
+	}
+
+	var events []execEvent
+	dec := json.NewDecoder(&stdout)
+	for dec.More() {
+		var event execEvent
+		if err := dec.Decode(&event); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+	if events[0].Stream != "stdout" || events[0].Data != "out\n" {
+		t.Fatalf("stdout event = %+v", events[0])
+	}
+	if events[1].Stream != "stderr" || events[1].Data != "err\n" {
+		t.Fatalf("stderr event = %+v", events[1])
+	}
+	if exit := events[2]; exit.ExitCode == nil || *exit.ExitCode != 2 || exit.Message != "exit status 2" {
+		t.Fatalf("exit event = %+v", exit)
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("json mode wrote to stderr: %q", stderr.String())
+	}
+}
+
+func TestExecCmd_ForwardsPipedStdin(t *testing.T) {
+	srv := &execServer{echoStdin: true}
+	useFakeExecAgent(t, srv)
+
+	var stdout bytes.Buffer
+	cmd := NewExecCmd()
+	cmd.SetIn(strings.NewReader("line 1\nline 2\n"))
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"web", "cat"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if stdout.String() != "line 1\nline 2\n" {
+		t.Fatalf("stdin not echoed, got %q", stdout.String())
+	}
+
+	cmd = NewExecCmd()
+	cmd.SetIn(strings.NewReader("ignored"))
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"web", "--no-stdin", "cat"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if len(srv.last.GetStdin()) != 0 {
+		t.Fatalf("--no-stdin still forwarded %q", srv.last.GetStdin())
+	}
+}
+
+func TestReadPipedStdin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input")
+	if err := os.WriteFile(path, []byte("from a file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if got, err := readPipedStdin(file); err != nil || string(got) != "from a file" {
+		t.Fatalf("regular file = %q, %v", got, err)
+	}
+
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	if got, err := readPipedStdin(devNull); err != nil || got != nil {
+		t.Fatalf("%s = %q, %v; want nothing forwarded", os.DevNull, got, err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	go func() {
+		_, _ = w.Write(make([]byte, maxPipedStdin+1))
+		_ = w.Close()
+	}()
+	if _, err := readPipedStdin(r); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("oversized pipe: %v", err)
+	}
+}
+
+func TestGroupExecResults(t *testing.T) {
+	results := []execResult{
+		{label: "a", stdout: "v1\n"},
+		{label: "b", stdout: "v2\n"},
+		{label: "c", stdout: "v1\n"},
+		{label: "d", stdout: "v1\n", exitCode: 1},
+	}
+
+	groups := groupExecResults(results)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %+v", groups)
+	}
+	if got := strings.Join(groups[0].labels, ","); got != "a,c" {
+		t.Fatalf("identical outputs not grouped: %s", got)
+	}
+	if got := strings.Join(groups[1].labels, ","); got != "b" {
+		t.Fatalf("divergent output grouped: %s", got)
+	}
+	if got := strings.Join(groups[2].labels, ","); got != "d" {
+		t.Fatalf("different exit code grouped: %s", got)
+	}
+}
+
+func TestExecCmd_AllDedupe(t *testing.T) {
+	same := &execServer{resp: &convoypb.CommandResponse{Stdout: "1.2.3\n"}}
+	other := &execServer{resp: &convoypb.CommandResponse{Stdout: "1.2.4\n"}}
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "web-1", Endpoint: startFakeAgent(t, same)},
+		{ID: "id-2", Name: "web-2", Endpoint: startFakeAgent(t, other)},
+		{ID: "id-3", Name: "web-3", Endpoint: startFakeAgent(t, same)},
+	}})
+
+	var stdout bytes.Buffer
+	cmd := NewExecCmd()
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"--all", "--dedupe", "cat", "/etc/version"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+
+	want := "==> web-1, web-3 (2) <==\n1.2.3\n==> web-2 <==\n1.2.4\n"
+	if stdout.String() != want {
+		t.Fatalf("unexpected output:\n%s", stdout.String())
+	}
+}
+
+func TestExecCmd_AppliesLabelEnvDefaults(t *testing.T) {
+	srv := &execServer{resp: &convoypb.CommandResponse{}}
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{
+		ID:       "id-1",
+		Name:     "web",
+		Endpoint: startFakeAgent(t, srv),
+		Labels: map[string]string{
+			orchestrator.EnvLabelPrefix + "LOG_LEVEL": "debug",
+			orchestrator.EnvLabelPrefix + "REGION":    "eu",
+			"team":                                    "infra",
+		},
+	}}})
+
+	cmd := NewExecCmd()
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"web", "-e", "LOG_LEVEL=warn", "env"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+
+	want := map[string]string{"LOG_LEVEL": "warn", "REGION": "eu"}
+	if got := srv.last.GetEnv(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("env = %v, want %v", got, want)
+	}
+}
+
+func TestExecCmd_MultipleRefsPrefixOutput(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "web-1", Endpoint: startFakeAgent(t, &execServer{resp: &convoypb.CommandResponse{Stdout: "up 3 days\nload 0.1\n"}})},
+		{ID: "id-2", Name: "web-2", Endpoint: startFakeAgent(t, &execServer{resp: &convoypb.CommandResponse{Stderr: "disk full\n", ExitCode: 2}})},
+		{ID: "id-3", Name: "web-3", Endpoint: startFakeAgent(t, &execServer{resp: &convoypb.CommandResponse{Stdout: "up 1 day"}})},
+	}})
+
+	var stdout, stderr bytes.Buffer
+	cmd := NewExecCmd()
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"web-1,web-2,web-3", "--concurrency", "2", "--", "uptime"})
+
+	var exitErr *ExitError
+	if err := cmd.Execute(); !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit code 1 when one container fails, got %v", err)
+	}
+
+	if want := "[web-1] up 3 days\n[web-1] load 0.1\n[web-3] up 1 day\n"; stdout.String() != want {
+		t.Fatalf("unexpected stdout:\n%s", stdout.String())
+	}
+	if want := "[web-2] disk full\n[web-2] exit code 2\n"; stderr.String() != want {
+		t.Fatalf("unexpected stderr:\n%s", stderr.String())
+	}
+}
+
+// slowExecServer records how many commands run at once across all instances sharing it.
+type slowExecServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	running, peak *atomic.Int32
+}
+
+func (s *slowExecServer) ExecuteCommand(context.Context, *convoypb.CommandRequest) (*convoypb.CommandResponse, error) {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	return &convoypb.CommandResponse{}, nil
+}
+
+func TestBroadcastCommand_BoundsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	var targets []*orchestrator.Container
+	for i := 0; i < 6; i++ {
+		srv := &slowExecServer{running: &running, peak: &peak}
+		targets = append(targets, &orchestrator.Container{ID: fmt.Sprint(i), Endpoint: startFakeAgent(t, srv)})
+	}
+
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	results := broadcastCommand(rpc, targets, &convoypb.CommandRequest{Args: []string{"true"}}, 2)
+	for _, result := range results {
+		if result.exitCode != 0 || result.message != "" {
+			t.Fatalf("unexpected failure: %+v", result)
+		}
+	}
+	if got := peak.Load(); got > 2 {
+		t.Fatalf("ran %d commands at once, want at most 2", got)
+	}
+}
+
+func TestExecCmd_ExpandLocal(t *testing.T) {
+	srv := &execServer{resp: &convoypb.CommandResponse{}}
+	useFakeExecAgent(t, srv)
+
+	for _, tt := range []struct {
+		flag bool
+		want string
+	}{
+		{flag: false, want: "echo ${TAG} $HOME ${PAIR}"},
+		{flag: true, want: "echo v2 $HOME 'a b'"},
+	} {
+		args := []string{"web", "-e", "TAG=v2", "-e", "PAIR=a b", "--", "echo", "${TAG}", "$HOME", "${PAIR}"}
+		if tt.flag {
+			args = append([]string{"--expand-local"}, args...)
+		}
+		cmd := NewExecCmd()
+		cmd.SetIn(strings.NewReader(""))
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("exec: %v", err)
+		}
+		if got := srv.last.GetArgs(); len(got) != 3 || got[2] != tt.want {
+			t.Fatalf("expand-local=%v: args = %q, want sh -c %q", tt.flag, got, tt.want)
+		}
+	}
+}
+
+func TestExecCmd_JSONFlagStreamsLikeOutputJSON(t *testing.T) {
+	useFakeExecAgent(t, &execServer{resp: &convoypb.CommandResponse{Stdout: "up 3 days\n"}})
+
+	var viaFlag, viaOutput bytes.Buffer
+	for args, out := range map[string]*bytes.Buffer{"--json": &viaFlag, "-o=json": &viaOutput} {
+		cmd := NewExecCmd()
+		cmd.SetIn(strings.NewReader(""))
+		cmd.SetOut(out)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs([]string{"web", args, "--", "uptime"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("exec %s: %v", args, err)
+		}
+	}
+	if viaFlag.String() == "" || viaFlag.String() != viaOutput.String() {
+		t.Fatalf("--json wrote %q, -o json wrote %q", viaFlag.String(), viaOutput.String())
+	}
+
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "web-1", Endpoint: "127.0.0.1:1"},
+		{ID: "id-2", Name: "web-2", Endpoint: "127.0.0.1:2"},
+	}})
+	_, _, err := runQuiet(t, NewExecCmd(), "web-1,web-2", "--json", "--", "uptime")
+	if err == nil || !strings.Contains(err.Error(), "several containers") {
+		t.Fatalf("--json on several containers = %v, want it rejected", err)
+	}
+}
+
+func TestExecCmd_TTYOnlyWhenStdoutIsATerminal(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		flag     bool
+		terminal bool
+		wantTTY  bool
+	}{
+		{name: "tty on a terminal", flag: true, terminal: true, wantTTY: true},
+		{name: "tty piped", flag: true, terminal: false},
+		{name: "no tty on a terminal", flag: false, terminal: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := &execServer{resp: &convoypb.CommandResponse{Stdout: "ok\n"}}
+			useFakeExecAgent(t, srv)
+			old := isTerminal
+			isTerminal = func(any) bool { return tc.terminal }
+			t.Cleanup(func() { isTerminal = old })
+
+			args := []string{"web", "--no-shell", "-e", "A=1", "--", "top"}
+			if tc.flag {
+				args = append([]string{"-t"}, args...)
+			}
+			cmd := NewExecCmd()
+			cmd.SetIn(strings.NewReader(""))
+			stdout, _, err := runQuiet(t, cmd, args...)
+			if err != nil {
+				t.Fatalf("exec: %v", err)
+			}
+			if stdout != "ok\n" {
+				t.Fatalf("stdout = %q", stdout)
+			}
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			if tc.wantTTY {
+				if srv.last != nil || srv.shell == nil {
+					t.Fatalf("want a terminal session only, got command %v and shell %v", srv.last, srv.shell)
+				}
+				if !srv.shell.GetTty() || !reflect.DeepEqual(srv.shell.GetArgs(), []string{"top"}) || srv.shell.GetEnv()["A"] != "1" {
+					t.Fatalf("shell start = %v, want top on a terminal with the env", srv.shell)
+				}
+				return
+			}
+			if srv.shell != nil || srv.last == nil {
+				t.Fatalf("want the buffered command only, got command %v and shell %v", srv.last, srv.shell)
+			}
+		})
+	}
+}
+
+func TestExecCmd_TTYRejectsMultiContainerModes(t *testing.T) {
+	useFakeExecAgent(t, &execServer{resp: &convoypb.CommandResponse{}})
+
+	for _, args := range [][]string{
+		{"-t", "--stream", "web", "top"},
+		{"-t", "--detach", "web", "top"},
+		{"-t", "--all", "top"},
+	} {
+		if _, _, err := runQuiet(t, NewExecCmd(), args...); err == nil || !strings.Contains(err.Error(), "--tty") {
+			t.Fatalf("exec %q: error = %v, want a --tty conflict", args, err)
+		}
+	}
+}
+
+func TestExecCmd_SendsUser(t *testing.T) {
+	srv := &execServer{resp: &convoypb.CommandResponse{}}
+	useFakeExecAgent(t, srv)
+
+	if _, _, err := runQuiet(t, NewExecCmd(), "web", "--user", "app:staff", "id"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if got := srv.last.GetUser(); got != "app:staff" {
+		t.Fatalf("user = %q, want app:staff", got)
+	}
+}
@@ -1,14 +1,20 @@
 package cmds
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 
-	convoypb "convoy/api"
 	"convoy/internal/orchestrator"
 
 	"github.com/spf13/cobra"
@@ -16,53 +22,122 @@ import (
 
 // NewHealthCmd creates the health command for checking container agent health.
 func NewHealthCmd() *cobra.Command {
-	var checkAll bool
-	var timeout time.Duration
+	var (
+		checkAll        bool
+		timeout         time.Duration
+		output          string
+		watch           bool
+		interval        time.Duration
+		exitOnUnhealthy bool
+		endpoint        string
+		verbose         bool
+	)
 
 	cmd := &cobra.Command{
-		Use:           "health [container-id|name]...",
-		Short:         "Check container agent health",
+		Use:   "health [container-id|name]...",
+		Short: "Check container agent health",
+		Long: `Check that container agents answer and report themselves healthy.
+
+The CLI and each agent also compare protocol versions. An agent whose major
+version differs from convoy's is reported unhealthy, since the two cannot
+work together; a differing minor version only adds a warning. --verbose shows
+the negotiated protocol version and the probe latency for every agent.
+
+For containers the runtime knows about, a HEALTHCHECK column shows the status
+of the container's own healthcheck, such as a Docker HEALTHCHECK: healthy,
+unhealthy, starting, or none when it has no healthcheck. It is informational
+and does not change the agent's status or the exit code.`,
 		Args:          cobra.ArbitraryArgs,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			containers, err := LoadContainers()
-			if err != nil {
-				return err
+			if output != "table" && output != "json" {
+				return fmt.Errorf("unsupported output format %q (want table or json)", output)
+			}
+			if watch && interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
 			}
 
-			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-			defer func() {
-				_ = writer.Flush()
-			}()
-			_, _ = fmt.Fprintln(writer, "NAME\tSTATUS")
+			// Results that need no probe, such as refs that did not resolve.
+			var fixed []orchestrator.HealthResult
+			var targets []healthTarget
+			// mgr looks up containers' own healthchecks; nil without containers.
+			var mgr *orchestrator.Manager
+			if endpoint != "" {
+				if checkAll || len(args) > 0 {
+					return errors.New("--endpoint cannot be combined with --all or container names")
+				}
+				if err := ValidateEndpoint(endpoint); err != nil {
+					return err
+				}
+				targets = []healthTarget{{Label: endpoint, Endpoint: endpoint}}
+			} else if checkAll {
+				containers, err := LoadContainers()
+				if err != nil {
+					return err
+				}
+				if len(containers.List()) == 0 {
+					fixed = append(fixed, orchestrator.HealthResult{Label: "all", Message: "no containers registered"})
+				} else {
+					targets = healthTargetsFor(containers.List())
+				}
+				mgr = healthManager()
+			} else {
+				if len(args) == 0 {
+					return errors.New("container id or name is required")
+				}
+				containers, err := LoadContainers()
+				if err != nil {
+					return err
+				}
 
-			if checkAll {
-				return runHealthChecks(writer, containers.List(), timeout)
+				var missing []string
+				targets, missing = resolveHealthTargets(args, containers)
+				if len(targets) == 0 && len(missing) == 0 {
+					return errors.New("no matching containers found")
+				}
+				for _, miss := range missing {
+					fixed = append(fixed, orchestrator.HealthResult{Label: miss, Message: "container not found"})
+				}
+				mgr = healthManager()
 			}
 
-			if len(args) == 0 {
-				return errors.New("container id or name is required")
-			}
+			// One client for every probe so watch mode reuses its connections.
+			rpc := NewRPCClientWithTimeout(timeout)
+			defer func() {
+				_ = rpc.Close()
+			}()
 
-			targets, missing := resolveHealthTargets(args, containers)
-			for _, miss := range missing {
-				_, _ = fmt.Fprintf(writer, "%s\tunhealthy: container not found\n", miss)
+			probe := func(ctx context.Context) []orchestrator.HealthResult {
+				results := checkHealthTargets(ctx, rpc.RPC, targets)
+				if mgr != nil {
+					addContainerHealth(mgr, targets, results)
+				}
+				return append(append([]orchestrator.HealthResult(nil), fixed...), results...)
 			}
-
-			failed := len(missing) > 0
-			if len(targets) == 0 {
-				if failed {
-					return errors.New("one or more containers unhealthy")
+			render := func(w io.Writer, results []orchestrator.HealthResult) error {
+				var summary *healthSummary
+				if checkAll {
+					s := summarizeHealth(results)
+					summary = &s
+				}
+				if output == "json" {
+					return writeHealthJSON(w, results, summary)
 				}
-				return errors.New("no matching containers found")
+				return writeHealthTable(w, results, summary, verbose)
 			}
 
-			if err := checkHealthTargets(writer, targets, timeout); err != nil {
-				failed = true
+			if watch {
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer stop()
+				return watchHealth(ctx, cmd.OutOrStdout(), probe, render, interval, exitOnUnhealthy)
 			}
 
-			if failed {
+			results := probe(context.Background())
+			if err := render(cmd.OutOrStdout(), results); err != nil {
+				return err
+			}
+			if !allHealthy(results) {
 				return errors.New("one or more containers unhealthy")
 			}
 			return nil
@@ -71,10 +146,57 @@ func NewHealthCmd() *cobra.Command {
 
 	cmd.Flags().BoolVarP(&checkAll, "all", "a", false, "Check all containers")
 	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "Timeout for health checks")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table or json")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Re-run the checks every --interval until interrupted")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "Time between checks in --watch mode")
+	cmd.Flags().BoolVar(&exitOnUnhealthy, "exit-on-unhealthy", false, "In --watch mode, exit non-zero as soon as any target is unhealthy")
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "Probe the agent at this host:port or unix:///socket instead of looking up containers")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Also show the negotiated protocol version and probe latency")
 
 	return cmd
 }
 
+// clearScreen moves the cursor home and clears the terminal before each watch frame.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// watchHealth redraws the probe results every interval until ctx is done.
+func watchHealth(ctx context.Context, w io.Writer, probe func(context.Context) []orchestrator.HealthResult, render func(io.Writer, []orchestrator.HealthResult) error, interval time.Duration, exitOnUnhealthy bool) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		results := probe(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		_, _ = fmt.Fprint(w, clearScreen)
+		_, _ = fmt.Fprintf(w, "Every %s: %s\n\n", interval, time.Now().Format(time.TimeOnly))
+		if err := render(w, results); err != nil {
+			return err
+		}
+
+		if exitOnUnhealthy && !allHealthy(results) {
+			return errors.New("one or more containers unhealthy")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func allHealthy(results []orchestrator.HealthResult) bool {
+	for _, result := range results {
+		if !result.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
 // healthTarget represents a container to check health on.
 type healthTarget struct {
 	Label     string
@@ -82,12 +204,34 @@ type healthTarget struct {
 	Container *orchestrator.Container
 }
 
-func runHealthChecks(writer io.Writer, containers []*orchestrator.Container, timeout time.Duration) error {
-	if len(containers) == 0 {
-		_, _ = fmt.Fprintln(writer, "all\tunhealthy: no containers registered")
-		return errors.New("no containers registered")
+// healthState classifies a probe result for display and summaries.
+type healthState string
+
+const (
+	healthHealthy     healthState = "healthy"
+	healthUnhealthy   healthState = "unhealthy"
+	healthUnreachable healthState = "unreachable"
+)
+
+func stateOf(result orchestrator.HealthResult) healthState {
+	switch {
+	case result.Healthy:
+		return healthHealthy
+	case result.Reachable:
+		return healthUnhealthy
+	default:
+		return healthUnreachable
 	}
+}
 
+// healthSummary counts results by state.
+type healthSummary struct {
+	Healthy     int `json:"healthy"`
+	Unhealthy   int `json:"unhealthy"`
+	Unreachable int `json:"unreachable"`
+}
+
+func healthTargetsFor(containers []*orchestrator.Container) []healthTarget {
 	targets := make([]healthTarget, 0, len(containers))
 	for _, container := range containers {
 		if container == nil {
@@ -99,17 +243,7 @@ func runHealthChecks(writer io.Writer, containers []*orchestrator.Container, tim
 			Container: container,
 		})
 	}
-
-	if len(targets) == 0 {
-		_, _ = fmt.Fprintln(writer, "all\tunhealthy: no containers registered")
-		return errors.New("no containers registered")
-	}
-
-	if err := checkHealthTargets(writer, targets, timeout); err != nil {
-		return errors.New("one or more containers unhealthy")
-	}
-
-	return nil
+	return targets
 }
 
 func resolveHealthTargets(args []string, idx *ContainerIndex) ([]healthTarget, []string) {
@@ -135,42 +269,169 @@ func resolveHealthTargets(args []string, idx *ContainerIndex) ([]healthTarget, [
 	return targets, missing
 }
 
-func checkHealthTargets(writer io.Writer, targets []healthTarget, timeout time.Duration) error {
-	rpc := NewRPCClientWithTimeout(timeout)
-	defer func() {
-		_ = rpc.Close()
-	}()
+// checkHealthTargets probes every target concurrently and returns results in target order.
+func checkHealthTargets(ctx context.Context, rpc *orchestrator.RPC, targets []healthTarget) []orchestrator.HealthResult {
+	results := make([]orchestrator.HealthResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target healthTarget) {
+			defer wg.Done()
+			results[i] = orchestrator.ProbeHealth(ctx, rpc, target.Label, target.Endpoint)
+		}(i, target)
+	}
+	wg.Wait()
 
-	failed := false
-	for _, target := range targets {
-		if target.Endpoint == "" {
-			_, _ = fmt.Fprintf(writer, "%s\tunhealthy: missing endpoint\n", target.Label)
-			failed = true
-			continue
-		}
+	return results
+}
+
+// healthManager returns the container manager for healthcheck lookups, or nil
+// when it is unavailable, in which case the HEALTHCHECK column is left out.
+func healthManager() *orchestrator.Manager {
+	app, err := getApp()
+	if err != nil {
+		return nil
+	}
+	mgr, err := app.Manager()
+	if err != nil {
+		return nil
+	}
+	return mgr
+}
 
-		resp, err := rpc.CheckHealth(context.Background(), target.Endpoint, &convoypb.HealthRequest{})
-		if err != nil {
-			_, _ = fmt.Fprintf(writer, "%s\tunhealthy: %v\n", target.Label, err)
-			failed = true
+// addContainerHealth fills in the healthcheck status of every target backed by
+// a container, concurrently. Lookups that fail leave the status unknown.
+func addContainerHealth(mgr *orchestrator.Manager, targets []healthTarget, results []orchestrator.HealthResult) {
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		if target.Container == nil || target.Container.ID == "" {
 			continue
 		}
-
-		if resp.GetStatus() != convoypb.HealthResponse_STATUS_HEALTHY {
-			msg := resp.GetMessage()
-			if msg == "" {
-				msg = resp.GetStatus().String()
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			if health, err := mgr.HealthStatus(id); err == nil {
+				results[i].ContainerHealth = health
 			}
-			_, _ = fmt.Fprintf(writer, "%s\tunhealthy: %s\n", target.Label, msg)
-			failed = true
-			continue
+		}(i, target.Container.ID)
+	}
+	wg.Wait()
+}
+
+func summarizeHealth(results []orchestrator.HealthResult) healthSummary {
+	var summary healthSummary
+	for _, result := range results {
+		switch stateOf(result) {
+		case healthHealthy:
+			summary.Healthy++
+		case healthUnhealthy:
+			summary.Unhealthy++
+		default:
+			summary.Unreachable++
 		}
+	}
+	return summary
+}
+
+func writeHealthTable(w io.Writer, results []orchestrator.HealthResult, summary *healthSummary, verbose bool) error {
+	// The HEALTHCHECK column only appears when some container's status is known.
+	withContainerHealth := slices.ContainsFunc(results, func(result orchestrator.HealthResult) bool {
+		return result.ContainerHealth != ""
+	})
 
-		_, _ = fmt.Fprintf(writer, "%s\thealthy\n", target.Label)
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"NAME"}
+	if verbose {
+		header = append(header, "PROTOCOL", "LATENCY")
+	}
+	if withContainerHealth {
+		header = append(header, "HEALTHCHECK")
+	}
+	_, _ = fmt.Fprintln(writer, strings.Join(append(header, "STATUS"), "\t"))
+	for _, result := range results {
+		row := []string{result.Label}
+		if verbose {
+			row = append(row, healthProtocol(result), healthLatency(result))
+		}
+		if withContainerHealth {
+			row = append(row, cmp.Or(string(result.ContainerHealth), "-"))
+		}
+		status := string(stateOf(result))
+		if message := cmp.Or(result.Message, result.Warning); message != "" {
+			status += ": " + message
+		}
+		_, _ = fmt.Fprintln(writer, strings.Join(append(row, status), "\t"))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
 	}
 
-	if failed {
-		return errors.New("one or more containers unhealthy")
+	if summary != nil {
+		_, _ = fmt.Fprintf(w, "\n%d healthy, %d unhealthy, %d unreachable\n", summary.Healthy, summary.Unhealthy, summary.Unreachable)
 	}
 	return nil
 }
+
+// healthProtocol describes the protocol version negotiated with an agent,
+// noting the agent's own when it differs.
+func healthProtocol(result orchestrator.HealthResult) string {
+	switch {
+	case !result.Reachable:
+		return "-"
+	case result.Protocol == "":
+		return "agent " + result.AgentProtocol
+	case result.AgentProtocol != "" && result.AgentProtocol != result.Protocol:
+		return fmt.Sprintf("%s (agent %s)", result.Protocol, result.AgentProtocol)
+	default:
+		return result.Protocol
+	}
+}
+
+func healthLatency(result orchestrator.HealthResult) string {
+	if !result.Reachable {
+		return "-"
+	}
+	return result.Latency.Round(time.Microsecond).String()
+}
+
+// healthJSON is the JSON rendering of a probe result.
+type healthJSON struct {
+	Name          string      `json:"name"`
+	Endpoint      string      `json:"endpoint,omitempty"`
+	Healthy       bool        `json:"healthy"`
+	Status        healthState `json:"status"`
+	Message       string      `json:"message,omitempty"`
+	Warning       string      `json:"warning,omitempty"`
+	Protocol      string      `json:"protocol_version,omitempty"`
+	AgentProtocol string      `json:"agent_protocol_version,omitempty"`
+	LatencyMS     float64     `json:"latency_ms"`
+	// ContainerHealth is the container's own healthcheck status, when known.
+	ContainerHealth orchestrator.ContainerHealth `json:"container_health,omitempty"`
+}
+
+func writeHealthJSON(w io.Writer, results []orchestrator.HealthResult, summary *healthSummary) error {
+	rows := make([]healthJSON, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, healthJSON{
+			Name:            result.Label,
+			Endpoint:        result.Endpoint,
+			Healthy:         result.Healthy,
+			Status:          stateOf(result),
+			Message:         result.Message,
+			Warning:         result.Warning,
+			Protocol:        result.Protocol,
+			AgentProtocol:   result.AgentProtocol,
+			LatencyMS:       float64(result.Latency) / float64(time.Millisecond),
+			ContainerHealth: result.ContainerHealth,
+		})
+	}
+
+	payload := struct {
+		Results []healthJSON   `json:"results"`
+		Summary *healthSummary `json:"summary,omitempty"`
+	}{Results: rows, Summary: summary}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(payload)
+}
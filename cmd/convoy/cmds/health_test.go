@@ -0,0 +1,294 @@
+package cmds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+	"convoy/internal/orchestrator"
+)
+
+type healthServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	status convoypb.HealthResponse_Status
+}
+
+func (s *healthServer) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	return &convoypb.HealthResponse{Status: s.status}, nil
+}
+
+func closedEndpoint(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+	return addr
+}
+
+func TestHealthSummaryCountsMixedTargets(t *testing.T) {
+	targets := []healthTarget{
+		{Label: "ok-1", Endpoint: startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_HEALTHY})},
+		{Label: "ok-2", Endpoint: startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_HEALTHY})},
+		{Label: "sick", Endpoint: startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_UNHEALTHY})},
+		{Label: "gone", Endpoint: closedEndpoint(t)},
+		{Label: "no-endpoint"},
+	}
+
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 500 * time.Millisecond, CallTimeout: 500 * time.Millisecond})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	results := checkHealthTargets(context.Background(), rpc, targets)
+	summary := summarizeHealth(results)
+	if summary != (healthSummary{Healthy: 2, Unhealthy: 1, Unreachable: 2}) {
+		t.Fatalf("unexpected summary: %+v (results %+v)", summary, results)
+	}
+
+	var table bytes.Buffer
+	if err := writeHealthTable(&table, results, &summary, false); err != nil {
+		t.Fatalf("write table: %v", err)
+	}
+	if !strings.HasSuffix(table.String(), "2 healthy, 1 unhealthy, 2 unreachable\n") {
+		t.Fatalf("missing summary line:\n%s", table.String())
+	}
+
+	var out bytes.Buffer
+	if err := writeHealthJSON(&out, results, &summary); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	var decoded struct {
+		Results []healthJSON  `json:"results"`
+		Summary healthSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	if decoded.Summary != summary || len(decoded.Results) != len(targets) {
+		t.Fatalf("unexpected json payload: %s", out.String())
+	}
+}
+
+// frameWriter cancels the watch once it has seen the given number of frames.
+type frameWriter struct {
+	bytes.Buffer
+	frames int
+	stopAt int
+	cancel context.CancelFunc
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	if string(p) == clearScreen {
+		w.frames++
+		if w.frames > w.stopAt {
+			w.cancel()
+		}
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestWatchHealth_RedrawsAndReusesConnections(t *testing.T) {
+	targets := []healthTarget{
+		{Label: "ok-1", Endpoint: startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_HEALTHY})},
+		{Label: "ok-2", Endpoint: startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_HEALTHY})},
+	}
+
+	var dials atomic.Int32
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{
+		DialTimeout: time.Second,
+		Dialer: func(ctx context.Context, endpoint string) (net.Conn, error) {
+			dials.Add(1)
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", endpoint)
+		},
+	})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := &frameWriter{stopAt: 3, cancel: cancel}
+
+	probe := func(ctx context.Context) []orchestrator.HealthResult {
+		return checkHealthTargets(ctx, rpc, targets)
+	}
+	render := func(w io.Writer, results []orchestrator.HealthResult) error {
+		return writeHealthTable(w, results, nil, false)
+	}
+
+	if err := watchHealth(ctx, out, probe, render, 10*time.Millisecond, false); err != nil {
+		t.Fatalf("watchHealth: %v", err)
+	}
+
+	if out.frames < 3 {
+		t.Fatalf("expected at least 3 frames, got %d", out.frames)
+	}
+	if got := strings.Count(out.String(), "ok-1  healthy"); got < 3 {
+		t.Fatalf("expected a table per frame, got %d:\n%s", got, out.String())
+	}
+	if got := dials.Load(); got != int32(len(targets)) {
+		t.Fatalf("expected one dial per target across iterations, got %d", got)
+	}
+}
+
+func TestWatchHealth_ExitOnUnhealthy(t *testing.T) {
+	sick := []healthTarget{{Label: "sick", Endpoint: startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_UNHEALTHY})}}
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	probe := func(ctx context.Context) []orchestrator.HealthResult {
+		return checkHealthTargets(ctx, rpc, sick)
+	}
+	render := func(w io.Writer, results []orchestrator.HealthResult) error {
+		return writeHealthTable(w, results, nil, false)
+	}
+
+	if err := watchHealth(context.Background(), io.Discard, probe, render, time.Hour, true); err == nil {
+		t.Fatalf("expected watch to stop with an error on an unhealthy target")
+	}
+}
+
+func TestHealthCmd_JSONOutput(t *testing.T) {
+	okEndpoint := startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_HEALTHY})
+	sickEndpoint := startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_UNHEALTHY})
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "ok", Endpoint: okEndpoint},
+		{ID: "id-2", Name: "sick", Endpoint: sickEndpoint},
+	}})
+
+	var out bytes.Buffer
+	cmd := NewHealthCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"ok", "sick", "--output", "json"})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected an error when a container is unhealthy")
+	}
+
+	var decoded struct {
+		Results []map[string]any `json:"results"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode json: %v\n%s", err, out.String())
+	}
+	if len(decoded.Results) != 2 {
+		t.Fatalf("expected 2 results, got %s", out.String())
+	}
+
+	want := []struct {
+		name, endpoint, status string
+		healthy                bool
+	}{
+		{name: "ok", endpoint: okEndpoint, status: "healthy", healthy: true},
+		{name: "sick", endpoint: sickEndpoint, status: "unhealthy", healthy: false},
+	}
+	for i, w := range want {
+		row := decoded.Results[i]
+		if row["name"] != w.name || row["endpoint"] != w.endpoint || row["status"] != w.status || row["healthy"] != w.healthy {
+			t.Fatalf("result %d = %v, want %+v", i, row, w)
+		}
+		if latency, ok := row["latency_ms"].(float64); !ok || latency <= 0 {
+			t.Fatalf("result %d: latency_ms not populated: %v", i, row["latency_ms"])
+		}
+	}
+	if _, ok := decoded.Results[1]["message"]; !ok {
+		t.Fatalf("expected a message for the unhealthy result: %v", decoded.Results[1])
+	}
+}
+
+// versionedHealthServer is a healthy agent reporting a fixed protocol version.
+type versionedHealthServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	version string
+}
+
+func (s *versionedHealthServer) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_HEALTHY, ProtocolVersion: s.version}, nil
+}
+
+func TestHealthCmd_VerboseShowsProtocol(t *testing.T) {
+	endpoint := startFakeAgent(t, &versionedHealthServer{version: convoypb.ProtocolVersion})
+
+	stdout, _, err := runQuiet(t, NewHealthCmd(), "--endpoint", endpoint, "--verbose")
+	if err != nil {
+		t.Fatalf("health: %v\n%s", err, stdout)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "PROTOCOL") {
+		t.Fatalf("unexpected table:\n%s", stdout)
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 4 || fields[1] != convoypb.ProtocolVersion || fields[3] != "healthy" {
+		t.Fatalf("row = %q, want the negotiated protocol %s", lines[1], convoypb.ProtocolVersion)
+	}
+}
+
+func TestHealthCmd_MajorProtocolSkewIsUnhealthy(t *testing.T) {
+	endpoint := startFakeAgent(t, &versionedHealthServer{version: "99.0"})
+
+	stdout, _, err := runQuiet(t, NewHealthCmd(), "--endpoint", endpoint)
+	if err == nil {
+		t.Fatalf("health succeeded against an incompatible agent:\n%s", stdout)
+	}
+	if !strings.Contains(stdout, "unhealthy: agent speaks protocol 99.0, incompatible") {
+		t.Fatalf("table does not explain the skew:\n%s", stdout)
+	}
+}
+
+func TestHealthCmd_ShowsContainerHealthcheck(t *testing.T) {
+	endpoint := startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_HEALTHY})
+	useFakeApp(t, &fakeRuntime{
+		containers: []*orchestrator.Container{
+			{ID: "id-1", Name: "web", Endpoint: endpoint},
+			{ID: "id-2", Name: "db", Endpoint: endpoint},
+			{ID: "id-3", Name: "cache", Endpoint: endpoint},
+			{ID: "id-4", Name: "plain", Endpoint: endpoint},
+		},
+		health: map[string]orchestrator.ContainerHealth{
+			"id-1": orchestrator.ContainerHealthUnhealthy,
+			"id-2": orchestrator.ContainerHealthStarting,
+			"id-3": orchestrator.ContainerHealthNone,
+		},
+	})
+
+	stdout, _, err := runQuiet(t, NewHealthCmd(), "web", "db", "cache", "plain")
+	if err != nil {
+		t.Fatalf("a failing healthcheck should not fail the agent check: %v\n%s", err, stdout)
+	}
+	want := [][]string{
+		{"NAME", "HEALTHCHECK", "STATUS"},
+		{"web", "unhealthy", "healthy"},
+		{"db", "starting", "healthy"},
+		{"cache", "none", "healthy"},
+		{"plain", "-", "healthy"},
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("unexpected table:\n%s", stdout)
+	}
+	for i, line := range lines {
+		if got := strings.Fields(line); strings.Join(got, " ") != strings.Join(want[i], " ") {
+			t.Fatalf("row %d = %q, want %q", i, got, want[i])
+		}
+	}
+
+	stdout, _, err = runQuiet(t, NewHealthCmd(), "web", "-o", "json")
+	if err != nil {
+		t.Fatalf("health -o json: %v", err)
+	}
+	if !strings.Contains(stdout, `"container_health": "unhealthy"`) {
+		t.Fatalf("json output lacks the healthcheck status:\n%s", stdout)
+	}
+}
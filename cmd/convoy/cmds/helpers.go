@@ -1,11 +1,21 @@
 package cmds
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"convoy/internal/environ"
 	"convoy/internal/orchestrator"
+
+	"github.com/spf13/cobra"
 )
 
 // ContainerIndex provides a fast lookup of containers by ID or name.
@@ -13,6 +23,8 @@ type ContainerIndex struct {
 	byID   map[string]*orchestrator.Container
 	byName map[string]*orchestrator.Container
 	list   []*orchestrator.Container
+	// direct, when set, is the agent address every reference resolves to.
+	direct string
 }
 
 // NewContainerIndex builds an index from a list of containers.
@@ -34,8 +46,17 @@ func NewContainerIndex(containers []*orchestrator.Container) *ContainerIndex {
 	return idx
 }
 
+// DirectIndex returns an index that resolves every reference to the agent at
+// endpoint, for --endpoint overrides that bypass container lookup.
+func DirectIndex(endpoint string) *ContainerIndex {
+	return &ContainerIndex{direct: endpoint}
+}
+
 // Resolve finds a container by name or ID. Returns nil if not found.
 func (idx *ContainerIndex) Resolve(ref string) *orchestrator.Container {
+	if idx.direct != "" {
+		return &orchestrator.Container{ID: ref, Name: ref, Endpoint: idx.direct}
+	}
 	if c := idx.byName[ref]; c != nil {
 		return c
 	}
@@ -59,7 +80,14 @@ func (idx *ContainerIndex) List() []*orchestrator.Container {
 	return idx.list
 }
 
+// warningOutput receives warnings from helpers that have no command at hand.
+// Tests replace it to capture them.
+var warningOutput io.Writer = os.Stderr
+
 // LoadContainers fetches the container list from the manager and returns an index.
+// When agent_registry_url is configured, agents from the registry are added
+// unless a managed container already uses their name. An unreachable registry
+// is only warned about, so commands still see the runtime's containers.
 func LoadContainers() (*ContainerIndex, error) {
 	app, err := getApp()
 	if err != nil {
@@ -76,20 +104,96 @@ func LoadContainers() (*ContainerIndex, error) {
 		return nil, err
 	}
 
+	cfg, err := app.Config()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AgentRegistry != "" {
+		agents, err := orchestrator.NewHTTPRegistrySource(cfg.AgentRegistry, nil).ListContainers(context.Background())
+		if err != nil {
+			_, _ = fmt.Fprintf(warningOutput, "Warning: agent registry unavailable, listing local containers only: %v\n", err)
+		} else {
+			containers = mergeRegistryAgents(containers, agents)
+		}
+	}
+
 	return NewContainerIndex(containers), nil
 }
 
+// mergeRegistryAgents appends the agents whose names no container has taken.
+func mergeRegistryAgents(containers, agents []*orchestrator.Container) []*orchestrator.Container {
+	taken := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		if c != nil {
+			taken[c.Name] = true
+			taken[c.ID] = true
+		}
+	}
+	for _, a := range agents {
+		if !taken[a.Name] {
+			containers = append(containers, a)
+		}
+	}
+	return containers
+}
+
+// LoadContainersOrEndpoint returns DirectIndex(endpoint) when an --endpoint
+// override is given, without asking the manager for containers, and
+// LoadContainers otherwise.
+func LoadContainersOrEndpoint(endpoint string) (*ContainerIndex, error) {
+	if endpoint == "" {
+		return LoadContainers()
+	}
+	if err := ValidateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return DirectIndex(endpoint), nil
+}
+
+// ValidateEndpoint checks that endpoint is a host:port agent address or the
+// unix:///path of an agent listening on a Unix domain socket.
+func ValidateEndpoint(endpoint string) error {
+	if path, ok := strings.CutPrefix(endpoint, orchestrator.UnixEndpointPrefix); ok {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("invalid endpoint %q: unix socket path must be absolute", endpoint)
+		}
+		return nil
+	}
+
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	if host == "" {
+		return fmt.Errorf("invalid endpoint %q: missing host", endpoint)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid endpoint %q: port must be between 1 and 65535", endpoint)
+	}
+	return nil
+}
+
 // RPCClient wraps an RPC instance with a cleanup function.
 type RPCClient struct {
 	*orchestrator.RPC
 }
 
+// Connection limits for the RPC clients commands create. Long-running commands
+// such as run and health --watch talk to containers that come and go, so idle
+// agent connections are closed rather than kept until the command exits.
+const (
+	rpcMaxConns    = 64
+	rpcIdleTimeout = 2 * time.Minute
+)
+
 // NewRPCClient creates an RPC client with the given timeout configuration.
 // The caller should defer Close() after use.
 func NewRPCClient(dialTimeout, callTimeout time.Duration) *RPCClient {
 	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{
 		DialTimeout: dialTimeout,
 		CallTimeout: callTimeout,
+		MaxConns:    rpcMaxConns,
+		IdleTimeout: rpcIdleTimeout,
 	})
 	return &RPCClient{RPC: rpc}
 }
@@ -110,6 +214,189 @@ func ParseEnvVars(envVars []string) map[string]string {
 	return env
 }
 
+// ParseEnvFiles reads the dotenv files at paths and merges them, later files
+// overriding earlier ones.
+func ParseEnvFiles(paths []string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read env file: %w", err)
+		}
+		fileEnv, err := ParseEnvFile(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		env = environ.Merge(env, fileEnv)
+	}
+	return env, nil
+}
+
+// ParseEnvFile parses dotenv content: KEY=value lines, optionally prefixed with
+// "export". Blank lines and # comments are skipped. Values may be wrapped in
+// single quotes, taken literally, or double quotes, which understand \n, \t,
+// \" and \\ escapes; unquoted values end at a " #" comment and are trimmed.
+func ParseEnvFile(content string) (map[string]string, error) {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		value, err := envFileValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		env[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// envFileValue unquotes one dotenv value.
+func envFileValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	switch quote := raw[0]; quote {
+	case '\'', '"':
+		end := -1
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			if c == quote {
+				end = i
+				break
+			}
+			if quote == '"' && c == '\\' && i+1 < len(raw) {
+				i++
+				switch raw[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case '"', '\\':
+					b.WriteByte(raw[i])
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(raw[i])
+				}
+				continue
+			}
+			b.WriteByte(c)
+		}
+		if end < 0 {
+			return "", fmt.Errorf("unterminated %c quote", quote)
+		}
+		if rest := strings.TrimSpace(raw[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected %q after quoted value", rest)
+		}
+		return b.String(), nil
+	default:
+		if idx := strings.Index(raw, " #"); idx >= 0 {
+			raw = raw[:idx]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
+
+// PrefixedEnvVars selects entries from environ (in os.Environ form) whose key starts with prefix.
+// When strip is true the prefix is removed from the forwarded key; keys that become empty are dropped.
+func PrefixedEnvVars(environ []string, prefix string, strip bool) map[string]string {
+	env := make(map[string]string)
+	if prefix == "" {
+		return env
+	}
+
+	for _, e := range environ {
+		idx := strings.Index(e, "=")
+		if idx <= 0 {
+			continue
+		}
+		key := e[:idx]
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if strip {
+			key = strings.TrimPrefix(key, prefix)
+			if key == "" {
+				continue
+			}
+		}
+		env[key] = e[idx+1:]
+	}
+	return env
+}
+
+// LabelEnv extracts default environment variables from container labels carrying
+// orchestrator.EnvLabelPrefix.
+func LabelEnv(labels map[string]string) map[string]string {
+	env := make(map[string]string)
+	for key, value := range labels {
+		if name := strings.TrimPrefix(key, orchestrator.EnvLabelPrefix); name != key && name != "" {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// localVarPattern matches the ${NAME} references ExpandLocal substitutes.
+var localVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandLocal replaces ${NAME} in each arg with env[NAME] before the command leaves
+// the client. Unknown names and bare $NAME references are left for the remote shell.
+// With quote, each value is shell-quoted so a command run under sh -c receives it
+// as one word rather than re-parsing its spaces or metacharacters.
+func ExpandLocal(args []string, env map[string]string, quote bool) []string {
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = localVarPattern.ReplaceAllStringFunc(arg, func(ref string) string {
+			value, ok := env[ref[2:len(ref)-1]]
+			if !ok {
+				return ref
+			}
+			if quote {
+				return shellQuote(value)
+			}
+			return value
+		})
+	}
+	return expanded
+}
+
+// shellUnsafe matches any character that needs quoting in a POSIX shell word.
+var shellUnsafe = regexp.MustCompile(`[^A-Za-z0-9_@%+=:,./-]`)
+
+// shellQuote returns s as a single POSIX shell word, leaving it bare when it
+// has nothing the shell would interpret.
+func shellQuote(s string) string {
+	if s != "" && !shellUnsafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ExitError carries a remote exit status that the CLI should exit with.
+// The remote process has already reported its own output, so main exits silently.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
 // ContainerLabel returns the best display label for a container (Name if available, otherwise ID).
 func ContainerLabel(c *orchestrator.Container) string {
 	if c == nil {
@@ -149,3 +436,51 @@ func (idx *ContainerIndex) AllContainerIDs() []string {
 	}
 	return ids
 }
+
+// reporter prints the outcome of commands acting on containers. With quiet set,
+// progress is dropped, failures go to stderr and only the IDs of affected
+// containers are printed, one per line, for scripts. With events set, every
+// report is written as that JSON event instead.
+type reporter struct {
+	cmd    *cobra.Command
+	quiet  bool
+	events *eventEmitter
+}
+
+// emit writes ev with --json only, for steps the human-readable output does not mention.
+func (r reporter) emit(ev event) {
+	if r.events != nil {
+		r.events.emit(ev)
+	}
+}
+
+// progress prints a human-readable status line unless quiet.
+func (r reporter) progress(ev event, format string, args ...any) {
+	switch {
+	case r.events != nil:
+		r.events.emit(ev)
+	case !r.quiet:
+		_, _ = fmt.Fprintf(r.cmd.OutOrStdout(), format+"\n", args...)
+	}
+}
+
+// failure reports a problem with one container on stderr, quiet or not.
+func (r reporter) failure(ev event, format string, args ...any) {
+	if r.events != nil {
+		r.events.emit(ev)
+		return
+	}
+	_, _ = fmt.Fprintf(r.cmd.ErrOrStderr(), format+"\n", args...)
+}
+
+// done reports that container ev.ID was affected: its ID when quiet, the formatted line otherwise.
+func (r reporter) done(ev event, format string, args ...any) {
+	switch {
+	case r.events != nil:
+		r.events.emit(ev)
+	case r.quiet:
+		_, _ = fmt.Fprintln(r.cmd.OutOrStdout(), ev.ID)
+	default:
+		_, _ = fmt.Fprintf(r.cmd.OutOrStdout(), format+"\n", args...)
+	}
+}
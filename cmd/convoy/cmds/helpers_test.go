@@ -0,0 +1,207 @@
+package cmds
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"convoy/internal/orchestrator"
+)
+
+func TestPrefixedEnvVars_OnlyForwardsPrefixed(t *testing.T) {
+	environ := []string{
+		"CONVOY_TOKEN=secret",
+		"CONVOY_REGION=eu",
+		"HOME=/root",
+		"PATH=/usr/bin",
+		"NOT_CONVOY_X=1",
+		"CONVOY_=empty",
+	}
+
+	kept := PrefixedEnvVars(environ, "CONVOY_", false)
+	if len(kept) != 3 {
+		t.Fatalf("expected 3 forwarded vars, got %v", kept)
+	}
+	if kept["CONVOY_TOKEN"] != "secret" || kept["CONVOY_REGION"] != "eu" {
+		t.Fatalf("unexpected forwarded vars: %v", kept)
+	}
+	if _, ok := kept["HOME"]; ok {
+		t.Fatalf("unprefixed var forwarded: %v", kept)
+	}
+
+	stripped := PrefixedEnvVars(environ, "CONVOY_", true)
+	if len(stripped) != 2 || stripped["TOKEN"] != "secret" || stripped["REGION"] != "eu" {
+		t.Fatalf("unexpected stripped vars: %v", stripped)
+	}
+
+	if none := PrefixedEnvVars(environ, "", false); len(none) != 0 {
+		t.Fatalf("empty prefix should forward nothing, got %v", none)
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	content := `# deploy settings
+
+export REGION=eu-west-1
+PLAIN = value with spaces   # trailing comment
+HASH=abc#123
+EMPTY=
+SINGLE='literal $HOME \n # kept'
+DOUBLE="line one\nline \"two\""
+QUOTED_COMMENT="x" # comment
+`
+	env, err := ParseEnvFile(content)
+	if err != nil {
+		t.Fatalf("ParseEnvFile: %v", err)
+	}
+	want := map[string]string{
+		"REGION":         "eu-west-1",
+		"PLAIN":          "value with spaces",
+		"HASH":           "abc#123",
+		"EMPTY":          "",
+		"SINGLE":         `literal $HOME \n # kept`,
+		"DOUBLE":         "line one\nline \"two\"",
+		"QUOTED_COMMENT": "x",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Fatalf("env = %q, want %q", env, want)
+	}
+}
+
+func TestParseEnvFile_Errors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "A=1\nnot a pair", want: "line 2: expected KEY=value"},
+		{content: "=value", want: "line 1: expected KEY=value"},
+		{content: `A="open`, want: "unterminated \" quote"},
+		{content: `A='x' y`, want: "after quoted value"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseEnvFile(tt.content); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("ParseEnvFile(%q) error = %v, want %q", tt.content, err, tt.want)
+		}
+	}
+}
+
+func TestParseEnvFiles_LaterFilesWin(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.env")
+	local := filepath.Join(dir, "local.env")
+	if err := os.WriteFile(base, []byte("A=1\nB=2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte("B=3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := ParseEnvFiles([]string{base, local})
+	if err != nil {
+		t.Fatalf("ParseEnvFiles: %v", err)
+	}
+	if !reflect.DeepEqual(env, map[string]string{"A": "1", "B": "3"}) {
+		t.Fatalf("unexpected env: %v", env)
+	}
+
+	if _, err := ParseEnvFiles([]string{filepath.Join(dir, "missing.env")}); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+}
+
+func TestExpandLocal(t *testing.T) {
+	env := map[string]string{"TAG": "v2", "DIR": "/srv/app"}
+	got := ExpandLocal([]string{"pull app:${TAG}", "${DIR}/bin", "$TAG", "${MISSING}"}, env, true)
+	want := []string{"pull app:v2", "/srv/app/bin", "$TAG", "${MISSING}"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("arg %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	env = map[string]string{"MSG": "hi; rm -rf /", "NAME": "it's", "EMPTY": ""}
+	args := []string{"echo ${MSG}", "${NAME}", "x${EMPTY}y"}
+	if got, want := ExpandLocal(args, env, true), []string{`echo 'hi; rm -rf /'`, `'it'\''s'`, "x''y"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("quoted = %q, want %q", got, want)
+	}
+	if got, want := ExpandLocal(args, env, false), []string{"echo hi; rm -rf /", "it's", "xy"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unquoted = %q, want %q", got, want)
+	}
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		ok       bool
+	}{
+		{endpoint: "10.0.0.5:6000", ok: true},
+		{endpoint: "agent.internal:6000", ok: true},
+		{endpoint: "[::1]:6000", ok: true},
+		{endpoint: "10.0.0.5", ok: false},
+		{endpoint: ":6000", ok: false},
+		{endpoint: "10.0.0.5:http", ok: false},
+		{endpoint: "10.0.0.5:70000", ok: false},
+		{endpoint: "unix:///run/convoy/agent.sock", ok: true},
+		{endpoint: "unix://agent.sock", ok: false},
+	}
+	for _, tt := range tests {
+		if err := ValidateEndpoint(tt.endpoint); (err == nil) != tt.ok {
+			t.Errorf("ValidateEndpoint(%q) = %v, want ok=%v", tt.endpoint, err, tt.ok)
+		}
+	}
+}
+
+func TestLoadContainers_AddsRegistryAgents(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"agent_id": "remote", "endpoint": "203.0.113.9:6000", "health": "healthy"},
+			{"agent_id": "web", "endpoint": "203.0.113.10:6000", "health": "healthy"}
+		]`))
+	}))
+	defer registry.Close()
+
+	fake := useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Endpoint: "127.0.0.1:16000"}}})
+	fake.cfg.AgentRegistry = registry.URL
+
+	idx, err := LoadContainers()
+	if err != nil {
+		t.Fatalf("LoadContainers: %v", err)
+	}
+	if len(idx.List()) != 2 {
+		t.Fatalf("listed %d containers, want 2", len(idx.List()))
+	}
+	if c := idx.Resolve("remote"); c == nil || c.Endpoint != "203.0.113.9:6000" {
+		t.Fatalf("registry agent not resolvable: %+v", c)
+	}
+	if c := idx.Resolve("web"); c == nil || c.Endpoint != "127.0.0.1:16000" {
+		t.Fatalf("managed container should win over a registry agent with its name: %+v", c)
+	}
+}
+
+func TestLoadContainers_UnreachableRegistryOnlyWarns(t *testing.T) {
+	registry := httptest.NewServer(http.NotFoundHandler())
+	registry.Close()
+
+	fake := useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web"}}})
+	fake.cfg.AgentRegistry = registry.URL
+	var warnings bytes.Buffer
+	old := warningOutput
+	warningOutput = &warnings
+	t.Cleanup(func() { warningOutput = old })
+
+	idx, err := LoadContainers()
+	if err != nil {
+		t.Fatalf("LoadContainers: %v", err)
+	}
+	if len(idx.List()) != 1 || idx.Resolve("web") == nil {
+		t.Fatalf("listed %v, want the runtime's container", idx.List())
+	}
+	if !strings.Contains(warnings.String(), "agent registry unavailable") {
+		t.Fatalf("warnings = %q, want the registry failure reported", warnings.String())
+	}
+}
@@ -0,0 +1,99 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	convoypb "convoy/api"
+)
+
+// NewJobsCmd creates the jobs command for listing background jobs on an agent.
+func NewJobsCmd() *cobra.Command {
+	var (
+		endpoint string
+		timeout  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "jobs [container-id|name]",
+		Short: "List background jobs",
+		Long: `List the background jobs started with "convoy exec --detach" on a container's
+agent. Finished jobs stay listed for the agent's job_retention_sec.
+
+  convoy jobs web`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, err := jobEndpoint(args, endpoint)
+			if err != nil {
+				return err
+			}
+
+			rpc := NewRPCClientWithTimeout(timeout)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			jobs, err := rpc.ListJobs(context.Background(), target)
+			if err != nil {
+				return fmt.Errorf("list jobs: %w", err)
+			}
+			if len(jobs) == 0 {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No jobs")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintf(w, "JOB\tSTATE\tEXIT\tSTARTED\tCOMMAND\n")
+			for _, job := range jobs {
+				exit := "-"
+				if job.GetState() != convoypb.JobStatus_RUNNING {
+					exit = fmt.Sprint(job.GetExitCode())
+				}
+				started := time.Unix(job.GetStartedUnix(), 0).Format(time.DateTime)
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", job.GetJobId(), jobState(job), exit, started, strings.Join(job.GetArgs(), " "))
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "Dial the agent at this host:port or unix:///socket instead of resolving a container (omit the container argument)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for reaching the agent")
+
+	return cmd
+}
+
+// jobEndpoint returns the agent address for the job commands: --endpoint, or
+// the endpoint of the single container named in args.
+func jobEndpoint(args []string, endpoint string) (string, error) {
+	if endpoint != "" {
+		if len(args) > 0 {
+			return "", errors.New("--endpoint cannot be combined with a container")
+		}
+		return endpoint, ValidateEndpoint(endpoint)
+	}
+	if len(args) == 0 {
+		return "", errors.New("container id or name is required")
+	}
+
+	containers, err := LoadContainers()
+	if err != nil {
+		return "", err
+	}
+	container, err := containers.ResolveWithEndpoint(args[0])
+	if err != nil {
+		return "", err
+	}
+	return container.Endpoint, nil
+}
+
+// jobState names a job's state for display: running, exited or failed.
+func jobState(job *convoypb.JobStatus) string {
+	return strings.ToLower(job.GetState().String())
+}
@@ -0,0 +1,95 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"convoy/internal/agent"
+	"convoy/internal/orchestrator"
+)
+
+func TestDetachedJob_PollWhileRunningThenLogs(t *testing.T) {
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Endpoint: endpoint}}})
+	dir := t.TempDir()
+
+	stdout, stderr, err := runQuiet(t, NewExecCmd(), "web", "--detach", "--no-stdin", "-w", dir, "--",
+		"echo started; while [ ! -e release ]; do sleep 0.01; done; echo finished >&2; exit 3")
+	if err != nil {
+		t.Fatalf("exec --detach: %v (stderr %q)", err, stderr)
+	}
+	jobID := strings.TrimSpace(stdout)
+	if len(jobID) != 32 || !strings.Contains(stderr, "convoy logs web --job "+jobID) {
+		t.Fatalf("stdout = %q, stderr = %q; want the job ID and how to read its output", stdout, stderr)
+	}
+
+	listed, _, err := runQuiet(t, NewJobsCmd(), "web")
+	if err != nil {
+		t.Fatalf("jobs: %v", err)
+	}
+	if !strings.Contains(listed, jobID) || !strings.Contains(listed, "running") {
+		t.Fatalf("jobs output %q does not show the running job", listed)
+	}
+
+	// logs prints output captured so far and no state while the job runs.
+	waitForLogs(t, jobID, func(out, errOut string) bool {
+		if out == "started\n" && errOut != "" {
+			t.Fatalf("unexpected state for a running job: %q", errOut)
+		}
+		return out == "started\n"
+	})
+
+	if err := os.WriteFile(filepath.Join(dir, "release"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForLogs(t, jobID, func(out, errOut string) bool {
+		return out == "started\nfinished\n" && errOut == "Job "+jobID+" exited with code 3\n"
+	})
+
+	listed, _, err = runQuiet(t, NewJobsCmd(), "web")
+	if err != nil {
+		t.Fatalf("jobs: %v", err)
+	}
+	if !strings.Contains(listed, "exited") || !strings.Contains(listed, "  3  ") {
+		t.Fatalf("jobs output %q does not show the exit code", listed)
+	}
+}
+
+// waitForLogs runs convoy logs for jobID until done accepts its output.
+func waitForLogs(t *testing.T, jobID string, done func(stdout, stderr string) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stdout, stderr, err := runQuiet(t, NewLogsCmd(), "web", "--job", jobID)
+		if err != nil {
+			t.Fatalf("logs: %v", err)
+		}
+		if done(stdout, stderr) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("logs never matched, last stdout %q stderr %q", stdout, stderr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobCmds_Errors(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "web", Endpoint: "127.0.0.1:1"},
+		{ID: "id-2", Name: "db", Endpoint: "127.0.0.1:2"},
+	}})
+
+	if _, _, err := runQuiet(t, NewLogsCmd(), "web"); err == nil || !strings.Contains(err.Error(), "--job is required") {
+		t.Fatalf("logs without --job: %v", err)
+	}
+	if _, _, err := runQuiet(t, NewJobsCmd()); err == nil || !strings.Contains(err.Error(), "container id or name is required") {
+		t.Fatalf("jobs without a container: %v", err)
+	}
+	if _, _, err := runQuiet(t, NewExecCmd(), "web,db", "--detach", "--", "true"); err == nil || !strings.Contains(err.Error(), "single container") {
+		t.Fatalf("exec --detach on several containers: %v", err)
+	}
+}
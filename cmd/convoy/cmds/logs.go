@@ -0,0 +1,74 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	convoypb "convoy/api"
+)
+
+// NewLogsCmd creates the logs command for reading a background job's output.
+func NewLogsCmd() *cobra.Command {
+	var (
+		jobID    string
+		endpoint string
+		timeout  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs [container-id|name] --job JOB",
+		Short: "Show a background job's output",
+		Long: `Print the output a background job started with "convoy exec --detach" has
+produced so far, stdout and stderr interleaved as the agent captured them. The
+job's state goes to stderr once it has finished.
+
+  convoy logs web --job 5d41402abc4b2a76b9719d911017c592`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jobID = strings.TrimSpace(jobID)
+			if jobID == "" {
+				return errors.New("--job is required")
+			}
+			target, err := jobEndpoint(args, endpoint)
+			if err != nil {
+				return err
+			}
+
+			rpc := NewRPCClientWithTimeout(timeout)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			job, err := rpc.GetJob(context.Background(), target, jobID, true)
+			if err != nil {
+				return fmt.Errorf("get job: %w", err)
+			}
+
+			_, _ = cmd.OutOrStdout().Write(job.GetOutput())
+			if job.GetTruncated() {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "warning: output truncated by the agent (max_output_bytes)")
+			}
+			switch job.GetState() {
+			case convoypb.JobStatus_EXITED:
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Job %s exited with code %d\n", job.GetJobId(), job.GetExitCode())
+			case convoypb.JobStatus_FAILED:
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Job %s failed: %s\n", job.GetJobId(), job.GetMessage())
+			case convoypb.JobStatus_CANCELED:
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Job %s was canceled\n", job.GetJobId())
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&jobID, "job", "", "ID of the job, as printed by exec --detach (required)")
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "Dial the agent at this host:port or unix:///socket instead of resolving a container (omit the container argument)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for reaching the agent")
+
+	return cmd
+}
@@ -0,0 +1,65 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+)
+
+// nameAttempts bounds how many candidates generate tries before giving up.
+const nameAttempts = 100
+
+var nameAdjectives = []string{
+	"amber", "bold", "brave", "bright", "calm", "clever", "cosmic", "crisp",
+	"eager", "fancy", "gentle", "golden", "happy", "hidden", "humble", "jolly",
+	"keen", "lively", "lucky", "mellow", "misty", "nimble", "noble", "quiet",
+	"rapid", "rustic", "shiny", "silent", "snowy", "steady", "sunny", "swift",
+	"tidy", "vivid", "wild", "witty",
+}
+
+var nameNouns = []string{
+	"badger", "beacon", "canyon", "comet", "falcon", "fern", "fjord", "glacier",
+	"harbor", "heron", "island", "lagoon", "lantern", "maple", "meadow", "meteor",
+	"otter", "panda", "pebble", "pine", "prairie", "raven", "reef", "river",
+	"robin", "sparrow", "summit", "thistle", "tiger", "tundra", "valley", "walrus",
+	"willow", "wombat", "yak", "zephyr",
+}
+
+// nameGenerator makes readable container names like brave-otter-042.
+type nameGenerator struct {
+	rng *rand.Rand
+}
+
+// newNameGenerator returns a generator seeded from seed, so tests get a
+// repeatable sequence.
+func newNameGenerator(seed uint64) *nameGenerator {
+	return &nameGenerator{rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// defaultNameGenerator returns the generator used by commands; tests replace
+// it to pin the names they expect.
+var defaultNameGenerator = func() *nameGenerator {
+	return newNameGenerator(rand.Uint64())
+}
+
+// generate returns a name for which taken reports false.
+func (g *nameGenerator) generate(taken func(string) bool) (string, error) {
+	for range nameAttempts {
+		name := fmt.Sprintf("%s-%s-%03d",
+			nameAdjectives[g.rng.IntN(len(nameAdjectives))],
+			nameNouns[g.rng.IntN(len(nameNouns))],
+			g.rng.IntN(1000))
+		if taken == nil || !taken(name) {
+			return name, nil
+		}
+	}
+	return "", errors.New("could not generate an unused container name; pass one explicitly")
+}
+
+// unusedContainerName picks a name that no container in containers uses as
+// its name or ID.
+func unusedContainerName(containers *ContainerIndex) (string, error) {
+	return defaultNameGenerator().generate(func(name string) bool {
+		return containers.Resolve(name) != nil
+	})
+}
@@ -0,0 +1,62 @@
+package cmds
+
+import (
+	"regexp"
+	"testing"
+)
+
+var generatedName = regexp.MustCompile(`^[a-z]+-[a-z]+-[0-9]{3}$`)
+
+// useNameSeed makes commands generate names from a generator seeded with seed.
+func useNameSeed(t *testing.T, seed uint64) {
+	t.Helper()
+	previous := defaultNameGenerator
+	defaultNameGenerator = func() *nameGenerator { return newNameGenerator(seed) }
+	t.Cleanup(func() { defaultNameGenerator = previous })
+}
+
+func TestNameGenerator_UniqueAcrossGenerations(t *testing.T) {
+	gen := newNameGenerator(7)
+	seen := make(map[string]bool)
+	for i := 0; i < 5000; i++ {
+		name, err := gen.generate(func(name string) bool { return seen[name] })
+		if err != nil {
+			t.Fatalf("generation %d: %v", i, err)
+		}
+		if !generatedName.MatchString(name) {
+			t.Fatalf("name %q is not adjective-noun-NNN", name)
+		}
+		if seen[name] {
+			t.Fatalf("name %q generated twice", name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestNameGenerator_AvoidsExistingNames(t *testing.T) {
+	existing := make(map[string]bool)
+	first := newNameGenerator(42)
+	for i := 0; i < 20; i++ {
+		name, err := first.generate(nil)
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		existing[name] = true
+	}
+
+	// The same seed replays the names above, so every one of them collides.
+	name, err := newNameGenerator(42).generate(func(name string) bool { return existing[name] })
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if existing[name] {
+		t.Fatalf("generated %q, which is already taken", name)
+	}
+}
+
+func TestNameGenerator_GivesUpWhenEverythingIsTaken(t *testing.T) {
+	_, err := newNameGenerator(1).generate(func(string) bool { return true })
+	if err == nil {
+		t.Fatalf("expected an error when no name is free")
+	}
+}
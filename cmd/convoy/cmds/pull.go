@@ -0,0 +1,50 @@
+package cmds
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"convoy/internal/orchestrator"
+)
+
+// showPullProgress has mgr draw the progress of image pulls on cmd's stderr,
+// unless quiet is set or stderr is not a terminal.
+func showPullProgress(cmd *cobra.Command, mgr *orchestrator.Manager, quiet bool) {
+	if quiet || !isTerminal(cmd.ErrOrStderr()) {
+		return
+	}
+	mgr.SetPullProgress((&pullPrinter{w: cmd.ErrOrStderr()}).update)
+}
+
+// pullPrinter draws pull progress as a single status line rewritten in place.
+// Concurrent pulls take turns on the line.
+type pullPrinter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (p *pullPrinter) update(progress orchestrator.PullProgress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = fmt.Fprintf(p.w, "\r\x1b[K%s", pullStatus(progress))
+	if progress.Done {
+		_, _ = fmt.Fprintln(p.w)
+	}
+}
+
+// pullStatus summarizes a pull, e.g. "Pulling alpine: 2/3 layers, 1.2MB of 3.4MB".
+func pullStatus(progress orchestrator.PullProgress) string {
+	status := fmt.Sprintf("Pulling %s: %d/%d layers", progress.Image, progress.CompleteLayers(), len(progress.Layers))
+	current, total := progress.Bytes()
+	switch {
+	case total > 0:
+		status += fmt.Sprintf(", %s of %s", units.HumanSize(float64(current)), units.HumanSize(float64(total)))
+	case current > 0:
+		status += ", " + units.HumanSize(float64(current))
+	}
+	return status
+}
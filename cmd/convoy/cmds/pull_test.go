@@ -0,0 +1,31 @@
+package cmds
+
+import (
+	"bytes"
+	"testing"
+
+	"convoy/internal/orchestrator"
+)
+
+func TestPullPrinter_RewritesOneLine(t *testing.T) {
+	var out bytes.Buffer
+	printer := &pullPrinter{w: &out}
+
+	progress := orchestrator.PullProgress{Image: "alpine:3.20", Layers: []orchestrator.LayerProgress{
+		{ID: "aaa", Complete: true, Current: 1000, Total: 1000},
+		{ID: "bbb", Current: 500, Total: 2000},
+	}}
+	printer.update(progress)
+	progress.Done = true
+	printer.update(progress)
+
+	want := "\r\x1b[KPulling alpine:3.20: 1/2 layers, 1.5kB of 3kB" +
+		"\r\x1b[KPulling alpine:3.20: 1/2 layers, 1.5kB of 3kB\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+
+	if got := pullStatus(orchestrator.PullProgress{Image: "alpine", Layers: []orchestrator.LayerProgress{{ID: "aaa"}}}); got != "Pulling alpine: 0/1 layers" {
+		t.Fatalf("status before any bytes = %q", got)
+	}
+}
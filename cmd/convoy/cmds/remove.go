@@ -1,22 +1,63 @@
 package cmds
 
 import (
-	"errors"
+	"fmt"
 
 	"github.com/spf13/cobra"
 )
 
 // NewRemoveCmd creates the remove command for removing containers.
 func NewRemoveCmd() *cobra.Command {
+	var quiet bool
+
 	cmd := &cobra.Command{
 		Use:          "remove [container-id]",
 		Short:        "Remove containers",
+		Long:         "Remove containers, stopping any that are still running.",
 		Args:         cobra.MinimumNArgs(1),
 		SilenceUsage: true,
-		RunE: func(_ *cobra.Command, _ []string) error {
-			return errors.New("remove command not implemented")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app, err := getApp()
+			if err != nil {
+				return err
+			}
+
+			mgr, err := app.Manager()
+			if err != nil {
+				return err
+			}
+
+			containers, err := LoadContainers()
+			if err != nil {
+				return fmt.Errorf("list containers: %w", err)
+			}
+
+			report := reporter{cmd: cmd, quiet: quiet}
+			resolved, missing := containers.ResolveContainerIDs(args)
+
+			var lastErr error
+			for _, m := range missing {
+				report.failure(event{Event: "remove_error", Container: m, Error: "container not found"}, "Container not found: %s", m)
+				lastErr = fmt.Errorf("container not found: %s", m)
+			}
+
+			for _, containerID := range resolved {
+				label := ContainerLabel(containers.Resolve(containerID))
+				if err := mgr.Remove(containerID); err != nil {
+					report.failure(event{Event: "remove_error", Container: label, ID: containerID, Error: err.Error()}, "Failed to remove %s: %v", label, err)
+					lastErr = fmt.Errorf("remove %s: %w", label, err)
+					continue
+				}
+
+				app.Registry().Remove(containerID)
+				report.done(event{Event: "remove_done", Container: label, ID: containerID}, "Removed %s", label)
+			}
+
+			return lastErr
 		},
 	}
 
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print only the IDs of removed containers")
+
 	return cmd
 }
@@ -0,0 +1,39 @@
+package cmds
+
+import (
+	"strings"
+	"testing"
+
+	"convoy/internal/orchestrator"
+)
+
+func TestRemoveCmd_QuietPrintsIDsAndFailsOnMissing(t *testing.T) {
+	rt := &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web"}, {ID: "id-2", Name: "db"}}}
+	useFakeApp(t, rt)
+
+	stdout, stderr, err := runQuiet(t, NewRemoveCmd(), "-q", "web", "db", "missing")
+	if err == nil {
+		t.Fatalf("expected an error for the missing container")
+	}
+	if stdout != "id-1\nid-2\n" {
+		t.Fatalf("stdout = %q, want only the removed IDs", stdout)
+	}
+	if !strings.Contains(stderr, "Container not found: missing") {
+		t.Fatalf("stderr = %q", stderr)
+	}
+	if got := strings.Join(rt.calls, ","); got != "remove:id-1,remove:id-2" {
+		t.Fatalf("calls = %s", got)
+	}
+}
+
+func TestRemoveCmd_ReportsEachContainer(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web"}}})
+
+	stdout, _, err := runQuiet(t, NewRemoveCmd(), "web")
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if stdout != "Removed web\n" {
+		t.Fatalf("stdout = %q", stdout)
+	}
+}
@@ -0,0 +1,101 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewRestartCmd creates the restart command for restarting containers.
+func NewRestartCmd() *cobra.Command {
+	var (
+		restartAll bool
+		wait       time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:          "restart [container-id|name]...",
+		Short:        "Restart containers",
+		Args:         cobra.ArbitraryArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app, err := getApp()
+			if err != nil {
+				return err
+			}
+
+			mgr, err := app.Manager()
+			if err != nil {
+				return err
+			}
+
+			containers, err := LoadContainers()
+			if err != nil {
+				return fmt.Errorf("list containers: %w", err)
+			}
+
+			var targetIDs []string
+			var errs []error
+			switch {
+			case restartAll:
+				targetIDs = containers.AllContainerIDs()
+				if len(targetIDs) == 0 {
+					_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No containers registered")
+					return nil
+				}
+			case len(args) == 0:
+				return fmt.Errorf("provide container names or IDs, or use -a")
+			default:
+				resolved, missing := containers.ResolveContainerIDs(args)
+				for _, m := range missing {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Container not found: %s\n", m)
+					errs = append(errs, fmt.Errorf("container not found: %s", m))
+				}
+				targetIDs = resolved
+			}
+
+			rpc := NewRPCClientWithTimeout(wait)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			for _, containerID := range targetIDs {
+				label := containerID
+				if container := containers.Resolve(containerID); container != nil {
+					label = ContainerLabel(container)
+				}
+
+				if err := mgr.Restart(containerID); err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to restart %s: %v\n", label, err)
+					errs = append(errs, fmt.Errorf("restart %s: %w", label, err))
+					continue
+				}
+
+				if wait > 0 {
+					endpoint := startedEndpoint(mgr, containerID)
+					err := errors.New("it has no gRPC endpoint to wait on")
+					if endpoint != "" {
+						err = waitForAgent(context.Background(), rpc.RPC, endpoint, wait, agentPollInterval)
+					}
+					if err != nil {
+						_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Restarted %s but agent is not healthy: %v\n", label, err)
+						errs = append(errs, fmt.Errorf("restart %s: agent is not healthy: %w", label, err))
+						continue
+					}
+				}
+
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Restarted %s\n", label)
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&restartAll, "all", "a", false, "Restart all managed containers")
+	cmd.Flags().DurationVar(&wait, "wait", 0, "Wait up to this long for the agent to report healthy (0 disables)")
+
+	return cmd
+}
@@ -0,0 +1,188 @@
+package cmds
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"convoy/internal/app"
+	"convoy/internal/orchestrator"
+	"convoy/pkg/loadbalancer"
+)
+
+// fakeRuntime is an in-memory orchestrator.Runtime for command tests. It is
+// safe for the concurrent use of commands that work on several containers.
+type fakeRuntime struct {
+	mu         sync.Mutex
+	containers []*orchestrator.Container
+	calls      []string
+	failStart  map[string]bool
+	specs      []orchestrator.ContainerSpec
+	failCreate error
+	// health holds each container's healthcheck status; missing IDs report it unknown.
+	health map[string]orchestrator.ContainerHealth
+	// ignoreSignals holds the IDs of containers that only SIGKILL stops.
+	ignoreSignals map[string]bool
+	// onStart, when set, runs at the start of every StartContainer call.
+	onStart func(id string)
+}
+
+func (f *fakeRuntime) record(call string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+func (f *fakeRuntime) CreateContainer(spec orchestrator.ContainerSpec) (*orchestrator.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.specs = append(f.specs, spec)
+	if f.failCreate != nil {
+		return nil, f.failCreate
+	}
+	c := &orchestrator.Container{ID: spec.Name + "-id", Name: spec.Name, Image: spec.Image, Labels: spec.Labels}
+	f.containers = append(f.containers, c)
+	f.calls = append(f.calls, "create:"+spec.Name)
+	return c, nil
+}
+
+func (f *fakeRuntime) StartContainer(id string) error {
+	if f.onStart != nil {
+		f.onStart(id)
+	}
+	f.record("start:" + id)
+	if f.failStart[id] {
+		return fmt.Errorf("start %s failed", id)
+	}
+	return nil
+}
+
+func (f *fakeRuntime) StopContainer(id string) error {
+	f.record("stop:" + id)
+	return nil
+}
+
+// KillContainer stops the container unless it is in ignoreSignals and the
+// signal is anything but SIGKILL.
+func (f *fakeRuntime) KillContainer(id, signal string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "kill:"+id+":"+signal)
+	if f.ignoreSignals[id] && signal != "SIGKILL" {
+		return nil
+	}
+	for _, c := range f.containers {
+		if c.ID == id {
+			c.Running = false
+		}
+	}
+	return nil
+}
+
+func (f *fakeRuntime) RemoveContainer(id string) error {
+	f.record("remove:" + id)
+	return nil
+}
+
+func (f *fakeRuntime) ListContainers() ([]*orchestrator.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.containers), nil
+}
+
+func (f *fakeRuntime) HealthStatus(id string) (orchestrator.ContainerHealth, error) {
+	return f.health[id], nil
+}
+
+// fakeApp implements AppProvider around a fakeRuntime.
+type fakeApp struct {
+	cfg      *app.Config
+	manager  *orchestrator.Manager
+	registry *orchestrator.Registry
+	balancer *orchestrator.Balancer
+}
+
+func (a *fakeApp) Config() (*app.Config, error)              { return a.cfg, nil }
+func (a *fakeApp) Manager() (*orchestrator.Manager, error)   { return a.manager, nil }
+func (a *fakeApp) Registry() *orchestrator.Registry          { return a.registry }
+func (a *fakeApp) Balancer() (*orchestrator.Balancer, error) { return a.balancer, nil }
+
+// useFakeApp points getApp at rt for the duration of the test.
+func useFakeApp(t *testing.T, rt *fakeRuntime) *fakeApp {
+	t.Helper()
+	mgr, err := orchestrator.NewManager(rt)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	balancer, err := orchestrator.NewBalancer(loadbalancer.NewRoundRobin())
+	if err != nil {
+		t.Fatalf("NewBalancer: %v", err)
+	}
+	fake := &fakeApp{
+		cfg:      &app.Config{Image: "convoy:test", GRPCPort: 50051, DockerHost: "unix:///tmp/docker.sock", AgentGRPCPort: 6000},
+		manager:  mgr,
+		registry: orchestrator.NewRegistry(),
+		balancer: balancer,
+	}
+
+	previous := GetAppFunc
+	GetAppFunc = func() (AppProvider, error) { return fake, nil }
+	t.Cleanup(func() { GetAppFunc = previous })
+	return fake
+}
+
+func TestRestartCmd_RestartsEachContainer(t *testing.T) {
+	rt := &fakeRuntime{
+		containers: []*orchestrator.Container{{ID: "id-1", Name: "web"}, {ID: "id-2", Name: "db"}},
+		failStart:  map[string]bool{"id-2": true},
+	}
+	useFakeApp(t, rt)
+
+	var out bytes.Buffer
+	cmd := NewRestartCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"web", "db", "missing"})
+
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatalf("expected aggregated error")
+	}
+	for _, want := range []string{"restart db", "container not found: missing"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not mention %q", err, want)
+		}
+	}
+
+	if got, want := strings.Join(rt.calls, ","), "stop:id-1,start:id-1,stop:id-2,start:id-2"; got != want {
+		t.Fatalf("calls = %s, want %s", got, want)
+	}
+	if !strings.Contains(out.String(), "Restarted web") || !strings.Contains(out.String(), "Failed to restart db") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRestartCmd_FailsWhenAgentNeverHealthy(t *testing.T) {
+	endpoint := startFakeAgent(t, &delayedHealthServer{readyAt: time.Now().Add(time.Hour)})
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "web", Endpoint: endpoint},
+		{ID: "id-2", Name: "db"},
+	}})
+
+	stdout, stderr, err := runQuiet(t, NewRestartCmd(), "web", "db", "--wait", "200ms")
+	if err == nil {
+		t.Fatalf("restart succeeded, want the failed health waits returned")
+	}
+	for _, want := range []string{"restart web: agent is not healthy", "restart db: agent is not healthy: it has no gRPC endpoint"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not mention %q", err, want)
+		}
+	}
+	if !strings.Contains(stderr, "Restarted web but agent is not healthy") || strings.Contains(stdout, "Restarted web\n") {
+		t.Fatalf("expected a health failure on stderr instead of success:\nstdout: %s\nstderr: %s", stdout, stderr)
+	}
+}
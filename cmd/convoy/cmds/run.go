@@ -0,0 +1,159 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	convoypb "convoy/api"
+	"convoy/internal/environ"
+	"convoy/internal/orchestrator"
+
+	"github.com/spf13/cobra"
+)
+
+// NewRunCmd creates the run command for spreading commands across containers.
+func NewRunCmd() *cobra.Command {
+	var (
+		envVars         []string
+		count           int
+		timeout         time.Duration
+		refreshInterval time.Duration
+		noShell         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "run [command] [args...]",
+		Short: "Run a command on containers picked by the load balancer",
+		Long: `Run a command --count times, picking a healthy container for each run in
+round-robin order.
+
+The rotation lives only as long as one invocation: every run command starts
+again from the first healthy container, so repeated "convoy run -n 1" calls all
+land on the same one. Use a single invocation with --count to spread work.
+
+Only running containers whose agent reports healthy are picked. During long
+runs the containers are re-listed and their agents re-probed every
+--refresh-interval, so containers that are stopped or become unhealthy are
+skipped and recovered ones are picked again:
+
+  convoy run --count 100 --refresh-interval 5s -- ./process-batch`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if count < 1 {
+				return errors.New("--count must be at least 1")
+			}
+
+			containers, err := LoadContainers()
+			if err != nil {
+				return err
+			}
+
+			var (
+				mu         sync.Mutex
+				byEndpoint = indexByEndpoint(containers.List())
+			)
+			endpoints := orchestrator.RunningEndpoints(containers.List())
+			if len(endpoints) == 0 {
+				return errors.New("no running containers with a gRPC endpoint")
+			}
+
+			appProvider, err := getApp()
+			if err != nil {
+				return err
+			}
+			balancer, err := appProvider.Balancer()
+			if err != nil {
+				return err
+			}
+
+			rpc := NewRPCClientWithTimeout(timeout)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			orchestrator.RefreshBalancerOnce(ctx, rpc.RPC, balancer, endpoints)
+			if refreshInterval > 0 {
+				source := func() ([]string, error) {
+					latest, err := LoadContainers()
+					if err != nil {
+						return nil, err
+					}
+					mu.Lock()
+					byEndpoint = indexByEndpoint(latest.List())
+					mu.Unlock()
+					return orchestrator.RunningEndpoints(latest.List()), nil
+				}
+				go orchestrator.ReconcileBalancer(ctx, rpc.RPC, balancer, source, refreshInterval)
+			}
+
+			env := ParseEnvVars(envVars)
+			var failed int
+			for i := 0; i < count; i++ {
+				endpoint := balancer.Next()
+				if endpoint == "" {
+					return errors.New("no healthy containers available")
+				}
+				mu.Lock()
+				container := byEndpoint[endpoint]
+				mu.Unlock()
+				if container == nil {
+					container = &orchestrator.Container{Name: endpoint, Endpoint: endpoint}
+				}
+
+				req := &convoypb.CommandRequest{
+					Args:           shellCommand(args, noShell),
+					Env:            environ.Merge(LabelEnv(container.Labels), env),
+					TimeoutSeconds: int32(timeout.Seconds()),
+				}
+				resp, err := rpc.ExecuteCommand(ctx, endpoint, req)
+				label := ContainerLabel(container)
+				if err != nil {
+					failed++
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "[%s] error: %v\n", label, err)
+					continue
+				}
+
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "==> %s <==\n", label)
+				_, _ = fmt.Fprint(cmd.OutOrStdout(), resp.GetStdout())
+				_, _ = fmt.Fprint(cmd.ErrOrStderr(), resp.GetStderr())
+				if resp.GetTruncated() {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "[%s] %s\n", label, truncatedNotice)
+				}
+				if resp.GetExitCode() != 0 {
+					failed++
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "[%s] exit code %d\n", label, resp.GetExitCode())
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d runs failed", failed, count)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&envVars, "env", "e", nil, "Set environment variables (can be repeated)")
+	cmd.Flags().IntVarP(&count, "count", "n", 1, "Number of times to run the command")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Timeout for each run")
+	cmd.Flags().DurationVar(&refreshInterval, "refresh-interval", 10*time.Second, "How often to re-probe agent health during the run (0 disables)")
+	cmd.Flags().BoolVar(&noShell, "no-shell", false, "Pass arguments directly as argv instead of wrapping them in sh -c")
+
+	return cmd
+}
+
+func indexByEndpoint(containers []*orchestrator.Container) map[string]*orchestrator.Container {
+	byEndpoint := make(map[string]*orchestrator.Container, len(containers))
+	for _, c := range containers {
+		if c != nil && c.Endpoint != "" {
+			byEndpoint[c.Endpoint] = c
+		}
+	}
+	return byEndpoint
+}
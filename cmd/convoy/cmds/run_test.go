@@ -0,0 +1,60 @@
+package cmds
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	convoypb "convoy/api"
+	"convoy/internal/orchestrator"
+)
+
+// runServer is a fake agent answering health checks and echoing its name.
+type runServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	name    string
+	healthy bool
+}
+
+func (s *runServer) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	if !s.healthy {
+		return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_UNHEALTHY}, nil
+	}
+	return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_HEALTHY}, nil
+}
+
+func (s *runServer) ExecuteCommand(context.Context, *convoypb.CommandRequest) (*convoypb.CommandResponse, error) {
+	return &convoypb.CommandResponse{Stdout: "ran on " + s.name + "\n"}, nil
+}
+
+func TestRunCmd_RoundRobinsAcrossHealthyContainers(t *testing.T) {
+	var containers []*orchestrator.Container
+	for _, agent := range []*runServer{{name: "a", healthy: true}, {name: "down"}, {name: "b", healthy: true}, {name: "stopped", healthy: true}} {
+		containers = append(containers, &orchestrator.Container{ID: "id-" + agent.name, Name: agent.name, Endpoint: startFakeAgent(t, agent), Running: agent.name != "stopped"})
+	}
+	useFakeApp(t, &fakeRuntime{containers: containers})
+
+	var stdout, stderr bytes.Buffer
+	cmd := NewRunCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"--count", "4", "--refresh-interval", "0", "--", "hostname"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("run: %v (stderr %q)", err, stderr.String())
+	}
+
+	var picked []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if name, ok := strings.CutPrefix(line, "==> "); ok {
+			picked = append(picked, strings.TrimSuffix(name, " <=="))
+		}
+	}
+	if got := strings.Join(picked, ","); got != "a,b,a,b" {
+		t.Fatalf("picked %s, want a,b,a,b skipping the unhealthy and stopped containers\n%s", got, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "==> b <==\nran on b\n") {
+		t.Fatalf("output of each run should follow its container header:\n%s", stdout.String())
+	}
+}
@@ -0,0 +1,149 @@
+package cmds
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// secretFileMode is the permission secrets are written with inside the container.
+const secretFileMode = 0o600
+
+// secretSpec maps a secret name to the local file holding its value.
+type secretSpec struct {
+	name string
+	path string
+}
+
+// NewSecretCmd creates the secret command for placing secret files in containers.
+func NewSecretCmd() *cobra.Command {
+	var (
+		fromFiles []string
+		dir       string
+		timeout   time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "secret [container-id|name] --from-file name=/path...",
+		Short: "Write secrets from local files into a container",
+		Long: `Write secrets from local files into a container without baking them into the image.
+
+Each secret is written as <dir>/<name> with mode 0600. The default directory,
+/run/secrets, is tmpfs-backed in most images so values never touch the disk.
+
+Examples:
+  convoy secret web --from-file db_password=./secrets/db.txt
+  convoy secret web --from-file api_key=./key --dir /run/app-secrets`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(fromFiles) == 0 {
+				return fmt.Errorf("at least one --from-file is required")
+			}
+
+			specs, err := parseSecretSpecs(fromFiles)
+			if err != nil {
+				return err
+			}
+
+			tarData, err := buildSecretsTar(specs)
+			if err != nil {
+				return err
+			}
+
+			containers, err := LoadContainers()
+			if err != nil {
+				return err
+			}
+
+			container, err := containers.ResolveWithEndpoint(args[0])
+			if err != nil {
+				return err
+			}
+
+			rpc := NewRPCClient(timeout, 0)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			opts := copyOptions{overwrite: true}
+			if _, err := pushTarToContainer(context.Background(), rpc.RPC, container.Endpoint, tarData, dir, opts); err != nil {
+				return fmt.Errorf("write secrets to %s: %w", ContainerLabel(container), err)
+			}
+
+			for _, spec := range specs {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Wrote secret %s to %s:%s\n", spec.name, ContainerLabel(container), path.Join(dir, spec.name))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&fromFiles, "from-file", nil, "Secret as name=/local/path (can be repeated)")
+	cmd.Flags().StringVar(&dir, "dir", "/run/secrets", "Directory inside the container to write secrets to")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Timeout for the transfer")
+
+	return cmd
+}
+
+// parseSecretSpecs parses name=/path pairs, rejecting names that would escape the secrets directory.
+func parseSecretSpecs(values []string) ([]secretSpec, error) {
+	specs := make([]secretSpec, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		name, filePath, ok := strings.Cut(value, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || filePath == "" {
+			return nil, fmt.Errorf("invalid --from-file %q (want name=/path)", value)
+		}
+		if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			return nil, fmt.Errorf("invalid secret name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate secret name %q", name)
+		}
+		seen[name] = true
+		specs = append(specs, secretSpec{name: name, path: filePath})
+	}
+	return specs, nil
+}
+
+// buildSecretsTar packs each secret as a flat 0600 entry.
+func buildSecretsTar(specs []secretSpec) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	now := time.Now()
+	for _, spec := range specs {
+		data, err := os.ReadFile(spec.path)
+		if err != nil {
+			return nil, fmt.Errorf("read secret %s: %w", spec.name, err)
+		}
+
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     spec.name,
+			Mode:     secretFileMode,
+			Size:     int64(len(data)),
+			ModTime:  now,
+			Format:   tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
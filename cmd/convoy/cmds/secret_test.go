@@ -0,0 +1,57 @@
+package cmds
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"convoy/internal/agent"
+	"convoy/internal/orchestrator"
+)
+
+func TestSecretCmd_WritesFileWithRestrictiveMode(t *testing.T) {
+	endpoint := startFakeAgent(t, agent.NewServer(&agent.Config{MaxConcurrent: 2}))
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Endpoint: endpoint}}})
+
+	base := t.TempDir()
+	local := filepath.Join(base, "db.txt")
+	writeTestFile(t, local, "hunter2")
+
+	secretsDir := filepath.Join(base, "run", "secrets")
+	// A stale, world-readable copy must be tightened, not just rewritten.
+	writeTestFile(t, filepath.Join(secretsDir, "db_password"), "old")
+	if err := os.Chmod(filepath.Join(secretsDir, "db_password"), 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	cmd := NewSecretCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"web", "--from-file", "db_password=" + local, "--dir", secretsDir})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("secret: %v", err)
+	}
+
+	target := filepath.Join(secretsDir, "db_password")
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("stat secret: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Fatalf("secret mode = %o, want 600", mode)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "hunter2" {
+		t.Fatalf("secret content = %q", got)
+	}
+}
+
+func TestParseSecretSpecs_RejectsUnsafeNames(t *testing.T) {
+	for _, value := range []string{"noequals", "=./x", "../evil=./x", "a/b=./x", "name="} {
+		if _, err := parseSecretSpecs([]string{value}); err == nil {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+	}
+	if _, err := parseSecretSpecs([]string{"a=./x", "a=./y"}); err == nil {
+		t.Fatalf("expected duplicate names to be rejected")
+	}
+}
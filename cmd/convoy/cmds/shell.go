@@ -1,22 +1,144 @@
 package cmds
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	convoypb "convoy/api"
+	"convoy/internal/environ"
+	"convoy/internal/orchestrator"
 
 	"github.com/spf13/cobra"
 )
 
 // NewShellCmd creates the shell command for opening interactive shells in containers.
 func NewShellCmd() *cobra.Command {
+	var (
+		envVars        []string
+		envFiles       []string
+		workDir        string
+		endpoint       string
+		dialTimeout    time.Duration
+		resumeAttempts int
+		logFile        string
+		user           string
+	)
+
 	cmd := &cobra.Command{
-		Use:          "shell [container-id]",
-		Short:        "Open an interactive shell",
+		Use:   "shell [container-id|name] [-- command [args...]]",
+		Short: "Open an interactive shell",
+		Long: `Open a shell in a container, relaying standard input and output. Without a
+command the agent's configured shell is started.
+
+If the connection drops, convoy reconnects and resumes the same session: the
+agent keeps the shell running for its shell_resume_grace_sec. Input or output
+in flight at the moment of the drop may be lost. When the session can no longer
+be resumed the command exits with an error saying so.
+
+--log-file appends a transcript of the session to a local file for audits:
+every line of input and output with a timestamp and its stream (in, out or
+err), between start and exit lines. Control characters, such as terminal
+escape sequences, are written escaped as \xNN. The file is created readable
+by its owner only, as a transcript may hold anything typed or shown.
+
+  convoy shell web
+  convoy shell web -- bash -l
+  convoy shell web --user app
+  convoy shell --endpoint 127.0.0.1:16000
+  convoy shell web --log-file ~/audit/web.log`,
+		Args:         cobra.ArbitraryArgs,
 		SilenceUsage: true,
-		Args:         cobra.ExactArgs(1),
-		RunE: func(_ *cobra.Command, _ []string) error {
-			return errors.New("shell command not implemented")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if endpoint == "" && len(args) == 0 {
+				return errors.New("container id or name is required")
+			}
+			if resumeAttempts < 0 {
+				return errors.New("--resume-attempts must not be negative")
+			}
+
+			containers, err := LoadContainersOrEndpoint(endpoint)
+			if err != nil {
+				return err
+			}
+
+			var container *orchestrator.Container
+			if endpoint != "" {
+				container = containers.Resolve(endpoint)
+			} else {
+				if container, err = containers.ResolveWithEndpoint(args[0]); err != nil {
+					return err
+				}
+				args = args[1:]
+			}
+
+			fileEnv, err := ParseEnvFiles(envFiles)
+			if err != nil {
+				return err
+			}
+
+			rpc := NewRPCClient(dialTimeout, 0)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			start := &convoypb.ShellStart{
+				Args:    args,
+				Env:     environ.Merge(LabelEnv(container.Labels), fileEnv, ParseEnvVars(envVars)),
+				WorkDir: workDir,
+				User:    user,
+			}
+			stdio := orchestrator.ShellIO{Stdin: cmd.InOrStdin(), Stdout: cmd.OutOrStdout(), Stderr: cmd.ErrOrStderr()}
+			var audit *transcript
+			if logFile != "" {
+				f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+				if err != nil {
+					return fmt.Errorf("open log file: %w", err)
+				}
+				defer func() {
+					_ = f.Close()
+				}()
+				audit = newTranscript(f)
+				stdio.Stdin = audit.reader("in", stdio.Stdin)
+				stdio.Stdout = audit.writer("out", stdio.Stdout)
+				stdio.Stderr = audit.writer("err", stdio.Stderr)
+				audit.event("start", "%s %s", ContainerLabel(container), strings.Join(args, " "))
+			}
+			opts := orchestrator.ShellOptions{
+				ResumeAttempts: resumeAttempts,
+				OnResume: func(attempt int, err error) {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "convoy: connection lost (%v); resuming session (attempt %d of %d)\n", err, attempt, resumeAttempts)
+					if audit != nil {
+						audit.event("drop", "connection lost (%v); resuming, attempt %d", err, attempt)
+					}
+				},
+			}
+
+			exit, err := rpc.RunShell(context.Background(), container.Endpoint, start, stdio, opts)
+			if err != nil {
+				if audit != nil {
+					audit.event("error", "%v", err)
+				}
+				return err
+			}
+			if audit != nil {
+				audit.event("exit", "%d %s", exit.GetExitCode(), exit.GetMessage())
+			}
+			return remoteExit(cmd, exit.GetExitCode(), exit.GetMessage())
 		},
 	}
 
+	cmd.Flags().StringArrayVarP(&envVars, "env", "e", nil, "Set environment variables (can be repeated)")
+	cmd.Flags().StringArrayVar(&envFiles, "env-file", nil, "Read environment variables from a dotenv file; -e overrides them (can be repeated)")
+	cmd.Flags().StringVarP(&workDir, "workdir", "w", "", "Working directory inside the container")
+	cmd.Flags().StringVarP(&user, "user", "u", "", "Run the shell as this user: name or uid, optionally with :group or :gid")
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "Dial the agent at this host:port or unix:///socket instead of resolving a container (omit the container argument)")
+	cmd.Flags().DurationVar(&dialTimeout, "dial-timeout", 10*time.Second, "Timeout for connecting to the agent")
+	cmd.Flags().StringVar(&logFile, "log-file", "", "Append a timestamped transcript of the session's input and output to this file")
+	cmd.Flags().IntVar(&resumeAttempts, "resume-attempts", 5, "How many times to try resuming the session after the connection drops (0 disables)")
+
 	return cmd
 }
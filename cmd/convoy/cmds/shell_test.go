@@ -0,0 +1,137 @@
+package cmds
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+)
+
+// echoShellServer echoes shell input back as output and exits with code 3 once input ends.
+type echoShellServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	start *convoypb.ShellStart
+}
+
+func (s *echoShellServer) ExecuteShell(stream convoypb.ConvoyService_ExecuteShellServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.start = req.GetStart()
+	if err := stream.Send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Session{Session: &convoypb.ShellSession{SessionId: "s1"}}}); err != nil {
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF || req.GetInput().GetEof() {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Output{
+			Output: &convoypb.ShellOutput{Stream: convoypb.ShellOutput_STDOUT, Data: req.GetInput().GetData()},
+		}}); err != nil {
+			return err
+		}
+	}
+	return stream.Send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Exit{Exit: &convoypb.ShellExit{ExitCode: 3}}})
+}
+
+func TestShellCmd_RelaysInputAndExitCode(t *testing.T) {
+	srv := &echoShellServer{}
+	endpoint := startFakeAgent(t, srv)
+
+	var stdout bytes.Buffer
+	cmd := NewShellCmd()
+	cmd.SetIn(strings.NewReader("echo hi\n"))
+	cmd.SetOut(&stdout)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--endpoint", endpoint, "--", "bash", "-l"})
+
+	err := cmd.Execute()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	if stdout.String() != "echo hi\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	if got := srv.start.GetArgs(); len(got) != 2 || got[0] != "bash" {
+		t.Fatalf("shell args = %q", got)
+	}
+}
+
+func TestShellCmd_LogFileRecordsTranscript(t *testing.T) {
+	endpoint := startFakeAgent(t, &echoShellServer{})
+	logFile := filepath.Join(t.TempDir(), "session.log")
+
+	cmd := NewShellCmd()
+	cmd.SetIn(strings.NewReader("echo hi\nprintf '\x1b[1mbold\\\\'\r\n"))
+	_, _, err := runQuiet(t, cmd, "--endpoint", endpoint, "--log-file", logFile, "--", "sh")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("read transcript: %v", err)
+	}
+	stamp := regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z `)
+	var got []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if !stamp.MatchString(line) {
+			t.Fatalf("transcript line without a timestamp: %q", line)
+		}
+		got = append(got, stamp.ReplaceAllString(line, ""))
+	}
+	want := []string{
+		"start " + endpoint + " sh",
+		"in    echo hi",
+		"in    printf '\\x1b[1mbold\\\\\\\\'",
+		"out   echo hi",
+		"out   printf '\\x1b[1mbold\\\\\\\\'",
+		"exit  3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("transcript =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTranscript_JoinsLinesSplitAcrossWrites(t *testing.T) {
+	var buf bytes.Buffer
+	audit := newTranscript(&buf)
+	audit.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	audit.record("in", []byte("ec"))
+	audit.record("out", []byte("pro"))
+	audit.record("in", []byte("ho hi\r"))
+	audit.record("in", []byte("\nls\r"))
+	audit.record("out", []byte("mpt$ "))
+	audit.event("exit", "%d", 0)
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		got = append(got, strings.TrimPrefix(line, "2024-01-02T03:04:05.000Z "))
+	}
+	want := []string{
+		"in    echo hi",
+		"in    ls",
+		"out   prompt$ ",
+		"exit  0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("transcript =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -1,20 +1,62 @@
 package cmds
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	convoypb "convoy/api"
+	"convoy/internal/environ"
 	"convoy/internal/orchestrator"
 )
 
+// agentPollInterval is the delay between health probes while waiting for an agent.
+const agentPollInterval = 250 * time.Millisecond
+
 // NewStartCmd creates the start command for starting containers.
 func NewStartCmd() *cobra.Command {
+	var (
+		envVars        []string
+		envPrefix      string
+		stripPrefix    bool
+		wait           time.Duration
+		idempotencyKey string
+		labels         []string
+		image          string
+		restart        string
+		quietPull      bool
+		pull           string
+		publish        []string
+		network        string
+		newNetwork     bool
+		readyCmd       string
+		readyInterval  time.Duration
+		quiet          bool
+		jsonEvents     bool
+		concurrency    int
+	)
+
 	cmd := &cobra.Command{
-		Use:          "start [container-id]",
-		Short:        "Start containers",
-		Args:         cobra.MinimumNArgs(1),
+		Use:   "start [container-id...]",
+		Short: "Start containers",
+		Long: `Start containers, creating any that are not registered yet.
+
+By default start waits for each container's agent to report healthy. To gate on
+the application itself, --ready-cmd runs a shell command in the container after
+the agent is up and retries it every --ready-interval until it exits zero; both
+steps share the --wait budget:
+
+  convoy start web --ready-cmd 'curl -sf localhost:8080/ready' --wait 1m
+
+Several containers are started at once, at most --concurrency at a time, and
+their output is printed in the order they were named. With no container, start
+creates one under a generated name such as brave-otter-042.`,
+		Args:         cobra.ArbitraryArgs,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			app, err := getApp()
@@ -31,6 +73,7 @@ func NewStartCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			showPullProgress(cmd, mgr, quietPull)
 
 			registry := app.Registry()
 
@@ -40,55 +83,287 @@ func NewStartCmd() *cobra.Command {
 				return err
 			}
 
-			var lastErr error
-			for _, arg := range args {
-				containerName := strings.TrimSpace(arg)
-				if containerName == "" {
-					continue
+			image = strings.TrimSpace(image)
+			if cmd.Flags().Changed("image") && image == "" {
+				return fmt.Errorf("--image must not be empty")
+			}
+			if image == "" {
+				image = cfg.Image
+			}
+
+			ports, err := parsePublishFlag(publish)
+			if err != nil {
+				return err
+			}
+			restartPolicy, err := parseRestartFlag(cmd, restart)
+			if err != nil {
+				return err
+			}
+			pullPolicy, err := parsePullFlag(cmd, pull)
+			if err != nil {
+				return err
+			}
+
+			if newNetwork && strings.TrimSpace(network) == "" {
+				return fmt.Errorf("--create-network requires --network")
+			}
+
+			readyCmd = strings.TrimSpace(readyCmd)
+			if readyCmd != "" && wait <= 0 {
+				return fmt.Errorf("--ready-cmd requires a positive --wait")
+			}
+			if readyInterval <= 0 {
+				return fmt.Errorf("--ready-interval must be positive")
+			}
+
+			if concurrency < 1 {
+				return fmt.Errorf("--concurrency must be at least 1")
+			}
+
+			idempotencyKey = strings.TrimSpace(idempotencyKey)
+			if idempotencyKey != "" && len(args) > 1 {
+				return fmt.Errorf("--idempotency-key can only be used with a single container")
+			}
+
+			if len(args) == 0 {
+				name, err := unusedContainerName(containers)
+				if err != nil {
+					return err
 				}
+				args = []string{name}
+			}
+
+			env := environ.Merge(PrefixedEnvVars(os.Environ(), envPrefix, stripPrefix), ParseEnvVars(envVars))
+
+			rpc := NewRPCClientWithTimeout(wait)
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			// waitForStarted gates on the agent and then, if configured, the readiness
+			// command, with one --wait deadline covering both.
+			waitForStarted := func(endpoint string) error {
+				ctx, cancel := context.WithTimeout(context.Background(), wait)
+				defer cancel()
+				if err := waitForAgent(ctx, rpc.RPC, endpoint, wait, agentPollInterval); err != nil {
+					return fmt.Errorf("agent is not healthy: %w", err)
+				}
+				if readyCmd == "" {
+					return nil
+				}
+				if err := waitForReady(ctx, rpc.RPC, endpoint, readyCmd, readyInterval); err != nil {
+					return fmt.Errorf("app is not ready: %w", err)
+				}
+				return nil
+			}
+
+			// The idempotency key names at most one container, so look it up once.
+			var keyed *orchestrator.Container
+			if idempotencyKey != "" {
+				if keyed, err = mgr.FindByIdempotencyKey(idempotencyKey); err != nil {
+					return err
+				}
+			}
+
+			// startOne starts the container named arg, creating it first when
+			// needed, and reports through report.
+			startOne := func(report reporter, arg string) error {
+				containerName := strings.TrimSpace(arg)
 
 				// Try to resolve existing container
 				var containerID string
 				var displayLabel string
-				if existing := containers.Resolve(containerName); existing != nil {
+				existing := containers.Resolve(containerName)
+				if existing == nil {
+					existing = keyed
+				}
+
+				if existing != nil && existing.Running {
+					report.done(event{Event: "start_running", Container: ContainerLabel(existing), ID: existing.ID}, "%s is already running", ContainerLabel(existing))
+					return nil
+				}
+
+				if existing != nil {
 					containerID = existing.ID
 					displayLabel = ContainerLabel(existing)
 				} else {
 					// Create new container
-					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "No registered container: %s\nCreating new container...\n", arg)
+					report.progress(event{Event: "start_create", Container: containerName}, "No registered container: %s\nCreating new container...", arg)
 					spec := orchestrator.ContainerSpec{
-						Name:  containerName,
-						Image: cfg.Image,
+						Name:          containerName,
+						Image:         image,
+						Environment:   env,
+						Ports:         ports,
+						Network:       strings.TrimSpace(network),
+						CreateNetwork: newNetwork,
+						PullPolicy:    pullPolicy,
+						RestartPolicy: restartPolicy,
+					}
+					spec.Labels = ParseEnvVars(labels)
+					if idempotencyKey != "" {
+						spec.Labels[orchestrator.IdempotencyKeyLabel] = idempotencyKey
+					}
+
+					if warning := floatingTagWarning(cfg, spec.Image); warning != "" {
+						report.progress(event{Event: "start_warning", Container: containerName, Error: warning}, "Warning: %s", warning)
 					}
 
 					container, createErr := mgr.Create(spec)
 					if createErr != nil {
-						_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Failed to create container %s: %v\n", arg, createErr)
-						lastErr = fmt.Errorf("create %s: %w", arg, createErr)
-						continue
+						report.failure(event{Event: "start_error", Container: containerName, Error: createErr.Error()}, "Failed to create container %s: %v", arg, createErr)
+						return fmt.Errorf("create %s: %w", arg, createErr)
 					}
 
 					if regErr := registry.Register(container); regErr != nil {
-						_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Warning: failed to register %s: %v\n", container.ID, regErr)
+						report.failure(event{Event: "start_warning", Container: containerName, ID: container.ID, Error: regErr.Error()}, "Warning: failed to register %s: %v", container.ID, regErr)
 					}
 
 					containerID = container.ID
 					displayLabel = containerName
-					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Created container %s (id=%s)\n", containerName, container.ID)
+					report.progress(event{Event: "start_created", Container: containerName, ID: container.ID}, "Created container %s (id=%s)", containerName, container.ID)
 				}
 
+				report.emit(event{Event: "start_start", Container: displayLabel, ID: containerID})
 				if err := mgr.Start(containerID); err != nil {
-					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Failed to start %s: %v\n", displayLabel, err)
-					lastErr = fmt.Errorf("start %s: %w", displayLabel, err)
-					continue
+					report.failure(event{Event: "start_error", Container: displayLabel, ID: containerID, Error: err.Error()}, "Failed to start %s: %v", displayLabel, err)
+					return fmt.Errorf("start %s: %w", displayLabel, err)
 				}
 
-				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Started %s\n", displayLabel)
+				if wait > 0 {
+					// The endpoint is only known once Docker has assigned ports, so look it up again.
+					endpoint := startedEndpoint(mgr, containerID)
+					if endpoint == "" {
+						report.failure(event{Event: "start_warning", Container: displayLabel, ID: containerID, Error: "no gRPC endpoint; not waiting for agent"}, "Warning: %s has no gRPC endpoint; not waiting for agent", displayLabel)
+					} else if err := waitForStarted(endpoint); err != nil {
+						report.failure(event{Event: "start_error", Container: displayLabel, ID: containerID, Error: err.Error()}, "%s started but %v", displayLabel, err)
+						return fmt.Errorf("start %s: %w", displayLabel, err)
+					}
+				}
+
+				report.done(event{Event: "start_done", Container: displayLabel, ID: containerID}, "Started %s", displayLabel)
+				return nil
+			}
+
+			var names []string
+			for _, arg := range args {
+				if strings.TrimSpace(arg) != "" {
+					names = append(names, arg)
+				}
+			}
+
+			// Containers start concurrently, each reporting into its own buffers,
+			// and their output is written in argument order as soon as every
+			// container before them has finished.
+			type startResult struct {
+				out, errOut bytes.Buffer
+				err         error
+				done        chan struct{}
+			}
+			results := make([]*startResult, len(names))
+			sem := make(chan struct{}, concurrency)
+			for i, arg := range names {
+				result := &startResult{done: make(chan struct{})}
+				results[i] = result
+				go func() {
+					defer close(result.done)
+					sem <- struct{}{}
+					defer func() { <-sem }()
+
+					sub := &cobra.Command{}
+					sub.SetOut(&result.out)
+					sub.SetErr(&result.errOut)
+					result.err = startOne(reporter{cmd: sub, quiet: quiet, events: newEventEmitter(&result.out, jsonEvents)}, arg)
+				}()
+			}
+
+			var lastErr error
+			for _, result := range results {
+				<-result.done
+				_, _ = cmd.OutOrStdout().Write(result.out.Bytes())
+				_, _ = cmd.ErrOrStderr().Write(result.errOut.Bytes())
+				if result.err != nil {
+					lastErr = result.err
+				}
 			}
 
 			return lastErr
 		},
 	}
 
+	cmd.Flags().StringArrayVarP(&envVars, "env", "e", nil, "Set environment variables for new containers (can be repeated)")
+	cmd.Flags().StringVar(&envPrefix, "env-prefix", "", "Forward host environment variables with this prefix to new containers")
+	cmd.Flags().BoolVar(&stripPrefix, "strip-prefix", false, "Strip --env-prefix from forwarded variable names")
+	cmd.Flags().StringArrayVarP(&labels, "label", "l", nil, "Add a key=value label to new containers (can be repeated)")
+	cmd.Flags().StringVar(&image, "image", "", "Image for new containers (defaults to the configured image; ignored for existing ones)")
+	cmd.Flags().StringArrayVarP(&publish, "publish", "p", nil, "Publish a port of new containers as host:container[/protocol] (can be repeated)")
+	cmd.Flags().StringVar(&network, "network", "", "Network for new containers: host, none or a network name (defaults to the configured network)")
+	cmd.Flags().BoolVar(&newNetwork, "create-network", false, "Create the --network network if it does not exist")
+	cmd.Flags().StringVar(&restart, "restart", "", "Restart policy for new containers: no, on-failure[:max-retries], always or unless-stopped (default unless-stopped)")
+	cmd.Flags().StringVar(&pull, "pull", "", "When to pull the image of new containers: always, missing or never (defaults to the pull_always setting)")
+	cmd.Flags().BoolVar(&quietPull, "quiet-pull", false, "Do not show image pull progress")
+	cmd.Flags().StringVar(&idempotencyKey, "idempotency-key", "", "Reuse the container created with this key instead of creating a duplicate")
+	cmd.Flags().DurationVar(&wait, "wait", 30*time.Second, "Wait up to this long for the agent to report healthy (0 disables)")
+	cmd.Flags().StringVar(&readyCmd, "ready-cmd", "", "Shell command run in the container until it exits zero before the start counts as ready")
+	cmd.Flags().DurationVar(&readyInterval, "ready-interval", time.Second, "Delay between --ready-cmd attempts")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print only the IDs of started containers")
+	cmd.Flags().BoolVar(&jsonEvents, "json", false, "Print one JSON event per line instead of human-readable output")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 4, "Maximum number of containers to create and start at once")
+
 	return cmd
 }
+
+// startedEndpoint returns the current gRPC endpoint of container id, or "" when unknown.
+func startedEndpoint(mgr *orchestrator.Manager, id string) string {
+	containers, err := mgr.List()
+	if err != nil {
+		return ""
+	}
+	for _, c := range containers {
+		if c != nil && c.ID == id {
+			return c.Endpoint
+		}
+	}
+	return ""
+}
+
+// waitForAgent polls the agent at endpoint until it reports healthy or wait elapses.
+func waitForAgent(ctx context.Context, rpc *orchestrator.RPC, endpoint string, wait, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	for {
+		result := orchestrator.ProbeHealth(ctx, rpc, endpoint, endpoint)
+		if result.Healthy {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not healthy after %s: %w", wait, result.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// waitForReady runs command in the container at endpoint until it exits zero or ctx is done.
+func waitForReady(ctx context.Context, rpc *orchestrator.RPC, endpoint, command string, interval time.Duration) error {
+	req := &convoypb.CommandRequest{Args: shellCommand([]string{command}, false)}
+	for {
+		var lastErr error
+		resp, err := rpc.ExecuteCommand(ctx, endpoint, req)
+		switch {
+		case err != nil:
+			lastErr = err
+		case resp.GetExitCode() != 0:
+			lastErr = fmt.Errorf("%q exited with code %d", command, resp.GetExitCode())
+		default:
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out: %w", lastErr)
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package cmds
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+	"convoy/internal/orchestrator"
+)
+
+type delayedHealthServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	readyAt time.Time
+}
+
+func (s *delayedHealthServer) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	if time.Now().Before(s.readyAt) {
+		return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_DEGRADED, Message: "starting"}, nil
+	}
+	return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_HEALTHY}, nil
+}
+
+func TestWaitForAgent_BecomesHealthy(t *testing.T) {
+	endpoint := startFakeAgent(t, &delayedHealthServer{readyAt: time.Now().Add(300 * time.Millisecond)})
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: time.Second, CallTimeout: time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	start := time.Now()
+	if err := waitForAgent(context.Background(), rpc, endpoint, 5*time.Second, 50*time.Millisecond); err != nil {
+		t.Fatalf("waitForAgent: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("reported healthy before the agent was ready (%s)", elapsed)
+	}
+}
+
+func TestWaitForAgent_TimesOut(t *testing.T) {
+	endpoint := startFakeAgent(t, &delayedHealthServer{readyAt: time.Now().Add(time.Hour)})
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: time.Second, CallTimeout: time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	if err := waitForAgent(context.Background(), rpc, endpoint, 200*time.Millisecond, 50*time.Millisecond); err == nil {
+		t.Fatalf("expected timeout error")
+	}
+}
+
+func TestStartCmd_FailsWhenAgentNeverHealthy(t *testing.T) {
+	endpoint := startFakeAgent(t, &delayedHealthServer{readyAt: time.Now().Add(time.Hour)})
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Endpoint: endpoint}}})
+
+	stdout, stderr, err := runQuiet(t, NewStartCmd(), "web", "--wait", "200ms")
+	if err == nil || !strings.Contains(err.Error(), "agent is not healthy") {
+		t.Fatalf("start = %v, want the failed health wait returned", err)
+	}
+	if !strings.Contains(stderr, "web started but agent is not healthy") || strings.Contains(stdout, "Started web") {
+		t.Fatalf("expected a health failure on stderr instead of success:\nstdout: %s\nstderr: %s", stdout, stderr)
+	}
+}
+
+func TestStartCmd_IdempotencyKeyReusesContainer(t *testing.T) {
+	rt := &fakeRuntime{}
+	useFakeApp(t, rt)
+
+	for _, name := range []string{"web", "web-retry"} {
+		cmd := NewStartCmd()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{name, "--idempotency-key", "deploy-42", "--wait", "0"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("start %s: %v", name, err)
+		}
+	}
+
+	if len(rt.containers) != 1 {
+		t.Fatalf("expected a single container, got %d", len(rt.containers))
+	}
+	if got := rt.containers[0].Labels[orchestrator.IdempotencyKeyLabel]; got != "deploy-42" {
+		t.Fatalf("idempotency key label = %q", got)
+	}
+	if got, want := strings.Join(rt.calls, ","), "create:web,start:web-id,start:web-id"; got != want {
+		t.Fatalf("calls = %s, want %s", got, want)
+	}
+}
+
+func TestStartCmd_AlreadyRunningSkipsStart(t *testing.T) {
+	rt := &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "web", Running: true},
+		{ID: "id-2", Name: "db"},
+	}}
+	useFakeApp(t, rt)
+
+	var out strings.Builder
+	cmd := NewStartCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"web", "db", "--wait", "0"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "web is already running") {
+		t.Fatalf("missing already running message:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "Started web") {
+		t.Fatalf("running container reported as started:\n%s", out.String())
+	}
+	if got, want := strings.Join(rt.calls, ","), "start:id-2"; got != want {
+		t.Fatalf("calls = %s, want %s", got, want)
+	}
+}
+
+func TestStartCmd_ImageFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "override", args: []string{"web", "--image", "nginx:1.27", "--wait", "0"}, want: "nginx:1.27"},
+		{name: "config default", args: []string{"web", "--wait", "0"}, want: "convoy:test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &fakeRuntime{}
+			useFakeApp(t, rt)
+
+			cmd := NewStartCmd()
+			cmd.SetOut(io.Discard)
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("start: %v", err)
+			}
+			if len(rt.specs) != 1 || rt.specs[0].Image != tt.want {
+				t.Fatalf("created with specs %+v, want image %q", rt.specs, tt.want)
+			}
+		})
+	}
+
+	useFakeApp(t, &fakeRuntime{})
+	cmd := NewStartCmd()
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"web", "--image", " "})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected an error for an empty --image")
+	}
+}
+
+// readyServer is a healthy agent whose readiness command fails until it has run failures times.
+type readyServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	failures int
+
+	mu    sync.Mutex
+	calls []time.Time
+	args  []string
+}
+
+func (s *readyServer) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_HEALTHY}, nil
+}
+
+func (s *readyServer) ExecuteCommand(_ context.Context, req *convoypb.CommandRequest) (*convoypb.CommandResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, time.Now())
+	s.args = req.GetArgs()
+	if len(s.calls) <= s.failures {
+		return &convoypb.CommandResponse{ExitCode: 7}, nil
+	}
+	return &convoypb.CommandResponse{}, nil
+}
+
+func TestStartCmd_ReadyCmdRetriesUntilSuccess(t *testing.T) {
+	srv := &readyServer{failures: 3}
+	endpoint := startFakeAgent(t, srv)
+	rt := &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Endpoint: endpoint}}}
+	useFakeApp(t, rt)
+
+	var out strings.Builder
+	cmd := NewStartCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"web", "--ready-cmd", "curl -sf localhost:8080/ready", "--ready-interval", "50ms", "--wait", "5s"})
+
+	start := time.Now()
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.calls) != 4 {
+		t.Fatalf("readiness command ran %d times, want 4", len(srv.calls))
+	}
+	if elapsed < 150*time.Millisecond {
+		t.Fatalf("start returned after %s, before three retry intervals", elapsed)
+	}
+	if got, want := strings.Join(srv.args, " "), "sh -c curl -sf localhost:8080/ready"; got != want {
+		t.Fatalf("readiness argv = %q, want %q", got, want)
+	}
+	if !strings.Contains(out.String(), "Started web") {
+		t.Fatalf("missing started message:\n%s", out.String())
+	}
+}
+
+func TestStartCmd_ReadyCmdTimesOut(t *testing.T) {
+	endpoint := startFakeAgent(t, &readyServer{failures: 1 << 30})
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Endpoint: endpoint}}})
+
+	stdout, stderr, err := runQuiet(t, NewStartCmd(), "web", "--ready-cmd", "false", "--ready-interval", "20ms", "--wait", "200ms")
+	if err == nil || !strings.Contains(err.Error(), "app is not ready") {
+		t.Fatalf("start = %v, want the failed readiness wait reported", err)
+	}
+	if !strings.Contains(stderr, "web started but app is not ready") || strings.Contains(stdout, "Started web") {
+		t.Fatalf("expected a readiness failure on stderr instead of success:\nstdout: %s\nstderr: %s", stdout, stderr)
+	}
+}
+
+func TestStartCmd_QuietPrintsOnlyIDs(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-db", Name: "db"}}})
+
+	stdout, stderr, err := runQuiet(t, NewStartCmd(), "-q", "--wait", "0", "web", "db")
+	if err != nil {
+		t.Fatalf("start: %v (stderr %q)", err, stderr)
+	}
+	if stdout != "web-id\nid-db\n" {
+		t.Fatalf("stdout = %q, want only the two IDs", stdout)
+	}
+	if stderr != "" {
+		t.Fatalf("unexpected stderr %q", stderr)
+	}
+}
+
+func TestStartCmd_GeneratesNameWithoutArgs(t *testing.T) {
+	useNameSeed(t, 9)
+	rt := &fakeRuntime{}
+	useFakeApp(t, rt)
+
+	stdout, stderr, err := runQuiet(t, NewStartCmd(), "--wait", "0")
+	if err != nil {
+		t.Fatalf("start: %v (stderr %q)", err, stderr)
+	}
+	if len(rt.specs) != 1 || !generatedName.MatchString(rt.specs[0].Name) {
+		t.Fatalf("expected one container with a generated name, got %+v", rt.specs)
+	}
+	name := rt.specs[0].Name
+	if !reflect.DeepEqual(rt.calls, []string{"create:" + name, "start:" + name + "-id"}) {
+		t.Fatalf("unexpected runtime calls: %v", rt.calls)
+	}
+	if !strings.Contains(stdout, "Started "+name) {
+		t.Fatalf("expected the generated name in the output, got %q", stdout)
+	}
+}
+
+func TestStartCmd_JSONEvents(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Running: true}}})
+
+	stdout, _, err := runQuiet(t, NewStartCmd(), "--json", "web", "db", "--wait", "0")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	events := decodeEvents(t, stdout)
+	if got, want := eventNames(events), "start_running,start_create,start_created,start_start,start_done"; got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+	if done := events[4]; done.Container != "db" || done.ID != "db-id" {
+		t.Fatalf("start_done = %+v", done)
+	}
+}
+
+func TestStartCmd_StartsConcurrentlyInInputOrder(t *testing.T) {
+	names := []string{"a", "b", "c"}
+	arrived := make(chan string, len(names))
+	release := make(chan struct{})
+	rt := &fakeRuntime{
+		failStart: map[string]bool{"b-id": true},
+		// Hold every start until all three are in flight at once.
+		onStart: func(id string) {
+			arrived <- id
+			<-release
+		},
+	}
+	useFakeApp(t, rt)
+
+	go func() {
+		defer close(release)
+		for range names {
+			select {
+			case <-arrived:
+			case <-time.After(5 * time.Second):
+				t.Error("containers were not started concurrently")
+				return
+			}
+		}
+	}()
+
+	stdout, stderr, err := runQuiet(t, NewStartCmd(), "--wait", "0", "--concurrency", "3", "a", "b", "c")
+	if err == nil || err.Error() != "start b: start b-id failed" {
+		t.Fatalf("err = %v, want the failure of b", err)
+	}
+
+	// Each container is created before it is started, whatever the interleaving.
+	for _, name := range names {
+		create := slices.Index(rt.calls, "create:"+name)
+		start := slices.Index(rt.calls, "start:"+name+"-id")
+		if create < 0 || start < create {
+			t.Fatalf("calls = %v, want %s created then started", rt.calls, name)
+		}
+	}
+
+	var order []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.HasPrefix(line, "Started ") {
+			order = append(order, line)
+		}
+	}
+	want := []string{"Started a", "Started c"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("results = %q, want them in argument order %q", order, want)
+	}
+	if !strings.Contains(stderr, "Failed to start b: start b-id failed") {
+		t.Fatalf("failure of b missing from stderr: %q", stderr)
+	}
+}
+
+func TestStartCmd_RejectsZeroConcurrency(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{})
+	if _, _, err := runQuiet(t, NewStartCmd(), "--concurrency", "0", "web"); err == nil || !strings.Contains(err.Error(), "--concurrency") {
+		t.Fatalf("err = %v, want --concurrency rejected", err)
+	}
+}
@@ -1,21 +1,73 @@
 package cmds
 
 import (
+	"bufio"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"convoy/internal/orchestrator"
 )
 
+// confirmStopThreshold is how many containers stop --all removes without asking
+// for confirmation first.
+const confirmStopThreshold = 5
+
 // NewStopCmd creates the stop command for stopping containers.
 func NewStopCmd() *cobra.Command {
-	var stopAll bool
+	var (
+		stopAll    bool
+		quiet      bool
+		jsonEvents bool
+		signal     string
+		timeout    time.Duration
+		selector   string
+		yes        bool
+	)
 
 	cmd := &cobra.Command{
-		Use:          "stop [container-id]",
-		Short:        "Stop containers",
+		Use:   "stop [container-id]",
+		Short: "Stop containers",
+		Long: `Stop and remove containers. A running container's main process is sent
+its image's STOPSIGNAL (SIGTERM by default), or the --signal of your choice for
+applications that shut down cleanly on something else, and given the image's
+stop timeout or --timeout to exit. A container still running after that is
+force-killed with SIGKILL, which the output points out.
+
+--all can be narrowed with a label --selector. When it would remove more than
+5 containers, stop asks for confirmation on a terminal and refuses otherwise
+unless --yes is given.
+
+  convoy stop web --signal SIGINT --timeout 30s
+  convoy stop --all --selector app=web,tier!=db --yes`,
 		Args:         cobra.ArbitraryArgs,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if timeout < 0 {
+				return fmt.Errorf("--timeout must not be negative")
+			}
+			// Without --timeout each container gets its own stop timeout.
+			stopTimeout := time.Duration(-1)
+			if cmd.Flags().Changed("timeout") {
+				stopTimeout = timeout
+			}
+			if cmd.Flags().Changed("signal") {
+				parsed, err := orchestrator.ParseSignal(signal)
+				if err != nil {
+					return err
+				}
+				signal = parsed
+			}
+			if selector != "" && !stopAll {
+				return fmt.Errorf("--selector can only be used with --all")
+			}
+			sel, err := orchestrator.ParseSelector(selector)
+			if err != nil {
+				return err
+			}
+
 			app, err := getApp()
 			if err != nil {
 				return err
@@ -38,25 +90,40 @@ func NewStopCmd() *cobra.Command {
 				_ = registry.Register(container)
 			}
 
+			report := reporter{cmd: cmd, quiet: quiet, events: newEventEmitter(cmd.OutOrStdout(), jsonEvents)}
 			var targetIDs []string
+			var lastErr error
 			switch {
 			case stopAll:
-				targetIDs = containers.AllContainerIDs()
+				for _, id := range containers.AllContainerIDs() {
+					if c := containers.Resolve(id); c != nil && sel.Matches(c.Labels) {
+						targetIDs = append(targetIDs, id)
+					}
+				}
 				if len(targetIDs) == 0 {
-					_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No containers registered")
+					if selector != "" {
+						report.progress(event{Event: "stop_none"}, "No containers match %s", sel)
+						return nil
+					}
+					report.progress(event{Event: "stop_none"}, "No containers registered")
 					return nil
 				}
+				if len(targetIDs) > confirmStopThreshold && !yes {
+					if err := confirmStop(cmd, len(targetIDs)); err != nil {
+						return err
+					}
+				}
 			case len(args) == 0:
 				return fmt.Errorf("provide container names or IDs, or use -a")
 			default:
 				resolved, missing := containers.ResolveContainerIDs(args)
 				for _, m := range missing {
-					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Container not found: %s\n", m)
+					report.failure(event{Event: "stop_error", Container: m, Error: "container not found"}, "Container not found: %s", m)
+					lastErr = fmt.Errorf("container not found: %s", m)
 				}
 				targetIDs = resolved
 			}
 
-			var lastErr error
 			for _, containerID := range targetIDs {
 				container := containers.Resolve(containerID)
 				label := containerID
@@ -64,20 +131,32 @@ func NewStopCmd() *cobra.Command {
 					label = ContainerLabel(container)
 				}
 
-				if err := mgr.Stop(containerID); err != nil {
-					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Failed to stop %s: %v\n", label, err)
+				report.emit(event{Event: "stop_start", Container: label, ID: containerID})
+				result, err := mgr.StopGracefully(containerID, signal, stopTimeout)
+				if result.SignalErr != nil && signal != "" {
+					report.progress(event{Event: "stop_signal_error", Container: label, ID: containerID, Signal: signal, Error: result.SignalErr.Error()}, "Could not send %s to %s, stopping it instead: %v", signal, label, result.SignalErr)
+				}
+				if result.Signal != "" {
+					report.emit(event{Event: "stop_signal", Container: label, ID: containerID, Signal: result.Signal})
+				}
+				if err != nil {
+					report.failure(event{Event: "stop_error", Container: label, ID: containerID, Error: err.Error()}, "Failed to stop %s: %v", label, err)
 					lastErr = fmt.Errorf("stop %s: %w", label, err)
 					continue
 				}
 
 				if removeErr := mgr.Remove(containerID); removeErr != nil {
-					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Failed to remove %s: %v\n", label, removeErr)
+					report.failure(event{Event: "stop_error", Container: label, ID: containerID, Error: removeErr.Error()}, "Failed to remove %s: %v", label, removeErr)
 					lastErr = fmt.Errorf("remove %s: %w", label, removeErr)
 					continue
 				}
 
 				registry.Remove(containerID)
-				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Stopped and removed %s\n", label)
+				if result.ForceKilled {
+					report.done(event{Event: "stop_done", Container: label, ID: containerID, ForceKilled: true}, "Stopped and removed %s (force-killed after ignoring the stop signal for %s)", label, result.Timeout)
+					continue
+				}
+				report.done(event{Event: "stop_done", Container: label, ID: containerID}, "Stopped and removed %s", label)
 			}
 
 			return lastErr
@@ -85,6 +164,28 @@ func NewStopCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVarP(&stopAll, "all", "a", false, "Stop and remove all managed containers")
+	cmd.Flags().StringVar(&selector, "selector", "", "With --all, only stop containers whose labels match, e.g. app=web,tier!=db,canary")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation before stopping many containers")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print only the IDs of stopped containers")
+	cmd.Flags().BoolVar(&jsonEvents, "json", false, "Print one JSON event per line instead of human-readable output")
+	cmd.Flags().StringVarP(&signal, "signal", "s", "", "Signal asking containers to stop, e.g. SIGINT, HUP or 15 (default: the image's STOPSIGNAL, else SIGTERM)")
+	cmd.Flags().DurationVarP(&timeout, "timeout", "t", 0, "How long a container may take to exit before it is force-killed (default: the container's stop timeout, else 10s)")
 
 	return cmd
 }
+
+// confirmStop asks on the terminal whether n containers should really be
+// stopped and removed. Without a terminal to ask on it refuses, so scripts have
+// to pass --yes.
+func confirmStop(cmd *cobra.Command, n int) error {
+	if !isTerminal(cmd.InOrStdin()) {
+		return fmt.Errorf("refusing to stop %d containers without confirmation; pass --yes", n)
+	}
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Stop and remove %d containers? [y/N] ", n)
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("aborted; no containers stopped")
+}
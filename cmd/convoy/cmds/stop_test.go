@@ -0,0 +1,219 @@
+package cmds
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"convoy/internal/orchestrator"
+)
+
+// runQuiet executes cmd with args and returns what it wrote to stdout and stderr.
+func runQuiet(t *testing.T, cmd *cobra.Command, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return stdout.String(), stderr.String(), err
+}
+
+func TestStopCmd_QuietSendsFailuresToStderr(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web"}}})
+
+	stdout, stderr, err := runQuiet(t, NewStopCmd(), "--quiet", "web", "missing")
+	if err == nil {
+		t.Fatal("stop: expected an error for the missing container")
+	}
+	if stdout != "id-1\n" {
+		t.Fatalf("stdout = %q, want only the stopped ID", stdout)
+	}
+	if !strings.Contains(stderr, "Container not found: missing") {
+		t.Fatalf("stderr = %q, want the missing container reported", stderr)
+	}
+}
+
+func TestStopCmd_QuietFailsForUnknownContainer(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{})
+
+	stdout, _, err := runQuiet(t, NewStopCmd(), "--quiet", "ghost")
+	if err == nil || !strings.Contains(err.Error(), "container not found: ghost") {
+		t.Fatalf("err = %v, want container not found", err)
+	}
+	if stdout != "" {
+		t.Fatalf("stdout = %q, want nothing", stdout)
+	}
+}
+
+func TestStopCmd_JSONEvents(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web"}}})
+
+	stdout, stderr, err := runQuiet(t, NewStopCmd(), "--json", "web", "missing")
+	if err == nil {
+		t.Fatal("stop: expected an error for the missing container")
+	}
+	if strings.Contains(stderr, "Container not found") {
+		t.Fatalf("stderr = %q, want the failure as an event", stderr)
+	}
+
+	events := decodeEvents(t, stdout)
+	if got, want := eventNames(events), "stop_error,stop_start,stop_done"; got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+	if events[0].Container != "missing" || events[0].Error == "" {
+		t.Fatalf("missing container event = %+v", events[0])
+	}
+	if done := events[2]; done.Container != "web" || done.ID != "id-1" {
+		t.Fatalf("stop_done = %+v", done)
+	}
+}
+
+func TestStopCmd_SignalIsForwardedBeforeStop(t *testing.T) {
+	rt := &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web", Running: true}}}
+	useFakeApp(t, rt)
+
+	stdout, _, err := runQuiet(t, NewStopCmd(), "--signal", "int", "web")
+	if err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if got, want := strings.Join(rt.calls, ","), "kill:id-1:SIGINT,stop:id-1,remove:id-1"; got != want {
+		t.Fatalf("calls = %s, want %s", got, want)
+	}
+	if !strings.Contains(stdout, "Stopped and removed web") {
+		t.Fatalf("stdout = %q", stdout)
+	}
+}
+
+func TestStopCmd_RejectsUnknownSignal(t *testing.T) {
+	rt := &fakeRuntime{containers: []*orchestrator.Container{{ID: "id-1", Name: "web"}}}
+	useFakeApp(t, rt)
+
+	_, _, err := runQuiet(t, NewStopCmd(), "--signal", "SIGBOGUS", "web")
+	if err == nil || !strings.Contains(err.Error(), `invalid signal "SIGBOGUS"`) {
+		t.Fatalf("err = %v, want the signal rejected", err)
+	}
+	if len(rt.calls) != 0 {
+		t.Fatalf("calls = %v, want nothing touched", rt.calls)
+	}
+}
+
+func TestStopCmd_ForceKillsContainerIgnoringSignal(t *testing.T) {
+	rt := &fakeRuntime{
+		containers: []*orchestrator.Container{
+			{ID: "id-1", Name: "web", Running: true},
+			{ID: "id-2", Name: "db", Running: true},
+		},
+		ignoreSignals: map[string]bool{"id-1": true},
+	}
+	useFakeApp(t, rt)
+
+	stdout, _, err := runQuiet(t, NewStopCmd(), "--timeout", "50ms", "web", "db")
+	if err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	want := "kill:id-1:SIGTERM,kill:id-1:SIGKILL,stop:id-1,remove:id-1,kill:id-2:SIGTERM,stop:id-2,remove:id-2"
+	if got := strings.Join(rt.calls, ","); got != want {
+		t.Fatalf("calls = %s, want %s", got, want)
+	}
+	if !strings.Contains(stdout, "Stopped and removed web (force-killed after ignoring the stop signal for 50ms)") {
+		t.Fatalf("stdout = %q, want web reported as force-killed", stdout)
+	}
+	if !strings.Contains(stdout, "Stopped and removed db\n") {
+		t.Fatalf("stdout = %q, want db stopped gracefully", stdout)
+	}
+}
+
+func TestStopCmd_JSONReportsForceKill(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{
+		containers:    []*orchestrator.Container{{ID: "id-1", Name: "web", Running: true}},
+		ignoreSignals: map[string]bool{"id-1": true},
+	})
+
+	stdout, _, err := runQuiet(t, NewStopCmd(), "--json", "--timeout", "0", "web")
+	if err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	events := decodeEvents(t, stdout)
+	if got, want := eventNames(events), "stop_start,stop_signal,stop_done"; got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+	if events[1].Signal != "SIGTERM" || !events[2].ForceKilled {
+		t.Fatalf("events = %+v, want SIGTERM then a force-killed stop", events)
+	}
+}
+
+// labelledContainers returns n containers labelled app=web, the first one also tier=db.
+func labelledContainers(n int) []*orchestrator.Container {
+	containers := []*orchestrator.Container{{ID: "id-api", Name: "api", Labels: map[string]string{"app": "api"}}}
+	for i := 0; i < n; i++ {
+		labels := map[string]string{"app": "web"}
+		if i == 0 {
+			labels["tier"] = "db"
+		}
+		containers = append(containers, &orchestrator.Container{ID: fmt.Sprintf("id-%d", i), Name: fmt.Sprintf("web-%d", i), Labels: labels})
+	}
+	return containers
+}
+
+func TestStopCmd_AllWithSelectorStopsOnlyMatching(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: labelledContainers(3)})
+
+	stdout, _, err := runQuiet(t, NewStopCmd(), "--all", "--quiet", "--selector", "app=web,tier!=db")
+	if err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if stdout != "id-1\nid-2\n" {
+		t.Fatalf("stdout = %q, want only the matching containers stopped", stdout)
+	}
+
+	stdout, _, err = runQuiet(t, NewStopCmd(), "--all", "--selector", "app=nothing")
+	if err != nil || !strings.Contains(stdout, "No containers match app=nothing") {
+		t.Fatalf("stop = %q, %v; want nothing matched", stdout, err)
+	}
+
+	if _, _, err := runQuiet(t, NewStopCmd(), "--selector", "app=web", "web-1"); err == nil {
+		t.Fatalf("expected --selector without --all to be rejected")
+	}
+}
+
+func TestStopCmd_ConfirmsBeforeStoppingMany(t *testing.T) {
+	rt := &fakeRuntime{containers: labelledContainers(confirmStopThreshold + 1)}
+	useFakeApp(t, rt)
+	old := isTerminal
+	t.Cleanup(func() { isTerminal = old })
+
+	isTerminal = func(any) bool { return false }
+	_, _, err := runQuiet(t, NewStopCmd(), "--all", "--selector", "app=web")
+	if err == nil || !strings.Contains(err.Error(), "pass --yes") {
+		t.Fatalf("stop without a terminal = %v, want a refusal", err)
+	}
+
+	isTerminal = func(any) bool { return true }
+	cmd := NewStopCmd()
+	cmd.SetIn(strings.NewReader("n\n"))
+	_, stderr, err := runQuiet(t, cmd, "--all", "--selector", "app=web")
+	if err == nil || !strings.Contains(stderr, "Stop and remove 6 containers? [y/N]") {
+		t.Fatalf("stop answered no = %v, stderr %q; want the prompt and an abort", err, stderr)
+	}
+	if len(rt.calls) != 0 {
+		t.Fatalf("calls = %v, want nothing stopped before confirmation", rt.calls)
+	}
+
+	cmd = NewStopCmd()
+	cmd.SetIn(strings.NewReader("yes\n"))
+	if _, _, err := runQuiet(t, cmd, "--all", "--selector", "app=web", "--quiet"); err != nil {
+		t.Fatalf("stop answered yes: %v", err)
+	}
+
+	rt = &fakeRuntime{containers: labelledContainers(confirmStopThreshold + 1)}
+	useFakeApp(t, rt)
+	isTerminal = func(any) bool { return false }
+	stdout, _, err := runQuiet(t, NewStopCmd(), "--all", "--yes", "--quiet")
+	if err != nil || strings.Count(stdout, "\n") != confirmStopThreshold+2 {
+		t.Fatalf("stop --yes = %q, %v; want every container stopped", stdout, err)
+	}
+}
@@ -0,0 +1,121 @@
+package cmds
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTranscriptLine is how much of an unterminated line a transcript holds
+// before writing it out anyway.
+const maxTranscriptLine = 64 * 1024
+
+// transcript records a shell session for auditing: one timestamped line per
+// line of input or output, tagged with its stream. Control characters such as
+// terminal escape sequences are written as \xNN so the file stays plain text.
+type transcript struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+	// partial holds each stream's unterminated line until the rest arrives;
+	// afterCR marks streams whose last byte ended a line with CR, so the LF
+	// of a CRLF split across writes does not make an empty line.
+	partial map[string][]byte
+	afterCR map[string]bool
+}
+
+func newTranscript(w io.Writer) *transcript {
+	return &transcript{w: w, now: time.Now, partial: make(map[string][]byte), afterCR: make(map[string]bool)}
+}
+
+// event records a line about the session itself, such as its start or exit,
+// after any unterminated lines so the transcript keeps them in order.
+func (t *transcript) event(kind, format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flushLocked()
+	t.writeLine(kind, strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+// record writes data seen on stream, one transcript line per line of data. A
+// line ends at LF, CR or CRLF, since raw terminal input ends lines with CR; a
+// line that has not ended yet waits for the next write on the same stream.
+func (t *transcript) record(stream string, data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, c := range data {
+		afterCR := t.afterCR[stream]
+		t.afterCR[stream] = c == '\r'
+		switch {
+		case c == '\n' && afterCR:
+		case c == '\n' || c == '\r':
+			t.writeLine(stream, escapeControl(t.partial[stream]))
+			t.partial[stream] = t.partial[stream][:0]
+		default:
+			t.partial[stream] = append(t.partial[stream], c)
+			if len(t.partial[stream]) >= maxTranscriptLine {
+				t.writeLine(stream, escapeControl(t.partial[stream]))
+				t.partial[stream] = t.partial[stream][:0]
+			}
+		}
+	}
+}
+
+// flushLocked writes out every stream's unterminated line.
+func (t *transcript) flushLocked() {
+	streams := make([]string, 0, len(t.partial))
+	for stream, line := range t.partial {
+		if len(line) > 0 {
+			streams = append(streams, stream)
+		}
+	}
+	sort.Strings(streams)
+	for _, stream := range streams {
+		t.writeLine(stream, escapeControl(t.partial[stream]))
+		t.partial[stream] = t.partial[stream][:0]
+	}
+}
+
+func (t *transcript) writeLine(kind, text string) {
+	_, _ = fmt.Fprintf(t.w, "%s %-5s %s\n", t.now().UTC().Format("2006-01-02T15:04:05.000Z"), kind, text)
+}
+
+// reader returns r, recording everything read from it as stream.
+func (t *transcript) reader(stream string, r io.Reader) io.Reader {
+	return io.TeeReader(r, transcriptStream{t, stream})
+}
+
+// writer returns w, recording everything written to it as stream.
+func (t *transcript) writer(stream string, w io.Writer) io.Writer {
+	return io.MultiWriter(w, transcriptStream{t, stream})
+}
+
+type transcriptStream struct {
+	t      *transcript
+	stream string
+}
+
+func (s transcriptStream) Write(p []byte) (int, error) {
+	s.t.record(s.stream, p)
+	return len(p), nil
+}
+
+// escapeControl returns data as text with control characters other than tab,
+// and backslashes, escaped.
+func escapeControl(data []byte) string {
+	var b strings.Builder
+	for _, c := range data {
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '\t' || (c >= 0x20 && c != 0x7f):
+			b.WriteByte(c)
+		default:
+			_, _ = fmt.Fprintf(&b, `\x%02x`, c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package cmds
+
+import (
+	"github.com/moby/term"
+)
+
+// isTerminal reports whether stream is an interactive terminal. Tests replace it
+// to exercise the terminal code paths without one.
+var isTerminal = func(stream any) bool {
+	fd, ok := term.GetFdInfo(stream)
+	return ok && term.IsTerminal(fd)
+}
+
+// terminalSize returns the rows and columns of the terminal behind stream, or
+// zeros when it has none, which leaves the choice to the agent.
+func terminalSize(stream any) (rows, cols uint32) {
+	fd, ok := term.GetFdInfo(stream)
+	if !ok {
+		return 0, 0
+	}
+	size, err := term.GetWinsize(fd)
+	if err != nil {
+		return 0, 0
+	}
+	return uint32(size.Height), uint32(size.Width)
+}
+
+// rawTerminal puts the terminal behind stream into raw mode, so keys reach the
+// remote program as typed, and returns the function restoring it. It does
+// nothing when stream is not a terminal.
+func rawTerminal(stream any) (restore func(), err error) {
+	fd, ok := term.GetFdInfo(stream)
+	if !ok || !term.IsTerminal(fd) {
+		return func() {}, nil
+	}
+	state, err := term.SetRawTerminal(fd)
+	if err != nil {
+		return nil, err
+	}
+	return func() { _ = term.RestoreTerminal(fd, state) }, nil
+}
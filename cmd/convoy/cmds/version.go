@@ -0,0 +1,70 @@
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"text/tabwriter"
+
+	convoypb "convoy/api"
+
+	"github.com/spf13/cobra"
+)
+
+// BuildInfo describes the convoy binary. The main package fills it from
+// variables set with -ldflags at build time.
+type BuildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+}
+
+// versionJSON is the JSON rendering of the version command.
+type versionJSON struct {
+	BuildInfo
+	GoVersion       string `json:"go_version"`
+	ProtocolVersion string `json:"protocol_version"`
+}
+
+// NewVersionCmd creates the version command for printing build metadata.
+func NewVersionCmd(info BuildInfo) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print version information",
+		Long: `Print the convoy version, the commit and date it was built from, the Go
+version it was built with and the agent protocol version it speaks. Include
+this in bug reports.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payload := versionJSON{
+				BuildInfo:       info,
+				GoVersion:       runtime.Version(),
+				ProtocolVersion: convoypb.ProtocolVersion,
+			}
+
+			switch output {
+			case "text":
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 1, ' ', 0)
+				_, _ = fmt.Fprintf(w, "Version:\t%s\n", payload.Version)
+				_, _ = fmt.Fprintf(w, "Commit:\t%s\n", payload.Commit)
+				_, _ = fmt.Fprintf(w, "Built:\t%s\n", payload.Date)
+				_, _ = fmt.Fprintf(w, "Go version:\t%s\n", payload.GoVersion)
+				_, _ = fmt.Fprintf(w, "Protocol:\t%s\n", payload.ProtocolVersion)
+				return w.Flush()
+			case "json":
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(payload)
+			default:
+				return fmt.Errorf("unsupported output format %q (want text or json)", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
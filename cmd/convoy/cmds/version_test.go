@@ -0,0 +1,36 @@
+package cmds
+
+import (
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	convoypb "convoy/api"
+)
+
+func TestVersionCmd_PrintsBuildInfo(t *testing.T) {
+	info := BuildInfo{Version: "v1.2.3", Commit: "abc1234", Date: "2026-01-02T03:04:05Z"}
+
+	stdout, _, err := runQuiet(t, NewVersionCmd(info))
+	if err != nil {
+		t.Fatalf("version: %v", err)
+	}
+	for _, want := range []string{"v1.2.3", "abc1234", "2026-01-02T03:04:05Z", runtime.Version(), convoypb.ProtocolVersion} {
+		if !strings.Contains(stdout, want) {
+			t.Fatalf("output %q does not mention %q", stdout, want)
+		}
+	}
+
+	stdout, _, err = runQuiet(t, NewVersionCmd(info), "-o", "json")
+	if err != nil {
+		t.Fatalf("version -o json: %v", err)
+	}
+	var got versionJSON
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("decode %q: %v", stdout, err)
+	}
+	if got.BuildInfo != info || got.GoVersion != runtime.Version() || got.ProtocolVersion != convoypb.ProtocolVersion {
+		t.Fatalf("json = %+v", got)
+	}
+}
@@ -0,0 +1,197 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"convoy/internal/orchestrator"
+
+	"github.com/spf13/cobra"
+)
+
+// waitCondition is a state convoy wait can wait for.
+type waitCondition struct {
+	// check reports whether the container ref is in the state, and if not, why.
+	check func(ctx context.Context, w *waitSources, ref string) (bool, string)
+	// done describes a container once it is in the state.
+	done string
+}
+
+// waitSources are where the wait conditions look: the container list, the
+// runtime and the agents.
+type waitSources struct {
+	containers *ContainerIndex
+	mgr        *orchestrator.Manager
+	rpc        *orchestrator.RPC
+}
+
+var waitConditions = map[string]waitCondition{
+	"healthy": {check: waitHealthy, done: "%s is healthy"},
+	"running": {check: waitRunning, done: "%s is running"},
+	"removed": {check: waitRemoved, done: "%s has been removed"},
+}
+
+// NewWaitCmd creates the wait command for blocking until containers reach a state.
+func NewWaitCmd() *cobra.Command {
+	var (
+		condition string
+		timeout   time.Duration
+		interval  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "wait container-id|name... --for healthy|running|removed",
+		Short: "Wait until containers reach a state",
+		Long: `Block until every given container reaches a state, checking every --interval,
+and exit 0 once they all have. If --timeout passes first, the containers still
+waited for are listed on stderr with the reason and the command fails.
+
+  healthy  the agent answers healthy and the container's own healthcheck, if
+           it has one, reports healthy
+  running  the runtime reports the container running
+  removed  the container no longer exists; a name that is not known counts
+
+  convoy start web && convoy wait web --for healthy --timeout 2m`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cond, ok := waitConditions[condition]
+			if !ok {
+				return fmt.Errorf("unsupported condition %q (want healthy, running or removed)", condition)
+			}
+			if timeout <= 0 || interval <= 0 {
+				return errors.New("--timeout and --interval must be positive")
+			}
+
+			app, err := getApp()
+			if err != nil {
+				return err
+			}
+			mgr, err := app.Manager()
+			if err != nil {
+				return err
+			}
+			rpc := NewRPCClientWithTimeout(min(interval, 5*time.Second))
+			defer func() {
+				_ = rpc.Close()
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			pending := slices.Compact(slices.Sorted(slices.Values(args)))
+			reasons := make([]string, len(pending))
+			for {
+				// The list is reloaded every round, as containers come and go while waiting.
+				containers, err := LoadContainers()
+				if err != nil {
+					return err
+				}
+				sources := &waitSources{containers: containers, mgr: mgr, rpc: rpc.RPC}
+
+				// Checks are bounded by the RPC timeout rather than ctx, so a round
+				// cut short by the deadline still reports why each container failed.
+				met := make([]bool, len(pending))
+				var wg sync.WaitGroup
+				for i, ref := range pending {
+					wg.Add(1)
+					go func(i int, ref string) {
+						defer wg.Done()
+						met[i], reasons[i] = cond.check(context.Background(), sources, ref)
+					}(i, ref)
+				}
+				wg.Wait()
+
+				var still []string
+				var stillReasons []string
+				for i, ref := range pending {
+					if met[i] {
+						_, _ = fmt.Fprintf(cmd.OutOrStdout(), cond.done+"\n", ref)
+						continue
+					}
+					still = append(still, ref)
+					stillReasons = append(stillReasons, reasons[i])
+				}
+				pending, reasons = still, stillReasons
+				if len(pending) == 0 {
+					return nil
+				}
+
+				select {
+				case <-ctx.Done():
+					for i, ref := range pending {
+						_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", ref, reasons[i])
+					}
+					return fmt.Errorf("timed out after %s waiting for %s to be %s", timeout, strings.Join(pending, ", "), condition)
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&condition, "for", "healthy", "State to wait for: healthy, running or removed")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Minute, "Give up and fail after this long")
+	cmd.Flags().DurationVar(&interval, "interval", time.Second, "Time between checks")
+
+	return cmd
+}
+
+func waitHealthy(ctx context.Context, w *waitSources, ref string) (bool, string) {
+	c := w.containers.Resolve(ref)
+	if c == nil {
+		return false, "container not found"
+	}
+	if c.Endpoint == "" {
+		return false, "no agent endpoint"
+	}
+	if result := orchestrator.ProbeHealth(ctx, w.rpc, ref, c.Endpoint); !result.Healthy {
+		return false, "agent " + string(stateOf(result)) + ": " + result.Message
+	}
+	if c.ID != "" {
+		health, err := w.mgr.HealthStatus(c.ID)
+		if err != nil {
+			return false, err.Error()
+		}
+		if health == orchestrator.ContainerHealthStarting || health == orchestrator.ContainerHealthUnhealthy {
+			return false, "healthcheck " + string(health)
+		}
+	}
+	return true, ""
+}
+
+func waitRunning(_ context.Context, w *waitSources, ref string) (bool, string) {
+	c := w.containers.Resolve(ref)
+	if c == nil {
+		return false, "container not found"
+	}
+	state, err := w.mgr.Inspect(c.ID)
+	if err != nil {
+		return false, err.Error()
+	}
+	if !state.Running {
+		return false, "container " + state.Status
+	}
+	return true, ""
+}
+
+func waitRemoved(_ context.Context, w *waitSources, ref string) (bool, string) {
+	c := w.containers.Resolve(ref)
+	if c == nil {
+		return true, ""
+	}
+	state, err := w.mgr.Inspect(c.ID)
+	if errors.Is(err, orchestrator.ErrContainerNotFound) {
+		return true, ""
+	}
+	if err != nil {
+		return false, err.Error()
+	}
+	return false, "container still exists (" + state.Status + ")"
+}
@@ -0,0 +1,66 @@
+package cmds
+
+import (
+	"strings"
+	"testing"
+
+	convoypb "convoy/api"
+	"convoy/internal/orchestrator"
+)
+
+func TestWaitCmd_Healthy(t *testing.T) {
+	endpoint := startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_HEALTHY})
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "web", Endpoint: endpoint},
+		{ID: "id-2", Name: "db", Endpoint: endpoint},
+	}})
+
+	stdout, _, err := runQuiet(t, NewWaitCmd(), "web", "db", "--for", "healthy", "--timeout", "5s", "--interval", "10ms")
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if stdout != "db is healthy\nweb is healthy\n" {
+		t.Fatalf("stdout = %q", stdout)
+	}
+}
+
+func TestWaitCmd_TimesOutOnFailingHealthcheck(t *testing.T) {
+	endpoint := startFakeAgent(t, &healthServer{status: convoypb.HealthResponse_STATUS_HEALTHY})
+	useFakeApp(t, &fakeRuntime{
+		containers: []*orchestrator.Container{
+			{ID: "id-1", Name: "web", Endpoint: endpoint},
+			{ID: "id-2", Name: "db", Endpoint: endpoint},
+		},
+		health: map[string]orchestrator.ContainerHealth{"id-2": orchestrator.ContainerHealthStarting},
+	})
+
+	stdout, stderr, err := runQuiet(t, NewWaitCmd(), "web", "db", "--timeout", "100ms", "--interval", "10ms")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms waiting for db to be healthy") {
+		t.Fatalf("err = %v, want a timeout for db", err)
+	}
+	if stdout != "web is healthy\n" || !strings.HasPrefix(stderr, "db: healthcheck starting\n") {
+		t.Fatalf("stdout = %q, stderr = %q", stdout, stderr)
+	}
+}
+
+func TestWaitCmd_RunningAndRemoved(t *testing.T) {
+	useFakeApp(t, &fakeRuntime{containers: []*orchestrator.Container{
+		{ID: "id-1", Name: "web", Running: true},
+		{ID: "id-2", Name: "db"},
+	}})
+
+	if stdout, _, err := runQuiet(t, NewWaitCmd(), "web", "--for", "running"); err != nil || stdout != "web is running\n" {
+		t.Fatalf("wait running = %q, %v", stdout, err)
+	}
+	if stdout, _, err := runQuiet(t, NewWaitCmd(), "old", "--for", "removed"); err != nil || stdout != "old has been removed\n" {
+		t.Fatalf("wait removed = %q, %v", stdout, err)
+	}
+
+	_, stderr, err := runQuiet(t, NewWaitCmd(), "db", "--for", "running", "--timeout", "50ms", "--interval", "10ms")
+	if err == nil || !strings.HasPrefix(stderr, "db: container exited\n") {
+		t.Fatalf("wait for a stopped container = %v, stderr %q", err, stderr)
+	}
+	if _, _, err := runQuiet(t, NewWaitCmd(), "db", "--for", "ready"); err == nil || !strings.Contains(err.Error(), "unsupported condition") {
+		t.Fatalf("unknown condition error = %v", err)
+	}
+}
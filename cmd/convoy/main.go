@@ -1,12 +1,28 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"os"
+
+	"convoy/cmd/convoy/cmds"
+)
+
+// Build metadata, set at build time with
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse --short HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
 )
 
 func main() {
 	if err := Execute(); err != nil {
+		var exitErr *cmds.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		log.Printf("error: %v", err)
 		os.Exit(1)
 	}
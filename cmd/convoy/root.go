@@ -1,9 +1,12 @@
 package main
 
 import (
+	"os"
+	"strings"
 	"sync"
 
 	"convoy/cmd/convoy/cmds"
+	"convoy/internal/app"
 
 	"github.com/spf13/cobra"
 )
@@ -18,9 +21,11 @@ var (
 
 	cliOpts struct {
 		configPath string
+		profile    string
+		dockerHost string
 	}
 
-	runtimeFactory RuntimeFactory = dockerRuntimeFactory
+	runtimeFactory RuntimeFactory = configuredRuntimeFactory
 
 	appOnce     sync.Once
 	appInstance *Application
@@ -34,7 +39,11 @@ func Execute() error {
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cliOpts.configPath, "config", "", "Path to config file (defaults to ~/.config/convoy/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cliOpts.profile, "profile", "", "Config profile to use instead of the current context (see convoy context)")
+	rootCmd.PersistentFlags().StringVar(&cliOpts.dockerHost, "docker-host", "", "Docker daemon address (overrides docker_host from config and $DOCKER_HOST)")
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		cmds.CLIOpts.ConfigPath = cliOpts.configPath
+		cmds.CLIOpts.Profile = cliOpts.profile
 		if shouldSkipAppInit(cmd) {
 			return nil
 		}
@@ -46,6 +55,7 @@ func init() {
 	cmds.GetAppFunc = func() (cmds.AppProvider, error) {
 		// Sync CLI options to cmds package
 		cmds.CLIOpts.ConfigPath = cliOpts.configPath
+		cmds.CLIOpts.Profile = cliOpts.profile
 		return getApp()
 	}
 
@@ -53,12 +63,24 @@ func init() {
 		cmds.NewConfigCmd(),
 		cmds.NewListCmd(),
 		cmds.NewHealthCmd(),
+		cmds.NewWaitCmd(),
+		cmds.NewCreateCmd(),
 		cmds.NewStartCmd(),
 		cmds.NewStopCmd(),
+		cmds.NewRestartCmd(),
 		cmds.NewRemoveCmd(),
 		cmds.NewExecCmd(),
+		cmds.NewJobsCmd(),
+		cmds.NewLogsCmd(),
+		cmds.NewCancelCmd(),
+		cmds.NewRunCmd(),
+		cmds.NewAPICmd(),
 		cmds.NewShellCmd(),
 		cmds.NewCopyCmd(),
+		cmds.NewCopyBatchCmd(),
+		cmds.NewSecretCmd(),
+		cmds.NewContextCmd(),
+		cmds.NewVersionCmd(cmds.BuildInfo{Version: version, Commit: commit, Date: date}),
 	)
 }
 
@@ -67,7 +89,22 @@ func shouldSkipAppInit(cmd *cobra.Command) bool {
 		return false
 	}
 
-	if cmd.Name() == "init" && cmd.HasParent() && cmd.Parent().Name() == "config" {
+	if (cmd.Name() == "init" || cmd.Name() == "validate") && cmd.HasParent() && cmd.Parent().Name() == "config" {
+		return true
+	}
+
+	// The version must print even without a usable config.
+	if cmd.Name() == "version" && cmd.HasParent() && !cmd.Parent().HasParent() {
+		return true
+	}
+
+	// An explicit agent address needs neither the config nor the container list.
+	if flag := cmd.Flags().Lookup("endpoint"); flag != nil && flag.Changed {
+		return true
+	}
+
+	// Switching contexts must work even when the current one has a broken config.
+	if cmd.HasParent() && cmd.Parent().Name() == "context" {
 		return true
 	}
 
@@ -80,7 +117,13 @@ func initializeApplication() error {
 	}
 
 	appOnce.Do(func() {
-		appInstance = newApplication(cliOpts.configPath, runtimeFactory)
+		cfgPath, err := app.ResolveConfigPath(cliOpts.configPath, cliOpts.profile)
+		if err != nil {
+			appInitErr = err
+			return
+		}
+		appInstance = newApplication(cfgPath, runtimeFactory)
+		appInstance.dockerHost = resolveDockerHost(cliOpts.dockerHost)
 		_, appInitErr = appInstance.Config()
 	})
 
@@ -94,3 +137,13 @@ func getApp() (*Application, error) {
 
 	return appInstance, nil
 }
+
+// resolveDockerHost picks the Docker daemon override: the --docker-host flag
+// wins, then $DOCKER_HOST. An empty result keeps the config file value.
+func resolveDockerHost(flagValue string) string {
+	if host := strings.TrimSpace(flagValue); host != "" {
+		return host
+	}
+
+	return strings.TrimSpace(os.Getenv("DOCKER_HOST"))
+}
@@ -7,6 +7,29 @@ import (
 	"convoy/internal/orchestrator"
 )
 
+// runtimeFactories maps each runtime config value to the factory building it.
+// Register new runtimes here.
+var runtimeFactories = map[string]RuntimeFactory{
+	app.RuntimeDocker: dockerRuntimeFactory,
+	app.RuntimePodman: podmanRuntimeFactory,
+	app.RuntimeMock:   mockRuntimeFactory,
+}
+
+// configuredRuntimeFactory builds the runtime selected by cfg.Runtime,
+// defaulting to Docker when unset.
+func configuredRuntimeFactory(cfg *app.Config) (orchestrator.Runtime, error) {
+	name := cfg.Runtime
+	if name == "" {
+		name = app.RuntimeDocker
+	}
+
+	factory, ok := runtimeFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown runtime %q", name)
+	}
+	return factory(cfg)
+}
+
 // dockerRuntimeFactory builds the Docker runtime using application config.
 func dockerRuntimeFactory(cfg *app.Config) (orchestrator.Runtime, error) {
 	dRuntime, err := orchestrator.NewDockerRuntime(cfg)
@@ -16,3 +39,18 @@ func dockerRuntimeFactory(cfg *app.Config) (orchestrator.Runtime, error) {
 
 	return dRuntime, nil
 }
+
+// podmanRuntimeFactory builds the Podman runtime using application config.
+func podmanRuntimeFactory(cfg *app.Config) (orchestrator.Runtime, error) {
+	pRuntime, err := orchestrator.NewPodmanRuntime(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("init podman runtime: %w", err)
+	}
+
+	return pRuntime, nil
+}
+
+// mockRuntimeFactory builds the file-backed mock runtime.
+func mockRuntimeFactory(cfg *app.Config) (orchestrator.Runtime, error) {
+	return orchestrator.NewMockRuntime(cfg), nil
+}
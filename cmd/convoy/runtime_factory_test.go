@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"convoy/internal/app"
+	"convoy/internal/orchestrator"
+)
+
+func TestConfiguredRuntimeFactory(t *testing.T) {
+	newCfg := func(runtime string) *app.Config {
+		return &app.Config{Runtime: runtime, Image: "alpine", DockerHost: "unix:///tmp/convoy-test.sock", AgentGRPCPort: 6000}
+	}
+
+	for _, name := range []string{"", app.RuntimeDocker} {
+		rt, err := configuredRuntimeFactory(newCfg(name))
+		if err != nil {
+			t.Fatalf("runtime %q: %v", name, err)
+		}
+		if _, ok := rt.(*orchestrator.DockerRuntime); !ok {
+			t.Fatalf("runtime %q built %T, want *orchestrator.DockerRuntime", name, rt)
+		}
+	}
+
+	rt, err := configuredRuntimeFactory(newCfg(app.RuntimePodman))
+	if err != nil {
+		t.Fatalf("podman: %v", err)
+	}
+	if _, ok := rt.(*orchestrator.PodmanRuntime); !ok {
+		t.Fatalf("podman built %T, want *orchestrator.PodmanRuntime", rt)
+	}
+
+	rt, err = configuredRuntimeFactory(newCfg(app.RuntimeMock))
+	if err != nil {
+		t.Fatalf("mock: %v", err)
+	}
+	if _, ok := rt.(*orchestrator.MockRuntime); !ok {
+		t.Fatalf("mock built %T, want *orchestrator.MockRuntime", rt)
+	}
+
+	if _, err := configuredRuntimeFactory(newCfg("containerd")); err == nil {
+		t.Fatalf("expected an error for an unknown runtime")
+	}
+}
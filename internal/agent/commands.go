@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	pathpkg "path"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// allowAllCommands is the allowlist entry that permits every command.
+const allowAllCommands = "*"
+
+// shellNames are the shells whose -c script checkCommand inspects.
+var shellNames = []string{"sh", "bash", "dash", "ash", "zsh", "ksh", "mksh"}
+
+// scriptMetachars separate, chain or substitute commands in a shell script.
+// A script containing any of them is not a single command.
+const scriptMetachars = ";&|`()\n"
+
+// programMetachars make the first word of a script something other than the
+// literal program it runs: quoting, expansion, globbing or a variable assignment.
+const programMetachars = "'\"\\$*?[=~{"
+
+// shellRunners are keywords and builtins that run another command, so a script
+// starting with one of them is not judged by its first word.
+var shellRunners = []string{"exec", "command", "builtin", "eval", "time", "nohup", ".", "source", "!", "if", "while", "until", "for", "case"}
+
+// checkCommand reports PermissionDenied when argv may not be run under the
+// configured command lists. Entries are glob patterns matched against the base
+// name of the program; a denylist match wins over an allowlist match, and an
+// empty allowlist permits everything.
+//
+// A shell run with -c, as convoy exec does by default, is judged by the program
+// its script runs, so allowing sh does not allow everything. The script must be
+// a single command for that: one that chains, pipes or substitutes commands is
+// refused whenever either list restricts anything, since the agent cannot tell
+// what it would run. The shell itself is still subject to the denylist.
+func (s *Server) checkCommand(ctx context.Context, argv []string) error {
+	name := pathpkg.Base(argv[0])
+	if matchesAny(s.cfg.DeniedCommands, name) {
+		return s.denyCommand(ctx, name, "denied_commands")
+	}
+
+	script, ok := shellScript(argv)
+	if !ok {
+		if len(s.cfg.AllowedCommands) > 0 && !matchesAny(s.cfg.AllowedCommands, name) {
+			return s.denyCommand(ctx, name, "allowed_commands")
+		}
+		return nil
+	}
+
+	words, simple := singleCommand(script)
+	if !simple {
+		if s.restrictsCommands() {
+			s.logf("agent %s: blocked %s script %q from %s (not a single command) request_id=%s", s.cfg.AgentID, name, script, peerKey(ctx), requestID(ctx))
+			return status.Errorf(codes.PermissionDenied, "%s scripts that chain or substitute commands are not permitted on this agent", name)
+		}
+		return nil
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	return s.checkCommand(ctx, words)
+}
+
+// singleCommand splits a shell script into words when it runs exactly one
+// program named literally by its first word.
+func singleCommand(script string) ([]string, bool) {
+	if strings.ContainsAny(script, scriptMetachars) {
+		return nil, false
+	}
+	words := strings.Fields(script)
+	if len(words) > 0 && (strings.ContainsAny(words[0], programMetachars) || slices.Contains(shellRunners, words[0])) {
+		return nil, false
+	}
+	return words, true
+}
+
+// restrictsCommands reports whether the command lists refuse anything at all.
+func (s *Server) restrictsCommands() bool {
+	allowAll := len(s.cfg.AllowedCommands) == 0 || slices.Contains(s.cfg.AllowedCommands, allowAllCommands)
+	return !allowAll || len(s.cfg.DeniedCommands) > 0
+}
+
+// shellScript returns the script argv passes to a known shell with -c, and
+// whether it does so at all.
+func shellScript(argv []string) (string, bool) {
+	if !slices.Contains(shellNames, pathpkg.Base(argv[0])) {
+		return "", false
+	}
+	for i := 1; i < len(argv); i++ {
+		arg := argv[i]
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') || arg == "--" {
+			return "", false
+		}
+		if arg == "-o" || arg == "+o" {
+			// The next argument names the option.
+			i++
+			continue
+		}
+		if arg[0] == '-' && !strings.HasPrefix(arg, "--") && strings.Contains(arg[1:], "c") {
+			if i+1 < len(argv) {
+				return argv[i+1], true
+			}
+			return "", true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) denyCommand(ctx context.Context, name, list string) error {
+	s.logf("agent %s: blocked command %q from %s (%s) request_id=%s", s.cfg.AgentID, name, peerKey(ctx), list, requestID(ctx))
+	return status.Errorf(codes.PermissionDenied, "command %q is not permitted on this agent", name)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := pathpkg.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		program string
+		want    codes.Code
+	}{
+		{name: "default allows all", program: "/usr/bin/curl", want: codes.OK},
+		{name: "wildcard allows all", allowed: []string{"*"}, program: "rm", want: codes.OK},
+		{name: "allowlist match", allowed: []string{"ls", "cat"}, program: "/bin/cat", want: codes.OK},
+		{name: "allowlist glob", allowed: []string{"python*"}, program: "python3", want: codes.OK},
+		{name: "not on allowlist", allowed: []string{"ls", "cat"}, program: "/bin/rm", want: codes.PermissionDenied},
+		{name: "denylist", denied: []string{"rm"}, program: "/bin/rm", want: codes.PermissionDenied},
+		{name: "denylist other", denied: []string{"rm"}, program: "ls", want: codes.OK},
+		{name: "deny wins over allow", allowed: []string{"*"}, denied: []string{"rm"}, program: "rm", want: codes.PermissionDenied},
+		{name: "deny wins over explicit allow", allowed: []string{"rm", "ls"}, denied: []string{"rm"}, program: "rm", want: codes.PermissionDenied},
+		{name: "both lists, allowed and not denied", allowed: []string{"rm", "ls"}, denied: []string{"rm"}, program: "ls", want: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer(&Config{AgentID: "test", AllowedCommands: tt.allowed, DeniedCommands: tt.denied})
+			if got := status.Code(srv.checkCommand(context.Background(), []string{tt.program})); got != tt.want {
+				t.Fatalf("checkCommand(%q) = %s, want %s", tt.program, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCommand_ShellScripts(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		args    []string
+		want    codes.Code
+	}{
+		{name: "default allows any script", args: []string{"sh", "-c", "ls | wc -l"}, want: codes.OK},
+		{name: "script program allowed", allowed: []string{"ls"}, args: []string{"sh", "-c", "ls -la /tmp"}, want: codes.OK},
+		{name: "script program not allowed", allowed: []string{"ls"}, args: []string{"sh", "-c", "rm -rf /tmp/x"}, want: codes.PermissionDenied},
+		{name: "allowing the shell does not allow its script", allowed: []string{"sh"}, args: []string{"/bin/sh", "-c", "rm -rf /tmp/x"}, want: codes.PermissionDenied},
+		{name: "allowed shell without a script", allowed: []string{"sh"}, args: []string{"sh"}, want: codes.OK},
+		{name: "combined flags", allowed: []string{"ls"}, args: []string{"bash", "-lc", "ls"}, want: codes.OK},
+		{name: "option argument skipped", allowed: []string{"ls"}, args: []string{"bash", "-o", "pipefail", "-c", "rm x"}, want: codes.PermissionDenied},
+		{name: "denied script program", denied: []string{"rm"}, args: []string{"sh", "-c", "rm -rf /tmp/x"}, want: codes.PermissionDenied},
+		{name: "nested shell", allowed: []string{"ls"}, args: []string{"sh", "-c", "sh -c rm"}, want: codes.PermissionDenied},
+		{name: "chained script under allowlist", allowed: []string{"ls"}, args: []string{"sh", "-c", "ls; rm -rf /"}, want: codes.PermissionDenied},
+		{name: "substitution under denylist", denied: []string{"rm"}, args: []string{"sh", "-c", "echo $(rm -rf /)"}, want: codes.PermissionDenied},
+		{name: "quoted program under denylist", denied: []string{"rm"}, args: []string{"sh", "-c", "'rm' -rf /"}, want: codes.PermissionDenied},
+		{name: "exec wrapper under denylist", denied: []string{"rm"}, args: []string{"sh", "-c", "exec rm -rf /"}, want: codes.PermissionDenied},
+		{name: "quoted arguments are fine", allowed: []string{"echo"}, args: []string{"sh", "-c", "echo 'hello world' $HOME"}, want: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer(&Config{AgentID: "test", AllowedCommands: tt.allowed, DeniedCommands: tt.denied})
+			if got := status.Code(srv.checkCommand(context.Background(), tt.args)); got != tt.want {
+				t.Fatalf("checkCommand(%q) = %s, want %s", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandLists_EnforcedOnRPCs(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{
+		AgentID:         "test",
+		MaxConcurrent:   1,
+		AllowedCommands: []string{"echo", "sh"},
+		DeniedCommands:  []string{"sh"},
+	}))
+	ctx := context.Background()
+
+	resp, err := client.ExecuteCommand(ctx, &convoypb.CommandRequest{Args: []string{"echo", "hi"}})
+	if err != nil || resp.GetStdout() != "hi\n" {
+		t.Fatalf("allowed command: resp=%+v err=%v", resp, err)
+	}
+
+	// The default convoy exec wrapping is judged by the program the script runs.
+	resp, err = client.ExecuteCommand(ctx, &convoypb.CommandRequest{Args: []string{"bash", "-c", "echo wrapped"}})
+	if err != nil || resp.GetStdout() != "wrapped\n" {
+		t.Fatalf("allowed command in a shell script: resp=%+v err=%v", resp, err)
+	}
+
+	if _, err := client.ExecuteCommand(ctx, &convoypb.CommandRequest{Args: []string{"/bin/sh", "-c", "true"}}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("denied command: got %v, want PermissionDenied", err)
+	}
+
+	stream, err := client.ExecuteCommandStream(ctx, &convoypb.CommandRequest{Args: []string{"cat"}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("streamed command off the allowlist: got %v, want PermissionDenied", err)
+	}
+
+	shell, err := client.ExecuteShell(ctx)
+	if err != nil {
+		t.Fatalf("ExecuteShell: %v", err)
+	}
+	if err := shell.Send(&convoypb.ShellRequest{Payload: &convoypb.ShellRequest_Start{
+		Start: &convoypb.ShellStart{Args: []string{"sh"}},
+	}}); err != nil {
+		t.Fatalf("send start: %v", err)
+	}
+	_, err = shell.Recv()
+	if err == io.EOF || status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("denied shell: got %v, want PermissionDenied", err)
+	}
+}
@@ -1,15 +1,20 @@
 package agent
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
 	"os"
+	pathpkg "path"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	convoypb "convoy/api"
+	"convoy/internal/configfile"
 )
 
 const (
@@ -25,15 +30,109 @@ type Config struct {
 	MaxConcurrent int
 	ExecTimeout   time.Duration
 	AgentID       string
+	AgentIDFile   string
 	ConfigPath    string
+	// Listen, when set, is where the agent serves instead of GRPCPort on all
+	// interfaces: tcp://host:port or unix:///path/to/agent.sock.
+	Listen string
+	// WriteRetries is how many times a file write hitting a transient error
+	// (ENOSPC, EIO, EAGAIN, EINTR) is retried during a copy before failing.
+	WriteRetries int
+	// WriteRetryBackoff is the delay before the first retry; it doubles on each attempt.
+	WriteRetryBackoff time.Duration
+	// EnableReflection registers the gRPC reflection service so `convoy api`
+	// and generic gRPC tools can list the methods the agent exposes.
+	EnableReflection bool
+	// KeepaliveMinTime is the shortest client ping interval the agent accepts;
+	// clients pinging more often are disconnected. Pings are allowed without an
+	// active stream so idle shells stay up.
+	KeepaliveMinTime time.Duration
+	// RateLimitPerSecond caps the sustained request rate per client address;
+	// excess calls fail with ResourceExhausted. Zero disables rate limiting.
+	RateLimitPerSecond float64
+	// RateLimitBurst is how many requests a client may make at once before the rate applies.
+	RateLimitBurst int
+	// RateLimitExemptHealth lets health checks bypass the rate limit.
+	RateLimitExemptHealth bool
+	// RateLimitMethods overrides the rate for individual RPCs, keyed by method
+	// name (e.g. ExecuteCommand, Copy, ExecuteShell). Each method gets its own
+	// per-client bucket; a zero PerSecond leaves the method unlimited.
+	RateLimitMethods map[string]RateLimit
+	// DefaultWorkDir is where commands and shells run when the request sets no work dir.
+	DefaultWorkDir string
+	// AllowedCommands lists glob patterns for the programs (matched on the base
+	// name of argv[0]) that commands and shells may run. Empty or ["*"] allows all.
+	// A shell's -c script is judged by the program it runs; scripts that chain
+	// or substitute commands are refused while either list restricts anything.
+	AllowedCommands []string
+	// DeniedCommands lists programs that are always refused, even when allowed.
+	DeniedCommands []string
+	// EnvPassthrough lists glob patterns for the agent's own environment
+	// variables that commands and shells inherit. Nil inherits everything, which
+	// exposes any secrets in the agent's environment to every command; an empty
+	// list inherits nothing. Variables set by the request are always applied.
+	EnvPassthrough []string
+	// MaxOutputBytes caps how much of each of stdout and stderr ExecuteCommand
+	// buffers; anything beyond it is dropped and the response marked truncated.
+	MaxOutputBytes int
+	// ShellResumeGrace is how long a shell whose stream dropped keeps running
+	// for the client to resume it. Zero kills the shell as soon as its stream ends.
+	ShellResumeGrace time.Duration
+	// MaxExecTimeout caps the timeout of ExecuteCommand and ExecuteCommandStream,
+	// including timeouts requested by clients. Zero leaves them unbounded.
+	MaxExecTimeout time.Duration
+	// JobRetention is how long a finished background job, and its captured
+	// output, stays available to GetJob. Zero keeps jobs until the agent stops.
+	JobRetention time.Duration
+	// MaxJobs caps how many background jobs run at once. Jobs have their own
+	// slots so long-running ones cannot starve commands of MaxConcurrent; zero
+	// allows as many as MaxConcurrent.
+	MaxJobs int
+	// HeartbeatURL, when set, is an HTTP registry the agent POSTs a heartbeat
+	// to every HeartbeatInterval so the CLI can find it without Docker.
+	HeartbeatURL      string
+	HeartbeatInterval time.Duration
+	// AdvertiseEndpoint is the address reported in heartbeats. Empty derives
+	// it from the hostname and listen address.
+	AdvertiseEndpoint string
+}
+
+// RateLimit is a per-client request rate for a single RPC method.
+type RateLimit struct {
+	PerSecond float64 `yaml:"per_second" json:"per_second" toml:"per_second"`
+	Burst     int     `yaml:"burst" json:"burst" toml:"burst"`
 }
 
 type fileConfig struct {
-	GRPCPort       int    `yaml:"grpc_port"`
-	ShellPath      string `yaml:"shell_path"`
-	MaxConcurrent  int    `yaml:"max_concurrent"`
-	ExecTimeoutSec int    `yaml:"exec_timeout_sec"`
-	AgentID        string `yaml:"agent_id"`
+	GRPCPort         int      `yaml:"grpc_port" json:"grpc_port" toml:"grpc_port"`
+	Listen           string   `yaml:"listen" json:"listen" toml:"listen"`
+	ShellPath        string   `yaml:"shell_path" json:"shell_path" toml:"shell_path"`
+	MaxConcurrent    int      `yaml:"max_concurrent" json:"max_concurrent" toml:"max_concurrent"`
+	ExecTimeoutSec   int      `yaml:"exec_timeout_sec" json:"exec_timeout_sec" toml:"exec_timeout_sec"`
+	AgentID          string   `yaml:"agent_id" json:"agent_id" toml:"agent_id"`
+	AgentIDFile      string   `yaml:"agent_id_file" json:"agent_id_file" toml:"agent_id_file"`
+	WriteRetries     int      `yaml:"write_retries" json:"write_retries" toml:"write_retries"`
+	WriteBackoffMS   int      `yaml:"write_retry_backoff_ms" json:"write_retry_backoff_ms" toml:"write_retry_backoff_ms"`
+	Reflection       bool     `yaml:"enable_reflection" json:"enable_reflection" toml:"enable_reflection"`
+	KeepaliveMin     int      `yaml:"keepalive_min_time_sec" json:"keepalive_min_time_sec" toml:"keepalive_min_time_sec"`
+	RateLimit        float64  `yaml:"rate_limit_per_second" json:"rate_limit_per_second" toml:"rate_limit_per_second"`
+	RateBurst        int      `yaml:"rate_limit_burst" json:"rate_limit_burst" toml:"rate_limit_burst"`
+	RateExemptHealth bool     `yaml:"rate_limit_exempt_health" json:"rate_limit_exempt_health" toml:"rate_limit_exempt_health"`
+	DefaultWorkDir   string   `yaml:"default_work_dir" json:"default_work_dir" toml:"default_work_dir"`
+	AllowedCommands  []string `yaml:"allowed_commands" json:"allowed_commands" toml:"allowed_commands"`
+	DeniedCommands   []string `yaml:"denied_commands" json:"denied_commands" toml:"denied_commands"`
+	EnvPassthrough   []string `yaml:"env_passthrough" json:"env_passthrough" toml:"env_passthrough"`
+	MaxOutputBytes   int      `yaml:"max_output_bytes" json:"max_output_bytes" toml:"max_output_bytes"`
+	ShellResumeSec   int      `yaml:"shell_resume_grace_sec" json:"shell_resume_grace_sec" toml:"shell_resume_grace_sec"`
+	MaxExecSec       int      `yaml:"max_exec_timeout_sec" json:"max_exec_timeout_sec" toml:"max_exec_timeout_sec"`
+	MaxJobs          int      `yaml:"max_jobs" json:"max_jobs" toml:"max_jobs"`
+	JobRetentionSec  *int     `yaml:"job_retention_sec" json:"job_retention_sec" toml:"job_retention_sec"`
+	HeartbeatURL     string   `yaml:"heartbeat_url" json:"heartbeat_url" toml:"heartbeat_url"`
+	HeartbeatSec     int      `yaml:"heartbeat_interval_sec" json:"heartbeat_interval_sec" toml:"heartbeat_interval_sec"`
+	Advertise        string   `yaml:"advertise_endpoint" json:"advertise_endpoint" toml:"advertise_endpoint"`
+
+	// Per-method overrides of rate_limit_per_second and rate_limit_burst.
+	RateMethods map[string]RateLimit `yaml:"rate_limit_methods" json:"rate_limit_methods" toml:"rate_limit_methods"`
 }
 
 const (
@@ -41,9 +140,21 @@ const (
 	defaultShellPath     = "/bin/sh"
 	defaultMaxConcurrent = 4
 	defaultExecTimeout   = 60
+	defaultWriteRetries  = 3
+	defaultWriteBackoff  = 100
+	defaultKeepaliveMin  = 15
+	// defaultMaxOutput keeps both streams of a response well under gRPC's
+	// default 4 MiB message limit on the client.
+	defaultMaxOutput    = 1 << 20
+	defaultShellResume  = 30
+	defaultJobRetention = 3600
+	defaultHeartbeat    = 30
 )
 
-// LoadConfig loads the agent configuration from disk, applying environment overrides.
+// LoadConfig loads the agent configuration from disk, applying environment
+// overrides. An empty path means the default location, where a missing file
+// leaves the defaults in place; a path given explicitly must exist. The format
+// follows the extension: .json, .toml, or YAML for anything else.
 func LoadConfig(path string) (*Config, error) {
 	configPath := path
 	if configPath == "" {
@@ -54,16 +165,25 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 
+	// A missing default file is not an error: containerized agents are often
+	// configured purely through CONVOY_AGENT_* variables on top of the defaults.
 	data, err := os.ReadFile(configPath)
-	if err != nil {
+	if errors.Is(err, os.ErrNotExist) && path == "" {
+		log.Printf("agent config %s not found; using defaults and environment", configPath)
+		data = nil
+	} else if err != nil {
 		return nil, fmt.Errorf("read config %q: %w", configPath, err)
 	}
 
 	var cfg fileConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := configfile.Decode(configPath, data, &cfg); err != nil {
 		return nil, fmt.Errorf("parse config %q: %w", configPath, err)
 	}
 
+	if idFile := getEnv("CONVOY_AGENT_ID_FILE", ""); idFile != "" {
+		cfg.AgentIDFile = idFile
+	}
+
 	applyDefaults(&cfg)
 	if err := validateConfig(cfg); err != nil {
 		return nil, err
@@ -71,17 +191,51 @@ func LoadConfig(path string) (*Config, error) {
 
 	agentCfg := &Config{
 		GRPCPort:      cfg.GRPCPort,
+		Listen:        cfg.Listen,
 		ShellPath:     cfg.ShellPath,
 		MaxConcurrent: cfg.MaxConcurrent,
 		ExecTimeout:   time.Duration(cfg.ExecTimeoutSec) * time.Second,
 		AgentID:       cfg.AgentID,
+		AgentIDFile:   cfg.AgentIDFile,
 		ConfigPath:    configPath,
+
+		WriteRetries:      cfg.WriteRetries,
+		WriteRetryBackoff: time.Duration(cfg.WriteBackoffMS) * time.Millisecond,
+		EnableReflection:  cfg.Reflection,
+		KeepaliveMinTime:  time.Duration(cfg.KeepaliveMin) * time.Second,
+
+		RateLimitPerSecond:    cfg.RateLimit,
+		RateLimitBurst:        cfg.RateBurst,
+		RateLimitExemptHealth: cfg.RateExemptHealth,
+		RateLimitMethods:      cfg.RateMethods,
+		DefaultWorkDir:        cfg.DefaultWorkDir,
+
+		AllowedCommands: cfg.AllowedCommands,
+		DeniedCommands:  cfg.DeniedCommands,
+		EnvPassthrough:  cfg.EnvPassthrough,
+		MaxOutputBytes:  cfg.MaxOutputBytes,
+
+		ShellResumeGrace: time.Duration(cfg.ShellResumeSec) * time.Second,
+		MaxExecTimeout:   time.Duration(cfg.MaxExecSec) * time.Second,
+		JobRetention:     time.Duration(*cfg.JobRetentionSec) * time.Second,
+		MaxJobs:          cfg.MaxJobs,
+
+		HeartbeatURL:      cfg.HeartbeatURL,
+		HeartbeatInterval: time.Duration(cfg.HeartbeatSec) * time.Second,
+		AdvertiseEndpoint: cfg.Advertise,
 	}
 
 	if port := getEnvInt("CONVOY_AGENT_GRPC_PORT", 0); port > 0 {
 		agentCfg.GRPCPort = port
 	}
 
+	if listen := getEnv("CONVOY_AGENT_LISTEN", ""); listen != "" {
+		if _, _, err := parseListen(listen); err != nil {
+			return nil, fmt.Errorf("invalid CONVOY_AGENT_LISTEN: %w", err)
+		}
+		agentCfg.Listen = listen
+	}
+
 	if shell := getEnv("CONVOY_AGENT_SHELL", ""); shell != "" {
 		agentCfg.ShellPath = shell
 	}
@@ -94,10 +248,48 @@ func LoadConfig(path string) (*Config, error) {
 		agentCfg.ExecTimeout = timeout
 	}
 
+	if ceiling := getEnvDuration("CONVOY_AGENT_MAX_EXEC_TIMEOUT", 0); ceiling > 0 {
+		agentCfg.MaxExecTimeout = ceiling
+	}
+
+	if target := getEnv("CONVOY_AGENT_HEARTBEAT_URL", ""); target != "" {
+		if err := checkHeartbeatURL(target); err != nil {
+			return nil, fmt.Errorf("invalid CONVOY_AGENT_HEARTBEAT_URL: %w", err)
+		}
+		agentCfg.HeartbeatURL = target
+	}
+
+	if endpoint := getEnv("CONVOY_AGENT_ADVERTISE_ENDPOINT", ""); endpoint != "" {
+		agentCfg.AdvertiseEndpoint = endpoint
+	}
+
 	if agentID := getEnv("CONVOY_AGENT_ID", ""); agentID != "" {
 		agentCfg.AgentID = agentID
 	}
 
+	if enabled := getEnv("CONVOY_AGENT_REFLECTION", ""); enabled != "" {
+		agentCfg.EnableReflection, _ = strconv.ParseBool(enabled)
+	}
+
+	if dir := getEnv("CONVOY_AGENT_WORK_DIR", ""); dir != "" {
+		agentCfg.DefaultWorkDir = dir
+	}
+
+	if names := getEnv("CONVOY_AGENT_ENV_PASSTHROUGH", ""); names != "" {
+		agentCfg.EnvPassthrough = nil
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				agentCfg.EnvPassthrough = append(agentCfg.EnvPassthrough, name)
+			}
+		}
+	}
+
+	if dir := agentCfg.DefaultWorkDir; dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("invalid config: default_work_dir %q is not an existing directory", dir)
+		}
+	}
+
 	return agentCfg, nil
 }
 
@@ -141,8 +333,48 @@ func applyDefaults(cfg *fileConfig) {
 		cfg.ExecTimeoutSec = defaultExecTimeout
 	}
 
+	if cfg.KeepaliveMin == 0 {
+		cfg.KeepaliveMin = defaultKeepaliveMin
+	}
+
+	if cfg.WriteRetries == 0 {
+		cfg.WriteRetries = defaultWriteRetries
+	}
+
+	if cfg.WriteBackoffMS == 0 {
+		cfg.WriteBackoffMS = defaultWriteBackoff
+	}
+
+	if cfg.MaxOutputBytes == 0 {
+		cfg.MaxOutputBytes = defaultMaxOutput
+	}
+
+	if cfg.ShellResumeSec == 0 {
+		cfg.ShellResumeSec = defaultShellResume
+	}
+
+	// An explicit job_retention_sec of 0 keeps jobs forever; only a missing
+	// one gets the default.
+	if cfg.JobRetentionSec == nil {
+		retention := defaultJobRetention
+		cfg.JobRetentionSec = &retention
+	}
+
+	// Without max_jobs, background jobs get as many slots as commands.
+	if cfg.MaxJobs == 0 {
+		cfg.MaxJobs = cfg.MaxConcurrent
+	}
+
+	if cfg.HeartbeatSec == 0 {
+		cfg.HeartbeatSec = defaultHeartbeat
+	}
+
+	if len(cfg.AllowedCommands) == 0 {
+		cfg.AllowedCommands = []string{allowAllCommands}
+	}
+
 	if strings.TrimSpace(cfg.AgentID) == "" {
-		cfg.AgentID = defaultAgentID()
+		cfg.AgentID = defaultAgentID(cfg.AgentIDFile)
 	}
 }
 
@@ -153,6 +385,12 @@ func validateConfig(cfg fileConfig) error {
 		problems = append(problems, "grpc_port must be between 1 and 65535")
 	}
 
+	if cfg.Listen != "" {
+		if _, _, err := parseListen(cfg.Listen); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
 	if strings.TrimSpace(cfg.ShellPath) == "" {
 		problems = append(problems, "shell_path is required")
 	}
@@ -165,10 +403,80 @@ func validateConfig(cfg fileConfig) error {
 		problems = append(problems, "exec_timeout_sec must be greater than 0")
 	}
 
+	if cfg.RateLimit < 0 {
+		problems = append(problems, "rate_limit_per_second must not be negative")
+	}
+
+	if cfg.RateBurst < 0 {
+		problems = append(problems, "rate_limit_burst must not be negative")
+	}
+
+	for name, limit := range cfg.RateMethods {
+		if !isServiceMethod(name) {
+			problems = append(problems, fmt.Sprintf("rate_limit_methods: unknown method %q", name))
+		}
+		if limit.PerSecond < 0 || limit.Burst < 0 {
+			problems = append(problems, fmt.Sprintf("rate_limit_methods: %s must not be negative", name))
+		}
+	}
+
+	if cfg.KeepaliveMin < 0 {
+		problems = append(problems, "keepalive_min_time_sec must not be negative")
+	}
+
+	if cfg.WriteRetries < 0 {
+		problems = append(problems, "write_retries must not be negative")
+	}
+
+	if cfg.WriteBackoffMS < 0 {
+		problems = append(problems, "write_retry_backoff_ms must not be negative")
+	}
+
+	if cfg.MaxOutputBytes < 0 {
+		problems = append(problems, "max_output_bytes must not be negative")
+	}
+
+	if cfg.ShellResumeSec < 0 {
+		problems = append(problems, "shell_resume_grace_sec must not be negative")
+	}
+
+	if cfg.MaxExecSec < 0 {
+		problems = append(problems, "max_exec_timeout_sec must not be negative")
+	}
+
+	if cfg.JobRetentionSec != nil && *cfg.JobRetentionSec < 0 {
+		problems = append(problems, "job_retention_sec must not be negative")
+	}
+
+	if cfg.MaxJobs < 0 {
+		problems = append(problems, "max_jobs must not be negative")
+	}
+
+	if cfg.HeartbeatURL != "" {
+		if err := checkHeartbeatURL(cfg.HeartbeatURL); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if cfg.HeartbeatSec < 0 {
+		problems = append(problems, "heartbeat_interval_sec must not be negative")
+	}
+
 	if strings.TrimSpace(cfg.AgentID) == "" {
 		problems = append(problems, "agent_id is required")
 	}
 
+	for _, list := range []struct {
+		key      string
+		patterns []string
+	}{{"allowed_commands", cfg.AllowedCommands}, {"denied_commands", cfg.DeniedCommands}, {"env_passthrough", cfg.EnvPassthrough}} {
+		for _, pattern := range list.patterns {
+			if _, err := pathpkg.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+				problems = append(problems, fmt.Sprintf("%s entry %q is not a valid pattern", list.key, pattern))
+			}
+		}
+	}
+
 	if len(problems) > 0 {
 		return errors.New("invalid config: " + strings.Join(problems, "; "))
 	}
@@ -176,6 +484,21 @@ func validateConfig(cfg fileConfig) error {
 	return nil
 }
 
+// isServiceMethod reports whether name is an RPC of the agent's gRPC service.
+func isServiceMethod(name string) bool {
+	for _, method := range convoypb.ConvoyService_ServiceDesc.Methods {
+		if method.MethodName == name {
+			return true
+		}
+	}
+	for _, stream := range convoypb.ConvoyService_ServiceDesc.Streams {
+		if stream.StreamName == name {
+			return true
+		}
+	}
+	return false
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -201,9 +524,46 @@ func getEnvDuration(key string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
-func defaultAgentID() string {
+// defaultAgentID prefers the persisted ID in idFile, generating it on first use,
+// and falls back to the hostname when no file is configured or it cannot be used.
+func defaultAgentID(idFile string) string {
+	if strings.TrimSpace(idFile) != "" {
+		id, err := loadOrCreateAgentID(idFile)
+		if err == nil {
+			return id
+		}
+		log.Printf("agent id file %s unusable, falling back to hostname: %v", idFile, err)
+	}
+
 	if hostname, err := os.Hostname(); err == nil && hostname != "" {
 		return hostname
 	}
 	return "convoy-agent"
 }
+
+// loadOrCreateAgentID reads the agent ID stored at path, writing a new random ID when the file is absent.
+func loadOrCreateAgentID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("read agent id: %w", err)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate agent id: %w", err)
+	}
+	id := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("create agent id dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(id+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("write agent id: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,312 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+)
+
+func TestLoadConfig_AgentIDFilePersists(t *testing.T) {
+	dir := t.TempDir()
+	idFile := filepath.Join(dir, "state", "agent-id")
+	cfgPath := filepath.Join(dir, "agent.yaml")
+	if err := os.WriteFile(cfgPath, []byte("agent_id_file: "+idFile+"\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	first, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("first LoadConfig: %v", err)
+	}
+	second, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("second LoadConfig: %v", err)
+	}
+
+	if first.AgentID == "" || first.AgentID != second.AgentID {
+		t.Fatalf("agent id not stable: %q vs %q", first.AgentID, second.AgentID)
+	}
+
+	hostname, _ := os.Hostname()
+	if first.AgentID == hostname {
+		t.Fatalf("expected generated id, got hostname %q", first.AgentID)
+	}
+
+	info, err := NewServer(second).GetInfo(context.Background(), &convoypb.InfoRequest{})
+	if err != nil {
+		t.Fatalf("GetInfo: %v", err)
+	}
+	if info.GetAgentId() != first.AgentID {
+		t.Fatalf("GetInfo reported %q, want %q", info.GetAgentId(), first.AgentID)
+	}
+}
+
+func TestLoadConfig_ExplicitAgentIDWins(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "agent.yaml")
+	content := "agent_id: fixed\nagent_id_file: " + filepath.Join(dir, "agent-id") + "\n"
+	if err := os.WriteFile(cfgPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.AgentID != "fixed" {
+		t.Fatalf("expected explicit agent id, got %q", cfg.AgentID)
+	}
+}
+
+func TestLoadConfig_DefaultWorkDirMustExist(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "agent.yaml")
+	write := func(workDir string) {
+		content := "agent_id: fixed\ndefault_work_dir: " + workDir + "\n"
+		if err := os.WriteFile(cfgPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+
+	write(dir)
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.DefaultWorkDir != dir {
+		t.Fatalf("DefaultWorkDir = %q, want %q", cfg.DefaultWorkDir, dir)
+	}
+
+	write(filepath.Join(dir, "missing"))
+	if _, err := LoadConfig(cfgPath); err == nil {
+		t.Fatalf("expected an error for a missing default_work_dir")
+	}
+}
+
+func TestLoadConfig_CommandLists(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "agent.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(cfgPath, []byte("agent_id: fixed\n"+content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+
+	write("")
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.AllowedCommands) != 1 || cfg.AllowedCommands[0] != "*" || len(cfg.DeniedCommands) != 0 {
+		t.Fatalf("default lists: allowed=%q denied=%q", cfg.AllowedCommands, cfg.DeniedCommands)
+	}
+
+	write("allowed_commands: [ls, cat]\ndenied_commands: [rm]\n")
+	if cfg, err = LoadConfig(cfgPath); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.AllowedCommands) != 2 || len(cfg.DeniedCommands) != 1 {
+		t.Fatalf("lists: allowed=%q denied=%q", cfg.AllowedCommands, cfg.DeniedCommands)
+	}
+
+	write("denied_commands: [\"[\"]\n")
+	if _, err := LoadConfig(cfgPath); err == nil {
+		t.Fatalf("expected an error for a malformed pattern")
+	}
+}
+
+func TestLoadConfig_EnvPassthrough(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "agent.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(cfgPath, []byte("agent_id: fixed\n"+content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+
+	write("")
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.EnvPassthrough != nil {
+		t.Fatalf("expected full passthrough by default, got %q", cfg.EnvPassthrough)
+	}
+
+	write("env_passthrough: [PATH, LC_*]\n")
+	if cfg, err = LoadConfig(cfgPath); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.EnvPassthrough) != 2 || cfg.EnvPassthrough[1] != "LC_*" {
+		t.Fatalf("env_passthrough = %q", cfg.EnvPassthrough)
+	}
+
+	t.Setenv("CONVOY_AGENT_ENV_PASSTHROUGH", "HOME, TERM")
+	if cfg, err = LoadConfig(cfgPath); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.EnvPassthrough) != 2 || cfg.EnvPassthrough[0] != "HOME" || cfg.EnvPassthrough[1] != "TERM" {
+		t.Fatalf("env override: env_passthrough = %q", cfg.EnvPassthrough)
+	}
+}
+
+func TestLoadConfig_RateLimitMethods(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "agent.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(cfgPath, []byte("agent_id: fixed\n"+content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+
+	write("rate_limit_methods:\n  ExecuteShell: {per_second: 0.5, burst: 2}\n")
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got := cfg.RateLimitMethods["ExecuteShell"]; got != (RateLimit{PerSecond: 0.5, Burst: 2}) {
+		t.Fatalf("ExecuteShell limit = %+v", got)
+	}
+
+	write("rate_limit_methods:\n  Exec: {per_second: 1}\n")
+	if _, err := LoadConfig(cfgPath); err == nil {
+		t.Fatalf("expected an error for an unknown method")
+	}
+}
+
+func TestLoadConfig_MissingFileUsesDefaultsAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(agentConfigDirEnvVar, dir)
+	t.Setenv("CONVOY_AGENT_ID", "from-env")
+	t.Setenv("CONVOY_AGENT_GRPC_PORT", "7100")
+	t.Setenv("CONVOY_AGENT_WORK_DIR", dir)
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig without a file: %v", err)
+	}
+	if cfg.AgentID != "from-env" || cfg.GRPCPort != 7100 || cfg.DefaultWorkDir != dir {
+		t.Fatalf("env overrides not applied: %+v", cfg)
+	}
+	if cfg.MaxConcurrent != defaultMaxConcurrent || cfg.ShellPath != defaultShellPath {
+		t.Fatalf("defaults not applied: %+v", cfg)
+	}
+
+	// Only the default location may be missing; a path given explicitly is
+	// most likely a typo.
+	if _, err := LoadConfig(filepath.Join(dir, "agent.yaml")); err == nil {
+		t.Fatalf("expected an error for a missing explicit config path")
+	}
+
+	t.Setenv("CONVOY_AGENT_WORK_DIR", filepath.Join(dir, "missing"))
+	if _, err := LoadConfig(""); err == nil {
+		t.Fatalf("expected env-only config to still be validated")
+	}
+}
+
+func TestLoadConfig_JobRetention(t *testing.T) {
+	dir := t.TempDir()
+	for content, want := range map[string]time.Duration{
+		"":                        defaultJobRetention * time.Second,
+		"job_retention_sec: 0\n":  0,
+		"job_retention_sec: 90\n": 90 * time.Second,
+	} {
+		cfgPath := filepath.Join(dir, "agent.yaml")
+		if err := os.WriteFile(cfgPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		cfg, err := LoadConfig(cfgPath)
+		if err != nil {
+			t.Fatalf("LoadConfig(%q): %v", content, err)
+		}
+		if cfg.JobRetention != want {
+			t.Fatalf("LoadConfig(%q).JobRetention = %v, want %v", content, cfg.JobRetention, want)
+		}
+	}
+}
+
+func TestLoadConfig_MaxJobs(t *testing.T) {
+	dir := t.TempDir()
+	for content, want := range map[string]int{
+		"":                                 defaultMaxConcurrent,
+		"max_concurrent: 7\n":              7,
+		"max_concurrent: 7\nmax_jobs: 2\n": 2,
+	} {
+		cfgPath := filepath.Join(dir, "agent.yaml")
+		if err := os.WriteFile(cfgPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		cfg, err := LoadConfig(cfgPath)
+		if err != nil {
+			t.Fatalf("LoadConfig(%q): %v", content, err)
+		}
+		if cfg.MaxJobs != want {
+			t.Fatalf("LoadConfig(%q).MaxJobs = %d, want %d", content, cfg.MaxJobs, want)
+		}
+	}
+}
+
+func TestLoadConfig_EnvOverridesFile(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "agent.yaml")
+	if err := os.WriteFile(cfgPath, []byte("agent_id: from-file\ngrpc_port: 7000\nmax_concurrent: 9\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("CONVOY_AGENT_GRPC_PORT", "7100")
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.AgentID != "from-file" || cfg.GRPCPort != 7100 || cfg.MaxConcurrent != 9 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadConfig_UnreadableFileFails(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadConfig(dir); err == nil {
+		t.Fatalf("expected an error reading a directory as config")
+	}
+
+	cfgPath := filepath.Join(dir, "agent.yaml")
+	if err := os.WriteFile(cfgPath, []byte("grpc_port: [not a number"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadConfig(cfgPath); err == nil {
+		t.Fatalf("expected a parse error")
+	}
+}
+
+func TestLoadConfig_Formats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"agent.yaml": "agent_id: a1\nmax_concurrent: 2\ndenied_commands: [rm]\nrate_limit_methods:\n  Copy: {per_second: 2, burst: 3}\n",
+		"agent.json": `{"agent_id": "a1", "max_concurrent": 2, "denied_commands": ["rm"], "rate_limit_methods": {"Copy": {"per_second": 2, "burst": 3}}}`,
+		"agent.toml": "agent_id = \"a1\"\nmax_concurrent = 2\ndenied_commands = [\"rm\"]\n\n[rate_limit_methods.Copy]\nper_second = 2\nburst = 3\n",
+	}
+
+	var want *Config
+	for _, name := range []string{"agent.yaml", "agent.json", "agent.toml"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig(%s): %v", name, err)
+		}
+		cfg.ConfigPath = ""
+		if want == nil {
+			want = cfg
+			if cfg.AgentID != "a1" || cfg.MaxConcurrent != 2 || cfg.RateLimitMethods["Copy"].Burst != 3 {
+				t.Fatalf("unexpected YAML config: %+v", cfg)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(cfg, want) {
+			t.Fatalf("%s parsed to %+v, want %+v", name, cfg, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// heartbeatMaxBackoff caps the wait between heartbeats while the registry
+	// keeps failing.
+	heartbeatMaxBackoff = 5 * time.Minute
+	heartbeatTimeout    = 10 * time.Second
+)
+
+// Heartbeat is the JSON document the agent POSTs to its registry. A registry
+// serving the CLI answers GET on the same URL with an array of the latest
+// heartbeat from each agent.
+type Heartbeat struct {
+	AgentID   string    `json:"agent_id"`
+	Endpoint  string    `json:"endpoint"`
+	Health    string    `json:"health"`
+	Hostname  string    `json:"hostname,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// runHeartbeat reports the agent to HeartbeatURL until ctx is done. A failed
+// report is retried with exponential backoff instead of every interval.
+func (s *Server) runHeartbeat(ctx context.Context, client *http.Client) {
+	failures := 0
+	for {
+		if err := s.sendHeartbeat(ctx, client); err != nil {
+			failures++
+			s.logf("agent %s: heartbeat to %s failed (attempt %d): %v", s.cfg.AgentID, s.cfg.HeartbeatURL, failures, err)
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(heartbeatDelay(s.heartbeatInterval(), failures)):
+		}
+	}
+}
+
+// sendHeartbeat POSTs one heartbeat; any non-2xx answer counts as a failure.
+func (s *Server) sendHeartbeat(ctx context.Context, client *http.Client) error {
+	body, err := json.Marshal(s.heartbeat())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, heartbeatTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.HeartbeatURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("registry answered %s", resp.Status)
+	}
+	return nil
+}
+
+// heartbeat describes the agent, with the readiness CheckHealth would report
+// named healthy, degraded or unhealthy.
+func (s *Server) heartbeat() Heartbeat {
+	hostname, _ := os.Hostname()
+	health, _ := s.readiness()
+	return Heartbeat{
+		AgentID:   s.cfg.AgentID,
+		Endpoint:  s.advertiseEndpoint(hostname),
+		Health:    strings.ToLower(strings.TrimPrefix(health.String(), "STATUS_")),
+		Hostname:  hostname,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// advertiseEndpoint is AdvertiseEndpoint, or else the listen address with an
+// unspecified host replaced by hostname.
+func (s *Server) advertiseEndpoint(hostname string) string {
+	if s.cfg.AdvertiseEndpoint != "" {
+		return s.cfg.AdvertiseEndpoint
+	}
+
+	network, addr, err := s.cfg.listenAddress()
+	if err != nil {
+		return ""
+	}
+	if network == "unix" {
+		return "unix://" + addr
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = hostname
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func (s *Server) heartbeatInterval() time.Duration {
+	if s.cfg.HeartbeatInterval > 0 {
+		return s.cfg.HeartbeatInterval
+	}
+	return defaultHeartbeat * time.Second
+}
+
+// heartbeatDelay is the wait before the next heartbeat: the interval, doubled
+// for each consecutive failure up to heartbeatMaxBackoff.
+func heartbeatDelay(interval time.Duration, failures int) time.Duration {
+	delay := interval
+	for i := 0; i < failures && delay < heartbeatMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, max(interval, heartbeatMaxBackoff))
+}
+
+func checkHeartbeatURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("heartbeat_url %q must be an http or https URL", raw)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+)
+
+func TestSendHeartbeat_Payload(t *testing.T) {
+	received := make(chan Heartbeat, 1)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var hb Heartbeat
+		if err := json.NewDecoder(r.Body).Decode(&hb); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		received <- hb
+	}))
+	defer registry.Close()
+
+	srv := NewServer(&Config{AgentID: "agent-7", HeartbeatURL: registry.URL, AdvertiseEndpoint: "10.0.0.7:6000"})
+	if err := srv.sendHeartbeat(context.Background(), registry.Client()); err != nil {
+		t.Fatalf("sendHeartbeat: %v", err)
+	}
+
+	hb := <-received
+	if hb.AgentID != "agent-7" || hb.Endpoint != "10.0.0.7:6000" || hb.Health != "healthy" {
+		t.Fatalf("unexpected heartbeat %+v", hb)
+	}
+	if time.Since(hb.Timestamp) > time.Minute {
+		t.Fatalf("stale timestamp %s", hb.Timestamp)
+	}
+}
+
+func TestAdvertiseEndpoint_Derived(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{cfg: Config{GRPCPort: 6000}, want: "agent-host:6000"},
+		{cfg: Config{Listen: "tcp://0.0.0.0:7000"}, want: "agent-host:7000"},
+		{cfg: Config{Listen: "tcp://10.1.2.3:7000"}, want: "10.1.2.3:7000"},
+		{cfg: Config{Listen: "unix:///run/agent.sock"}, want: "unix:///run/agent.sock"},
+		{cfg: Config{GRPCPort: 6000, AdvertiseEndpoint: "public:16000"}, want: "public:16000"},
+	}
+	for _, tt := range tests {
+		cfg := tt.cfg
+		if got := NewServer(&cfg).advertiseEndpoint("agent-host"); got != tt.want {
+			t.Errorf("advertiseEndpoint(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestHeartbeatDelay_Backoff(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{interval: 30 * time.Second, failures: 0, want: 30 * time.Second},
+		{interval: 30 * time.Second, failures: 1, want: time.Minute},
+		{interval: 30 * time.Second, failures: 3, want: 4 * time.Minute},
+		{interval: 30 * time.Second, failures: 4, want: heartbeatMaxBackoff},
+		{interval: 30 * time.Second, failures: 100, want: heartbeatMaxBackoff},
+		{interval: 10 * time.Minute, failures: 2, want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := heartbeatDelay(tt.interval, tt.failures); got != tt.want {
+			t.Errorf("heartbeatDelay(%s, %d) = %s, want %s", tt.interval, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRunHeartbeat_BacksOffWhileRegistryFails(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+	)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, time.Now())
+		if len(attempts) <= 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer registry.Close()
+
+	interval := 20 * time.Millisecond
+	srv := NewServer(&Config{AgentID: "a", GRPCPort: 6000, HeartbeatURL: registry.URL, HeartbeatInterval: interval})
+	logs := captureLogs(srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.runHeartbeat(ctx, registry.Client())
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(attempts)
+		mu.Unlock()
+		if n >= 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d heartbeats sent", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	// After the third failure the wait is 8x the interval; once a heartbeat
+	// succeeds it drops back to the interval.
+	if gap := attempts[3].Sub(attempts[2]); gap < 8*interval {
+		t.Fatalf("gap after three failures = %s, want at least %s", gap, 8*interval)
+	}
+	if backoff, gap := attempts[3].Sub(attempts[2]), attempts[4].Sub(attempts[3]); gap >= backoff {
+		t.Fatalf("gap after a success = %s, want it shorter than the %s backoff", gap, backoff)
+	}
+	if got := logs(); !strings.Contains(got, "heartbeat to") || !strings.Contains(got, "attempt 3") {
+		t.Fatalf("failures not logged: %q", got)
+	}
+}
+
+func TestHeartbeat_ReportsReadiness(t *testing.T) {
+	srv := NewServer(&Config{AgentID: "agent-7", MaxConcurrent: 1})
+	if got := srv.heartbeat().Health; got != "healthy" {
+		t.Fatalf("idle agent health = %q", got)
+	}
+
+	if err := srv.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if got := srv.heartbeat().Health; got != "degraded" {
+		t.Fatalf("busy agent health = %q, want degraded", got)
+	}
+	resp, _ := srv.CheckHealth(context.Background(), nil)
+	if resp.GetStatus().String() != "STATUS_DEGRADED" {
+		t.Fatalf("CheckHealth on a busy agent = %s", resp.GetStatus())
+	}
+	srv.release()
+
+	resp, _ = srv.CheckHealth(context.Background(), &convoypb.HealthRequest{ProtocolVersion: "999.0"})
+	if resp.GetStatus().String() != "STATUS_UNHEALTHY" || !strings.Contains(resp.GetMessage(), "incompatible") {
+		t.Fatalf("CheckHealth from a caller of another major version = %s %q", resp.GetStatus(), resp.GetMessage())
+	}
+
+	srv.drain()
+	if got := srv.heartbeat().Health; got != "unhealthy" {
+		t.Fatalf("draining agent health = %q, want unhealthy", got)
+	}
+}
@@ -0,0 +1,292 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxJobOutput caps the output GetJob returns, keeping the response under
+// gRPC's default 4 MiB message limit whatever max_output_bytes allows.
+const maxJobOutput = 3 << 20
+
+// job is a command started by StartJob. It runs detached from the request that
+// started it, holding one of the agent's job slots, and writes its combined
+// stdout and stderr to a temporary file until it is forgotten.
+type job struct {
+	id      string
+	args    []string
+	started time.Time
+	logPath string
+	cancel  context.CancelFunc
+	// done is closed once the command has finished and the fields below are set.
+	done chan struct{}
+
+	finished  time.Time
+	state     convoypb.JobStatus_State
+	exitCode  int32
+	message   string
+	truncated bool
+}
+
+// StartJob runs a command in the background and returns its status as soon as
+// it has started. The job keeps running when the caller disconnects; its
+// timeout is worked out as for ExecuteCommand. When every job slot is taken
+// the request is refused rather than queued.
+func (s *Server) StartJob(ctx context.Context, req *convoypb.CommandRequest) (*convoypb.JobStatus, error) {
+	if len(req.GetArgs()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "args required")
+	}
+	if err := s.checkCommand(ctx, req.GetArgs()); err != nil {
+		return nil, err
+	}
+	runAs, err := lookupUser(req.GetUser())
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case s.jobSlots <- struct{}{}:
+	default:
+		return nil, status.Errorf(codes.ResourceExhausted, "all %d job slots are busy", cap(s.jobSlots))
+	}
+	release := func() { <-s.jobSlots }
+
+	procCtx, cancel := context.WithCancel(context.Background())
+	if timeout := s.durationFromRequest(ctx, req.GetTimeoutSeconds()); timeout > 0 {
+		procCtx, cancel = context.WithTimeout(context.Background(), timeout)
+	}
+
+	logFile, err := os.CreateTemp("", "convoy-job-*.log")
+	if err != nil {
+		cancel()
+		release()
+		return nil, status.Errorf(codes.Internal, "create job log: %v", err)
+	}
+
+	cmd := exec.CommandContext(procCtx, req.GetArgs()[0], req.GetArgs()[1:]...)
+	killGroupOnCancel(cmd)
+	runAs.apply(cmd)
+	cmd.Dir = s.workDir(req.GetWorkDir())
+	cmd.Env = mergeEnv(s.cfg.EnvPassthrough, runAs.env(req.GetEnv()))
+	if len(req.GetStdin()) > 0 {
+		cmd.Stdin = bytes.NewReader(req.GetStdin())
+	}
+	// One writer for both streams keeps their output in the order it was produced.
+	output := &cappedWriter{w: logFile, limit: s.cfg.MaxOutputBytes}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Start(); err != nil {
+		_ = logFile.Close()
+		_ = os.Remove(logFile.Name())
+		cancel()
+		release()
+		return nil, status.Errorf(codes.Internal, "start job: %v", err)
+	}
+
+	j := &job{
+		id:      newSessionID(),
+		args:    req.GetArgs(),
+		started: time.Now(),
+		logPath: logFile.Name(),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	s.jobsMu.Lock()
+	s.jobs[j.id] = j
+	s.jobsMu.Unlock()
+
+	go func() {
+		waitErr := finishedRun(cmd.Wait())
+		_ = logFile.Close()
+
+		j.finished = time.Now()
+		j.truncated = output.truncated
+		j.state, j.exitCode, j.message = jobResult(procCtx, waitErr)
+		close(j.done)
+		cancel()
+		release()
+
+		if retention := s.cfg.JobRetention; retention > 0 {
+			time.AfterFunc(retention, func() { s.forgetJob(j.id) })
+		}
+	}()
+
+	s.logf("agent %s: started job %s %q for %s request_id=%s", s.cfg.AgentID, j.id, j.args[0], peerKey(ctx), requestID(ctx))
+	return j.status(false)
+}
+
+// jobResult turns the outcome of a finished job into its final state.
+func jobResult(procCtx context.Context, waitErr error) (convoypb.JobStatus_State, int32, string) {
+	var exitErr *exec.ExitError
+	switch {
+	case waitErr == nil:
+		return convoypb.JobStatus_EXITED, 0, ""
+	case errors.Is(procCtx.Err(), context.DeadlineExceeded):
+		return convoypb.JobStatus_FAILED, -1, "job timed out"
+	case errors.Is(procCtx.Err(), context.Canceled):
+		return convoypb.JobStatus_CANCELED, -1, "job canceled"
+	case errors.As(waitErr, &exitErr):
+		return convoypb.JobStatus_EXITED, int32(exitErr.ExitCode()), exitErr.Error()
+	default:
+		return convoypb.JobStatus_FAILED, -1, waitErr.Error()
+	}
+}
+
+// GetJob reports the state of a background job, with its output so far when
+// include_output is set.
+func (s *Server) GetJob(_ context.Context, req *convoypb.JobRequest) (*convoypb.JobStatus, error) {
+	j, err := s.lookupJob(req.GetJobId())
+	if err != nil {
+		return nil, err
+	}
+	return j.status(req.GetIncludeOutput())
+}
+
+// ListJobs reports every job the agent still remembers, oldest first, without output.
+func (s *Server) ListJobs(_ context.Context, _ *convoypb.JobListRequest) (*convoypb.JobListResponse, error) {
+	s.jobsMu.Lock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.jobsMu.Unlock()
+
+	sort.Slice(jobs, func(a, b int) bool {
+		if !jobs[a].started.Equal(jobs[b].started) {
+			return jobs[a].started.Before(jobs[b].started)
+		}
+		return jobs[a].id < jobs[b].id
+	})
+
+	resp := &convoypb.JobListResponse{}
+	for _, j := range jobs {
+		st, err := j.status(false)
+		if err != nil {
+			return nil, err
+		}
+		resp.Jobs = append(resp.Jobs, st)
+	}
+	return resp, nil
+}
+
+// CancelJob stops the job or shell session with the given id. A job is killed
+// along with every process it started and the call returns once it has ended,
+// so a following GetJob reports it canceled.
+func (s *Server) CancelJob(ctx context.Context, req *convoypb.CancelRequest) (*convoypb.CancelResponse, error) {
+	id := req.GetId()
+
+	s.jobsMu.Lock()
+	j := s.jobs[id]
+	s.jobsMu.Unlock()
+	if j != nil {
+		select {
+		case <-j.done:
+			return &convoypb.CancelResponse{Kind: "job", AlreadyFinished: true}, nil
+		default:
+		}
+		j.cancel()
+		select {
+		case <-j.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		s.logf("agent %s: canceled job %s for %s request_id=%s", s.cfg.AgentID, id, peerKey(ctx), requestID(ctx))
+		return &convoypb.CancelResponse{Kind: "job"}, nil
+	}
+
+	if sess, err := s.lookupShell(id); err == nil {
+		sess.mu.Lock()
+		s.expireShellLocked(sess)
+		sess.mu.Unlock()
+		s.logf("agent %s: canceled shell session %s for %s request_id=%s", s.cfg.AgentID, id, peerKey(ctx), requestID(ctx))
+		return &convoypb.CancelResponse{Kind: "shell"}, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "no job or shell session %s", id)
+}
+
+// status describes the job, reading its output file when withOutput is set.
+func (j *job) status(withOutput bool) (*convoypb.JobStatus, error) {
+	st := &convoypb.JobStatus{
+		JobId:       j.id,
+		State:       convoypb.JobStatus_RUNNING,
+		Args:        j.args,
+		StartedUnix: j.started.Unix(),
+	}
+	select {
+	case <-j.done:
+		st.State = j.state
+		st.ExitCode = j.exitCode
+		st.Message = j.message
+		st.FinishedUnix = j.finished.Unix()
+		st.Truncated = j.truncated
+	default:
+	}
+
+	if withOutput {
+		file, err := os.Open(j.logPath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "read job output: %v", err)
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, maxJobOutput+1))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "read job output: %v", err)
+		}
+		if len(data) > maxJobOutput {
+			data = data[:maxJobOutput]
+			st.Truncated = true
+		}
+		st.Output = data
+	}
+	return st, nil
+}
+
+func (s *Server) lookupJob(id string) (*job, error) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	j := s.jobs[id]
+	if j == nil {
+		return nil, status.Errorf(codes.NotFound, "job %s not found; it may have expired", id)
+	}
+	return j, nil
+}
+
+// forgetJob drops a finished job and deletes its output.
+func (s *Server) forgetJob(id string) {
+	s.jobsMu.Lock()
+	j := s.jobs[id]
+	delete(s.jobs, id)
+	s.jobsMu.Unlock()
+
+	if j != nil {
+		_ = os.Remove(j.logPath)
+	}
+}
+
+// closeJobs stops running jobs and deletes all job output when the agent shuts down.
+func (s *Server) closeJobs() {
+	s.jobsMu.Lock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.jobsMu.Unlock()
+
+	for _, j := range jobs {
+		j.cancel()
+		<-j.done
+		s.forgetJob(j.id)
+	}
+}
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pollJob fetches job id with its output until done reports true or a few seconds pass.
+func pollJob(t *testing.T, client convoypb.ConvoyServiceClient, id string, done func(*convoypb.JobStatus) bool) *convoypb.JobStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st, err := client.GetJob(context.Background(), &convoypb.JobRequest{JobId: id, IncludeOutput: true})
+		if err != nil {
+			t.Fatalf("GetJob: %v", err)
+		}
+		if done(st) {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job never reached the expected state, last status %v", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartJob_PollWhileRunningThenCompleted(t *testing.T) {
+	dir := t.TempDir()
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 2}))
+
+	script := `echo started; while [ ! -e release ]; do sleep 0.01; done; echo finished >&2; exit 3`
+	started, err := client.StartJob(context.Background(), &convoypb.CommandRequest{Args: []string{"sh", "-c", script}, WorkDir: dir})
+	if err != nil {
+		t.Fatalf("StartJob: %v", err)
+	}
+	if started.GetJobId() == "" || started.GetState() != convoypb.JobStatus_RUNNING {
+		t.Fatalf("StartJob returned %v, want a running job", started)
+	}
+
+	running := pollJob(t, client, started.GetJobId(), func(st *convoypb.JobStatus) bool {
+		return string(st.GetOutput()) == "started\n"
+	})
+	if running.GetState() != convoypb.JobStatus_RUNNING || running.GetFinishedUnix() != 0 {
+		t.Fatalf("job should still be running: %v", running)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "release"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	done := pollJob(t, client, started.GetJobId(), func(st *convoypb.JobStatus) bool {
+		return st.GetState() != convoypb.JobStatus_RUNNING
+	})
+	if done.GetState() != convoypb.JobStatus_EXITED || done.GetExitCode() != 3 {
+		t.Fatalf("finished job = %v, want exited with code 3", done)
+	}
+	if got := string(done.GetOutput()); got != "started\nfinished\n" {
+		t.Fatalf("output = %q, want stdout and stderr in order", got)
+	}
+
+	list, err := client.ListJobs(context.Background(), &convoypb.JobListRequest{})
+	if err != nil {
+		t.Fatalf("ListJobs: %v", err)
+	}
+	if len(list.GetJobs()) != 1 || list.GetJobs()[0].GetJobId() != started.GetJobId() || len(list.GetJobs()[0].GetOutput()) != 0 {
+		t.Fatalf("ListJobs = %v, want the one job without output", list.GetJobs())
+	}
+}
+
+func TestStartJob_CapsOutputAndTimesOut(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1, MaxOutputBytes: 4}))
+
+	started, err := client.StartJob(context.Background(), &convoypb.CommandRequest{
+		Args:           []string{"sh", "-c", "echo 0123456789; exec sleep 5"},
+		TimeoutSeconds: 1,
+	})
+	if err != nil {
+		t.Fatalf("StartJob: %v", err)
+	}
+
+	done := pollJob(t, client, started.GetJobId(), func(st *convoypb.JobStatus) bool {
+		return st.GetState() != convoypb.JobStatus_RUNNING
+	})
+	if done.GetState() != convoypb.JobStatus_FAILED || done.GetMessage() != "job timed out" {
+		t.Fatalf("job = %v, want it failed by the timeout", done)
+	}
+	if string(done.GetOutput()) != "0123" || !done.GetTruncated() {
+		t.Fatalf("output = %q truncated=%v, want the first 4 bytes marked truncated", done.GetOutput(), done.GetTruncated())
+	}
+}
+
+func TestStartJob_HasItsOwnSlots(t *testing.T) {
+	dir := t.TempDir()
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1, MaxJobs: 1}))
+
+	script := `while [ ! -e release ]; do sleep 0.01; done`
+	if _, err := client.StartJob(context.Background(), &convoypb.CommandRequest{Args: []string{"sh", "-c", script}, WorkDir: dir}); err != nil {
+		t.Fatalf("StartJob: %v", err)
+	}
+	t.Cleanup(func() { _ = os.WriteFile(filepath.Join(dir, "release"), nil, 0o644) })
+
+	_, err := client.StartJob(context.Background(), &convoypb.CommandRequest{Args: []string{"true"}})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second StartJob error = %v, want ResourceExhausted", err)
+	}
+
+	// The running job does not hold the only command slot.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := client.ExecuteCommand(ctx, &convoypb.CommandRequest{Args: []string{"echo", "ok"}})
+	if err != nil || resp.GetStdout() != "ok\n" {
+		t.Fatalf("ExecuteCommand while a job runs = %v, %v", resp, err)
+	}
+}
+
+func TestGetJob_ForgottenAfterRetention(t *testing.T) {
+	srv := NewServer(&Config{MaxConcurrent: 1, JobRetention: 200 * time.Millisecond})
+	client := dialServer(t, srv)
+
+	started, err := client.StartJob(context.Background(), &convoypb.CommandRequest{Args: []string{"true"}})
+	if err != nil {
+		t.Fatalf("StartJob: %v", err)
+	}
+	j, err := srv.lookupJob(started.GetJobId())
+	if err != nil {
+		t.Fatalf("lookupJob: %v", err)
+	}
+	logPath := j.logPath
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := client.GetJob(context.Background(), &convoypb.JobRequest{JobId: started.GetJobId()})
+		if status.Code(err) == codes.NotFound {
+			break
+		}
+		if err != nil {
+			t.Fatalf("GetJob: %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job was never forgotten")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Fatalf("job output %s should be deleted, stat err = %v", logPath, err)
+	}
+}
+
+func TestStartJob_RejectsDeniedCommand(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1, DeniedCommands: []string{"rm"}}))
+
+	_, err := client.StartJob(context.Background(), &convoypb.CommandRequest{Args: []string{"rm", "-rf", "/tmp/x"}})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("StartJob error = %v, want PermissionDenied", err)
+	}
+}
+
+func TestCancelJob_FinishedUnknownAndShells(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 2, ShellResumeGrace: time.Minute}))
+
+	started, err := client.StartJob(context.Background(), &convoypb.CommandRequest{Args: []string{"true"}})
+	if err != nil {
+		t.Fatalf("StartJob: %v", err)
+	}
+	pollJob(t, client, started.GetJobId(), func(st *convoypb.JobStatus) bool {
+		return st.GetState() == convoypb.JobStatus_EXITED
+	})
+	resp, err := client.CancelJob(context.Background(), &convoypb.CancelRequest{Id: started.GetJobId()})
+	if err != nil || !resp.GetAlreadyFinished() {
+		t.Fatalf("CancelJob on a finished job = %v, %v; want already finished", resp, err)
+	}
+
+	if _, err := client.CancelJob(context.Background(), &convoypb.CancelRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("CancelJob on an unknown id = %v, want NotFound", err)
+	}
+
+	stream, session, err := openShell(t, context.Background(), client, &convoypb.ShellStart{Args: []string{"sleep", "30"}})
+	if err != nil {
+		t.Fatalf("open shell: %v", err)
+	}
+	resp, err = client.CancelJob(context.Background(), &convoypb.CancelRequest{Id: session.GetSessionId()})
+	if err != nil || resp.GetKind() != "shell" {
+		t.Fatalf("CancelJob on a shell = %v, %v", resp, err)
+	}
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("shell ended without an exit message: %v", err)
+		}
+		if msg.GetExit() != nil {
+			break
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	convoypb "convoy/api"
+)
+
+// maliciousTar builds an archive from headers, giving regular files a short body.
+func maliciousTar(t *testing.T, headers ...*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, header := range headers {
+		body := ""
+		if header.Typeflag == tar.TypeReg {
+			body = "pwned"
+			header.Size = int64(len(body))
+		}
+		if header.Mode == 0 {
+			header.Mode = 0o644
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatalf("write body: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// pushWithStart streams data to the agent using start and returns the error, if any.
+func pushWithStart(t *testing.T, client convoypb.ConvoyServiceClient, start *convoypb.CopyStart, data []byte) error {
+	t.Helper()
+	stream, err := client.Copy(context.Background())
+	if err != nil {
+		t.Fatalf("open copy: %v", err)
+	}
+	for _, msg := range []*convoypb.CopyRequest{
+		{Payload: &convoypb.CopyRequest_Start{Start: start}},
+		{Payload: &convoypb.CopyRequest_Chunk{Chunk: &convoypb.CopyChunk{Data: data}}},
+		{Payload: &convoypb.CopyRequest_Chunk{Chunk: &convoypb.CopyChunk{Eof: true}}},
+	} {
+		if err := stream.Send(msg); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("close send: %v", err)
+	}
+	_, err = stream.Recv()
+	return err
+}
+
+func TestCopyToAgent_RefusesToWriteThroughEscapingSymlink(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+	base := t.TempDir()
+	dest := filepath.Join(base, "a", "b", "dest")
+	outside := filepath.Join(base, "a", "etc")
+	if err := os.MkdirAll(outside, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	data := maliciousTar(t,
+		&tar.Header{Name: "etc", Typeflag: tar.TypeSymlink, Linkname: "../../etc"},
+		&tar.Header{Name: "etc/passwd", Typeflag: tar.TypeReg},
+	)
+	start := &convoypb.CopyStart{Direction: convoypb.CopyStart_TO_AGENT, Path: dest, Overwrite: true}
+	err := pushWithStart(t, client, start, data)
+	if err == nil || !strings.Contains(err.Error(), "through a symlink") {
+		t.Fatalf("expected the write through the symlink to be refused, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "passwd")); !os.IsNotExist(err) {
+		t.Fatalf("file was written outside the destination: %v", err)
+	}
+}
+
+func TestCopyToAgent_NoFollowRejectsEscapingSymlinks(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	for _, linkname := range []string{"../../etc", "/etc", "sub/../../outside"} {
+		t.Run(linkname, func(t *testing.T) {
+			dest := t.TempDir()
+			start := &convoypb.CopyStart{Direction: convoypb.CopyStart_TO_AGENT, Path: dest, Overwrite: true, NoFollow: true}
+			err := pushWithStart(t, client, start, maliciousTar(t, &tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: linkname}))
+			if err == nil || !strings.Contains(err.Error(), "points outside the destination") {
+				t.Fatalf("expected rejection, got %v", err)
+			}
+			if _, err := os.Lstat(filepath.Join(dest, "link")); !os.IsNotExist(err) {
+				t.Fatalf("symlink was created: %v", err)
+			}
+		})
+	}
+
+	// Links that stay inside the destination are still recreated.
+	dest := t.TempDir()
+	start := &convoypb.CopyStart{Direction: convoypb.CopyStart_TO_AGENT, Path: dest, Overwrite: true, NoFollow: true}
+	data := maliciousTar(t, &tar.Header{Name: "conf/current", Typeflag: tar.TypeSymlink, Linkname: "../releases/v2"})
+	if err := os.MkdirAll(filepath.Join(dest, "conf"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := pushWithStart(t, client, start, data); err != nil {
+		t.Fatalf("inside link rejected: %v", err)
+	}
+	if link, _ := os.Readlink(filepath.Join(dest, "conf", "current")); link != "../releases/v2" {
+		t.Fatalf("link = %q", link)
+	}
+}
+
+func TestCopyToAgent_RejectsHardlinks(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+	start := &convoypb.CopyStart{Direction: convoypb.CopyStart_TO_AGENT, Path: t.TempDir(), Overwrite: true}
+
+	err := pushWithStart(t, client, start, maliciousTar(t, &tar.Header{Name: "shadow", Typeflag: tar.TypeLink, Linkname: "../../etc/shadow"}))
+	if err == nil || !strings.Contains(err.Error(), "hardlink") {
+		t.Fatalf("expected hardlink rejection, got %v", err)
+	}
+}
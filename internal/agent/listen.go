@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// listenAddress returns the network and address the agent serves on: Listen
+// when set, otherwise every interface on GRPCPort.
+func (cfg *Config) listenAddress() (string, string, error) {
+	if cfg.Listen == "" {
+		return "tcp", fmt.Sprintf(":%d", cfg.GRPCPort), nil
+	}
+	return parseListen(cfg.Listen)
+}
+
+// parseListen splits a tcp://host:port or unix:///path/to.sock listen address
+// into the network and address accepted by net.Listen.
+func parseListen(listen string) (string, string, error) {
+	scheme, addr, ok := strings.Cut(listen, "://")
+	if !ok {
+		return "", "", fmt.Errorf("listen %q must start with tcp:// or unix://", listen)
+	}
+
+	switch scheme {
+	case "tcp":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return "", "", fmt.Errorf("listen %q: %w", listen, err)
+		}
+		return "tcp", addr, nil
+	case "unix":
+		if addr == "" {
+			return "", "", fmt.Errorf("listen %q: missing socket path", listen)
+		}
+		return "unix", addr, nil
+	default:
+		return "", "", fmt.Errorf("listen %q: unsupported scheme %q", listen, scheme)
+	}
+}
+
+// listen opens the agent's listener. A socket file left behind by an agent
+// that did not shut down cleanly is replaced, but only once a dial shows
+// nothing is serving on it; a live socket and any other file are left alone.
+func listen(network, addr string) (net.Listener, error) {
+	if network == "unix" {
+		if info, err := os.Lstat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if err := removeStaleSocket(addr); err != nil {
+				return nil, err
+			}
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("stat socket %s: %w", addr, err)
+		}
+	}
+	return net.Listen(network, addr)
+}
+
+// removeStaleSocket removes the socket at addr if connecting to it is refused.
+func removeStaleSocket(addr string) error {
+	conn, err := net.DialTimeout("unix", addr, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("socket %s is in use by another process", addr)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("check socket %s: %w", addr, err)
+	}
+	if err := os.Remove(addr); err != nil {
+		return fmt.Errorf("remove stale socket %s: %w", addr, err)
+	}
+	return nil
+}
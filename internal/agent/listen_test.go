@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+	"convoy/internal/orchestrator"
+)
+
+func TestParseListen(t *testing.T) {
+	tests := []struct {
+		listen  string
+		network string
+		addr    string
+		ok      bool
+	}{
+		{listen: "tcp://:6000", network: "tcp", addr: ":6000", ok: true},
+		{listen: "tcp://127.0.0.1:7000", network: "tcp", addr: "127.0.0.1:7000", ok: true},
+		{listen: "unix:///run/convoy/agent.sock", network: "unix", addr: "/run/convoy/agent.sock", ok: true},
+		{listen: ":6000"},
+		{listen: "tcp://6000"},
+		{listen: "unix://"},
+		{listen: "udp://:6000"},
+	}
+	for _, tt := range tests {
+		network, addr, err := parseListen(tt.listen)
+		if (err == nil) != tt.ok || network != tt.network || addr != tt.addr {
+			t.Errorf("parseListen(%q) = %q, %q, %v", tt.listen, network, addr, err)
+		}
+	}
+}
+
+func TestStart_ServesOnUnixSocket(t *testing.T) {
+	// Socket paths are limited to ~100 bytes, which t.TempDir can exceed.
+	dir, err := os.MkdirTemp("", "convoy")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "agent.sock")
+
+	// A socket left over from an earlier run must not stop the agent starting.
+	if err := os.WriteFile(socket, nil, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := listen("unix", socket); err == nil {
+		t.Fatalf("expected listen to refuse replacing a regular file")
+	}
+	if err := os.Remove(socket); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	// A socket something is still serving on is not taken over.
+	live, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	live.(*net.UnixListener).SetUnlinkOnClose(false)
+	if _, err := listen("unix", socket); err == nil {
+		t.Fatalf("expected listen to refuse a socket in use")
+	}
+	// Once closed without unlinking, it is stale and gets replaced below.
+	_ = live.Close()
+	if _, err := os.Lstat(socket); err != nil {
+		t.Fatalf("expected the stale socket to remain: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	srv := NewServer(&Config{MaxConcurrent: 1, Listen: "unix://" + socket})
+	go func() { done <- srv.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	rpc := orchestrator.NewRPC(orchestrator.RPCConfig{DialTimeout: 5 * time.Second})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	resp, err := rpc.CheckHealth(context.Background(), orchestrator.UnixEndpointPrefix+socket, &convoypb.HealthRequest{})
+	if err != nil {
+		t.Fatalf("CheckHealth over unix socket: %v", err)
+	}
+	if resp.GetStatus() != convoypb.HealthResponse_STATUS_HEALTHY {
+		t.Fatalf("status = %s, want healthy", resp.GetStatus())
+	}
+}
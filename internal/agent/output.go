@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// cappedBuffer collects command output up to limit bytes and silently drops the
+// rest, so a chatty command cannot exhaust agent memory. A zero limit keeps everything.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write always reports the full length so the command keeps running rather
+// than failing on a short write once the cap is reached. The cut is moved back
+// to the last complete rune: the output ends up in a proto string, which must
+// stay valid UTF-8.
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.truncated {
+		return n, nil
+	}
+	if b.limit > 0 {
+		if room := b.limit - b.buf.Len(); len(p) > room {
+			b.buf.Write(p[:max(room, 0)])
+			b.buf.Truncate(completeRunes(b.buf.Bytes()))
+			b.truncated = true
+			return n, nil
+		}
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// completeRunes returns the length of data without a trailing rune that was
+// cut short, which may have started in an earlier write.
+func completeRunes(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// cappedWriter is cappedBuffer for output kept outside memory: it passes up to
+// limit bytes to w and drops the rest. A zero limit passes everything.
+type cappedWriter struct {
+	w         io.Writer
+	limit     int
+	written   int
+	truncated bool
+}
+
+func (c *cappedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if c.limit > 0 {
+		if room := c.limit - c.written; len(p) > room {
+			p = p[:max(room, 0)]
+			c.truncated = true
+		}
+	}
+	written, err := c.w.Write(p)
+	c.written += written
+	if err != nil {
+		return written, err
+	}
+	return n, nil
+}
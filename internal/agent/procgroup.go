@@ -0,0 +1,40 @@
+//go:build unix
+
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// groupWaitDelay bounds how long Wait keeps reading output after the group was
+// killed or the command exited, in case a process that left the group, such as
+// a daemon the command started, still holds the pipes open.
+const groupWaitDelay = time.Second
+
+// killGroupOnCancel starts cmd in a process group of its own and makes its
+// context ending kill the whole group, so children started by a shell die with
+// it instead of being orphaned.
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	killGroup(cmd)
+}
+
+// startWithTTY starts cmd on a new pseudo-terminal of rows x cols and returns
+// the terminal. cmd leads a new session, and so its own process group, which
+// is killed as a whole when its context ends.
+func startWithTTY(cmd *exec.Cmd, rows, cols uint16) (*os.File, error) {
+	killGroup(cmd)
+	return pty.StartWithSize(cmd, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
+func killGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = groupWaitDelay
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package agent
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// killGroupOnCancel leaves cmd as is; process groups are only used on Unix.
+func killGroupOnCancel(*exec.Cmd) {}
+
+// startWithTTY fails; terminals are only supported on Unix.
+func startWithTTY(*exec.Cmd, uint16, uint16) (*os.File, error) {
+	return nil, errors.New("terminals are not supported on this platform")
+}
@@ -0,0 +1,187 @@
+//go:build linux
+
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// childPID waits for the pid a test command wrote to path.
+func childPID(t *testing.T, path string) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(path)
+		if pid, convErr := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && convErr == nil {
+			return pid
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("command never wrote its child pid to %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// processGone reports whether pid has exited; zombies count as gone since
+// nothing in the container may be left to reap them.
+func processGone(pid int) bool {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	// The state follows the parenthesised command name.
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
+
+func waitGone(t *testing.T, pid int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !processGone(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %d outlived its parent", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCancelJob_KillsProcessTree(t *testing.T) {
+	dir := t.TempDir()
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	started, err := client.StartJob(context.Background(), &convoypb.CommandRequest{
+		Args:    []string{"sh", "-c", "sleep 30 & echo $! > child.pid; wait"},
+		WorkDir: dir,
+	})
+	if err != nil {
+		t.Fatalf("StartJob: %v", err)
+	}
+	child := childPID(t, filepath.Join(dir, "child.pid"))
+
+	resp, err := client.CancelJob(context.Background(), &convoypb.CancelRequest{Id: started.GetJobId()})
+	if err != nil {
+		t.Fatalf("CancelJob: %v", err)
+	}
+	if resp.GetKind() != "job" || resp.GetAlreadyFinished() {
+		t.Fatalf("CancelJob = %v, want a running job stopped", resp)
+	}
+
+	st, err := client.GetJob(context.Background(), &convoypb.JobRequest{JobId: started.GetJobId()})
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if st.GetState() != convoypb.JobStatus_CANCELED {
+		t.Fatalf("job = %v, want it canceled", st)
+	}
+	waitGone(t, child)
+}
+
+func TestExecuteCommand_TimeoutKillsChildren(t *testing.T) {
+	dir := t.TempDir()
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	started := time.Now()
+	_, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{
+		Args:           []string{"sh", "-c", "sleep 30 & echo $! > child.pid; wait"},
+		WorkDir:        dir,
+		TimeoutSeconds: 1,
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("ExecuteCommand error = %v, want DeadlineExceeded", err)
+	}
+	// The child holds the output pipes, so waiting for it would take its full 30s.
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Fatalf("ExecuteCommand took %s to return after its timeout", elapsed)
+	}
+	waitGone(t, childPID(t, filepath.Join(dir, "child.pid")))
+}
+
+func TestExecuteCommandStream_TimeoutKillsChildren(t *testing.T) {
+	dir := t.TempDir()
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	stream, err := client.ExecuteCommandStream(context.Background(), &convoypb.CommandRequest{
+		Args:           []string{"sh", "-c", "sleep 30 & echo $! > child.pid; wait"},
+		WorkDir:        dir,
+		TimeoutSeconds: 1,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCommandStream: %v", err)
+	}
+	var exit *convoypb.ShellExit
+	for exit == nil {
+		msg, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("stream ended without an exit message: %v", err)
+		}
+		exit = msg.GetExit()
+	}
+	if exit.GetMessage() != "command timed out" {
+		t.Fatalf("exit = %v, want a timeout", exit)
+	}
+	waitGone(t, childPID(t, filepath.Join(dir, "child.pid")))
+}
+
+func TestExecuteShell_TTYSessionRunsOnATerminal(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	stream, _, err := openShell(t, context.Background(), client, &convoypb.ShellStart{
+		Args: []string{"sh", "-c", "test -t 0 && test -t 1 && stty size"},
+		Tty:  true,
+		Rows: 30,
+		Cols: 100,
+	})
+	if err != nil {
+		t.Fatalf("open shell: %v", err)
+	}
+
+	var output strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v (output so far %q)", err, output.String())
+		}
+		if out := resp.GetOutput(); out != nil {
+			if out.GetStream() != convoypb.ShellOutput_STDOUT {
+				t.Fatalf("tty output arrived on %v, want it all on stdout", out.GetStream())
+			}
+			output.Write(out.GetData())
+		}
+		if exit := resp.GetExit(); exit != nil {
+			if exit.GetExitCode() != 0 {
+				t.Fatalf("exit code %d, want the shell to see a terminal (output %q)", exit.GetExitCode(), output.String())
+			}
+			break
+		}
+	}
+	// The terminal turns the newline into CRLF.
+	if output.String() != "30 100\r\n" {
+		t.Fatalf("output = %q, want the requested terminal size", output.String())
+	}
+}
+
+func TestExecuteCommand_BackgroundChildHoldingOutputIsNotAnError(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	for _, code := range []int32{0, 3} {
+		script := "sleep 5 & echo started; exit " + strconv.Itoa(int(code))
+		resp, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{Args: []string{"sh", "-c", script}})
+		if err != nil {
+			t.Fatalf("ExecuteCommand(%q): %v", script, err)
+		}
+		if resp.GetExitCode() != code || resp.GetStdout() != "started\n" {
+			t.Fatalf("%q: exit code %d stdout %q, want %d and the output before exit", script, resp.GetExitCode(), resp.GetStdout(), code)
+		}
+	}
+}
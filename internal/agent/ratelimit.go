@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	convoypb "convoy/api"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// maxIdleBuckets bounds how many per-peer limiters are kept before idle ones are pruned.
+const maxIdleBuckets = 1024
+
+// peerLimiter is a token-bucket rate limiter keyed by client address.
+type peerLimiter struct {
+	limit rate.Limit
+	burst int
+	now   func() time.Time
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newPeerLimiter(perSecond float64, burst int) *peerLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &peerLimiter{
+		limit:    rate.Limit(perSecond),
+		burst:    burst,
+		now:      time.Now,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// allow takes a token from key's bucket, reporting false when it is empty.
+func (l *peerLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	limiter, ok := l.limiters[key]
+	if !ok {
+		if len(l.limiters) >= maxIdleBuckets {
+			l.pruneLocked(now)
+		}
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[key] = limiter
+	}
+	return limiter.AllowN(now, 1)
+}
+
+// pruneLocked drops limiters that have refilled completely; they hold no state worth keeping.
+func (l *peerLimiter) pruneLocked(now time.Time) {
+	for key, limiter := range l.limiters {
+		if limiter.TokensAt(now) >= float64(l.burst) {
+			delete(l.limiters, key)
+		}
+	}
+}
+
+// peerKey identifies the calling client by host so all its connections share a bucket.
+func peerKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// rateLimitOptions returns interceptors enforcing the configured per-client rates, or
+// nothing when rate limiting is disabled. A method with its own entry in
+// RateLimitMethods is limited separately from the agent-wide rate.
+func (s *Server) rateLimitOptions() []grpc.ServerOption {
+	var global *peerLimiter
+	if s.cfg.RateLimitPerSecond > 0 {
+		global = newPeerLimiter(s.cfg.RateLimitPerSecond, s.cfg.RateLimitBurst)
+	}
+
+	methods := make(map[string]*peerLimiter, len(s.cfg.RateLimitMethods))
+	for name, limit := range s.cfg.RateLimitMethods {
+		fullMethod := "/" + convoypb.ConvoyService_ServiceDesc.ServiceName + "/" + name
+		if limit.PerSecond <= 0 {
+			methods[fullMethod] = nil
+			continue
+		}
+		methods[fullMethod] = newPeerLimiter(limit.PerSecond, limit.Burst)
+	}
+
+	if global == nil && len(methods) == 0 {
+		return nil
+	}
+
+	check := func(ctx context.Context, method string) error {
+		if s.cfg.RateLimitExemptHealth && method == convoypb.ConvoyService_CheckHealth_FullMethodName {
+			return nil
+		}
+		limiter, ok := methods[method]
+		if !ok {
+			limiter = global
+		}
+		if limiter == nil {
+			return nil
+		}
+		if !limiter.allow(peerKey(ctx)) {
+			return status.Errorf(codes.ResourceExhausted, "rate limit exceeded: %g requests per second", float64(limiter.limit))
+		}
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := check(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := check(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
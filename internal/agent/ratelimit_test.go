@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPeerLimiter_TokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newPeerLimiter(1, 2)
+	limiter.now = func() time.Time { return now }
+
+	for i, want := range []bool{true, true, false} {
+		if got := limiter.allow("10.0.0.1"); got != want {
+			t.Fatalf("call %d: allow = %v, want %v", i, got, want)
+		}
+	}
+	if !limiter.allow("10.0.0.2") {
+		t.Fatalf("another peer should have its own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if !limiter.allow("10.0.0.1") {
+		t.Fatalf("bucket did not refill")
+	}
+	if limiter.allow("10.0.0.1") {
+		t.Fatalf("refill exceeded the configured rate")
+	}
+}
+
+func TestRateLimit_RejectsBurstsAllowsSlowCallers(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{
+		MaxConcurrent:         1,
+		RateLimitPerSecond:    5,
+		RateLimitBurst:        2,
+		RateLimitExemptHealth: true,
+	}))
+	ctx := context.Background()
+
+	var allowed, rejected int
+	for i := 0; i < 6; i++ {
+		_, err := client.GetInfo(ctx, &convoypb.InfoRequest{})
+		switch status.Code(err) {
+		case codes.OK:
+			allowed++
+		case codes.ResourceExhausted:
+			rejected++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if allowed < 2 || rejected == 0 {
+		t.Fatalf("rapid calls: %d allowed, %d rejected", allowed, rejected)
+	}
+
+	if _, err := client.CheckHealth(ctx, &convoypb.HealthRequest{}); err != nil {
+		t.Fatalf("exempt health check was limited: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(250 * time.Millisecond)
+		if _, err := client.GetInfo(ctx, &convoypb.InfoRequest{}); err != nil {
+			t.Fatalf("slow call %d rejected: %v", i, err)
+		}
+	}
+}
+
+func TestRateLimit_PerMethod(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{
+		MaxConcurrent: 1,
+		RateLimitMethods: map[string]RateLimit{
+			"ExecuteCommand": {PerSecond: 5, Burst: 2},
+			"Copy":           {PerSecond: 5, Burst: 1},
+		},
+	}))
+	ctx := context.Background()
+	exec := func() error {
+		_, err := client.ExecuteCommand(ctx, &convoypb.CommandRequest{Args: []string{"true"}})
+		return err
+	}
+
+	var rejected int
+	for i := 0; i < 6; i++ {
+		switch err := exec(); status.Code(err) {
+		case codes.OK:
+		case codes.ResourceExhausted:
+			rejected++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if rejected == 0 {
+		t.Fatalf("burst of ExecuteCommand calls was never limited")
+	}
+
+	for i := 0; i < 6; i++ {
+		if _, err := client.GetInfo(ctx, &convoypb.InfoRequest{}); err != nil {
+			t.Fatalf("method without a limit was rejected: %v", err)
+		}
+	}
+
+	copyCode := func() codes.Code {
+		stream, err := client.Copy(ctx)
+		if err != nil {
+			return status.Code(err)
+		}
+		_ = stream.CloseSend()
+		_, err = stream.Recv()
+		return status.Code(err)
+	}
+	if first := copyCode(); first == codes.ResourceExhausted {
+		t.Fatalf("first Copy was limited")
+	}
+	if second := copyCode(); second != codes.ResourceExhausted {
+		t.Fatalf("second Copy = %s, want ResourceExhausted", second)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if err := exec(); err != nil {
+		t.Fatalf("ExecuteCommand did not recover after the window: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type requestIDKey struct{}
+
+// requestID returns the ID of the call ctx belongs to, or "" outside a call.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID attaches the caller's request ID to ctx, generating one when the
+// caller sent none, and echoes it back in the response headers.
+func withRequestID(ctx context.Context) (context.Context, string) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(convoypb.RequestIDMetadataKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = newRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(convoypb.RequestIDMetadataKey, id))
+	return context.WithValue(ctx, requestIDKey{}, id), id
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// requestIDStream overrides the context of a server stream.
+type requestIDStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestIDStream) Context() context.Context { return s.ctx }
+
+// requestIDOptions returns interceptors that tag every call with a request ID and
+// log it with the method, outcome and duration once the call finishes.
+func (s *Server) requestIDOptions() []grpc.ServerOption {
+	logCall := func(id, method string, started time.Time, err error) {
+		s.logf("request_id=%s method=%s code=%s duration=%s", id, method, status.Code(err), time.Since(started).Round(time.Millisecond))
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			started := time.Now()
+			ctx, id := withRequestID(ctx)
+			resp, err := handler(ctx, req)
+			logCall(id, info.FullMethod, started, err)
+			return resp, err
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			started := time.Now()
+			ctx, id := withRequestID(ss.Context())
+			err := handler(srv, &requestIDStream{ServerStream: ss, ctx: ctx})
+			logCall(id, info.FullMethod, started, err)
+			return err
+		}),
+	}
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// captureLogs redirects srv's log output into the returned function's result.
+func captureLogs(srv *Server) func() string {
+	var (
+		mu   sync.Mutex
+		logs strings.Builder
+	)
+	srv.logf = func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs.WriteString(fmt.Sprintf(format, args...) + "\n")
+	}
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return logs.String()
+	}
+}
+
+func TestRequestID_PropagatesToLogs(t *testing.T) {
+	srv := NewServer(&Config{AgentID: "test", MaxConcurrent: 1, DeniedCommands: []string{"rm"}})
+	logs := captureLogs(srv)
+	client := dialServer(t, srv)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), convoypb.RequestIDMetadataKey, "req-42")
+	var header metadata.MD
+	if _, err := client.GetInfo(ctx, &convoypb.InfoRequest{}, grpc.Header(&header)); err != nil {
+		t.Fatalf("GetInfo: %v", err)
+	}
+	if got := header.Get(convoypb.RequestIDMetadataKey); len(got) != 1 || got[0] != "req-42" {
+		t.Fatalf("response header request id = %q", got)
+	}
+	if want := "request_id=req-42 method=" + convoypb.ConvoyService_GetInfo_FullMethodName + " code=OK"; !strings.Contains(logs(), want) {
+		t.Fatalf("logs missing %q:\n%s", want, logs())
+	}
+
+	stream, err := client.ExecuteCommandStream(metadata.AppendToOutgoingContext(context.Background(), convoypb.RequestIDMetadataKey, "req-43"),
+		&convoypb.CommandRequest{Args: []string{"rm", "-rf", "/tmp/x"}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if err == nil {
+		t.Fatalf("expected the denied command to fail")
+	}
+	for _, want := range []string{
+		`blocked command "rm"`,
+		"(denied_commands) request_id=req-43",
+		"request_id=req-43 method=" + convoypb.ConvoyService_ExecuteCommandStream_FullMethodName + " code=PermissionDenied",
+	} {
+		if !strings.Contains(logs(), want) {
+			t.Fatalf("logs missing %q:\n%s", want, logs())
+		}
+	}
+}
+
+func TestRequestID_GeneratedWhenMissing(t *testing.T) {
+	srv := NewServer(&Config{MaxConcurrent: 1})
+	logs := captureLogs(srv)
+	client := dialServer(t, srv)
+
+	var header metadata.MD
+	if _, err := client.GetInfo(context.Background(), &convoypb.InfoRequest{}, grpc.Header(&header)); err != nil {
+		t.Fatalf("GetInfo: %v", err)
+	}
+	got := header.Get(convoypb.RequestIDMetadataKey)
+	if len(got) != 1 || got[0] == "" {
+		t.Fatalf("no generated request id in response header: %q", got)
+	}
+	if !regexp.MustCompile(`request_id=` + regexp.QuoteMeta(got[0]) + ` method=\S+GetInfo code=OK duration=`).MatchString(logs()) {
+		t.Fatalf("logs do not carry generated id %s:\n%s", got[0], logs())
+	}
+}
@@ -4,22 +4,30 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
-	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	convoypb "convoy/api"
+	"convoy/internal/environ"
+	"convoy/internal/transfer"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
 
@@ -28,7 +36,29 @@ type Server struct {
 	cfg  *Config
 	sema chan struct{}
 	grpc *grpc.Server
-	_    sync.Mutex
+	fs   fileSystem
+	logf func(format string, args ...any)
+	// draining is closed when the agent starts shutting down so long transfers
+	// can stop early and report what they completed.
+	draining  chan struct{}
+	drainOnce sync.Once
+	_         sync.Mutex
+
+	// shells holds running shell sessions by id so dropped clients can resume them.
+	shellsMu sync.Mutex
+	shells   map[string]*shellSession
+
+	// jobs holds background jobs by id until they are forgotten; jobSlots
+	// limits how many run at once, apart from sema.
+	jobsMu   sync.Mutex
+	jobs     map[string]*job
+	jobSlots chan struct{}
+
+	// partials holds the partial files raw pushes are writing to, so two
+	// uploads of the same file cannot interleave their bytes.
+	partialsMu sync.Mutex
+	partials   map[string]bool
+
 	convoypb.UnimplementedConvoyServiceServer
 }
 
@@ -39,43 +69,111 @@ func NewServer(cfg *Config) *Server {
 		maxConcurrent = 1
 	}
 
+	maxJobs := cfg.MaxJobs
+	if maxJobs <= 0 {
+		maxJobs = maxConcurrent
+	}
+
 	return &Server{
 		cfg:  cfg,
 		sema: make(chan struct{}, maxConcurrent),
+		fs:   osFS{},
+		logf: log.Printf,
+
+		draining: make(chan struct{}),
+		shells:   make(map[string]*shellSession),
+		jobs:     make(map[string]*job),
+		jobSlots: make(chan struct{}, maxJobs),
+		partials: make(map[string]bool),
 	}
 }
 
 // Start boots the gRPC server until the context is canceled.
 func (s *Server) Start(ctx context.Context) error {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.cfg.GRPCPort))
+	network, addr, err := s.cfg.listenAddress()
+	if err != nil {
+		return err
+	}
+	lis, err := listen(network, addr)
 	if err != nil {
 		return fmt.Errorf("listen: %w", err)
 	}
 
-	s.grpc = grpc.NewServer()
-	convoypb.RegisterConvoyServiceServer(s.grpc, s)
+	s.grpc = s.newGRPCServer()
+
+	if s.cfg.HeartbeatURL != "" {
+		go s.runHeartbeat(ctx, &http.Client{})
+	}
 
 	go func() {
 		<-ctx.Done()
+		s.drain()
 		s.grpc.GracefulStop()
+		s.closeShells()
+		s.closeJobs()
 	}()
 
-	log.Printf("convoy agent listening on %d", s.cfg.GRPCPort)
+	log.Printf("convoy agent listening on %s://%s", network, lis.Addr())
 	return s.grpc.Serve(lis)
 }
 
-// ExecuteCommand runs a non-interactive command on the host.
+// drain tells in-flight copies to wrap up ahead of a graceful stop.
+func (s *Server) drain() {
+	s.drainOnce.Do(func() { close(s.draining) })
+}
+
+// newGRPCServer builds a gRPC server exposing the convoy service and, when
+// enabled, the reflection service.
+func (s *Server) newGRPCServer() *grpc.Server {
+	// Request IDs come first so calls rejected by the rate limiter are logged too.
+	opts := []grpc.ServerOption{grpc.KeepaliveEnforcementPolicy(s.keepalivePolicy())}
+	opts = append(opts, s.requestIDOptions()...)
+	opts = append(opts, s.rateLimitOptions()...)
+	server := grpc.NewServer(opts...)
+	convoypb.RegisterConvoyServiceServer(server, s)
+	if s.cfg.EnableReflection {
+		reflection.Register(server)
+	}
+	return server
+}
+
+// keepalivePolicy accepts client pings down to KeepaliveMinTime, even between streams.
+func (s *Server) keepalivePolicy() keepalive.EnforcementPolicy {
+	minTime := s.cfg.KeepaliveMinTime
+	if minTime <= 0 {
+		minTime = defaultKeepaliveMin * time.Second
+	}
+	return keepalive.EnforcementPolicy{MinTime: minTime, PermitWithoutStream: true}
+}
+
+// workDir returns the directory to run a command in: the requested one, else the configured default.
+func (s *Server) workDir(requested string) string {
+	if requested != "" {
+		return requested
+	}
+	return s.cfg.DefaultWorkDir
+}
+
+// ExecuteCommand runs a non-interactive command on the host. On timeout or
+// cancellation the command is killed along with any processes it started.
 func (s *Server) ExecuteCommand(ctx context.Context, req *convoypb.CommandRequest) (*convoypb.CommandResponse, error) {
 	if len(req.GetArgs()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "args required")
 	}
+	if err := s.checkCommand(ctx, req.GetArgs()); err != nil {
+		return nil, err
+	}
+	runAs, err := lookupUser(req.GetUser())
+	if err != nil {
+		return nil, err
+	}
 
 	if err := s.acquire(ctx); err != nil {
 		return nil, err
 	}
 	defer s.release()
 
-	timeout := durationFromRequest(req.GetTimeoutSeconds(), s.cfg.ExecTimeout)
+	timeout := s.durationFromRequest(ctx, req.GetTimeoutSeconds())
 	cmdCtx := ctx
 	var cancel context.CancelFunc
 	if timeout > 0 {
@@ -84,18 +182,25 @@ func (s *Server) ExecuteCommand(ctx context.Context, req *convoypb.CommandReques
 	}
 
 	cmd := exec.CommandContext(cmdCtx, req.GetArgs()[0], req.GetArgs()[1:]...)
-	cmd.Dir = req.GetWorkDir()
-	cmd.Env = mergeEnv(req.GetEnv())
+	killGroupOnCancel(cmd)
+	runAs.apply(cmd)
+	cmd.Dir = s.workDir(req.GetWorkDir())
+	cmd.Env = mergeEnv(s.cfg.EnvPassthrough, runAs.env(req.GetEnv()))
+	if len(req.GetStdin()) > 0 {
+		cmd.Stdin = bytes.NewReader(req.GetStdin())
+	}
 
-	var stdoutBuf, stderrBuf bytes.Buffer
-	cmd.Stdout = &stdoutBuf
-	cmd.Stderr = &stderrBuf
+	stdoutBuf := &cappedBuffer{limit: s.cfg.MaxOutputBytes}
+	stderrBuf := &cappedBuffer{limit: s.cfg.MaxOutputBytes}
+	cmd.Stdout = stdoutBuf
+	cmd.Stderr = stderrBuf
 
-	err := cmd.Run()
+	err = finishedRun(cmd.Run())
 
 	resp := &convoypb.CommandResponse{
-		Stdout: stdoutBuf.String(),
-		Stderr: stderrBuf.String(),
+		Stdout:    stdoutBuf.String(),
+		Stderr:    stderrBuf.String(),
+		Truncated: stdoutBuf.truncated || stderrBuf.truncated,
 	}
 
 	if err != nil {
@@ -116,6 +221,11 @@ func (s *Server) ExecuteCommand(ctx context.Context, req *convoypb.CommandReques
 			return resp, status.Error(codes.Canceled, "command canceled")
 		}
 
+		// The command ran and exited non-zero; the caller reads the exit code from the response.
+		if exitErr != nil && exitErr.Exited() {
+			return resp, nil
+		}
+
 		return resp, status.Errorf(codes.Unknown, "command failed: %v", err)
 	}
 
@@ -123,196 +233,149 @@ func (s *Server) ExecuteCommand(ctx context.Context, req *convoypb.CommandReques
 	return resp, nil
 }
 
-// ExecuteShell runs an interactive shell session streamed over gRPC.
-func (s *Server) ExecuteShell(stream convoypb.ConvoyService_ExecuteShellServer) error {
+// ExecuteCommandStream runs a non-interactive command and streams stdout/stderr as it is produced.
+// The final message carries the exit code; output is never buffered beyond a single write.
+func (s *Server) ExecuteCommandStream(req *convoypb.CommandRequest, stream convoypb.ConvoyService_ExecuteCommandStreamServer) error {
+	if len(req.GetArgs()) == 0 {
+		return status.Error(codes.InvalidArgument, "args required")
+	}
+
 	ctx := stream.Context()
-	if err := s.acquire(ctx); err != nil {
+	if err := s.checkCommand(ctx, req.GetArgs()); err != nil {
 		return err
 	}
-	defer s.release()
-
-	firstReq, err := stream.Recv()
+	runAs, err := lookupUser(req.GetUser())
 	if err != nil {
 		return err
 	}
-
-	start := firstReq.GetStart()
-	if start == nil {
-		return status.Error(codes.InvalidArgument, "first message must be start")
-	}
-
-	args := start.GetArgs()
-	if len(args) == 0 {
-		args = []string{s.cfg.ShellPath}
+	if err := s.acquire(ctx); err != nil {
+		return err
 	}
+	defer s.release()
 
+	timeout := s.durationFromRequest(ctx, req.GetTimeoutSeconds())
 	cmdCtx := ctx
 	var cancel context.CancelFunc
-	if s.cfg.ExecTimeout > 0 {
-		cmdCtx, cancel = context.WithTimeout(ctx, s.cfg.ExecTimeout)
+	if timeout > 0 {
+		cmdCtx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 
-	cmd := exec.CommandContext(cmdCtx, args[0], args[1:]...)
-	cmd.Env = mergeEnv(start.GetEnv())
-	cmd.Dir = start.GetWorkDir()
-
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return status.Errorf(codes.Internal, "stdin pipe: %v", err)
-	}
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return status.Errorf(codes.Internal, "stdout pipe: %v", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return status.Errorf(codes.Internal, "stderr pipe: %v", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return status.Errorf(codes.Internal, "start shell: %v", err)
+	cmd := exec.CommandContext(cmdCtx, req.GetArgs()[0], req.GetArgs()[1:]...)
+	killGroupOnCancel(cmd)
+	runAs.apply(cmd)
+	cmd.Dir = s.workDir(req.GetWorkDir())
+	cmd.Env = mergeEnv(s.cfg.EnvPassthrough, runAs.env(req.GetEnv()))
+	if len(req.GetStdin()) > 0 {
+		cmd.Stdin = bytes.NewReader(req.GetStdin())
 	}
 
-	outputCh := make(chan *convoypb.ShellResponse, 16)
-	errCh := make(chan error, 2)
-	var wg sync.WaitGroup
-	wg.Add(2)
+	// exec copies stdout and stderr from separate goroutines; serialize the sends.
+	var sendMu sync.Mutex
+	cmd.Stdout = &outputStreamWriter{mu: &sendMu, stream: stream, kind: convoypb.ShellOutput_STDOUT}
+	cmd.Stderr = &outputStreamWriter{mu: &sendMu, stream: stream, kind: convoypb.ShellOutput_STDERR}
 
-	streamPipe := func(r io.Reader, streamType convoypb.ShellOutput_Stream) {
-		defer wg.Done()
-		buf := make([]byte, 32*1024)
-		for {
-			n, readErr := r.Read(buf)
-			if n > 0 {
-				chunk := make([]byte, n)
-				copy(chunk, buf[:n])
-				resp := &convoypb.ShellResponse{
-					Payload: &convoypb.ShellResponse_Output{
-						Output: &convoypb.ShellOutput{Stream: streamType, Data: chunk},
-					},
-				}
+	exit := &convoypb.ShellExit{}
+	if err := finishedRun(cmd.Run()); err != nil {
+		var exitErr *exec.ExitError
+		exit.ExitCode = -1
+		exit.Message = err.Error()
+		switch {
+		case errors.Is(cmdCtx.Err(), context.DeadlineExceeded):
+			exit.Message = "command timed out"
+		case errors.Is(cmdCtx.Err(), context.Canceled):
+			return status.Error(codes.Canceled, "command canceled")
+		case errors.As(err, &exitErr):
+			exit.ExitCode = int32(exitErr.ExitCode())
+		}
+	}
 
-				select {
-				case outputCh <- resp:
-				case <-cmdCtx.Done():
-					return
-				}
-			}
+	sendMu.Lock()
+	defer sendMu.Unlock()
+	return stream.Send(&convoypb.ShellResponse{
+		Payload: &convoypb.ShellResponse_Exit{Exit: exit},
+	})
+}
 
-			if readErr != nil {
-				if errors.Is(readErr, io.EOF) {
-					return
-				}
-				errCh <- readErr
-				return
-			}
-		}
+// finishedRun maps the error of running a command killed as a group on cancel.
+// A command that exited on its own while a background child it started still
+// held its output open ends with ErrWaitDelay once groupWaitDelay has passed;
+// it succeeded, and its exit status stays in cmd.ProcessState.
+func finishedRun(err error) error {
+	if errors.Is(err, exec.ErrWaitDelay) {
+		return nil
 	}
+	return err
+}
 
-	go streamPipe(stdout, convoypb.ShellOutput_STDOUT)
-	go streamPipe(stderr, convoypb.ShellOutput_STDERR)
+// outputStreamWriter forwards each write as a ShellOutput message.
+type outputStreamWriter struct {
+	mu     *sync.Mutex
+	stream convoypb.ConvoyService_ExecuteCommandStreamServer
+	kind   convoypb.ShellOutput_Stream
+}
 
-	go func() {
-		wg.Wait()
-		close(outputCh)
-		close(errCh)
-	}()
+func (w *outputStreamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-	inputErrCh := make(chan error, 1)
-	go func() {
-		for {
-			req, recvErr := stream.Recv()
-			if recvErr == io.EOF {
-				inputErrCh <- stdin.Close()
-				return
-			}
-			if recvErr != nil {
-				inputErrCh <- recvErr
-				return
-			}
-			input := req.GetInput()
-			if input == nil {
-				continue
-			}
-			if len(input.GetData()) > 0 {
-				if _, writeErr := stdin.Write(input.GetData()); writeErr != nil {
-					inputErrCh <- writeErr
-					return
-				}
-			}
-			if input.GetEof() {
-				inputErrCh <- stdin.Close()
-				return
-			}
-		}
-	}()
+	data := append([]byte(nil), p...)
+	if err := w.stream.Send(&convoypb.ShellResponse{
+		Payload: &convoypb.ShellResponse_Output{
+			Output: &convoypb.ShellOutput{Stream: w.kind, Data: data},
+		},
+	}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
 
-	for {
-		select {
-		case resp, ok := <-outputCh:
-			if !ok {
-				outputCh = nil
-				continue
-			}
-			if resp == nil {
-				continue
-			}
-			if err := stream.Send(resp); err != nil {
-				_ = cmd.Process.Kill()
-				return err
-			}
-		case pipeErr, ok := <-errCh:
-			if ok && pipeErr != nil {
-				_ = cmd.Process.Kill()
-				return pipeErr
-			}
-		case inputErr := <-inputErrCh:
-			if inputErr != nil {
-				_ = cmd.Process.Kill()
-				return inputErr
-			}
-			inputErrCh = nil
-		case <-cmdCtx.Done():
-			_ = cmd.Process.Kill()
-			return cmdCtx.Err()
-		default:
-			if outputCh == nil && inputErrCh == nil {
-				goto waitExit
-			}
-			time.Sleep(10 * time.Millisecond)
-		}
+// CheckHealth reports the agent's readiness.
+func (s *Server) CheckHealth(_ context.Context, req *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	log.Printf("health check requested")
+	health, message := s.readiness()
+	if caller := req.GetProtocolVersion(); !sameProtocolMajor(caller, convoypb.ProtocolVersion) {
+		health = convoypb.HealthResponse_STATUS_UNHEALTHY
+		message = fmt.Sprintf("caller speaks protocol %s, incompatible with the agent's %s", caller, convoypb.ProtocolVersion)
 	}
+	return &convoypb.HealthResponse{
+		Status:          health,
+		Message:         message,
+		ProtocolVersion: convoypb.ProtocolVersion,
+	}, nil
+}
 
-waitExit:
-	if err := cmd.Wait(); err != nil {
-		var exitErr *exec.ExitError
-		msg := err.Error()
-		exitCode := int32(-1)
-		if errors.As(err, &exitErr) {
-			exitCode = int32(exitErr.ExitCode())
-			msg = exitErr.Error()
-		}
-		return stream.Send(&convoypb.ShellResponse{
-			Payload: &convoypb.ShellResponse_Exit{
-				Exit: &convoypb.ShellExit{ExitCode: exitCode, Message: msg},
-			},
-		})
+// sameProtocolMajor reports whether two major.minor protocol versions share a
+// major version. Callers that send none predate versioning and are accepted.
+func sameProtocolMajor(caller, ours string) bool {
+	if caller == "" {
+		return true
 	}
+	callerMajor, _, _ := strings.Cut(caller, ".")
+	ourMajor, _, _ := strings.Cut(ours, ".")
+	return callerMajor == ourMajor
+}
 
-	return stream.Send(&convoypb.ShellResponse{
-		Payload: &convoypb.ShellResponse_Exit{
-			Exit: &convoypb.ShellExit{ExitCode: 0, Message: ""},
-		},
-	})
+// readiness reports whether the agent can take work: unhealthy once it is
+// draining for shutdown, degraded while every concurrency slot is taken.
+func (s *Server) readiness() (convoypb.HealthResponse_Status, string) {
+	select {
+	case <-s.draining:
+		return convoypb.HealthResponse_STATUS_UNHEALTHY, "shutting down"
+	default:
+	}
+	if len(s.sema) >= cap(s.sema) {
+		return convoypb.HealthResponse_STATUS_DEGRADED, fmt.Sprintf("all %d slots busy", cap(s.sema))
+	}
+	return convoypb.HealthResponse_STATUS_HEALTHY, "ok"
 }
 
-// CheckHealth reports basic readiness.
-func (s *Server) CheckHealth(_ context.Context, _ *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
-	log.Printf("health check requested")
-	return &convoypb.HealthResponse{
-		Status:  convoypb.HealthResponse_STATUS_HEALTHY,
-		Message: "ok",
+// GetInfo reports the agent identity.
+func (s *Server) GetInfo(_ context.Context, _ *convoypb.InfoRequest) (*convoypb.InfoResponse, error) {
+	hostname, _ := os.Hostname()
+	return &convoypb.InfoResponse{
+		AgentId:  s.cfg.AgentID,
+		Hostname: hostname,
 	}, nil
 }
 
@@ -337,6 +400,9 @@ func (s *Server) Copy(stream convoypb.ConvoyService_CopyServer) error {
 
 	switch start.GetDirection() {
 	case convoypb.CopyStart_TO_AGENT:
+		if start.GetRaw() {
+			return s.handleRawToAgent(stream, start)
+		}
 		return s.handleCopyToAgent(stream, start)
 	case convoypb.CopyStart_FROM_AGENT:
 		return s.handleCopyFromAgent(stream, start)
@@ -358,21 +424,46 @@ func (s *Server) handleCopyToAgent(stream convoypb.ConvoyService_CopyServer, sta
 		return status.Errorf(codes.Internal, "failed to create destination directory: %v", err)
 	}
 
+	// An atomic copy extracts into a staging directory; checks for existing
+	// files still look at the destination.
+	extractRoot := destRoot
+	var stage *staging
+	if start.GetAtomic() {
+		var err error
+		if stage, err = newStaging(destRoot); err != nil {
+			return status.Errorf(codes.Internal, "%v", err)
+		}
+		defer stage.discard()
+		extractRoot = stage.root
+	}
+
 	// Create a pipe to stream tar data
 	pr, pw := io.Pipe()
 	tarReader := tar.NewReader(pr)
 
 	var extractErr error
 	var totalBytes int64
-	var fileCount int32
+	var fileCount, skippedNewer, skippedExisting int32
+	// Directory metadata is applied last so writing their contents doesn't reset it.
+	var dirs []*tar.Header
 	extractDone := make(chan struct{})
 
 	// Extract tar in a goroutine
 	go func() {
 		defer close(extractDone)
+		// Keep consuming after an extraction error so the receive loop never blocks on the pipe.
+		defer func() {
+			_, _ = io.Copy(io.Discard, pr)
+		}()
 		for {
 			header, err := tarReader.Next()
 			if err == io.EOF {
+				for i := len(dirs) - 1; i >= 0; i-- {
+					if err := transfer.ApplyTarMetadata(filepath.Join(extractRoot, dirs[i].Name), dirs[i], tarMetadata(start)); err != nil {
+						extractErr = err
+						return
+					}
+				}
 				return
 			}
 			if err != nil {
@@ -380,14 +471,18 @@ func (s *Server) handleCopyToAgent(stream convoypb.ConvoyService_CopyServer, sta
 				return
 			}
 
-			targetPath := filepath.Join(destRoot, header.Name)
+			targetPath := filepath.Join(extractRoot, header.Name)
+			livePath := filepath.Join(destRoot, header.Name)
 
-			// Security check: prevent path traversal
-			rel, err := filepath.Rel(destRoot, targetPath)
-			if err != nil || strings.HasPrefix(rel, "..") {
+			// Security check: prevent path traversal, directly or through a symlink
+			if !transfer.InsideRoot(extractRoot, targetPath) {
 				extractErr = fmt.Errorf("invalid tar entry path: %s", header.Name)
 				return
 			}
+			if err := transfer.CheckParents(extractRoot, targetPath); err != nil {
+				extractErr = err
+				return
+			}
 
 			switch header.Typeflag {
 			case tar.TypeDir:
@@ -395,50 +490,135 @@ func (s *Server) handleCopyToAgent(stream convoypb.ConvoyService_CopyServer, sta
 					extractErr = fmt.Errorf("failed to create directory %s: %w", targetPath, err)
 					return
 				}
+				dirs = append(dirs, header)
 			case tar.TypeReg:
+				// Leave existing files untouched when overwrite is disabled
+				if !start.GetOverwrite() {
+					if _, err := os.Lstat(livePath); err == nil {
+						skippedExisting++
+						continue
+					}
+				}
+
+				// Keep the existing file when it is newer than the incoming entry
+				if start.GetSkipNewer() {
+					if info, err := os.Stat(livePath); err == nil && info.ModTime().After(header.ModTime) {
+						skippedNewer++
+						continue
+					}
+				}
+
 				// Ensure parent directory exists
 				if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
 					extractErr = fmt.Errorf("failed to create parent directory: %w", err)
 					return
 				}
 
-				file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+				// Replace a symlink in the way rather than writing through it
+				if info, err := os.Lstat(targetPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+					_ = os.Remove(targetPath)
+				}
+
+				file, err := s.fs.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
 				if err != nil {
 					extractErr = fmt.Errorf("failed to create file %s: %w", targetPath, err)
 					return
 				}
 
-				written, err := io.Copy(file, tarReader)
+				written, err := io.Copy(&retryWriter{w: file, retries: s.cfg.WriteRetries, backoff: s.cfg.WriteRetryBackoff}, tarReader)
 				_ = file.Close()
 				if err != nil {
 					extractErr = fmt.Errorf("failed to write file %s: %w", targetPath, err)
 					return
 				}
+				if err := transfer.ApplyTarMetadata(targetPath, header, tarMetadata(start)); err != nil {
+					extractErr = err
+					return
+				}
 				totalBytes += written
 				fileCount++
 
 			case tar.TypeSymlink:
+				if start.GetNoFollow() && transfer.LinkEscapes(extractRoot, targetPath, header.Linkname) {
+					extractErr = fmt.Errorf("symlink %s points outside the destination: %s", header.Name, header.Linkname)
+					return
+				}
+
 				// Remove existing symlink if overwrite is enabled
 				if start.GetOverwrite() {
 					_ = os.Remove(targetPath)
+				} else if stage != nil {
+					if _, err := os.Lstat(livePath); err == nil {
+						extractErr = fmt.Errorf("failed to create symlink %s: %w", livePath, os.ErrExist)
+						return
+					}
 				}
 				if err := os.Symlink(header.Linkname, targetPath); err != nil {
 					extractErr = fmt.Errorf("failed to create symlink %s: %w", targetPath, err)
 					return
 				}
+				if start.GetPreserveOwner() && os.Geteuid() == 0 {
+					if err := os.Lchown(targetPath, header.Uid, header.Gid); err != nil {
+						extractErr = fmt.Errorf("failed to set owner of %s: %w", targetPath, err)
+						return
+					}
+				}
 				fileCount++
+
+			case tar.TypeLink:
+				extractErr = fmt.Errorf("hardlink entries are not supported: %s", header.Name)
+				return
 			}
 		}
 	}()
 
-	// Receive chunks and write to pipe
+	recvCh := receiveCopy(stream)
+
+	// Write chunks to the pipe. The transfer is only complete once the client
+	// sends a chunk with eof set; the stream closing before that means data was lost.
+	hash := sha256.New()
+	var checksum string
 	for {
-		req, err := stream.Recv()
+		var r copyReceived
+		var ok bool
+		select {
+		case r, ok = <-recvCh:
+			if !ok {
+				// The receiver gave up because the client went away.
+				r.err = stream.Context().Err()
+			}
+		case <-s.draining:
+			_ = pw.CloseWithError(errors.New("agent shutting down"))
+			<-extractDone
+			// Report what was written before the interruption; files in the tar
+			// after that point were not created. An atomic copy wrote nothing.
+			if stage != nil {
+				totalBytes, fileCount, skippedNewer, skippedExisting = 0, 0, 0, 0
+			}
+			return stream.Send(&convoypb.CopyResponse{
+				Payload: &convoypb.CopyResponse_Result{
+					Result: &convoypb.CopyResult{
+						Success:         false,
+						Message:         "interrupted",
+						TotalBytes:      totalBytes,
+						FileCount:       fileCount,
+						SkippedNewer:    skippedNewer,
+						SkippedExisting: skippedExisting,
+					},
+				},
+			})
+		}
+
+		req, err := r.req, r.err
 		if err == io.EOF {
-			break
+			err = errors.New("stream closed before final chunk")
+			_ = pw.CloseWithError(err)
+			<-extractDone
+			return status.Errorf(codes.DataLoss, "copy incomplete: %v", err)
 		}
 		if err != nil {
 			_ = pw.CloseWithError(err)
+			<-extractDone
 			return status.Errorf(codes.Internal, "receive error: %v", err)
 		}
 
@@ -451,14 +631,16 @@ func (s *Server) handleCopyToAgent(stream convoypb.ConvoyService_CopyServer, sta
 			if _, err := pw.Write(chunk.GetData()); err != nil {
 				return status.Errorf(codes.Internal, "pipe write error: %v", err)
 			}
+			hash.Write(chunk.GetData())
 		}
 
 		if chunk.GetEof() {
+			checksum = chunk.GetSha256()
 			break
 		}
 	}
 
-	// Close pipe writer to signal EOF to tar reader
+	// Every chunk has been written; close the pipe so the tar reader sees EOF.
 	_ = pw.Close()
 
 	// Wait for extraction to complete
@@ -468,19 +650,275 @@ func (s *Server) handleCopyToAgent(stream convoypb.ConvoyService_CopyServer, sta
 		return status.Errorf(codes.Internal, "extraction failed: %v", extractErr)
 	}
 
+	if stage != nil {
+		if sum := hex.EncodeToString(hash.Sum(nil)); checksum != "" && checksum != sum {
+			return status.Errorf(codes.DataLoss, "checksum mismatch: got %s, client sent %s", sum, checksum)
+		}
+		if err := stage.commit(); err != nil {
+			return status.Errorf(codes.Internal, "commit failed: %v", err)
+		}
+		// Clean up before reporting success so the caller never sees the staging directory.
+		stage.discard()
+		// Moving entries into existing directories changed their times.
+		for i := len(dirs) - 1; i >= 0; i-- {
+			if err := transfer.ApplyTarMetadata(filepath.Join(destRoot, dirs[i].Name), dirs[i], tarMetadata(start)); err != nil {
+				return status.Errorf(codes.Internal, "%v", err)
+			}
+		}
+	}
+
 	// Send success result
+	return stream.Send(&convoypb.CopyResponse{
+		Payload: &convoypb.CopyResponse_Result{
+			Result: &convoypb.CopyResult{
+				Success:         true,
+				Message:         "copy completed successfully",
+				TotalBytes:      totalBytes,
+				FileCount:       fileCount,
+				SkippedNewer:    skippedNewer,
+				SkippedExisting: skippedExisting,
+			},
+		},
+	})
+}
+
+// handleRawToAgent receives a single file without tar framing. Data goes to a
+// partial file named after the destination and total size, which survives an
+// interrupted transfer. The agent first reports how many bytes it already
+// holds and their checksum; the client answers with a second CopyStart whose
+// offset is either that count, to resume, or zero, to start over.
+func (s *Server) handleRawToAgent(stream convoypb.ConvoyService_CopyServer, start *convoypb.CopyStart) error {
+	target := filepath.Clean(start.GetPath())
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		if start.GetName() == "" {
+			return status.Errorf(codes.InvalidArgument, "destination %s is a directory", target)
+		}
+		target = filepath.Join(target, filepath.Base(start.GetName()))
+	}
+	size := start.GetSize()
+	if size < 0 {
+		return status.Errorf(codes.InvalidArgument, "invalid size %d", size)
+	}
+	if start.GetAtomic() || start.GetPreserveTimes() || start.GetPreserveOwner() || start.GetSkipNewer() || len(start.GetExclude()) > 0 {
+		return status.Error(codes.InvalidArgument, "raw copies do not support atomic, preserve, skip-newer or exclude options")
+	}
+	if !start.GetOverwrite() {
+		if _, err := os.Lstat(target); err == nil {
+			return status.Errorf(codes.AlreadyExists, "destination %s exists and overwrite is disabled", target)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return status.Errorf(codes.Internal, "failed to create destination directory: %v", err)
+	}
+	partial := fmt.Sprintf("%s.%d.partial", target, size)
+	if !s.claimPartial(partial) {
+		return status.Errorf(codes.Aborted, "another upload to %s is in progress", target)
+	}
+	defer s.releasePartial(partial)
+
+	hash := sha256.New()
+	var held int64
+	if file, err := os.Open(partial); err == nil {
+		held, err = io.Copy(hash, file)
+		_ = file.Close()
+		if err != nil {
+			return status.Errorf(codes.Internal, "read partial file: %v", err)
+		}
+	}
+	if held > size {
+		held = 0
+		hash.Reset()
+	}
+
+	if err := stream.Send(&convoypb.CopyResponse{
+		Payload: &convoypb.CopyResponse_Progress{
+			Progress: &convoypb.CopyProgress{BytesTransferred: held, CurrentFile: target, Sha256: hex.EncodeToString(hash.Sum(nil))},
+		},
+	}); err != nil {
+		return status.Errorf(codes.Internal, "send error: %v", err)
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to receive resume offset: %v", err)
+	}
+	resume := req.GetStart()
+	if resume == nil {
+		return status.Error(codes.InvalidArgument, "second message must be CopyStart with the resume offset")
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	offset := resume.GetOffset()
+	switch {
+	case offset == 0:
+		flags |= os.O_TRUNC
+		hash.Reset()
+	case offset == held:
+		flags |= os.O_APPEND
+	default:
+		return status.Errorf(codes.OutOfRange, "offset %d does not match the %d bytes held", offset, held)
+	}
+
+	file, err := s.fs.OpenFile(partial, flags, 0o644)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to open partial file: %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	w := &retryWriter{w: file, retries: s.cfg.WriteRetries, backoff: s.cfg.WriteRetryBackoff}
+
+	received := offset
+	var checksum string
+	recvCh := receiveCopy(stream)
+	for {
+		var r copyReceived
+		var ok bool
+		select {
+		case r, ok = <-recvCh:
+			if !ok {
+				// The receiver gave up because the client went away.
+				r.err = stream.Context().Err()
+			}
+		case <-s.draining:
+			// The partial file is kept so the upload can resume once the agent is back.
+			return stream.Send(&convoypb.CopyResponse{
+				Payload: &convoypb.CopyResponse_Result{
+					Result: &convoypb.CopyResult{
+						Success:    false,
+						Message:    "interrupted",
+						TotalBytes: received - offset,
+					},
+				},
+			})
+		}
+
+		req, err := r.req, r.err
+		if err == io.EOF {
+			// The partial file is kept so the next attempt can resume from it.
+			return status.Errorf(codes.DataLoss, "copy incomplete after %d of %d bytes", received, size)
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "receive error: %v", err)
+		}
+
+		chunk := req.GetChunk()
+		if chunk == nil {
+			continue
+		}
+		if data := chunk.GetData(); len(data) > 0 {
+			if received+int64(len(data)) > size {
+				return status.Errorf(codes.InvalidArgument, "received more than the announced %d bytes", size)
+			}
+			if _, err := w.Write(data); err != nil {
+				return status.Errorf(codes.Internal, "failed to write %s: %v", partial, err)
+			}
+			hash.Write(data)
+			received += int64(len(data))
+		}
+		if chunk.GetEof() {
+			checksum = chunk.GetSha256()
+			break
+		}
+	}
+
+	if received != size {
+		return status.Errorf(codes.DataLoss, "copy incomplete after %d of %d bytes", received, size)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); checksum != "" && checksum != sum {
+		_ = os.Remove(partial)
+		return status.Errorf(codes.DataLoss, "checksum mismatch: got %s, client sent %s", sum, checksum)
+	}
+	if err := file.Close(); err != nil {
+		return status.Errorf(codes.Internal, "failed to close %s: %v", partial, err)
+	}
+	if err := finalizePartial(partial, target, start.GetOverwrite()); err != nil {
+		return err
+	}
+
 	return stream.Send(&convoypb.CopyResponse{
 		Payload: &convoypb.CopyResponse_Result{
 			Result: &convoypb.CopyResult{
 				Success:    true,
 				Message:    "copy completed successfully",
-				TotalBytes: totalBytes,
-				FileCount:  fileCount,
+				TotalBytes: received - offset,
+				FileCount:  1,
 			},
 		},
 	})
 }
 
+// copyReceived is one result of stream.Recv on a copy stream.
+type copyReceived struct {
+	req *convoypb.CopyRequest
+	err error
+}
+
+// receiveCopy receives copy requests in the background so a shutdown can
+// interrupt a transfer that is waiting on the client. It stops after an error
+// or the final chunk, or once the stream's context is done, so a handler may
+// stop reading at any point; the channel is then closed.
+func receiveCopy(stream convoypb.ConvoyService_CopyServer) <-chan copyReceived {
+	recvCh := make(chan copyReceived, 1)
+	go func() {
+		defer close(recvCh)
+		for {
+			req, err := stream.Recv()
+			select {
+			case recvCh <- copyReceived{req, err}:
+			case <-stream.Context().Done():
+				return
+			}
+			if err != nil || req.GetChunk().GetEof() {
+				return
+			}
+		}
+	}()
+	return recvCh
+}
+
+// claimPartial reserves a partial file for one upload, reporting false when
+// another upload is already writing to it.
+func (s *Server) claimPartial(partial string) bool {
+	s.partialsMu.Lock()
+	defer s.partialsMu.Unlock()
+	if s.partials[partial] {
+		return false
+	}
+	s.partials[partial] = true
+	return true
+}
+
+func (s *Server) releasePartial(partial string) {
+	s.partialsMu.Lock()
+	delete(s.partials, partial)
+	s.partialsMu.Unlock()
+}
+
+// finalizePartial moves a completed upload into place. Without overwrite it is
+// linked rather than renamed, so a file created at target meanwhile is kept.
+func finalizePartial(partial, target string, overwrite bool) error {
+	if overwrite {
+		if err := os.Rename(partial, target); err != nil {
+			return status.Errorf(codes.Internal, "failed to finalize %s: %v", target, err)
+		}
+		return nil
+	}
+	if err := os.Link(partial, target); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return status.Errorf(codes.AlreadyExists, "destination %s exists and overwrite is disabled", target)
+		}
+		return status.Errorf(codes.Internal, "failed to finalize %s: %v", target, err)
+	}
+	_ = os.Remove(partial)
+	return nil
+}
+
+// tarMetadata is what extraction restores onto each entry, as start asks.
+func tarMetadata(start *convoypb.CopyStart) transfer.Metadata {
+	return transfer.Metadata{Times: start.GetPreserveTimes(), Owner: start.GetPreserveOwner()}
+}
+
 // handleCopyFromAgent reads from local filesystem and sends tar data to client.
 func (s *Server) handleCopyFromAgent(stream convoypb.ConvoyService_CopyServer, start *convoypb.CopyStart) error {
 	srcPath := start.GetPath()
@@ -488,10 +926,29 @@ func (s *Server) handleCopyFromAgent(stream convoypb.ConvoyService_CopyServer, s
 		return status.Error(codes.InvalidArgument, "source path required for pull operation")
 	}
 
-	// Check if source exists
-	srcInfo, err := os.Stat(srcPath)
-	if err != nil {
-		return status.Errorf(codes.NotFound, "source path not found: %v", err)
+	if start.GetRaw() {
+		return s.handleRawFromAgent(stream, srcPath, start.GetOffset(), start.GetSha256())
+	}
+
+	// Expand glob patterns; a literal path is packed as before.
+	var matches []string
+	var srcInfo os.FileInfo
+	if transfer.HasGlobMeta(srcPath) {
+		var err error
+		matches, err = filepath.Glob(srcPath)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid pattern %q: %v", srcPath, err)
+		}
+		if len(matches) == 0 {
+			return status.Errorf(codes.NotFound, "no files match %q", srcPath)
+		}
+	} else {
+		// Check if source exists
+		var err error
+		srcInfo, err = os.Stat(srcPath)
+		if err != nil {
+			return status.Errorf(codes.NotFound, "source path not found: %v", err)
+		}
 	}
 
 	// Create a pipe to stream tar data
@@ -511,25 +968,28 @@ func (s *Server) handleCopyFromAgent(stream convoypb.ConvoyService_CopyServer, s
 			_ = pw.Close()
 		}()
 
-		if srcInfo.IsDir() {
-			tarErr = filepath.Walk(srcPath, func(path string, info os.FileInfo, walkErr error) error {
-				if walkErr != nil {
-					return walkErr
+		switch {
+		case matches != nil:
+			// Glob matches keep their layout relative to the first wildcard's parent.
+			root := transfer.GlobRoot(srcPath)
+			for _, match := range matches {
+				info, err := os.Stat(match)
+				if err != nil {
+					tarErr = err
+					return
 				}
-
-				relPath, err := filepath.Rel(srcPath, path)
+				relPath, err := filepath.Rel(root, match)
 				if err != nil {
-					return err
+					tarErr = err
+					return
 				}
-
-				// Skip the root directory itself
-				if relPath == "." {
-					return nil
+				if tarErr = s.addTreeToTar(tarWriter, match, filepath.ToSlash(relPath), info, start.GetExclude(), &totalBytes, &fileCount); tarErr != nil {
+					return
 				}
-
-				return s.addToTar(tarWriter, path, relPath, info, &totalBytes, &fileCount)
-			})
-		} else {
+			}
+		case srcInfo.IsDir():
+			tarErr = s.addTreeToTar(tarWriter, srcPath, "", srcInfo, start.GetExclude(), &totalBytes, &fileCount)
+		default:
 			// Single file
 			tarErr = s.addToTar(tarWriter, srcPath, filepath.Base(srcPath), srcInfo, &totalBytes, &fileCount)
 		}
@@ -594,6 +1054,144 @@ func (s *Server) handleCopyFromAgent(stream convoypb.ConvoyService_CopyServer, s
 	})
 }
 
+// handleRawFromAgent streams a single regular file starting at offset without
+// tar framing. A resumed pull sends the checksum of the bytes it already holds;
+// when the file is now shorter than offset or no longer starts with those bytes
+// the transfer restarts from zero. A CopyProgress announcing the offset actually
+// used precedes the data.
+func (s *Server) handleRawFromAgent(stream convoypb.ConvoyService_CopyServer, srcPath string, offset int64, held string) error {
+	if transfer.HasGlobMeta(srcPath) {
+		return status.Error(codes.InvalidArgument, "raw copy does not support patterns")
+	}
+	if offset < 0 {
+		return status.Errorf(codes.InvalidArgument, "invalid offset %d", offset)
+	}
+
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return status.Errorf(codes.NotFound, "source path not found: %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return status.Errorf(codes.Internal, "stat source: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		return status.Error(codes.InvalidArgument, "raw copy requires a regular file")
+	}
+	if offset > info.Size() {
+		offset = 0
+	}
+	if offset > 0 && held != "" {
+		hash := sha256.New()
+		if _, err := io.CopyN(hash, file, offset); err != nil {
+			return status.Errorf(codes.Internal, "read source: %v", err)
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != held {
+			offset = 0
+		}
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return status.Errorf(codes.Internal, "seek source: %v", err)
+	}
+	if err := stream.Send(&convoypb.CopyResponse{
+		Payload: &convoypb.CopyResponse_Progress{
+			Progress: &convoypb.CopyProgress{BytesTransferred: offset, CurrentFile: srcPath},
+		},
+	}); err != nil {
+		return status.Errorf(codes.Internal, "send error: %v", err)
+	}
+
+	var totalBytes int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := file.Read(buf)
+		if n > 0 {
+			chunk := make([]byte, n)
+			copy(chunk, buf[:n])
+			if err := stream.Send(&convoypb.CopyResponse{
+				Payload: &convoypb.CopyResponse_Chunk{
+					Chunk: &convoypb.CopyChunk{Data: chunk},
+				},
+			}); err != nil {
+				return status.Errorf(codes.Internal, "send error: %v", err)
+			}
+			totalBytes += int64(n)
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return status.Errorf(codes.Internal, "read error: %v", readErr)
+		}
+	}
+
+	if err := stream.Send(&convoypb.CopyResponse{
+		Payload: &convoypb.CopyResponse_Chunk{
+			Chunk: &convoypb.CopyChunk{Eof: true},
+		},
+	}); err != nil {
+		return status.Errorf(codes.Internal, "send EOF error: %v", err)
+	}
+
+	return stream.Send(&convoypb.CopyResponse{
+		Payload: &convoypb.CopyResponse_Result{
+			Result: &convoypb.CopyResult{
+				Success:    true,
+				Message:    "copy completed successfully",
+				TotalBytes: totalBytes,
+				FileCount:  1,
+			},
+		},
+	})
+}
+
+// addTreeToTar adds a file or directory tree to the tar archive under name, skipping excluded paths.
+// An empty name packs a directory's contents at the archive root.
+func (s *Server) addTreeToTar(tw *tar.Writer, srcPath, name string, info os.FileInfo, exclude []string, totalBytes *int64, fileCount *int32) error {
+	if name != "" && transfer.IsExcluded(exclude, name, info.IsDir()) {
+		return nil
+	}
+
+	if !info.IsDir() {
+		return s.addToTar(tw, srcPath, name, info, totalBytes, fileCount)
+	}
+
+	return filepath.Walk(srcPath, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		relPath, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return err
+		}
+
+		// Skip the root directory itself unless it is named
+		if relPath == "." {
+			if name == "" {
+				return nil
+			}
+			return s.addToTar(tw, path, name, info, totalBytes, fileCount)
+		}
+
+		entryName := filepath.ToSlash(filepath.Join(name, relPath))
+		if transfer.IsExcluded(exclude, entryName, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return s.addToTar(tw, path, entryName, info, totalBytes, fileCount)
+	})
+}
+
 // addToTar adds a file or directory to the tar archive.
 func (s *Server) addToTar(tw *tar.Writer, srcPath, relPath string, info os.FileInfo, totalBytes *int64, fileCount *int32) error {
 	header, err := tar.FileInfoHeader(info, "")
@@ -601,6 +1199,8 @@ func (s *Server) addToTar(tw *tar.Writer, srcPath, relPath string, info os.FileI
 		return err
 	}
 	header.Name = relPath
+	// PAX keeps sub-second modification times and access times.
+	header.Format = tar.FormatPAX
 
 	// Handle symlinks
 	if info.Mode()&os.ModeSymlink != 0 {
@@ -654,33 +1254,90 @@ func (s *Server) release() {
 	}
 }
 
-func durationFromRequest(seconds int32, fallback time.Duration) time.Duration {
+// durationFromRequest returns the timeout for a command: the requested number
+// of seconds, or ExecTimeout when none was requested, clamped to MaxExecTimeout.
+func (s *Server) durationFromRequest(ctx context.Context, seconds int32) time.Duration {
+	timeout := s.cfg.ExecTimeout
 	if seconds > 0 {
-		return time.Duration(seconds) * time.Second
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	if ceiling := s.cfg.MaxExecTimeout; ceiling > 0 && (timeout <= 0 || timeout > ceiling) {
+		s.logf("agent %s: clamped command timeout %s to max_exec_timeout %s for %s request_id=%s", s.cfg.AgentID, timeout, ceiling, peerKey(ctx), requestID(ctx))
+		timeout = ceiling
 	}
-	return fallback
+	return timeout
 }
 
-func mergeEnv(overrides map[string]string) []string {
+// mergeEnv builds a command's environment: the agent's own variables matching
+// passthrough (all of them when passthrough is nil) overlaid with overrides.
+func mergeEnv(passthrough []string, overrides map[string]string) []string {
 	base := map[string]string{}
 	for _, kv := range os.Environ() {
 		parts := strings.SplitN(kv, "=", 2)
-		if len(parts) == 2 {
+		if len(parts) == 2 && (passthrough == nil || matchesAny(passthrough, parts[0])) {
 			base[parts[0]] = parts[1]
 		}
 	}
 
-	for k, v := range overrides {
-		if k == "" {
-			continue
-		}
-		base[k] = v
-	}
+	merged := environ.Merge(base, overrides)
+	delete(merged, "")
 
-	result := make([]string, 0, len(base))
-	for k, v := range base {
+	result := make([]string, 0, len(merged))
+	for k, v := range merged {
 		result = append(result, fmt.Sprintf("%s=%s", k, v))
 	}
 
 	return result
 }
+
+// writableFile is the subset of *os.File used when extracting copied files.
+type writableFile interface {
+	io.Writer
+	Chmod(mode os.FileMode) error
+	Close() error
+}
+
+// fileSystem opens files for extraction; tests swap it to inject write failures.
+type fileSystem interface {
+	OpenFile(name string, flag int, perm os.FileMode) (writableFile, error)
+}
+
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (writableFile, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+// retryWriter retries writes that fail with a transient error, up to retries
+// times per file with exponential backoff. Other errors are returned at once.
+type retryWriter struct {
+	w       io.Writer
+	retries int
+	backoff time.Duration
+}
+
+func (r *retryWriter) Write(p []byte) (int, error) {
+	var total int
+	delay := r.backoff
+	for {
+		n, err := r.w.Write(p[total:])
+		total += n
+		if err == nil {
+			return total, nil
+		}
+		if r.retries <= 0 || !isTransientWriteError(err) {
+			return total, err
+		}
+		r.retries--
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func isTransientWriteError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) ||
+		errors.Is(err, syscall.EIO) ||
+		errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EINTR)
+}
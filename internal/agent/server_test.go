@@ -0,0 +1,751 @@
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialServer serves srv over an in-memory listener and returns a client for it.
+func dialServer(t *testing.T, srv *Server) convoypb.ConvoyServiceClient {
+	t.Helper()
+	return convoypb.NewConvoyServiceClient(dialConn(t, srv))
+}
+
+// dialConn serves srv over an in-memory listener and returns a connection to it.
+func dialConn(t *testing.T, srv *Server) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := srv.newGRPCServer()
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func TestExecuteCommandStream_DeliversOutputIncrementally(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	start := time.Now()
+	stream, err := client.ExecuteCommandStream(context.Background(), &convoypb.CommandRequest{
+		Args: []string{"sh", "-c", "echo first; sleep 1; echo second >&2; exit 3"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCommandStream: %v", err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("recv first: %v", err)
+	}
+	if got := string(first.GetOutput().GetData()); got != "first\n" {
+		t.Fatalf("first chunk = %q", got)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Fatalf("first chunk arrived after %s; output was buffered until exit", elapsed)
+	}
+
+	var stderr string
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if exit := resp.GetExit(); exit != nil {
+			if exit.GetExitCode() != 3 {
+				t.Fatalf("exit code = %d, want 3", exit.GetExitCode())
+			}
+			break
+		}
+		if resp.GetOutput().GetStream() == convoypb.ShellOutput_STDERR {
+			stderr += string(resp.GetOutput().GetData())
+		}
+	}
+	if stderr != "second\n" {
+		t.Fatalf("stderr = %q", stderr)
+	}
+}
+
+func TestExecuteCommandStream_LabelsStderrSeparately(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	stream, err := client.ExecuteCommandStream(context.Background(), &convoypb.CommandRequest{
+		Args: []string{"sh", "-c", "echo out1; echo err1 >&2; echo out2; echo err2 >&2"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCommandStream: %v", err)
+	}
+
+	var stdout, stderr string
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if resp.GetExit() != nil {
+			break
+		}
+		output := resp.GetOutput()
+		switch output.GetStream() {
+		case convoypb.ShellOutput_STDOUT:
+			stdout += string(output.GetData())
+		case convoypb.ShellOutput_STDERR:
+			stderr += string(output.GetData())
+		default:
+			t.Fatalf("chunk %q has no stream label", output.GetData())
+		}
+	}
+	if stdout != "out1\nout2\n" || stderr != "err1\nerr2\n" {
+		t.Fatalf("stdout = %q, stderr = %q", stdout, stderr)
+	}
+}
+
+func TestExecuteCommand_FeedsStdin(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	resp, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{
+		Args:  []string{"cat"},
+		Stdin: []byte("piped input\n"),
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCommand: %v", err)
+	}
+	if resp.GetStdout() != "piped input\n" {
+		t.Fatalf("stdout = %q", resp.GetStdout())
+	}
+}
+
+func TestExecuteCommand_ReportsExitCodeInResponse(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	resp, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{
+		Args: []string{"sh", "-c", "echo oops >&2; exit 3"},
+	})
+	if err != nil {
+		t.Fatalf("non-zero exit should not be an RPC error: %v", err)
+	}
+	if resp.GetExitCode() != 3 || resp.GetStderr() != "oops\n" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func tarWith(t *testing.T, name, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatalf("write body: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// pushTar streams data to the agent, optionally ending with an EOF chunk, and returns the outcome.
+func pushTar(t *testing.T, client convoypb.ConvoyServiceClient, dest string, data []byte, sendEOF bool) (*convoypb.CopyResult, error) {
+	t.Helper()
+	stream, err := client.Copy(context.Background())
+	if err != nil {
+		t.Fatalf("open copy: %v", err)
+	}
+	msgs := []*convoypb.CopyRequest{
+		{Payload: &convoypb.CopyRequest_Start{Start: &convoypb.CopyStart{Direction: convoypb.CopyStart_TO_AGENT, Path: dest, Overwrite: true}}},
+		{Payload: &convoypb.CopyRequest_Chunk{Chunk: &convoypb.CopyChunk{Data: data}}},
+	}
+	if sendEOF {
+		msgs = append(msgs, &convoypb.CopyRequest{Payload: &convoypb.CopyRequest_Chunk{Chunk: &convoypb.CopyChunk{Eof: true}}})
+	}
+	for _, msg := range msgs {
+		if err := stream.Send(msg); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("close send: %v", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetResult(), nil
+}
+
+func TestCopyToAgent_RequiresFinalChunk(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 2}))
+	data := tarWith(t, "file.txt", "complete")
+
+	dest := t.TempDir()
+	result, err := pushTar(t, client, dest, data, true)
+	if err != nil {
+		t.Fatalf("copy with EOF chunk: %v", err)
+	}
+	if result.GetFileCount() != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "file.txt")); string(got) != "complete" {
+		t.Fatalf("extracted content = %q", got)
+	}
+
+	// Closing the stream mid-archive must fail rather than report a truncated success.
+	if _, err := pushTar(t, client, t.TempDir(), data[:len(data)/2], false); status.Code(err) != codes.DataLoss {
+		t.Fatalf("expected DataLoss for premature close, got %v", err)
+	}
+}
+
+// flakyFS fails the first failures writes to each opened file with err.
+type flakyFS struct {
+	err      error
+	failures int
+	writes   int
+}
+
+func (f *flakyFS) OpenFile(name string, flag int, perm os.FileMode) (writableFile, error) {
+	file, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyFile{File: file, fs: f, remaining: f.failures}, nil
+}
+
+type flakyFile struct {
+	*os.File
+	fs        *flakyFS
+	remaining int
+}
+
+func (f *flakyFile) Write(p []byte) (int, error) {
+	f.fs.writes++
+	if f.remaining > 0 {
+		f.remaining--
+		return 0, &os.PathError{Op: "write", Path: f.Name(), Err: f.fs.err}
+	}
+	return f.File.Write(p)
+}
+
+func TestCopyToAgent_RetriesTransientWriteErrors(t *testing.T) {
+	fs := &flakyFS{err: syscall.ENOSPC, failures: 2}
+	srv := NewServer(&Config{MaxConcurrent: 1, WriteRetries: 3, WriteRetryBackoff: time.Millisecond})
+	srv.fs = fs
+	client := dialServer(t, srv)
+
+	dest := t.TempDir()
+	if _, err := pushTar(t, client, dest, tarWith(t, "file.txt", "eventually"), true); err != nil {
+		t.Fatalf("copy with transient failures: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "file.txt")); string(got) != "eventually" {
+		t.Fatalf("extracted content = %q", got)
+	}
+	if fs.writes != 3 {
+		t.Fatalf("expected 2 failed writes and 1 success, got %d writes", fs.writes)
+	}
+}
+
+func TestCopyToAgent_PermanentWriteErrorFailsFast(t *testing.T) {
+	fs := &flakyFS{err: syscall.EBADF, failures: 1}
+	srv := NewServer(&Config{MaxConcurrent: 1, WriteRetries: 3, WriteRetryBackoff: time.Millisecond})
+	srv.fs = fs
+	client := dialServer(t, srv)
+
+	if _, err := pushTar(t, client, t.TempDir(), tarWith(t, "file.txt", "never"), true); err == nil {
+		t.Fatalf("expected a permanent write error to fail the copy")
+	}
+	if fs.writes != 1 {
+		t.Fatalf("permanent error was retried: %d writes", fs.writes)
+	}
+}
+
+func TestReflection_GatedByConfig(t *testing.T) {
+	listServices := func(enabled bool) ([]string, error) {
+		conn := dialConn(t, NewServer(&Config{MaxConcurrent: 1, EnableReflection: enabled}))
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		req := &reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}}
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, svc := range resp.GetListServicesResponse().GetService() {
+			names = append(names, svc.GetName())
+		}
+		return names, nil
+	}
+
+	names, err := listServices(true)
+	if err != nil {
+		t.Fatalf("list services with reflection enabled: %v", err)
+	}
+	if !slices.Contains(names, "convoy.ConvoyService") {
+		t.Fatalf("ConvoyService not listed: %v", names)
+	}
+
+	if _, err := listServices(false); status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected Unimplemented with reflection disabled, got %v", err)
+	}
+}
+
+func TestKeepalive_PolicyAcceptsConformantClients(t *testing.T) {
+	if got := NewServer(&Config{}).keepalivePolicy(); got.MinTime != defaultKeepaliveMin*time.Second || !got.PermitWithoutStream {
+		t.Fatalf("unexpected default policy: %+v", got)
+	}
+
+	srv := NewServer(&Config{MaxConcurrent: 1, KeepaliveMinTime: 10 * time.Second})
+	if got := srv.keepalivePolicy().MinTime; got != 10*time.Second {
+		t.Fatalf("configured MinTime not applied: %s", got)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := srv.newGRPCServer()
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	// A client pinging at the agent's minimum interval must be served normally.
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: 10 * time.Second, Timeout: time.Second, PermitWithoutStream: true}),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	resp, err := convoypb.NewConvoyServiceClient(conn).ExecuteCommand(context.Background(), &convoypb.CommandRequest{Args: []string{"echo", "alive"}})
+	if err != nil {
+		t.Fatalf("ExecuteCommand: %v", err)
+	}
+	if resp.GetStdout() != "alive\n" {
+		t.Fatalf("unexpected stdout %q", resp.GetStdout())
+	}
+}
+
+func TestExecuteCommand_DefaultWorkDir(t *testing.T) {
+	defaultDir := t.TempDir()
+	overrideDir := t.TempDir()
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1, DefaultWorkDir: defaultDir}))
+
+	tests := []struct {
+		workDir string
+		want    string
+	}{
+		{workDir: "", want: defaultDir},
+		{workDir: overrideDir, want: overrideDir},
+	}
+	for _, tt := range tests {
+		resp, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{Args: []string{"pwd"}, WorkDir: tt.workDir})
+		if err != nil {
+			t.Fatalf("ExecuteCommand: %v", err)
+		}
+		if got := strings.TrimSpace(resp.GetStdout()); got != tt.want {
+			t.Fatalf("work dir %q: ran in %q, want %q", tt.workDir, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteCommand_TruncatesOutputPastLimit(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1, MaxOutputBytes: 10}))
+
+	resp, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{
+		Args: []string{"sh", "-c", "printf 0123456789abcdef; printf short >&2"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCommand: %v", err)
+	}
+	if resp.GetStdout() != "0123456789" {
+		t.Fatalf("stdout = %q, want the first 10 bytes", resp.GetStdout())
+	}
+	if resp.GetStderr() != "short" {
+		t.Fatalf("stderr = %q, want it untouched by the stdout cap", resp.GetStderr())
+	}
+	if !resp.GetTruncated() || resp.GetExitCode() != 0 {
+		t.Fatalf("truncated = %v, exit code = %d", resp.GetTruncated(), resp.GetExitCode())
+	}
+}
+
+func TestExecuteCommand_TruncatesOnRuneBoundary(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1, MaxOutputBytes: 4}))
+
+	// The cap falls inside the two-byte é, whether it arrives in one write or two.
+	for _, script := range []string{`printf 'abcé'`, `printf 'abc\303'; printf '\251xyz'`} {
+		resp, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{Args: []string{"sh", "-c", script}})
+		if err != nil {
+			t.Fatalf("ExecuteCommand(%s): %v", script, err)
+		}
+		if resp.GetStdout() != "abc" || !resp.GetTruncated() {
+			t.Fatalf("%s: stdout = %q truncated = %v, want \"abc\" cut before the partial rune", script, resp.GetStdout(), resp.GetTruncated())
+		}
+	}
+}
+
+func TestDurationFromRequest(t *testing.T) {
+	cases := []struct {
+		name     string
+		cfg      Config
+		seconds  int32
+		want     time.Duration
+		wantsLog bool
+	}{
+		{name: "request within ceiling", cfg: Config{ExecTimeout: time.Minute, MaxExecTimeout: time.Hour}, seconds: 120, want: 2 * time.Minute},
+		{name: "request above ceiling", cfg: Config{ExecTimeout: time.Minute, MaxExecTimeout: time.Hour}, seconds: 7200, want: time.Hour, wantsLog: true},
+		{name: "zero falls back", cfg: Config{ExecTimeout: time.Minute, MaxExecTimeout: time.Hour}, want: time.Minute},
+		{name: "fallback above ceiling", cfg: Config{ExecTimeout: 2 * time.Hour, MaxExecTimeout: time.Hour}, want: time.Hour, wantsLog: true},
+		{name: "no ceiling", cfg: Config{ExecTimeout: time.Minute}, seconds: 1 << 30, want: (1 << 30) * time.Second},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			srv := NewServer(&cfg)
+			logs := captureLogs(srv)
+
+			if got := srv.durationFromRequest(context.Background(), tc.seconds); got != tc.want {
+				t.Fatalf("timeout = %s, want %s", got, tc.want)
+			}
+			if logged := strings.Contains(logs(), "clamped command timeout"); logged != tc.wantsLog {
+				t.Fatalf("clamp logged = %v, want %v (logs %q)", logged, tc.wantsLog, logs())
+			}
+		})
+	}
+}
+
+func TestExecuteCommand_ClampsRequestedTimeout(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1, MaxExecTimeout: time.Second}))
+
+	start := time.Now()
+	_, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{
+		Args:           []string{"sleep", "30"},
+		TimeoutSeconds: 3600,
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("ExecuteCommand: got %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("command ran for %s despite the 1s ceiling", elapsed)
+	}
+}
+
+func TestExecuteCommand_EnvPassthrough(t *testing.T) {
+	t.Setenv("CONVOY_TEST_SECRET", "hunter2")
+	t.Setenv("CONVOY_TEST_KEEP", "kept")
+	t.Setenv("CONVOY_TEST_REPLACED", "host")
+
+	tests := []struct {
+		name        string
+		passthrough []string
+		want        []string
+		absent      []string
+	}{
+		{
+			name: "default inherits everything",
+			want: []string{"CONVOY_TEST_SECRET=hunter2", "CONVOY_TEST_KEEP=kept", "CONVOY_TEST_REPLACED=request", "CONVOY_TEST_EXTRA=request"},
+		},
+		{
+			name:        "allowlist filters host variables",
+			passthrough: []string{"CONVOY_TEST_KEEP", "CONVOY_TEST_REPL*"},
+			want:        []string{"CONVOY_TEST_KEEP=kept", "CONVOY_TEST_REPLACED=request", "CONVOY_TEST_EXTRA=request"},
+			absent:      []string{"CONVOY_TEST_SECRET="},
+		},
+		{
+			name:        "empty allowlist keeps only request variables",
+			passthrough: []string{},
+			want:        []string{"CONVOY_TEST_REPLACED=request", "CONVOY_TEST_EXTRA=request"},
+			absent:      []string{"CONVOY_TEST_SECRET=", "CONVOY_TEST_KEEP="},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dialServer(t, NewServer(&Config{MaxConcurrent: 1, EnvPassthrough: tt.passthrough}))
+			resp, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{
+				Args: []string{"env"},
+				Env:  map[string]string{"CONVOY_TEST_REPLACED": "request", "CONVOY_TEST_EXTRA": "request"},
+			})
+			if err != nil {
+				t.Fatalf("ExecuteCommand: %v", err)
+			}
+
+			lines := strings.Split(resp.GetStdout(), "\n")
+			for _, want := range tt.want {
+				if !slices.Contains(lines, want) {
+					t.Errorf("missing %s in environment:\n%s", want, resp.GetStdout())
+				}
+			}
+			for _, prefix := range tt.absent {
+				if strings.Contains(resp.GetStdout(), prefix) {
+					t.Errorf("unexpected %s in environment:\n%s", prefix, resp.GetStdout())
+				}
+			}
+		})
+	}
+}
+
+func TestCopyToAgent_ShutdownReportsPartialProgress(t *testing.T) {
+	srv := NewServer(&Config{MaxConcurrent: 1})
+	client := dialServer(t, srv)
+	dest := t.TempDir()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, content string }{{"first.txt", "hello"}, {"second.txt", "never arrives"}} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(f.content)); err != nil {
+			t.Fatalf("write body: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+
+	stream, err := client.Copy(context.Background())
+	if err != nil {
+		t.Fatalf("open copy: %v", err)
+	}
+	// Send the first entry and the second header, then stall as a slow client would.
+	for _, msg := range []*convoypb.CopyRequest{
+		{Payload: &convoypb.CopyRequest_Start{Start: &convoypb.CopyStart{Direction: convoypb.CopyStart_TO_AGENT, Path: dest, Overwrite: true}}},
+		{Payload: &convoypb.CopyRequest_Chunk{Chunk: &convoypb.CopyChunk{Data: buf.Bytes()[:1536]}}},
+	} {
+		if err := stream.Send(msg); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, _ := os.ReadFile(filepath.Join(dest, "first.txt")); string(data) == "hello" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("first file was never written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	srv.drain()
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("expected a result message, got %v", err)
+	}
+	result := resp.GetResult()
+	if result.GetSuccess() || result.GetMessage() != "interrupted" {
+		t.Fatalf("result = %+v, want an interrupted failure", result)
+	}
+	if result.GetFileCount() != 1 || result.GetTotalBytes() != int64(len("hello")) {
+		t.Fatalf("partial progress = %d files, %d bytes; want 1 file, 5 bytes", result.GetFileCount(), result.GetTotalBytes())
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "second.txt")); string(data) == "never arrives" {
+		t.Fatalf("second file should not have been completed")
+	}
+}
+
+func TestRawCopyToAgent_ShutdownKeepsPartialFile(t *testing.T) {
+	srv := NewServer(&Config{MaxConcurrent: 1})
+	client := dialServer(t, srv)
+	target := filepath.Join(t.TempDir(), "blob")
+
+	stream, err := client.Copy(context.Background())
+	if err != nil {
+		t.Fatalf("open copy: %v", err)
+	}
+	start := &convoypb.CopyStart{Direction: convoypb.CopyStart_TO_AGENT, Raw: true, Path: target, Size: 10, Overwrite: true}
+	if err := stream.Send(&convoypb.CopyRequest{Payload: &convoypb.CopyRequest_Start{Start: start}}); err != nil {
+		t.Fatalf("send start: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("receive offer: %v", err)
+	}
+	// Resume from nothing, send part of the file, then stall as a slow client would.
+	for _, msg := range []*convoypb.CopyRequest{
+		{Payload: &convoypb.CopyRequest_Start{Start: &convoypb.CopyStart{}}},
+		{Payload: &convoypb.CopyRequest_Chunk{Chunk: &convoypb.CopyChunk{Data: []byte("0123")}}},
+	} {
+		if err := stream.Send(msg); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	partial := target + ".10.partial"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, _ := os.ReadFile(partial); string(data) == "0123" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("partial file was never written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	srv.drain()
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("expected a result message, got %v", err)
+	}
+	if result := resp.GetResult(); result.GetSuccess() || result.GetMessage() != "interrupted" || result.GetTotalBytes() != 4 {
+		t.Fatalf("result = %+v, want an interrupted failure after 4 bytes", result)
+	}
+	if data, err := os.ReadFile(partial); err != nil || string(data) != "0123" {
+		t.Fatalf("partial file = %q, %v; want it kept for a resume", data, err)
+	}
+}
+
+// pushAtomic sends data as an atomic copy to dest, ending with an EOF chunk carrying
+// checksum unless interrupted, and returns the outcome.
+func pushAtomic(t *testing.T, client convoypb.ConvoyServiceClient, dest string, data []byte, checksum string, interrupted bool) (*convoypb.CopyResult, error) {
+	t.Helper()
+	stream, err := client.Copy(context.Background())
+	if err != nil {
+		t.Fatalf("open copy: %v", err)
+	}
+	msgs := []*convoypb.CopyRequest{
+		{Payload: &convoypb.CopyRequest_Start{Start: &convoypb.CopyStart{Direction: convoypb.CopyStart_TO_AGENT, Path: dest, Overwrite: true, Atomic: true}}},
+		{Payload: &convoypb.CopyRequest_Chunk{Chunk: &convoypb.CopyChunk{Data: data}}},
+	}
+	if !interrupted {
+		msgs = append(msgs, &convoypb.CopyRequest{Payload: &convoypb.CopyRequest_Chunk{Chunk: &convoypb.CopyChunk{Eof: true, Sha256: checksum}}})
+	}
+	for _, msg := range msgs {
+		if err := stream.Send(msg); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("close send: %v", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetResult(), nil
+}
+
+// assertNoStaging fails if a staging directory was left next to dest.
+func assertNoStaging(t *testing.T, dest string) {
+	t.Helper()
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(dest), ".convoy-*"))
+	if len(leftovers) != 0 {
+		t.Fatalf("staging left behind: %v", leftovers)
+	}
+}
+
+func TestCopyToAgent_AtomicReplacesOnSuccess(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+	dest := filepath.Join(t.TempDir(), "app")
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "config.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	data := tarWith(t, "config.txt", "new")
+	sum := sha256.Sum256(data)
+	if _, err := pushAtomic(t, client, dest, data, hex.EncodeToString(sum[:]), false); err != nil {
+		t.Fatalf("atomic copy: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "config.txt")); string(got) != "new" {
+		t.Fatalf("content = %q, want the new file", got)
+	}
+	assertNoStaging(t, dest)
+}
+
+func TestCopyToAgent_AtomicInterruptedLeavesDestinationUntouched(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+	dest := filepath.Join(t.TempDir(), "app")
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "config.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, content string }{{"config.txt", "new"}, {"extra.txt", "never arrives"}} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(f.content)); err != nil {
+			t.Fatalf("write body: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+
+	// The first entry arrives in full before the stream is cut mid-archive.
+	if _, err := pushAtomic(t, client, dest, buf.Bytes()[:1536], "", true); status.Code(err) != codes.DataLoss {
+		t.Fatalf("expected DataLoss for an interrupted copy, got %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "config.txt")); string(got) != "old" {
+		t.Fatalf("content = %q, want the original file", got)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "extra.txt")); !os.IsNotExist(err) {
+		t.Fatalf("extra.txt should not exist: %v", err)
+	}
+	assertNoStaging(t, dest)
+}
+
+func TestCopyToAgent_AtomicRejectsChecksumMismatch(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+	dest := filepath.Join(t.TempDir(), "app")
+
+	_, err := pushAtomic(t, client, dest, tarWith(t, "config.txt", "new"), strings.Repeat("0", 64), false)
+	if status.Code(err) != codes.DataLoss {
+		t.Fatalf("expected DataLoss for a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "config.txt")); !os.IsNotExist(err) {
+		t.Fatalf("config.txt should not have been committed: %v", err)
+	}
+	assertNoStaging(t, dest)
+}
@@ -0,0 +1,461 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Size of a tty session's terminal when the client does not give one.
+const (
+	defaultTTYRows = 24
+	defaultTTYCols = 80
+)
+
+// shellSession is a running shell that outlives the stream attached to it, so a
+// client whose connection drops can reattach within the resume grace period.
+type shellSession struct {
+	id     string
+	stdin  io.WriteCloser
+	cancel context.CancelFunc
+	// output carries the shell's stdout and stderr; it is closed once both are drained.
+	output chan *convoypb.ShellResponse
+	// done is closed once the process has exited and exit is set.
+	done     chan struct{}
+	exit     *convoypb.ShellExit
+	timedOut bool
+
+	mu sync.Mutex
+	// attachment is closed to take the session away from the stream serving it;
+	// nil while no stream is attached.
+	attachment chan struct{}
+	// pending is output taken for a stream that broke before it was delivered.
+	pending     *convoypb.ShellResponse
+	expiry      *time.Timer
+	expired     bool
+	stdinClosed bool
+}
+
+// ExecuteShell runs an interactive shell session streamed over gRPC. The first
+// response names the session. If the stream breaks, the shell keeps running for
+// ShellResumeGrace and a new stream can reattach by sending the session id in
+// ShellStart.resume_session_id; output produced meanwhile is held back until then.
+func (s *Server) ExecuteShell(stream convoypb.ConvoyService_ExecuteShellServer) error {
+	ctx := stream.Context()
+
+	firstReq, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	start := firstReq.GetStart()
+	if start == nil {
+		return status.Error(codes.InvalidArgument, "first message must be start")
+	}
+
+	var sess *shellSession
+	resumed := start.GetResumeSessionId() != ""
+	if resumed {
+		sess, err = s.lookupShell(start.GetResumeSessionId())
+	} else {
+		sess, err = s.startShell(ctx, start)
+	}
+	if err != nil {
+		return err
+	}
+
+	attachment, ok := sess.attach()
+	if !ok {
+		return status.Errorf(codes.NotFound, "shell session %s has expired", sess.id)
+	}
+
+	if err := stream.Send(&convoypb.ShellResponse{
+		Payload: &convoypb.ShellResponse_Session{
+			Session: &convoypb.ShellSession{SessionId: sess.id, Resumed: resumed},
+		},
+	}); err != nil {
+		s.detachShell(sess, attachment)
+		return err
+	}
+
+	return s.serveShell(stream, sess, attachment)
+}
+
+// startShell launches a shell and registers it as a session. The process is
+// bound to the session rather than the stream so it survives a dropped connection.
+func (s *Server) startShell(ctx context.Context, start *convoypb.ShellStart) (*shellSession, error) {
+	args := start.GetArgs()
+	if len(args) == 0 {
+		args = []string{s.cfg.ShellPath}
+	}
+	if err := s.checkCommand(ctx, args); err != nil {
+		return nil, err
+	}
+	runAs, err := lookupUser(start.GetUser())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	procCtx, cancel := context.WithCancel(context.Background())
+	if s.cfg.ExecTimeout > 0 {
+		procCtx, cancel = context.WithTimeout(context.Background(), s.cfg.ExecTimeout)
+	}
+
+	cmd := exec.CommandContext(procCtx, args[0], args[1:]...)
+	cmd.Env = mergeEnv(s.cfg.EnvPassthrough, runAs.env(start.GetEnv()))
+	cmd.Dir = s.workDir(start.GetWorkDir())
+
+	fail := func(code codes.Code, format string, err error) (*shellSession, error) {
+		cancel()
+		s.release()
+		return nil, status.Errorf(code, format, err)
+	}
+
+	var (
+		stdin   io.WriteCloser
+		outputs = map[convoypb.ShellOutput_Stream]io.Reader{}
+		// terminal is the pseudo-terminal of a tty session, closed once the shell exits.
+		terminal *os.File
+	)
+	if start.GetTty() {
+		rows, cols := uint16(start.GetRows()), uint16(start.GetCols())
+		if rows == 0 || cols == 0 {
+			rows, cols = defaultTTYRows, defaultTTYCols
+		}
+		runAs.apply(cmd)
+		if terminal, err = startWithTTY(cmd, rows, cols); err != nil {
+			return fail(codes.Internal, "start shell on a terminal: %v", err)
+		}
+		stdin = terminalInput{terminal}
+		outputs[convoypb.ShellOutput_STDOUT] = terminal
+	} else {
+		killGroupOnCancel(cmd)
+		runAs.apply(cmd)
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return fail(codes.Internal, "stdin pipe: %v", err)
+		}
+		if outputs[convoypb.ShellOutput_STDOUT], err = cmd.StdoutPipe(); err != nil {
+			return fail(codes.Internal, "stdout pipe: %v", err)
+		}
+		if outputs[convoypb.ShellOutput_STDERR], err = cmd.StderrPipe(); err != nil {
+			return fail(codes.Internal, "stderr pipe: %v", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return fail(codes.Internal, "start shell: %v", err)
+		}
+	}
+
+	sess := &shellSession{
+		id:     newSessionID(),
+		stdin:  stdin,
+		cancel: cancel,
+		output: make(chan *convoypb.ShellResponse, 16),
+		done:   make(chan struct{}),
+	}
+
+	var pumps sync.WaitGroup
+	pump := func(r io.Reader, streamType convoypb.ShellOutput_Stream) {
+		defer pumps.Done()
+		buf := make([]byte, 32*1024)
+		for {
+			n, readErr := r.Read(buf)
+			if n > 0 {
+				chunk := make([]byte, n)
+				copy(chunk, buf[:n])
+				resp := &convoypb.ShellResponse{
+					Payload: &convoypb.ShellResponse_Output{
+						Output: &convoypb.ShellOutput{Stream: streamType, Data: chunk},
+					},
+				}
+
+				// While no stream is attached this blocks, which in turn
+				// stalls the shell once its pipe buffer fills.
+				select {
+				case sess.output <- resp:
+				case <-procCtx.Done():
+					return
+				}
+			}
+			if readErr != nil {
+				return
+			}
+		}
+	}
+
+	for streamType, r := range outputs {
+		pumps.Add(1)
+		go pump(r, streamType)
+	}
+
+	go func() {
+		pumps.Wait()
+		close(sess.output)
+		waitErr := cmd.Wait()
+		if terminal != nil {
+			_ = terminal.Close()
+		}
+		sess.timedOut = errors.Is(procCtx.Err(), context.DeadlineExceeded)
+		sess.exit = shellExit(waitErr)
+		close(sess.done)
+		cancel()
+		s.release()
+	}()
+
+	s.shellsMu.Lock()
+	s.shells[sess.id] = sess
+	s.shellsMu.Unlock()
+
+	return sess, nil
+}
+
+// serveShell relays a session over stream until the shell exits, the stream
+// breaks (leaving the session to be resumed) or another stream takes it over.
+func (s *Server) serveShell(stream convoypb.ConvoyService_ExecuteShellServer, sess *shellSession, attachment chan struct{}) error {
+	ctx := stream.Context()
+
+	inputErrCh := make(chan error, 1)
+	go func() {
+		inputErrCh <- sess.relayInput(stream, attachment)
+	}()
+
+	for {
+		resp := sess.takePending()
+		if resp == nil {
+			var ok bool
+			select {
+			case resp, ok = <-sess.output:
+				if !ok {
+					return s.finishShell(stream, sess)
+				}
+			case inputErr := <-inputErrCh:
+				if inputErr != nil {
+					s.detachShell(sess, attachment)
+					return inputErr
+				}
+				inputErrCh = nil
+				continue
+			case <-attachment:
+				return status.Error(codes.Aborted, "shell session was resumed on another stream")
+			case <-ctx.Done():
+				s.detachShell(sess, attachment)
+				return ctx.Err()
+			}
+		}
+
+		if err := stream.Send(resp); err != nil {
+			sess.setPending(resp)
+			s.detachShell(sess, attachment)
+			return err
+		}
+	}
+}
+
+// finishShell reports the exit of a shell whose output has been fully delivered.
+func (s *Server) finishShell(stream convoypb.ConvoyService_ExecuteShellServer, sess *shellSession) error {
+	<-sess.done
+	s.forgetShell(sess.id)
+
+	if sess.timedOut {
+		return status.Error(codes.DeadlineExceeded, "shell timed out")
+	}
+	return stream.Send(&convoypb.ShellResponse{
+		Payload: &convoypb.ShellResponse_Exit{Exit: sess.exit},
+	})
+}
+
+// detachShell releases sess from a stream that broke. The shell keeps running
+// for the resume grace period and is killed if nobody reattaches by then.
+func (s *Server) detachShell(sess *shellSession, attachment chan struct{}) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.attachment != attachment {
+		// Another stream has already taken the session over.
+		return
+	}
+	sess.attachment = nil
+
+	grace := s.cfg.ShellResumeGrace
+	if grace <= 0 {
+		s.expireShellLocked(sess)
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(grace, func() {
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		if sess.expiry == timer {
+			s.expireShellLocked(sess)
+		}
+	})
+	sess.expiry = timer
+}
+
+func (s *Server) expireShellLocked(sess *shellSession) {
+	sess.expired = true
+	sess.expiry = nil
+	sess.cancel()
+	s.forgetShell(sess.id)
+}
+
+func (s *Server) lookupShell(id string) (*shellSession, error) {
+	s.shellsMu.Lock()
+	defer s.shellsMu.Unlock()
+
+	sess := s.shells[id]
+	if sess == nil {
+		return nil, status.Errorf(codes.NotFound, "shell session %s not found; it may have expired", id)
+	}
+	return sess, nil
+}
+
+func (s *Server) forgetShell(id string) {
+	s.shellsMu.Lock()
+	defer s.shellsMu.Unlock()
+	delete(s.shells, id)
+}
+
+// closeShells kills every remaining session when the agent shuts down.
+func (s *Server) closeShells() {
+	s.shellsMu.Lock()
+	sessions := make([]*shellSession, 0, len(s.shells))
+	for _, sess := range s.shells {
+		sessions = append(sessions, sess)
+	}
+	s.shellsMu.Unlock()
+
+	for _, sess := range sessions {
+		sess.mu.Lock()
+		s.expireShellLocked(sess)
+		sess.mu.Unlock()
+	}
+}
+
+// attach hands the session to a new stream, taking it from any stream still
+// attached, and returns the channel closed when it is taken away again. It
+// reports false when the session has already expired.
+func (sess *shellSession) attach() (chan struct{}, bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.expired {
+		return nil, false
+	}
+	if sess.attachment != nil {
+		close(sess.attachment)
+	}
+	if sess.expiry != nil {
+		sess.expiry.Stop()
+		sess.expiry = nil
+	}
+	sess.attachment = make(chan struct{})
+	return sess.attachment, true
+}
+
+// relayInput writes stdin data from stream to the shell until the client closes
+// its input or the stream breaks.
+func (sess *shellSession) relayInput(stream convoypb.ConvoyService_ExecuteShellServer, attachment chan struct{}) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return sess.closeStdin()
+		}
+		if err != nil {
+			return err
+		}
+
+		input := req.GetInput()
+		if input == nil {
+			continue
+		}
+		if data := input.GetData(); len(data) > 0 && sess.accepting(attachment) {
+			if _, err := sess.stdin.Write(data); err != nil {
+				// The shell closed its input; its output and exit still follow.
+				return nil
+			}
+		}
+		if input.GetEof() {
+			return sess.closeStdin()
+		}
+	}
+}
+
+// accepting reports whether input from the stream holding attachment should
+// still reach the shell.
+func (sess *shellSession) accepting(attachment chan struct{}) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.attachment == attachment && !sess.stdinClosed
+}
+
+func (sess *shellSession) closeStdin() error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.stdinClosed {
+		return nil
+	}
+	sess.stdinClosed = true
+	return sess.stdin.Close()
+}
+
+func (sess *shellSession) takePending() *convoypb.ShellResponse {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	resp := sess.pending
+	sess.pending = nil
+	return resp
+}
+
+func (sess *shellSession) setPending(resp *convoypb.ShellResponse) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.pending = resp
+}
+
+// terminalInput writes a tty session's input to its terminal. Closing it sends
+// end-of-file as a user at the terminal would, with Ctrl-D, rather than closing
+// the terminal and with it the session's output.
+type terminalInput struct {
+	*os.File
+}
+
+func (t terminalInput) Close() error {
+	_, err := t.Write([]byte{0x04})
+	return err
+}
+
+func shellExit(err error) *convoypb.ShellExit {
+	if err == nil {
+		return &convoypb.ShellExit{ExitCode: 0}
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &convoypb.ShellExit{ExitCode: int32(exitErr.ExitCode()), Message: exitErr.Error()}
+	}
+	return &convoypb.ShellExit{ExitCode: -1, Message: err.Error()}
+}
+
+func newSessionID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// openShell sends start on a new shell stream and returns it with the session it was given.
+func openShell(t *testing.T, ctx context.Context, client convoypb.ConvoyServiceClient, start *convoypb.ShellStart) (convoypb.ConvoyService_ExecuteShellClient, *convoypb.ShellSession, error) {
+	t.Helper()
+	stream, err := client.ExecuteShell(ctx)
+	if err != nil {
+		t.Fatalf("ExecuteShell: %v", err)
+	}
+	if err := stream.Send(&convoypb.ShellRequest{Payload: &convoypb.ShellRequest_Start{Start: start}}); err != nil {
+		t.Fatalf("send start: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.GetSession() == nil {
+		t.Fatalf("first response is not a session: %v", resp)
+	}
+	return stream, resp.GetSession(), nil
+}
+
+func TestExecuteShell_ResumeWithinGrace(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1, ShellResumeGrace: time.Minute}))
+
+	dropped, cancel := context.WithCancel(context.Background())
+	_, session, err := openShell(t, dropped, client, &convoypb.ShellStart{Args: []string{"sh", "-c", "read line; echo got $line"}})
+	if err != nil {
+		t.Fatalf("open shell: %v", err)
+	}
+	// Simulate the connection dropping while the shell waits for input.
+	cancel()
+
+	stream, resumed, err := openShell(t, context.Background(), client, &convoypb.ShellStart{ResumeSessionId: session.GetSessionId()})
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if !resumed.GetResumed() || resumed.GetSessionId() != session.GetSessionId() {
+		t.Fatalf("resumed session = %v, want %s", resumed, session.GetSessionId())
+	}
+
+	if err := stream.Send(&convoypb.ShellRequest{Payload: &convoypb.ShellRequest_Input{
+		Input: &convoypb.ShellInput{Data: []byte("hello\n")},
+	}}); err != nil {
+		t.Fatalf("send input: %v", err)
+	}
+
+	var stdout strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v (stdout so far %q)", err, stdout.String())
+		}
+		stdout.Write(resp.GetOutput().GetData())
+		if exit := resp.GetExit(); exit != nil {
+			if exit.GetExitCode() != 0 {
+				t.Fatalf("exit code %d: %s", exit.GetExitCode(), exit.GetMessage())
+			}
+			break
+		}
+	}
+	if stdout.String() != "got hello\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+}
+
+func TestExecuteShell_ResumeAfterExpiry(t *testing.T) {
+	srv := NewServer(&Config{MaxConcurrent: 1, ShellResumeGrace: 50 * time.Millisecond})
+	client := dialServer(t, srv)
+
+	dropped, cancel := context.WithCancel(context.Background())
+	_, session, err := openShell(t, dropped, client, &convoypb.ShellStart{Args: []string{"sleep", "30"}})
+	if err != nil {
+		t.Fatalf("open shell: %v", err)
+	}
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		srv.shellsMu.Lock()
+		remaining := len(srv.shells)
+		srv.shellsMu.Unlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session was not expired after the grace period")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	_, _, err = openShell(t, context.Background(), client, &convoypb.ShellStart{ResumeSessionId: session.GetSessionId()})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("resume after expiry: got %v, want NotFound", err)
+	}
+
+	// The expired shell's slot is released, so a new one can start.
+	ctx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if _, _, err := openShell(t, ctx, client, &convoypb.ShellStart{Args: []string{"true"}}); err != nil {
+		t.Fatalf("new shell after expiry: %v", err)
+	}
+}
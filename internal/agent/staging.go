@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// rename moves staged entries into place; tests swap it to simulate a staging
+// directory on another filesystem.
+var rename = os.Rename
+
+// staging holds an atomic copy until it is complete. Entries are extracted
+// under root and only moved into dest by commit, so a failed or interrupted
+// transfer never touches the destination.
+type staging struct {
+	dir  string
+	root string
+	dest string
+}
+
+// newStaging creates a staging directory for dest. It is placed next to dest so
+// commit can rename entries, falling back to the system temp directory when
+// that is not writable.
+func newStaging(dest string) (*staging, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(dest), ".convoy-stage-")
+	if err != nil {
+		if dir, err = os.MkdirTemp("", "convoy-stage-"); err != nil {
+			return nil, fmt.Errorf("create staging directory: %w", err)
+		}
+	}
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("create staging directory: %w", err)
+	}
+	return &staging{dir: dir, root: root, dest: dest}, nil
+}
+
+// discard removes the staging directory and anything left in it.
+func (st *staging) discard() {
+	_ = os.RemoveAll(st.dir)
+}
+
+// committed records one entry moved into the destination by commit. backup
+// holds the entry it replaced, or is empty when there was none.
+type committed struct {
+	target string
+	backup string
+}
+
+// commit moves every staged entry into the destination. Existing directories
+// are merged; other entries in the way are first moved aside, so a failure
+// part-way through restores the destination as it was.
+func (st *staging) commit() error {
+	var journal []committed
+	err := filepath.WalkDir(st.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(st.root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		target := filepath.Join(st.dest, rel)
+
+		existing, err := os.Lstat(target)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return err
+		case d.IsDir() && existing.IsDir():
+			return nil
+		case existing.IsDir():
+			return fmt.Errorf("cannot replace directory %s with a file", target)
+		}
+
+		entry := committed{target: target}
+		if existing != nil {
+			entry.backup = filepath.Join(filepath.Dir(target), ".convoy-old-"+filepath.Base(target))
+			if err := os.Rename(target, entry.backup); err != nil {
+				return fmt.Errorf("move aside %s: %w", target, err)
+			}
+		}
+		if err := moveEntry(path, target); err != nil {
+			if entry.backup != "" {
+				_ = os.Rename(entry.backup, target)
+			}
+			return fmt.Errorf("move %s into place: %w", rel, err)
+		}
+		journal = append(journal, entry)
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		for i := len(journal) - 1; i >= 0; i-- {
+			_ = os.RemoveAll(journal[i].target)
+			if journal[i].backup != "" {
+				_ = os.Rename(journal[i].backup, journal[i].target)
+			}
+		}
+		return err
+	}
+
+	for _, entry := range journal {
+		if entry.backup != "" {
+			_ = os.RemoveAll(entry.backup)
+		}
+	}
+	return nil
+}
+
+// moveEntry renames src to dst, copying it instead when they are on different
+// filesystems.
+func moveEntry(src, dst string) error {
+	err := rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyEntry(src, dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyEntry recreates the file, symlink or directory tree at src as dst,
+// keeping permissions and modification times.
+func copyEntry(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, dst)
+	case info.IsDir():
+		if err := os.Mkdir(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyEntry(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := copyFile(src, dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Chmod(mode); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// stageFile writes content at rel under the staging root.
+func stageFile(t *testing.T, st *staging, rel, content string) {
+	t.Helper()
+	path := filepath.Join(st.root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestStagingCommit_CrossDeviceFallsBackToCopy(t *testing.T) {
+	rename = func(string, string) error { return &os.LinkError{Op: "rename", Err: syscall.EXDEV} }
+	t.Cleanup(func() { rename = os.Rename })
+
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, "app.conf"), []byte("old"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	st, err := newStaging(dest)
+	if err != nil {
+		t.Fatalf("newStaging: %v", err)
+	}
+	defer st.discard()
+	stageFile(t, st, "app.conf", "new")
+	stageFile(t, st, "conf.d/extra.conf", "extra")
+
+	if err := st.commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	for rel, want := range map[string]string{"app.conf": "new", "conf.d/extra.conf": "extra"} {
+		if got, _ := os.ReadFile(filepath.Join(dest, rel)); string(got) != want {
+			t.Fatalf("%s = %q, want %q", rel, got, want)
+		}
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dest, ".convoy-old-*")); len(leftovers) != 0 {
+		t.Fatalf("backups left behind: %v", leftovers)
+	}
+}
+
+func TestStagingCommit_RollsBackOnFailure(t *testing.T) {
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, "a.conf"), []byte("old a"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	st, err := newStaging(dest)
+	if err != nil {
+		t.Fatalf("newStaging: %v", err)
+	}
+	defer st.discard()
+	stageFile(t, st, "a.conf", "new a")
+	stageFile(t, st, "b.conf", "new b")
+
+	// Fail on the second entry, after a.conf was already replaced.
+	rename = func(src, dst string) error {
+		if filepath.Base(dst) == "b.conf" {
+			return errors.New("disk on fire")
+		}
+		return os.Rename(src, dst)
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	if err := st.commit(); err == nil {
+		t.Fatalf("expected commit to fail")
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "a.conf")); string(got) != "old a" {
+		t.Fatalf("a.conf = %q, want the original restored", got)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "b.conf")); !os.IsNotExist(err) {
+		t.Fatalf("b.conf should not exist: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+//go:build unix
+
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// runAs is the user a command runs as instead of the agent's own.
+type runAs struct {
+	name     string
+	home     string
+	uid, gid uint32
+	groups   []uint32
+	// switchUser is false when the user is the agent's own, which needs no
+	// privilege and so also works for an agent not running as root.
+	switchUser bool
+}
+
+// lookupUser resolves a request's user: a name or uid, optionally followed by
+// :group or :gid. Unlike the user's primary group, an explicit group replaces
+// the supplementary groups too. It returns nil when spec is empty.
+func lookupUser(spec string) (*runAs, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	userSpec, groupSpec, hasGroup := strings.Cut(spec, ":")
+
+	u, err := lookupUserOrID(userSpec)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown user %q", userSpec)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "user %q has non-numeric uid %q", userSpec, u.Uid)
+	}
+
+	gidText := u.Gid
+	var groupIDs []string
+	if hasGroup {
+		g, err := lookupGroupOrID(groupSpec)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown group %q", groupSpec)
+		}
+		gidText = g.Gid
+	} else {
+		// Supplementary groups are best effort; without them the user keeps just its primary group.
+		groupIDs, _ = u.GroupIds()
+	}
+	gid, err := strconv.ParseUint(gidText, 10, 32)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "group of %q has non-numeric gid %q", spec, gidText)
+	}
+
+	r := &runAs{name: u.Username, home: u.HomeDir, uid: uint32(uid), gid: uint32(gid)}
+	for _, id := range groupIDs {
+		if n, err := strconv.ParseUint(id, 10, 32); err == nil {
+			r.groups = append(r.groups, uint32(n))
+		}
+	}
+
+	r.switchUser = int(r.uid) != os.Geteuid() || int(r.gid) != os.Getegid()
+	if r.switchUser && os.Geteuid() != 0 {
+		return nil, status.Errorf(codes.PermissionDenied, "agent is not running as root and cannot run commands as %q", spec)
+	}
+	return r, nil
+}
+
+func lookupUserOrID(spec string) (*user.User, error) {
+	if _, err := strconv.ParseUint(spec, 10, 32); err == nil {
+		return user.LookupId(spec)
+	}
+	return user.Lookup(spec)
+}
+
+func lookupGroupOrID(spec string) (*user.Group, error) {
+	if _, err := strconv.ParseUint(spec, 10, 32); err == nil {
+		return user.LookupGroupId(spec)
+	}
+	return user.LookupGroup(spec)
+}
+
+// apply makes cmd run as the user. It must be called after anything else that
+// sets cmd.SysProcAttr.
+func (r *runAs) apply(cmd *exec.Cmd) {
+	if r == nil || !r.switchUser {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: r.uid, Gid: r.gid, Groups: r.groups}
+}
+
+// env returns the request's env with HOME, USER and LOGNAME describing the
+// user, unless the request sets them itself.
+func (r *runAs) env(requested map[string]string) map[string]string {
+	if r == nil {
+		return requested
+	}
+	env := map[string]string{"HOME": r.home, "USER": r.name, "LOGNAME": r.name}
+	for k, v := range requested {
+		env[k] = v
+	}
+	return env
+}
@@ -0,0 +1,26 @@
+//go:build !unix
+
+package agent
+
+import (
+	"os/exec"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// runAs is unused; running commands as another user is only supported on Unix.
+type runAs struct{}
+
+func lookupUser(spec string) (*runAs, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	return nil, status.Error(codes.Unimplemented, "running commands as another user is not supported on this platform")
+}
+
+func (r *runAs) apply(*exec.Cmd) {}
+
+func (r *runAs) env(requested map[string]string) map[string]string {
+	return requested
+}
@@ -0,0 +1,70 @@
+//go:build unix
+
+package agent
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"strings"
+	"testing"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExecuteCommand_RunsAsRequestedUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("switching users needs root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skipf("no nobody user: %v", err)
+	}
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	resp, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{
+		Args:    []string{"sh", "-c", `id -u; id -g; echo "$HOME"`},
+		User:    "nobody",
+		WorkDir: "/",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCommand: %v", err)
+	}
+	want := nobody.Uid + "\n" + nobody.Gid + "\n" + nobody.HomeDir + "\n"
+	if resp.GetStdout() != want || resp.GetExitCode() != 0 {
+		t.Fatalf("stdout %q exit %d (%s), want %q", resp.GetStdout(), resp.GetExitCode(), resp.GetStderr(), want)
+	}
+
+	resp, err = client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{
+		Args:    []string{"id", "-u"},
+		User:    nobody.Uid + ":0",
+		WorkDir: "/",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCommand by uid: %v", err)
+	}
+	if strings.TrimSpace(resp.GetStdout()) != nobody.Uid {
+		t.Fatalf("uid = %q, want %s", resp.GetStdout(), nobody.Uid)
+	}
+}
+
+func TestExecuteCommand_RejectsUnusableUsers(t *testing.T) {
+	client := dialServer(t, NewServer(&Config{MaxConcurrent: 1}))
+
+	for _, spec := range []string{"no-such-user-convoy", "root:no-such-group-convoy"} {
+		_, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{Args: []string{"true"}, User: spec})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("user %q: error = %v, want InvalidArgument", spec, err)
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		_, err := client.ExecuteCommand(context.Background(), &convoypb.CommandRequest{Args: []string{"true"}, User: "root"})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("unprivileged agent: error = %v, want PermissionDenied", err)
+		}
+	}
+}
@@ -3,11 +3,12 @@ package app
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"gopkg.in/yaml.v3"
+	"convoy/internal/configfile"
 )
 
 const (
@@ -16,7 +17,18 @@ const (
 	configFileName  = "config.yaml"
 )
 
+// Container runtimes selectable with the runtime setting.
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+	// RuntimeMock keeps fake containers in a local state file, for trying the
+	// CLI without a container engine.
+	RuntimeMock = "mock"
+)
+
 const defaultConfigYAML = `# Configuration for convoy orchestrator
+# Container runtime: docker, podman or mock
+runtime: docker
 image: convoy:latest
 grpc_port: 50051
 docker_host: unix:///var/run/docker.sock
@@ -24,44 +36,102 @@ docker_network: bridge
 agent_grpc_port: 6000
 pull_always: false
 pull_timeout_sec: 300
+# Refuse to create containers unless their image is pinned as image@sha256:...
+# and the pulled image has that digest
+verify_image_digest: false
+# Dial agents on their docker_network IP before any published host port
+prefer_network_endpoint: false
+# HTTP registry that agents heartbeat to, for agents Docker cannot list
+# agent_registry_url: http://registry.internal:8080/agents
 `
 
-// Config holds application configuration loaded from YAML.
+// Config holds application configuration loaded from YAML, JSON or TOML.
 type Config struct {
-	Image          string `yaml:"image"`
-	GRPCPort       int    `yaml:"grpc_port"`
-	DockerHost     string `yaml:"docker_host"`
-	DockerNetwork  string `yaml:"docker_network"`
-	AgentGRPCPort  int    `yaml:"agent_grpc_port"`
-	PullAlways     bool   `yaml:"pull_always"`
-	PullTimeoutSec int    `yaml:"pull_timeout_sec"`
+	Runtime           string `yaml:"runtime" json:"runtime" toml:"runtime"`
+	Image             string `yaml:"image" json:"image" toml:"image"`
+	GRPCPort          int    `yaml:"grpc_port" json:"grpc_port" toml:"grpc_port"`
+	DockerHost        string `yaml:"docker_host" json:"docker_host" toml:"docker_host"`
+	DockerNetwork     string `yaml:"docker_network" json:"docker_network" toml:"docker_network"`
+	AgentGRPCPort     int    `yaml:"agent_grpc_port" json:"agent_grpc_port" toml:"agent_grpc_port"`
+	PullAlways        bool   `yaml:"pull_always" json:"pull_always" toml:"pull_always"`
+	PullTimeoutSec    int    `yaml:"pull_timeout_sec" json:"pull_timeout_sec" toml:"pull_timeout_sec"`
+	VerifyImageDigest bool   `yaml:"verify_image_digest" json:"verify_image_digest" toml:"verify_image_digest"`
+	PreferNetwork     bool   `yaml:"prefer_network_endpoint" json:"prefer_network_endpoint" toml:"prefer_network_endpoint"`
+	AgentRegistry     string `yaml:"agent_registry_url" json:"agent_registry_url" toml:"agent_registry_url"`
 }
 
-// InitializeConfig creates a default configuration file at the specified path if it does not already exist.
-// It ensures the directory structure is created and writes the default configuration content to the file.
+// ErrConfigExists is returned by InitializeConfig when the file is already there
+// and overwriting was not requested.
+var ErrConfigExists = errors.New("config already exists")
+
+// InitializeConfig creates a default configuration file at the specified path, in
+// the format LoadConfig reads for its extension. An existing file is only replaced
+// when overwrite is set; otherwise ErrConfigExists is returned. The file is written
+// with 0600 permissions since it may hold credentials.
 // Returns the configuration file path or an error if initialization fails.
-func InitializeConfig(path string) (string, error) {
+func InitializeConfig(path string, overwrite bool) (string, error) {
+	data, err := defaultConfigFor(path)
+	if err != nil {
+		return "", err
+	}
+
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", fmt.Errorf("create config dir %q: %w", dir, err)
 	}
 
-	if _, err := os.Stat(path); err == nil {
-		return "", fmt.Errorf("config already exists at %s", path)
-	} else if !errors.Is(err, os.ErrNotExist) {
-		return "", fmt.Errorf("stat config %q: %w", path, err)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+
+	f, err := os.OpenFile(path, flags, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return "", fmt.Errorf("%w at %s", ErrConfigExists, path)
 	}
+	if err != nil {
+		return "", fmt.Errorf("create config %q: %w", path, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
 
-	if err := os.WriteFile(path, []byte(defaultConfigYAML), 0o644); err != nil {
+	// OpenFile only applies the mode to new files; tighten a replaced one too.
+	if err := f.Chmod(0o600); err != nil {
+		return "", fmt.Errorf("chmod config %q: %w", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return "", fmt.Errorf("write config %q: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
 		return "", fmt.Errorf("write config %q: %w", path, err)
 	}
 
 	return path, nil
 }
 
+// defaultConfigFor returns the default configuration in the format of path: the
+// commented YAML as is for YAML files, and its settings re-encoded otherwise.
+func defaultConfigFor(path string) ([]byte, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".json" && ext != ".toml" {
+		return []byte(defaultConfigYAML), nil
+	}
+
+	var cfg Config
+	if err := configfile.Decode(".yaml", []byte(defaultConfigYAML), &cfg); err != nil {
+		return nil, fmt.Errorf("parse default config: %w", err)
+	}
+	data, err := configfile.Encode(path, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("encode default config: %w", err)
+	}
+	return data, nil
+}
+
 // LoadConfig loads configuration from the provided path. When path is empty the
 // default location (~/.config/convoy/config.yaml) is used. The location can be
-// overridden with the CONVOY_CONFIG_DIR environment variable.
+// overridden with the CONVOY_CONFIG_DIR environment variable. Files ending in
+// .json or .toml are parsed as such; anything else is read as YAML.
 func LoadConfig(path string) (*Config, error) {
 	cfgPath := path
 	if cfgPath == "" {
@@ -78,7 +148,7 @@ func LoadConfig(path string) (*Config, error) {
 	}
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := configfile.Decode(cfgPath, data, &cfg); err != nil {
 		return nil, fmt.Errorf("parse config %q: %w", cfgPath, err)
 	}
 
@@ -118,6 +188,12 @@ func defaultConfigDir() (string, error) {
 func (c *Config) Validate() error {
 	var problems []string
 
+	switch c.Runtime {
+	case "", RuntimeDocker, RuntimePodman, RuntimeMock:
+	default:
+		problems = append(problems, fmt.Sprintf("runtime must be %q, %q or %q, got %q", RuntimeDocker, RuntimePodman, RuntimeMock, c.Runtime))
+	}
+
 	if strings.TrimSpace(c.Image) == "" {
 		problems = append(problems, "image is required")
 	}
@@ -138,14 +214,34 @@ func (c *Config) Validate() error {
 		problems = append(problems, "pull_timeout_sec cannot be negative")
 	}
 
+	if c.AgentRegistry != "" {
+		if u, err := url.Parse(c.AgentRegistry); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, "agent_registry_url must be an http or https URL")
+		}
+	}
+
 	if len(problems) > 0 {
-		return errors.New("invalid config: " + strings.Join(problems, "; "))
+		return &ValidationError{Problems: problems}
 	}
 
 	return nil
 }
 
+// ValidationError lists every problem Validate found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
 func applyDefaults(cfg *Config) {
+	cfg.Runtime = strings.ToLower(strings.TrimSpace(cfg.Runtime))
+	if cfg.Runtime == "" {
+		cfg.Runtime = RuntimeDocker
+	}
+
 	if cfg.GRPCPort == 0 {
 		cfg.GRPCPort = 50051
 	}
@@ -155,7 +251,7 @@ func applyDefaults(cfg *Config) {
 	}
 
 	if strings.TrimSpace(cfg.DockerHost) == "" {
-		cfg.DockerHost = "unix:///var/run/docker.sock"
+		cfg.DockerHost = defaultDockerHost(cfg.Runtime)
 	}
 
 	if strings.TrimSpace(cfg.DockerNetwork) == "" {
@@ -166,3 +262,16 @@ func applyDefaults(cfg *Config) {
 		cfg.PullTimeoutSec = 300
 	}
 }
+
+// defaultDockerHost returns the API socket of the given runtime. Rootless Podman
+// listens under $XDG_RUNTIME_DIR; rootful Podman and Docker use system sockets.
+func defaultDockerHost(runtime string) string {
+	if runtime != RuntimePodman {
+		return "unix:///var/run/docker.sock"
+	}
+
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Geteuid() != 0 {
+		return "unix://" + filepath.Join(dir, "podman", "podman.sock")
+	}
+	return "unix:///run/podman/podman.sock"
+}
@@ -1,8 +1,10 @@
 package app
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -10,7 +12,7 @@ func TestInitializeConfigCreatesFile(t *testing.T) {
 	dir := t.TempDir()
 	cfgPath := filepath.Join(dir, "config.yaml")
 
-	created, err := InitializeConfig(cfgPath)
+	created, err := InitializeConfig(cfgPath, false)
 	if err != nil {
 		t.Fatalf("InitializeConfig error: %v", err)
 	}
@@ -27,4 +29,84 @@ func TestInitializeConfigCreatesFile(t *testing.T) {
 	if string(data) != defaultConfigYAML {
 		t.Fatalf("unexpected content: %s", string(data))
 	}
+
+	info, err := os.Stat(cfgPath)
+	if err != nil {
+		t.Fatalf("stat config: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("expected mode 0600, got %o", perm)
+	}
+}
+
+func TestInitializeConfigRefusesExistingFile(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("image: custom\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := InitializeConfig(cfgPath, false); !errors.Is(err, ErrConfigExists) {
+		t.Fatalf("expected ErrConfigExists, got %v", err)
+	}
+
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if string(data) != "image: custom\n" {
+		t.Fatalf("existing config was modified: %s", string(data))
+	}
+}
+
+func TestInitializeConfigOverwritesWithForce(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("image: custom\n# a much longer line than the defaults would leave behind if not truncated\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := InitializeConfig(cfgPath, true); err != nil {
+		t.Fatalf("InitializeConfig error: %v", err)
+	}
+
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if string(data) != defaultConfigYAML {
+		t.Fatalf("unexpected content: %s", string(data))
+	}
+
+	info, err := os.Stat(cfgPath)
+	if err != nil {
+		t.Fatalf("stat config: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("expected mode 0600, got %o", perm)
+	}
+}
+
+func TestInitializeConfigWritesTheFormatOfTheExtension(t *testing.T) {
+	dir := t.TempDir()
+	want, err := InitializeConfig(filepath.Join(dir, "config.yaml"), false)
+	if err != nil {
+		t.Fatalf("InitializeConfig yaml: %v", err)
+	}
+	wantCfg, err := LoadConfig(want)
+	if err != nil {
+		t.Fatalf("LoadConfig yaml: %v", err)
+	}
+
+	for _, name := range []string{"config.json", "config.toml"} {
+		path, err := InitializeConfig(filepath.Join(dir, name), false)
+		if err != nil {
+			t.Fatalf("InitializeConfig %s: %v", name, err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig %s: %v", name, err)
+		}
+		if !reflect.DeepEqual(cfg, wantCfg) {
+			t.Fatalf("%s loaded as %+v, want %+v", name, cfg, wantCfg)
+		}
+	}
 }
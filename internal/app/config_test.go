@@ -1,6 +1,7 @@
 package app
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -53,3 +54,83 @@ func TestConfigValidate(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestLoadConfig_Formats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": "image: alpine\ngrpc_port: 4000\ndocker_network: apps\npull_always: true\npull_timeout_sec: 60\n",
+		"config.json": `{"image": "alpine", "grpc_port": 4000, "docker_network": "apps", "pull_always": true, "pull_timeout_sec": 60}`,
+		"config.toml": "image = \"alpine\"\ngrpc_port = 4000\ndocker_network = \"apps\"\npull_always = true\npull_timeout_sec = 60\n",
+		"config.conf": "image: alpine\ngrpc_port: 4000\ndocker_network: apps\npull_always: true\npull_timeout_sec: 60\n",
+	}
+
+	var want *Config
+	for _, name := range []string{"config.yaml", "config.json", "config.toml", "config.conf"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("LoadConfig(%s): %v", name, err)
+		}
+		if want == nil {
+			want = cfg
+			if cfg.Image != "alpine" || cfg.GRPCPort != 4000 || !cfg.PullAlways {
+				t.Fatalf("unexpected YAML config: %+v", cfg)
+			}
+			continue
+		}
+		if *cfg != *want {
+			t.Fatalf("%s parsed to %+v, want %+v", name, cfg, want)
+		}
+	}
+}
+
+func TestLoadConfig_Runtime(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	dir := t.TempDir()
+
+	cases := []struct {
+		yaml     string
+		runtime  string
+		host     string
+		wantFail bool
+	}{
+		{yaml: "image: alpine\n", runtime: RuntimeDocker, host: "unix:///var/run/docker.sock"},
+		{yaml: "image: alpine\nruntime: Podman\n", runtime: RuntimePodman, host: "unix:///run/podman/podman.sock"},
+		{yaml: "image: alpine\nruntime: podman\ndocker_host: tcp://podman:8080\n", runtime: RuntimePodman, host: "tcp://podman:8080"},
+		{yaml: "image: alpine\nruntime: containerd\n", wantFail: true},
+	}
+
+	for i, tc := range cases {
+		path := filepath.Join(dir, fmt.Sprintf("config%d.yaml", i))
+		if err := os.WriteFile(path, []byte(tc.yaml), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		cfg, err := LoadConfig(path)
+		if tc.wantFail {
+			if err == nil {
+				t.Fatalf("%q: expected validation error", tc.yaml)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", tc.yaml, err)
+		}
+		if cfg.Runtime != tc.runtime || cfg.DockerHost != tc.host {
+			t.Fatalf("%q: runtime %q host %q, want %q %q", tc.yaml, cfg.Runtime, cfg.DockerHost, tc.runtime, tc.host)
+		}
+	}
+}
+
+func TestDefaultDockerHost_RootlessPodman(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("rootless socket is only used by non-root users")
+	}
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	if got, want := defaultDockerHost(RuntimePodman), "unix:///run/user/1000/podman/podman.sock"; got != want {
+		t.Fatalf("defaultDockerHost = %q, want %q", got, want)
+	}
+}
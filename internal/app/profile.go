@@ -0,0 +1,168 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	profilesDirName  = "profiles"
+	contextFileName  = "context"
+	profileExtension = ".yaml"
+)
+
+// DefaultContext names the context that uses the main config file rather than a profile.
+const DefaultContext = "default"
+
+// ProfilePath returns where the config for the named profile lives:
+// profiles/<name>.yaml inside the config directory.
+func ProfilePath(name string) (string, error) {
+	if err := validateProfileName(name); err != nil {
+		return "", err
+	}
+
+	dir, err := defaultConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, profilesDirName, name+profileExtension), nil
+}
+
+// ListProfiles returns the names of the saved profiles, sorted.
+func ListProfiles() ([]string, error) {
+	dir, err := defaultConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, profilesDirName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read profiles: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), profileExtension)
+		if ok && !entry.IsDir() && name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CurrentContext returns the profile selected with UseContext, or DefaultContext
+// when none is selected.
+func CurrentContext() (string, error) {
+	path, err := contextStatePath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultContext, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read current context: %w", err)
+	}
+
+	if name := strings.TrimSpace(string(data)); name != "" {
+		return name, nil
+	}
+	return DefaultContext, nil
+}
+
+// UseContext makes the named profile the one loaded when no --config or
+// --profile is given. DefaultContext switches back to the main config file.
+func UseContext(name string) error {
+	path, err := contextStatePath()
+	if err != nil {
+		return err
+	}
+
+	if name == DefaultContext {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("clear current context: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := existingProfilePath(name); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(name+"\n"), 0o644); err != nil {
+		return fmt.Errorf("write current context: %w", err)
+	}
+	return nil
+}
+
+// ResolveConfigPath picks the config file to load: an explicit path wins, then
+// the named profile, then the current context. An empty result means the
+// default config path.
+func ResolveConfigPath(path, profile string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	if profile == "" {
+		var err error
+		profile, err = CurrentContext()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if profile == DefaultContext {
+		return "", nil
+	}
+	return existingProfilePath(profile)
+}
+
+func existingProfilePath(name string) (string, error) {
+	path, err := ProfilePath(name)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("profile %q does not exist (expected %s)", name, path)
+	} else if err != nil {
+		return "", fmt.Errorf("stat profile %q: %w", name, err)
+	}
+	return path, nil
+}
+
+func contextStatePath() (string, error) {
+	dir, err := defaultConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, contextFileName), nil
+}
+
+func validateProfileName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("profile name is required")
+	}
+	if name == DefaultContext {
+		return fmt.Errorf("%q is reserved for the main config file", DefaultContext)
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	return nil
+}
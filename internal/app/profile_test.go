@@ -0,0 +1,91 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeProfile(t *testing.T, dir, name, image string) {
+	t.Helper()
+	profiles := filepath.Join(dir, profilesDirName)
+	if err := os.MkdirAll(profiles, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(profiles, name+".yaml"), []byte("image: "+image+"\n"), 0o600); err != nil {
+		t.Fatalf("write profile: %v", err)
+	}
+}
+
+func TestUseContext_PersistsAndResolves(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(configDirEnvVar, dir)
+	if err := os.WriteFile(filepath.Join(dir, configFileName), []byte("image: main\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	writeProfile(t, dir, "staging", "staging-image")
+	writeProfile(t, dir, "prod", "prod-image")
+
+	profiles, err := ListProfiles()
+	if err != nil || !reflect.DeepEqual(profiles, []string{"prod", "staging"}) {
+		t.Fatalf("ListProfiles = %v, %v", profiles, err)
+	}
+
+	if current, _ := CurrentContext(); current != DefaultContext {
+		t.Fatalf("initial context = %q", current)
+	}
+
+	if err := UseContext("staging"); err != nil {
+		t.Fatalf("UseContext: %v", err)
+	}
+	if current, _ := CurrentContext(); current != "staging" {
+		t.Fatalf("context = %q, want staging", current)
+	}
+
+	load := func(path, profile string) string {
+		t.Helper()
+		resolved, err := ResolveConfigPath(path, profile)
+		if err != nil {
+			t.Fatalf("ResolveConfigPath(%q, %q): %v", path, profile, err)
+		}
+		cfg, err := LoadConfig(resolved)
+		if err != nil {
+			t.Fatalf("LoadConfig(%q): %v", resolved, err)
+		}
+		return cfg.Image
+	}
+
+	if got := load("", ""); got != "staging-image" {
+		t.Fatalf("current context loaded %q", got)
+	}
+	if got := load("", "prod"); got != "prod-image" {
+		t.Fatalf("--profile loaded %q", got)
+	}
+	if got := load(filepath.Join(dir, configFileName), ""); got != "main" {
+		t.Fatalf("--config loaded %q", got)
+	}
+
+	if err := UseContext(DefaultContext); err != nil {
+		t.Fatalf("UseContext(default): %v", err)
+	}
+	if got := load("", ""); got != "main" {
+		t.Fatalf("default context loaded %q", got)
+	}
+}
+
+func TestUseContext_RejectsUnknownProfiles(t *testing.T) {
+	t.Setenv(configDirEnvVar, t.TempDir())
+
+	for _, name := range []string{"missing", "", "../escape"} {
+		if err := UseContext(name); err == nil {
+			t.Fatalf("UseContext(%q): expected an error", name)
+		}
+	}
+	if _, err := ResolveConfigPath("", "missing"); err == nil {
+		t.Fatalf("expected an error resolving a missing profile")
+	}
+	if current, _ := CurrentContext(); current != DefaultContext {
+		t.Fatalf("failed switch changed the context to %q", current)
+	}
+}
@@ -0,0 +1,49 @@
+// Package configfile decodes config files in the format named by their extension.
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Decode unmarshals data into v as JSON for .json paths, TOML for .toml paths and
+// YAML otherwise. Struct fields therefore need matching json, toml and yaml tags.
+func Decode(path string, data []byte, v any) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil
+		}
+		return json.Unmarshal(data, v)
+	case ".toml":
+		return toml.Unmarshal(data, v)
+	default:
+		return yaml.Unmarshal(data, v)
+	}
+}
+
+// Encode marshals v in the format Decode reads for path: indented JSON for
+// .json paths, TOML for .toml paths and YAML otherwise.
+func Encode(path string, v any) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case ".toml":
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return yaml.Marshal(v)
+	}
+}
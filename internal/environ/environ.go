@@ -0,0 +1,19 @@
+// Package environ holds the environment variable handling shared by the CLI
+// and the agent.
+package environ
+
+// Merge returns a new map holding every layer in order, so a variable set in a
+// later layer overrides the same name in an earlier one.
+func Merge(layers ...map[string]string) map[string]string {
+	size := 0
+	for _, layer := range layers {
+		size += len(layer)
+	}
+	merged := make(map[string]string, size)
+	for _, layer := range layers {
+		for k, v := range layer {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,14 @@
+package environ
+
+import "testing"
+
+func TestMerge_LaterLayersWin(t *testing.T) {
+	base := map[string]string{"A": "1", "B": "2"}
+	merged := Merge(base, map[string]string{"B": "3", "C": "4"}, map[string]string{"C": "5"})
+	if merged["A"] != "1" || merged["B"] != "3" || merged["C"] != "5" || len(merged) != 3 {
+		t.Fatalf("unexpected merge result: %v", merged)
+	}
+	if base["B"] != "2" {
+		t.Fatalf("Merge modified its input: %v", base)
+	}
+}
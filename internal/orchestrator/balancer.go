@@ -2,6 +2,8 @@ package orchestrator
 
 import (
 	"errors"
+	"slices"
+	"sync"
 
 	"convoy/pkg/loadbalancer"
 )
@@ -9,6 +11,9 @@ import (
 // Balancer wraps a loadbalancer.Balancer to select containers for work.
 type Balancer struct {
 	lb loadbalancer.Balancer
+
+	mu      sync.Mutex
+	servers map[string]bool
 }
 
 // NewBalancer creates a new Balancer.
@@ -17,7 +22,7 @@ func NewBalancer(lb loadbalancer.Balancer) (*Balancer, error) {
 		return nil, errors.New("load balancer is required")
 	}
 
-	return &Balancer{lb: lb}, nil
+	return &Balancer{lb: lb, servers: make(map[string]bool)}, nil
 }
 
 // Next returns the next container endpoint to use.
@@ -25,12 +30,28 @@ func (b *Balancer) Next() string {
 	return b.lb.Next()
 }
 
+// NextFor returns the container endpoint for key. Balancers that implement
+// loadbalancer.KeyedBalancer keep a key on the same endpoint; others ignore
+// the key and behave like Next.
+func (b *Balancer) NextFor(key string) string {
+	if keyed, ok := b.lb.(loadbalancer.KeyedBalancer); ok {
+		return keyed.NextFor(key)
+	}
+	return b.lb.Next()
+}
+
 // Add registers a container endpoint with the balancer.
 func (b *Balancer) Add(endpoint string) {
 	if endpoint == "" {
 		return
 	}
 
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.servers[endpoint] {
+		return
+	}
+	b.servers[endpoint] = true
 	b.lb.AddServer(endpoint)
 }
 
@@ -40,5 +61,50 @@ func (b *Balancer) Remove(endpoint string) {
 		return
 	}
 
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.servers[endpoint] {
+		return
+	}
+	delete(b.servers, endpoint)
 	b.lb.RemoveServer(endpoint)
 }
+
+// Members returns the registered endpoints in sorted order.
+func (b *Balancer) Members() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	members := make([]string, 0, len(b.servers))
+	for endpoint := range b.servers {
+		members = append(members, endpoint)
+	}
+	slices.Sort(members)
+	return members
+}
+
+// SetServers replaces the registered endpoints with endpoints, keeping the
+// balancer's position for servers present in both sets.
+func (b *Balancer) SetServers(endpoints []string) {
+	want := make(map[string]bool, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint != "" {
+			want[endpoint] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for endpoint := range b.servers {
+		if !want[endpoint] {
+			delete(b.servers, endpoint)
+			b.lb.RemoveServer(endpoint)
+		}
+	}
+	for _, endpoint := range endpoints {
+		if endpoint != "" && !b.servers[endpoint] {
+			b.servers[endpoint] = true
+			b.lb.AddServer(endpoint)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"convoy/pkg/loadbalancer"
+)
+
+func TestBalancer_NextFor(t *testing.T) {
+	keyed, err := NewBalancer(loadbalancer.NewConsistentHash(0))
+	if err != nil {
+		t.Fatalf("NewBalancer: %v", err)
+	}
+	keyed.SetServers([]string{"a:1", "b:1", "c:1"})
+
+	first := keyed.NextFor("session-42")
+	for i := 0; i < 5; i++ {
+		if got := keyed.NextFor("session-42"); got != first {
+			t.Fatalf("NextFor moved the key from %s to %s", first, got)
+		}
+	}
+
+	// Balancers without key support fall back to Next.
+	plain, err := NewBalancer(loadbalancer.NewRoundRobin())
+	if err != nil {
+		t.Fatalf("NewBalancer: %v", err)
+	}
+	plain.SetServers([]string{"a:1", "b:1"})
+	if a, b := plain.NextFor("k"), plain.NextFor("k"); a == b {
+		t.Fatalf("round robin NextFor returned %s twice, want it to rotate", a)
+	}
+}
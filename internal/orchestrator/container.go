@@ -1,8 +1,10 @@
 package orchestrator
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -10,13 +12,37 @@ import (
 // CLINameLabel is the label applied to containers managed by the CLI.
 const CLINameLabel = "convoy.cli.name"
 
+// Labels applied to every container Convoy creates, used for ownership tracking.
+const (
+	ManagedLabel = "convoy.managed"
+	NameLabel    = "convoy.name"
+	CreatedLabel = "convoy.created"
+)
+
+// EnvLabelPrefix marks labels holding default environment variables for commands run
+// in the container, e.g. "convoy.env.LOG_LEVEL=debug".
+const EnvLabelPrefix = "convoy.env."
+
+// IdempotencyKeyLabel marks a container with the key it was created under so
+// retried creates can return it instead of making a duplicate.
+const IdempotencyKeyLabel = "convoy.idempotency.key"
+
+// ImageDigestLabel records the repository digest of the image a container was
+// created from, when the image has one.
+const ImageDigestLabel = "convoy.image.digest"
+
 // Container represents a managed container instance.
 type Container struct {
-	ID        string
-	Name      string
-	Image     string
-	Endpoint  string
-	Labels    map[string]string
+	ID    string
+	Name  string
+	Image string
+	// ImageDigest is the digest the image resolved to when the container was
+	// created, such as "sha256:4bcff6…"; empty for images without one.
+	ImageDigest string
+	Endpoint    string
+	Labels      map[string]string
+	// Running reports whether the container was running when it was listed.
+	Running   bool
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -28,6 +54,104 @@ type ContainerSpec struct {
 	Labels      map[string]string
 	Environment map[string]string
 	Command     []string
+	// Volumes are bind mounts in Docker's host-path:container-path[:options] form.
+	Volumes []string
+	// Ports are published alongside the agent's gRPC port.
+	Ports []PortMapping
+	// Network is the network the container joins: NetworkHost, NetworkNone or
+	// the name of a user network. Empty uses the runtime's configured network.
+	Network string
+	// CreateNetwork creates the named Network first when it does not exist.
+	CreateNetwork bool
+	// PullPolicy says when the image is pulled; empty uses the runtime's
+	// configured default.
+	PullPolicy PullPolicy
+	// RestartPolicy says when the runtime restarts the container after it exits;
+	// the zero value leaves the runtime's default in place.
+	RestartPolicy RestartPolicy
+}
+
+// PullPolicy says when the image of a new container is pulled from its registry.
+type PullPolicy string
+
+// Image pull policies.
+const (
+	PullAlways  PullPolicy = "always"  // pull even when the image is present
+	PullMissing PullPolicy = "missing" // pull only images that are not present
+	PullNever   PullPolicy = "never"   // use local images only, failing when absent
+)
+
+// ParsePullPolicy checks that s names a pull policy.
+func ParsePullPolicy(s string) (PullPolicy, error) {
+	switch policy := PullPolicy(strings.TrimSpace(s)); policy {
+	case PullAlways, PullMissing, PullNever:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid pull policy %q (want always, missing or never)", s)
+	}
+}
+
+// Network modes that are not named networks.
+const (
+	NetworkHost = "host" // share the host's network stack
+	NetworkNone = "none" // no networking at all
+)
+
+// Restart policy names, spelled as Docker spells them.
+const (
+	RestartNo            = "no"
+	RestartOnFailure     = "on-failure"
+	RestartAlways        = "always"
+	RestartUnlessStopped = "unless-stopped"
+)
+
+// RestartPolicy controls whether a container is restarted after it exits.
+// MaxRetries caps the restarts of an on-failure policy; zero means no cap.
+type RestartPolicy struct {
+	Name       string
+	MaxRetries int
+}
+
+// ParseRestartPolicy parses a policy in Docker's --restart syntax: one of no,
+// on-failure, always or unless-stopped, with on-failure optionally followed by
+// ":N" to give up after N restarts.
+func ParseRestartPolicy(s string) (RestartPolicy, error) {
+	name, retries, hasRetries := strings.Cut(strings.TrimSpace(s), ":")
+	policy := RestartPolicy{Name: name}
+	if hasRetries {
+		n, err := strconv.Atoi(retries)
+		if err != nil {
+			return RestartPolicy{}, fmt.Errorf("invalid restart policy %q: max retries must be a number", s)
+		}
+		policy.MaxRetries = n
+	}
+	if err := policy.validate(); err != nil {
+		return RestartPolicy{}, err
+	}
+	return policy, nil
+}
+
+// String formats the policy in the syntax ParseRestartPolicy accepts.
+func (p RestartPolicy) String() string {
+	if p.MaxRetries > 0 {
+		return fmt.Sprintf("%s:%d", p.Name, p.MaxRetries)
+	}
+	return p.Name
+}
+
+func (p RestartPolicy) validate() error {
+	switch p.Name {
+	case RestartNo, RestartOnFailure, RestartAlways, RestartUnlessStopped:
+	default:
+		return fmt.Errorf("invalid restart policy %q (want no, on-failure[:N], always or unless-stopped)", p.String())
+	}
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("invalid restart policy %q: max retries must not be negative", p.String())
+	}
+	if p.MaxRetries > 0 && p.Name != RestartOnFailure {
+		return fmt.Errorf("invalid restart policy %q: max retries only apply to %s", p.String(), RestartOnFailure)
+	}
+	return nil
 }
 
 // Runtime defines the behavior required from a container runtime implementation.
@@ -39,6 +163,62 @@ type Runtime interface {
 	ListContainers() ([]*Container, error)
 }
 
+// Restarter is implemented by runtimes that can restart a container natively.
+type Restarter interface {
+	RestartContainer(id string) error
+}
+
+// Killer is implemented by runtimes that can send a signal to a container's main process.
+type Killer interface {
+	KillContainer(id, signal string) error
+}
+
+// PullProgressReporter is implemented by runtimes that can report the progress
+// of the image pulls they make while creating containers.
+type PullProgressReporter interface {
+	SetPullProgress(fn func(PullProgress))
+}
+
+// ContainerHealth is a container's own health as reported by the healthcheck
+// its image or runtime configures, as opposed to the agent's readiness.
+type ContainerHealth string
+
+// Container health states. The zero value means the health is unknown, for
+// example because the runtime cannot report it.
+const (
+	ContainerHealthNone      ContainerHealth = "none" // no healthcheck configured
+	ContainerHealthStarting  ContainerHealth = "starting"
+	ContainerHealthHealthy   ContainerHealth = "healthy"
+	ContainerHealthUnhealthy ContainerHealth = "unhealthy"
+)
+
+// HealthReporter is implemented by runtimes that can report a container's healthcheck status.
+type HealthReporter interface {
+	HealthStatus(id string) (ContainerHealth, error)
+}
+
+// ErrContainerNotFound is returned when a container does not exist in the runtime.
+var ErrContainerNotFound = errors.New("container not found")
+
+// ContainerState is a single container's current state in its runtime.
+type ContainerState struct {
+	Running bool
+	// Status is the runtime's own word for the state, such as "running" or "exited".
+	Status string
+	// Health is the container's healthcheck status, unset when the runtime cannot report it.
+	Health ContainerHealth
+	// StopSignal and StopTimeout are the container's own stop settings, such
+	// as an image's STOPSIGNAL; empty and zero when it has none.
+	StopSignal  string
+	StopTimeout time.Duration
+}
+
+// Inspector is implemented by runtimes that can look up one container's state
+// without listing them all.
+type Inspector interface {
+	Inspect(id string) (*ContainerState, error)
+}
+
 // Manager coordinates container operations through the Runtime interface.
 type Manager struct {
 	runtime Runtime
@@ -69,6 +249,16 @@ func (m *Manager) Create(spec ContainerSpec) (*Container, error) {
 		return nil, err
 	}
 
+	if key := strings.TrimSpace(spec.Labels[IdempotencyKeyLabel]); key != "" {
+		existing, err := m.FindByIdempotencyKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return existing, nil
+		}
+	}
+
 	container, err := m.runtime.CreateContainer(spec)
 	if err != nil {
 		return nil, fmt.Errorf("create container: %w", err)
@@ -77,6 +267,22 @@ func (m *Manager) Create(spec ContainerSpec) (*Container, error) {
 	return container, nil
 }
 
+// FindByIdempotencyKey returns the container created under key, or nil if none exists.
+func (m *Manager) FindByIdempotencyKey(key string) (*Container, error) {
+	containers, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range containers {
+		if c != nil && c.Labels[IdempotencyKeyLabel] == key {
+			return c, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // Start ensures the container is running.
 func (m *Manager) Start(id string) error {
 	if id == "" {
@@ -95,6 +301,166 @@ func (m *Manager) Stop(id string) error {
 	return m.runtime.StopContainer(id)
 }
 
+// Kill sends signal to the container's main process. The signal is checked
+// with ParseSignal first; runtimes that are not a Killer cannot be signalled.
+func (m *Manager) Kill(id, signal string) error {
+	if id == "" {
+		return errors.New("container id is required")
+	}
+
+	signal, err := ParseSignal(signal)
+	if err != nil {
+		return err
+	}
+	killer, ok := m.runtime.(Killer)
+	if !ok {
+		return errors.New("runtime cannot send signals to containers")
+	}
+	return killer.KillContainer(id, signal)
+}
+
+// stopPollInterval is how often StopGracefully checks whether a signalled
+// container has exited.
+const stopPollInterval = 100 * time.Millisecond
+
+// DefaultStopTimeout is how long StopGracefully waits for a container that
+// sets no stop timeout of its own.
+const DefaultStopTimeout = 10 * time.Second
+
+// StopResult reports how StopGracefully stopped a container.
+type StopResult struct {
+	// Signal is the signal the container was asked to stop with, empty when
+	// none was sent because it was not running or could not be signalled.
+	Signal string
+	// ForceKilled is set when the container was still running when the
+	// timeout ran out and had to be sent SIGKILL.
+	ForceKilled bool
+	// SignalErr is why the signal could not be sent, in which case the
+	// container was stopped by the runtime's own means instead.
+	SignalErr error
+	// Timeout is how long the container was given to exit.
+	Timeout time.Duration
+}
+
+// StopGracefully sends signal to a running container, waits up to timeout for
+// it to exit and escalates to SIGKILL if it has not. An empty signal and a
+// negative timeout use the container's own stop signal and timeout, as a plain
+// runtime stop would, falling back to SIGTERM and DefaultStopTimeout. Runtimes
+// that are not a Killer, and containers whose signal cannot be sent, are
+// stopped with the runtime's plain stop.
+func (m *Manager) StopGracefully(id, signal string, timeout time.Duration) (StopResult, error) {
+	state, err := m.Inspect(id)
+	if err != nil {
+		return StopResult{}, err
+	}
+	if _, ok := m.runtime.(Killer); !ok || !state.Running {
+		return StopResult{}, m.runtime.StopContainer(id)
+	}
+
+	signal = cmp.Or(signal, state.StopSignal, "SIGTERM")
+	if timeout < 0 {
+		timeout = cmp.Or(state.StopTimeout, DefaultStopTimeout)
+	}
+	result := StopResult{Timeout: timeout}
+	if result.SignalErr = m.Kill(id, signal); result.SignalErr != nil {
+		return result, m.runtime.StopContainer(id)
+	}
+	result.Signal, _ = ParseSignal(signal)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		state, err := m.Inspect(id)
+		if err != nil {
+			return result, err
+		}
+		if !state.Running {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			if err := m.Kill(id, "SIGKILL"); err != nil {
+				return result, fmt.Errorf("force-kill: %w", err)
+			}
+			result.ForceKilled = true
+			break
+		}
+		time.Sleep(min(stopPollInterval, time.Until(deadline)))
+	}
+
+	// Let the runtime settle the container's state now that it has exited.
+	return result, m.runtime.StopContainer(id)
+}
+
+// Restart restarts the container, using the runtime's native restart when it has one
+// and falling back to stop followed by start otherwise.
+func (m *Manager) Restart(id string) error {
+	if id == "" {
+		return errors.New("container id is required")
+	}
+
+	if restarter, ok := m.runtime.(Restarter); ok {
+		return restarter.RestartContainer(id)
+	}
+
+	if err := m.runtime.StopContainer(id); err != nil {
+		return err
+	}
+	return m.runtime.StartContainer(id)
+}
+
+// Inspect reports the container's current state, failing with
+// ErrContainerNotFound when it does not exist. Runtimes that are not an
+// Inspector are answered from their container list.
+func (m *Manager) Inspect(id string) (*ContainerState, error) {
+	if id == "" {
+		return nil, errors.New("container id is required")
+	}
+
+	if inspector, ok := m.runtime.(Inspector); ok {
+		return inspector.Inspect(id)
+	}
+
+	containers, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		if c != nil && c.ID == id {
+			state := &ContainerState{Running: c.Running, Status: "exited"}
+			if c.Running {
+				state.Status = "running"
+			}
+			if reporter, ok := m.runtime.(HealthReporter); ok {
+				if state.Health, err = reporter.HealthStatus(id); err != nil {
+					return nil, err
+				}
+			}
+			return state, nil
+		}
+	}
+	return nil, fmt.Errorf("inspect container %s: %w", id, ErrContainerNotFound)
+}
+
+// HealthStatus reports the container's healthcheck status, or the zero
+// ContainerHealth when the runtime cannot report it.
+func (m *Manager) HealthStatus(id string) (ContainerHealth, error) {
+	if id == "" {
+		return "", errors.New("container id is required")
+	}
+
+	if reporter, ok := m.runtime.(HealthReporter); ok {
+		return reporter.HealthStatus(id)
+	}
+	return "", nil
+}
+
+// SetPullProgress has fn called with the progress of image pulls made by later
+// creates. Runtimes that are not a PullProgressReporter pull silently.
+func (m *Manager) SetPullProgress(fn func(PullProgress)) {
+	if reporter, ok := m.runtime.(PullProgressReporter); ok {
+		reporter.SetPullProgress(fn)
+	}
+}
+
 // Remove deletes the container resources.
 func (m *Manager) Remove(id string) error {
 	if id == "" {
@@ -113,5 +479,37 @@ func validateSpec(spec ContainerSpec) error {
 		return errors.New("image is required")
 	}
 
+	for _, port := range spec.Ports {
+		if err := port.validate(); err != nil {
+			return err
+		}
+	}
+
+	switch strings.TrimSpace(spec.Network) {
+	case NetworkHost, NetworkNone:
+		if len(spec.Ports) > 0 {
+			return fmt.Errorf("ports cannot be published on the %s network", spec.Network)
+		}
+		if spec.CreateNetwork {
+			return fmt.Errorf("the %s network cannot be created", spec.Network)
+		}
+	case "":
+		if spec.CreateNetwork {
+			return errors.New("a network name is required to create the network")
+		}
+	}
+
+	if spec.PullPolicy != "" {
+		if _, err := ParsePullPolicy(string(spec.PullPolicy)); err != nil {
+			return err
+		}
+	}
+
+	if spec.RestartPolicy != (RestartPolicy{}) {
+		if err := spec.RestartPolicy.validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
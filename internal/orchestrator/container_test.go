@@ -0,0 +1,252 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeRuntime struct {
+	calls      []string
+	stopErr    error
+	containers []*Container
+}
+
+func (f *fakeRuntime) CreateContainer(spec ContainerSpec) (*Container, error) {
+	f.calls = append(f.calls, "create:"+spec.Name)
+	c := &Container{ID: fmt.Sprintf("%s-%d", spec.Name, len(f.containers)), Name: spec.Name, Labels: spec.Labels}
+	f.containers = append(f.containers, c)
+	return c, nil
+}
+
+func (f *fakeRuntime) StartContainer(id string) error {
+	f.calls = append(f.calls, "start:"+id)
+	return nil
+}
+
+func (f *fakeRuntime) StopContainer(id string) error {
+	f.calls = append(f.calls, "stop:"+id)
+	return f.stopErr
+}
+
+func (f *fakeRuntime) RemoveContainer(id string) error {
+	f.calls = append(f.calls, "remove:"+id)
+	return nil
+}
+
+func (f *fakeRuntime) ListContainers() ([]*Container, error) {
+	return f.containers, nil
+}
+
+type fakeRestartRuntime struct {
+	fakeRuntime
+}
+
+func (f *fakeRestartRuntime) RestartContainer(id string) error {
+	f.calls = append(f.calls, "restart:"+id)
+	return nil
+}
+
+func TestManagerRestart_FallsBackToStopStart(t *testing.T) {
+	rt := &fakeRuntime{}
+	mgr, err := NewManager(rt)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	if err := mgr.Restart("c1"); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	if want := []string{"stop:c1", "start:c1"}; !reflect.DeepEqual(rt.calls, want) {
+		t.Fatalf("calls = %v, want %v", rt.calls, want)
+	}
+
+	rt.calls = nil
+	rt.stopErr = errors.New("boom")
+	if err := mgr.Restart("c1"); err == nil {
+		t.Fatalf("expected stop error to abort restart")
+	}
+	if want := []string{"stop:c1"}; !reflect.DeepEqual(rt.calls, want) {
+		t.Fatalf("start must not run after failed stop, calls = %v", rt.calls)
+	}
+}
+
+func TestManagerRestart_UsesNativeRestart(t *testing.T) {
+	rt := &fakeRestartRuntime{}
+	mgr, err := NewManager(rt)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	if err := mgr.Restart("c1"); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	if want := []string{"restart:c1"}; !reflect.DeepEqual(rt.calls, want) {
+		t.Fatalf("calls = %v, want %v", rt.calls, want)
+	}
+
+	if err := mgr.Restart(""); err == nil {
+		t.Fatalf("expected error for empty id")
+	}
+}
+
+func TestManagerCreate_IdempotencyKey(t *testing.T) {
+	rt := &fakeRuntime{}
+	mgr, err := NewManager(rt)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	spec := ContainerSpec{Name: "web", Image: "convoy:latest", Labels: map[string]string{IdempotencyKeyLabel: "deploy-42"}}
+	first, err := mgr.Create(spec)
+	if err != nil {
+		t.Fatalf("first Create: %v", err)
+	}
+	second, err := mgr.Create(spec)
+	if err != nil {
+		t.Fatalf("second Create: %v", err)
+	}
+
+	if len(rt.containers) != 1 {
+		t.Fatalf("expected one container, got %d", len(rt.containers))
+	}
+	if second.ID != first.ID {
+		t.Fatalf("second create returned %s, want existing %s", second.ID, first.ID)
+	}
+
+	if _, err := mgr.Create(ContainerSpec{Name: "web", Image: "convoy:latest"}); err != nil {
+		t.Fatalf("Create without key: %v", err)
+	}
+	if len(rt.containers) != 2 {
+		t.Fatalf("create without key should not be deduplicated, got %d containers", len(rt.containers))
+	}
+}
+
+func TestManagerInspect_FallsBackToList(t *testing.T) {
+	rt := &fakeRuntime{containers: []*Container{{ID: "up", Running: true}, {ID: "down"}}}
+	mgr, err := NewManager(rt)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	state, err := mgr.Inspect("up")
+	if err != nil || !state.Running || state.Status != "running" || state.Health != "" {
+		t.Fatalf("Inspect(up) = %+v, %v", state, err)
+	}
+	state, err = mgr.Inspect("down")
+	if err != nil || state.Running || state.Status != "exited" {
+		t.Fatalf("Inspect(down) = %+v, %v", state, err)
+	}
+	if _, err := mgr.Inspect("gone"); !errors.Is(err, ErrContainerNotFound) {
+		t.Fatalf("Inspect(gone) error = %v, want ErrContainerNotFound", err)
+	}
+}
+
+func TestParseRestartPolicy(t *testing.T) {
+	for in, want := range map[string]RestartPolicy{
+		"no":             {Name: RestartNo},
+		"always":         {Name: RestartAlways},
+		"unless-stopped": {Name: RestartUnlessStopped},
+		"on-failure":     {Name: RestartOnFailure},
+		"on-failure:5":   {Name: RestartOnFailure, MaxRetries: 5},
+	} {
+		got, err := ParseRestartPolicy(in)
+		if err != nil || got != want {
+			t.Errorf("ParseRestartPolicy(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+		if got.String() != in {
+			t.Errorf("%+v formats as %q, want %q", got, got.String(), in)
+		}
+	}
+
+	for _, in := range []string{"", "sometimes", "always:3", "on-failure:x", "on-failure:-1"} {
+		if got, err := ParseRestartPolicy(in); err == nil {
+			t.Errorf("ParseRestartPolicy(%q) = %+v, want an error", in, got)
+		}
+	}
+
+	mgr, err := NewManager(&fakeRuntime{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if _, err := mgr.Create(ContainerSpec{Name: "web", Image: "alpine", RestartPolicy: RestartPolicy{Name: "sometimes"}}); err == nil {
+		t.Fatalf("Create accepted an invalid restart policy")
+	}
+}
+
+func TestManagerCreate_ValidatesNetwork(t *testing.T) {
+	mgr, err := NewManager(&fakeRuntime{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	for _, spec := range []ContainerSpec{
+		{Network: NetworkHost, Ports: []PortMapping{{HostPort: 8080, ContainerPort: 80}}},
+		{Network: NetworkNone, CreateNetwork: true},
+		{CreateNetwork: true},
+	} {
+		spec.Name, spec.Image = "web", "alpine"
+		if _, err := mgr.Create(spec); err == nil {
+			t.Errorf("Create accepted %+v", spec)
+		}
+	}
+	if _, err := mgr.Create(ContainerSpec{Name: "web", Image: "alpine", Network: "team-a", CreateNetwork: true}); err != nil {
+		t.Fatalf("Create on a new user network: %v", err)
+	}
+}
+
+// stoppingRuntime is a Killer and Inspector whose one container exits on
+// its stop signal, or only on SIGKILL when stubborn.
+type stoppingRuntime struct {
+	fakeRuntime
+	state    ContainerState
+	stubborn bool
+}
+
+func (f *stoppingRuntime) Inspect(string) (*ContainerState, error) {
+	state := f.state
+	return &state, nil
+}
+
+func (f *stoppingRuntime) KillContainer(id, signal string) error {
+	f.calls = append(f.calls, "kill:"+id+":"+signal)
+	if !f.stubborn || signal == "SIGKILL" {
+		f.state.Running = false
+	}
+	return nil
+}
+
+func TestStopGracefully_UsesContainerStopSettings(t *testing.T) {
+	rt := &stoppingRuntime{state: ContainerState{Running: true, StopSignal: "SIGQUIT", StopTimeout: time.Millisecond}, stubborn: true}
+	mgr, err := NewManager(rt)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	result, err := mgr.StopGracefully("c1", "", -1)
+	if err != nil {
+		t.Fatalf("StopGracefully: %v", err)
+	}
+	if got, want := fmt.Sprint(rt.calls), "[kill:c1:SIGQUIT kill:c1:SIGKILL stop:c1]"; got != want {
+		t.Fatalf("calls = %s, want %s", got, want)
+	}
+	if result.Signal != "SIGQUIT" || !result.ForceKilled || result.Timeout != time.Millisecond {
+		t.Fatalf("result = %+v, want the image's signal and timeout", result)
+	}
+
+	// An explicit signal and timeout win over the container's own.
+	rt = &stoppingRuntime{state: ContainerState{Running: true, StopSignal: "SIGQUIT", StopTimeout: time.Hour}}
+	mgr, _ = NewManager(rt)
+	if result, err := mgr.StopGracefully("c1", "SIGINT", time.Second); err != nil || result.Signal != "SIGINT" || result.Timeout != time.Second {
+		t.Fatalf("StopGracefully(SIGINT) = %+v, %v", result, err)
+	}
+
+	// Without either, SIGTERM and the default timeout apply.
+	rt = &stoppingRuntime{state: ContainerState{Running: true}}
+	mgr, _ = NewManager(rt)
+	if result, err := mgr.StopGracefully("c1", "", -1); err != nil || result.Signal != "SIGTERM" || result.Timeout != DefaultStopTimeout {
+		t.Fatalf("StopGracefully() = %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,330 @@
+package orchestrator
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	convoypb "convoy/api"
+)
+
+// copyChunkSize is the amount of tar data sent per Copy message.
+const copyChunkSize = 32 * 1024
+
+// CopyOptions controls a tar transfer to or from an agent.
+type CopyOptions struct {
+	Overwrite     bool
+	PreserveTimes bool
+	PreserveOwner bool
+	SkipNewer     bool
+	// Atomic has the agent stage pushed files and move them into place only once
+	// the transfer succeeded and the SHA-256 of the tar data matches.
+	Atomic bool
+	// NoFollow has the receiving side reject symlinks that are absolute or
+	// point outside the destination instead of recreating them.
+	NoFollow bool
+	// Exclude lists glob patterns the agent leaves out when sending files.
+	Exclude []string
+	// Progress, when set, is called as data moves with the tar bytes transferred
+	// and the number of tar entries seen so far, and once more when the transfer ends.
+	Progress func(bytes, files int64)
+}
+
+// PushTar streams the tar archive read from src to the agent at endpoint, which
+// extracts it under destPath, and returns the agent's result.
+func (r *RPC) PushTar(ctx context.Context, endpoint string, src io.Reader, destPath string, opts CopyOptions) (*convoypb.CopyResult, error) {
+	stream, err := r.Copy(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open copy stream: %w", err)
+	}
+
+	if err := stream.Send(&convoypb.CopyRequest{
+		Payload: &convoypb.CopyRequest_Start{
+			Start: &convoypb.CopyStart{
+				Direction:     convoypb.CopyStart_TO_AGENT,
+				Path:          destPath,
+				Overwrite:     opts.Overwrite,
+				PreserveTimes: opts.PreserveTimes,
+				PreserveOwner: opts.PreserveOwner,
+				SkipNewer:     opts.SkipNewer,
+				Atomic:        opts.Atomic,
+				NoFollow:      opts.NoFollow,
+			},
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to send start message: %w", err)
+	}
+
+	progress := newProgressTracker(opts.Progress)
+	defer progress.finish()
+
+	hash := sha256.New()
+
+	for {
+		// A fresh buffer per chunk: gRPC may hold on to a sent message.
+		buf := make([]byte, copyChunkSize)
+		n, readErr := io.ReadFull(src, buf)
+		if n > 0 {
+			if err := stream.Send(&convoypb.CopyRequest{
+				Payload: &convoypb.CopyRequest_Chunk{
+					Chunk: &convoypb.CopyChunk{Data: buf[:n]},
+				},
+			}); err != nil {
+				return nil, fmt.Errorf("failed to send data chunk: %w", err)
+			}
+			progress.add(buf[:n])
+			hash.Write(buf[:n])
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read tar data: %w", readErr)
+		}
+	}
+
+	if err := stream.Send(&convoypb.CopyRequest{
+		Payload: &convoypb.CopyRequest_Chunk{
+			Chunk: &convoypb.CopyChunk{Eof: true, Sha256: hex.EncodeToString(hash.Sum(nil))},
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to send EOF: %w", err)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return nil, fmt.Errorf("failed to close send: %w", err)
+	}
+
+	return awaitCopyResult(stream)
+}
+
+// PushFile uploads the single file read from src, size bytes long, to destPath
+// on the agent without tar framing; name is its base name should destPath be a
+// directory. When the agent holds a partial upload of the same destination and
+// size whose checksum matches the start of src, only the remainder is sent.
+// Without overwrite the agent refuses to replace an existing file. It returns
+// the offset the upload resumed from.
+func (r *RPC) PushFile(ctx context.Context, endpoint string, src io.ReadSeeker, size int64, destPath, name string, overwrite bool) (int64, *convoypb.CopyResult, error) {
+	stream, err := r.Copy(ctx, endpoint)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to open copy stream: %w", err)
+	}
+
+	if err := stream.Send(&convoypb.CopyRequest{
+		Payload: &convoypb.CopyRequest_Start{
+			Start: &convoypb.CopyStart{
+				Direction: convoypb.CopyStart_TO_AGENT,
+				Path:      destPath,
+				Overwrite: overwrite,
+				Raw:       true,
+				Size:      size,
+				Name:      name,
+			},
+		},
+	}); err != nil {
+		return 0, nil, fmt.Errorf("failed to send start message: %w", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		return 0, nil, fmt.Errorf("receive error: %w", err)
+	}
+	held := resp.GetProgress()
+	if held == nil {
+		return 0, nil, fmt.Errorf("agent did not report a resume offset")
+	}
+
+	// Resume only if the bytes the agent holds are the start of this file.
+	hash := sha256.New()
+	var offset int64
+	if n := held.GetBytesTransferred(); n > 0 && n <= size {
+		if _, err := io.CopyN(hash, src, n); err != nil {
+			return 0, nil, fmt.Errorf("failed to read source: %w", err)
+		}
+		if hex.EncodeToString(hash.Sum(nil)) == held.GetSha256() {
+			offset = n
+		} else {
+			hash.Reset()
+		}
+	}
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return 0, nil, fmt.Errorf("failed to seek source: %w", err)
+	}
+
+	if err := stream.Send(&convoypb.CopyRequest{
+		Payload: &convoypb.CopyRequest_Start{
+			Start: &convoypb.CopyStart{Direction: convoypb.CopyStart_TO_AGENT, Raw: true, Offset: offset},
+		},
+	}); err != nil {
+		return offset, nil, fmt.Errorf("failed to send resume offset: %w", err)
+	}
+
+	for {
+		buf := make([]byte, copyChunkSize)
+		n, readErr := io.ReadFull(src, buf)
+		if n > 0 {
+			if err := stream.Send(&convoypb.CopyRequest{
+				Payload: &convoypb.CopyRequest_Chunk{
+					Chunk: &convoypb.CopyChunk{Data: buf[:n]},
+				},
+			}); err != nil {
+				return offset, nil, fmt.Errorf("failed to send data chunk: %w", err)
+			}
+			hash.Write(buf[:n])
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return offset, nil, fmt.Errorf("failed to read source: %w", readErr)
+		}
+	}
+
+	if err := stream.Send(&convoypb.CopyRequest{
+		Payload: &convoypb.CopyRequest_Chunk{
+			Chunk: &convoypb.CopyChunk{Eof: true, Sha256: hex.EncodeToString(hash.Sum(nil))},
+		},
+	}); err != nil {
+		return offset, nil, fmt.Errorf("failed to send EOF: %w", err)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return offset, nil, fmt.Errorf("failed to close send: %w", err)
+	}
+
+	result, err := awaitCopyResult(stream)
+	return offset, result, err
+}
+
+// PullTar streams srcPath from the agent at endpoint into w as a tar archive.
+func (r *RPC) PullTar(ctx context.Context, endpoint, srcPath string, w io.Writer, opts CopyOptions) error {
+	stream, err := r.Copy(ctx, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to open copy stream: %w", err)
+	}
+
+	if err := stream.Send(&convoypb.CopyRequest{
+		Payload: &convoypb.CopyRequest_Start{
+			Start: &convoypb.CopyStart{
+				Direction: convoypb.CopyStart_FROM_AGENT,
+				Path:      srcPath,
+				Overwrite: opts.Overwrite,
+				Exclude:   opts.Exclude,
+			},
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to send start message: %w", err)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return fmt.Errorf("failed to close send: %w", err)
+	}
+
+	progress := newProgressTracker(opts.Progress)
+	defer progress.finish()
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("receive error: %w", err)
+		}
+
+		if chunk := resp.GetChunk(); chunk != nil {
+			if data := chunk.GetData(); len(data) > 0 {
+				if _, err := w.Write(data); err != nil {
+					return fmt.Errorf("write tar data: %w", err)
+				}
+				progress.add(data)
+			}
+			if chunk.GetEof() {
+				break
+			}
+		}
+
+		if result := resp.GetResult(); result != nil {
+			if !result.GetSuccess() {
+				return fmt.Errorf("copy failed: %s", result.GetMessage())
+			}
+		}
+	}
+
+	return nil
+}
+
+// awaitCopyResult drains the response stream of a push and returns the agent's final result.
+func awaitCopyResult(stream convoypb.ConvoyService_CopyClient) (*convoypb.CopyResult, error) {
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return &convoypb.CopyResult{Success: true}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("receive error: %w", err)
+		}
+
+		if result := resp.GetResult(); result != nil {
+			if !result.GetSuccess() {
+				return result, fmt.Errorf("copy failed: %s", result.GetMessage())
+			}
+			return result, nil
+		}
+	}
+}
+
+// progressTracker counts transferred bytes and, by parsing a copy of the stream,
+// tar entries, reporting both to a Progress callback. A nil tracker does nothing.
+type progressTracker struct {
+	fn    func(bytes, files int64)
+	bytes int64
+	files atomic.Int64
+	pw    *io.PipeWriter
+	done  chan struct{}
+}
+
+func newProgressTracker(fn func(bytes, files int64)) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+
+	pr, pw := io.Pipe()
+	p := &progressTracker{fn: fn, pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		tr := tar.NewReader(pr)
+		for {
+			if _, err := tr.Next(); err != nil {
+				break
+			}
+			p.files.Add(1)
+		}
+		// Keep draining so add never blocks on data that isn't valid tar.
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	return p
+}
+
+func (p *progressTracker) add(data []byte) {
+	if p == nil {
+		return
+	}
+	_, _ = p.pw.Write(data)
+	p.bytes += int64(len(data))
+	p.fn(p.bytes, p.files.Load())
+}
+
+// finish waits for the entry count to settle and reports the final totals.
+func (p *progressTracker) finish() {
+	if p == nil {
+		return
+	}
+	_ = p.pw.Close()
+	<-p.done
+	p.fn(p.bytes, p.files.Load())
+}
@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	convoypb "convoy/api"
+	"convoy/internal/agent"
+)
+
+func TestPushPullTar_ReportProgress(t *testing.T) {
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{
+		"agent": agent.NewServer(&agent.Config{MaxConcurrent: 2}),
+	})
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	files := map[string]string{"a.txt": "alpha", "b.txt": "bravo", "big.bin": string(bytes.Repeat([]byte("z"), 100<<10))}
+	for _, name := range []string{"a.txt", "b.txt", "big.bin"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := io.WriteString(tw, files[name]); err != nil {
+			t.Fatalf("write body: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+
+	type update struct{ bytes, files int64 }
+	var pushed []update
+	dest := t.TempDir()
+	result, err := rpc.PushTar(context.Background(), "agent", bytes.NewReader(archive.Bytes()), dest, CopyOptions{
+		Overwrite: true,
+		Progress:  func(b, f int64) { pushed = append(pushed, update{b, f}) },
+	})
+	if err != nil {
+		t.Fatalf("PushTar: %v", err)
+	}
+	if result.GetFileCount() != 3 {
+		t.Fatalf("agent extracted %d files", result.GetFileCount())
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "b.txt")); string(got) != "bravo" {
+		t.Fatalf("b.txt = %q", got)
+	}
+	if len(pushed) < 2 {
+		t.Fatalf("expected progress during the push, got %v", pushed)
+	}
+	if last := pushed[len(pushed)-1]; last != (update{int64(archive.Len()), 3}) {
+		t.Fatalf("final push progress = %+v, want %d bytes and 3 files", last, archive.Len())
+	}
+	for i := 1; i < len(pushed); i++ {
+		if pushed[i].bytes < pushed[i-1].bytes || pushed[i].files < pushed[i-1].files {
+			t.Fatalf("progress went backwards: %v", pushed)
+		}
+	}
+
+	var pulled []update
+	var out bytes.Buffer
+	err = rpc.PullTar(context.Background(), "agent", dest, &out, CopyOptions{
+		Progress: func(b, f int64) { pulled = append(pulled, update{b, f}) },
+	})
+	if err != nil {
+		t.Fatalf("PullTar: %v", err)
+	}
+	if len(pulled) == 0 {
+		t.Fatalf("progress callback not invoked during pull")
+	}
+	if last := pulled[len(pulled)-1]; last.bytes != int64(out.Len()) || last.files < 3 {
+		t.Fatalf("final pull progress = %+v, pulled %d bytes", last, out.Len())
+	}
+}
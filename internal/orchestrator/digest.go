@@ -0,0 +1,66 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+)
+
+// PinnedDigest returns the digest an image reference is pinned to, such as
+// "sha256:4bcff6…" for "alpine@sha256:4bcff6…", or "" when the reference
+// only names a tag, which the registry can move to another image at any time.
+func PinnedDigest(image string) string {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	if digested, ok := ref.(reference.Digested); ok {
+		return digested.Digest().String()
+	}
+	return ""
+}
+
+// repoDigest returns the digest under which the inspected image is known in
+// image's repository, preferring the digest image is pinned to. Images that
+// were built locally rather than pulled have none.
+func repoDigest(inspect types.ImageInspect, image string) string {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	pinned := PinnedDigest(image)
+
+	found := ""
+	for _, rd := range inspect.RepoDigests {
+		named, err := reference.ParseNormalizedNamed(rd)
+		if err != nil || named.Name() != ref.Name() {
+			continue
+		}
+		digested, ok := named.(reference.Digested)
+		if !ok {
+			continue
+		}
+		digest := digested.Digest().String()
+		if digest == pinned {
+			return digest
+		}
+		if found == "" {
+			found = digest
+		}
+	}
+	return found
+}
+
+// verifyDigest checks that an image pinned by digest resolved to that digest.
+// Images that are not pinned fail, since there is nothing to verify against.
+func verifyDigest(image, resolved string) error {
+	pinned := PinnedDigest(image)
+	if pinned == "" {
+		return fmt.Errorf("image %s is not pinned by digest (want image@sha256:...)", image)
+	}
+	if resolved != pinned {
+		return fmt.Errorf("image %s resolved to digest %q, want %s", image, resolved, pinned)
+	}
+	return nil
+}
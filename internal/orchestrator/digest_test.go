@@ -0,0 +1,64 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	digestA = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB = "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+func TestPinnedDigest(t *testing.T) {
+	for image, want := range map[string]string{
+		"alpine":                      "",
+		"alpine:3.20":                 "",
+		"alpine@" + digestA:           digestA,
+		"alpine:3.20@" + digestA:      digestA,
+		"ghcr.io/acme/app@" + digestB: digestB,
+		"not a valid reference":       "",
+	} {
+		if got := PinnedDigest(image); got != want {
+			t.Errorf("PinnedDigest(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestRepoDigest_FromImageInspect(t *testing.T) {
+	inspect := types.ImageInspect{RepoDigests: []string{
+		"ghcr.io/acme/app@" + digestB,
+		"alpine@" + digestA,
+		"docker.io/library/alpine@" + digestB,
+	}}
+
+	for image, want := range map[string]string{
+		"alpine:3.20":                     digestA,
+		"docker.io/library/alpine:latest": digestA,
+		"alpine@" + digestB:               digestB,
+		"ghcr.io/acme/app:1.0":            digestB,
+		"busybox":                         "",
+	} {
+		if got := repoDigest(inspect, image); got != want {
+			t.Errorf("repoDigest(%q) = %q, want %q", image, got, want)
+		}
+	}
+
+	if got := repoDigest(types.ImageInspect{}, "convoy:dev"); got != "" {
+		t.Errorf("locally built image has digest %q", got)
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	if err := verifyDigest("alpine@"+digestA, digestA); err != nil {
+		t.Fatalf("matching digest: %v", err)
+	}
+	if err := verifyDigest("alpine@"+digestA, digestB); err == nil || !strings.Contains(err.Error(), "resolved to digest") {
+		t.Fatalf("mismatched digest: err = %v", err)
+	}
+	if err := verifyDigest("alpine:3.20", digestA); err == nil || !strings.Contains(err.Error(), "not pinned") {
+		t.Fatalf("floating tag: err = %v", err)
+	}
+}
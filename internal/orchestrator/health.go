@@ -0,0 +1,150 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	convoypb "convoy/api"
+)
+
+// HealthResult is the outcome of probing a single agent.
+type HealthResult struct {
+	Label    string
+	Endpoint string
+	// Healthy is true only when the agent answered with STATUS_HEALTHY.
+	Healthy bool
+	// Reachable is true when the agent answered at all.
+	Reachable bool
+	Message   string
+	Latency   time.Duration
+	// Protocol is the protocol version negotiated with a reachable agent.
+	Protocol string
+	// AgentProtocol is the protocol version the agent reported.
+	AgentProtocol string
+	// Warning notes a protocol minor version skew that does not stop the agent from working.
+	Warning string
+	// ContainerHealth is the container's own healthcheck status. ProbeHealth
+	// leaves it unset; callers that know the container look it up separately.
+	ContainerHealth ContainerHealth
+}
+
+// ProbeHealth checks the agent at endpoint once and reports the result without rendering it.
+func ProbeHealth(ctx context.Context, rpc *RPC, label, endpoint string) HealthResult {
+	result := HealthResult{Label: label, Endpoint: endpoint}
+	if endpoint == "" {
+		result.Message = "missing endpoint"
+		return result
+	}
+
+	start := time.Now()
+	resp, err := rpc.CheckHealth(ctx, endpoint, &convoypb.HealthRequest{ProtocolVersion: convoypb.ProtocolVersion})
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Reachable = true
+	result.AgentProtocol = resp.GetProtocolVersion()
+	// An agent whose major version differs would misread requests, so it is
+	// never reported healthy however it answered.
+	result.Protocol, result.Warning, err = NegotiateProtocol(result.AgentProtocol)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if resp.GetStatus() != convoypb.HealthResponse_STATUS_HEALTHY {
+		result.Message = resp.GetMessage()
+		if result.Message == "" {
+			result.Message = resp.GetStatus().String()
+		}
+		return result
+	}
+
+	result.Healthy = true
+	return result
+}
+
+// Err returns nil for a healthy result and an error describing the failure otherwise.
+func (r HealthResult) Err() error {
+	if r.Healthy {
+		return nil
+	}
+	return errors.New(r.Message)
+}
+
+// RefreshBalancer re-probes endpoints every interval until ctx is done, keeping only the
+// healthy ones registered in b so dead agents drop out and recovered ones return.
+func RefreshBalancer(ctx context.Context, rpc *RPC, b *Balancer, endpoints []string, interval time.Duration) {
+	ReconcileBalancer(ctx, rpc, b, func() ([]string, error) { return endpoints, nil }, interval)
+}
+
+// EndpointSource lists the endpoints a balancer may draw from.
+type EndpointSource func() ([]string, error)
+
+// ReconcileBalancer is RefreshBalancer for a changing fleet: every interval it
+// re-lists the endpoints from source before probing them, so containers that
+// were stopped or removed are evicted along with unhealthy ones. When source
+// fails, the endpoints it returned last are probed instead; membership is left
+// alone until it has succeeded once.
+func ReconcileBalancer(ctx context.Context, rpc *RPC, b *Balancer, source EndpointSource, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		endpoints []string
+		listed    bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if latest, err := source(); err == nil {
+				endpoints, listed = latest, true
+			}
+			if listed {
+				RefreshBalancerOnce(ctx, rpc, b, endpoints)
+			}
+		}
+	}
+}
+
+// RunningEndpoints returns the endpoints of the running containers in containers.
+func RunningEndpoints(containers []*Container) []string {
+	var endpoints []string
+	for _, c := range containers {
+		if c != nil && c.Running && c.Endpoint != "" {
+			endpoints = append(endpoints, c.Endpoint)
+		}
+	}
+	return endpoints
+}
+
+// RefreshBalancerOnce probes endpoints concurrently and registers the healthy ones in b.
+func RefreshBalancerOnce(ctx context.Context, rpc *RPC, b *Balancer, endpoints []string) {
+	healthy := make([]bool, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint string) {
+			defer wg.Done()
+			healthy[i] = ProbeHealth(ctx, rpc, endpoint, endpoint).Healthy
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	live := make([]string, 0, len(endpoints))
+	for i, endpoint := range endpoints {
+		if healthy[i] {
+			live = append(live, endpoint)
+		}
+	}
+	b.SetServers(live)
+}
@@ -0,0 +1,199 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+	"convoy/pkg/loadbalancer"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type statusServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	status convoypb.HealthResponse_Status
+}
+
+func (s statusServer) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	return &convoypb.HealthResponse{Status: s.status}, nil
+}
+
+// bufconnRPC returns an RPC client whose endpoints are served in-process by the given servers.
+func bufconnRPC(t *testing.T, servers map[string]convoypb.ConvoyServiceServer) *RPC {
+	t.Helper()
+	return bufconnRPCWithConfig(t, servers, RPCConfig{DialTimeout: 200 * time.Millisecond})
+}
+
+// bufconnRPCWithConfig is bufconnRPC with a caller-supplied config; its Dialer is replaced.
+func bufconnRPCWithConfig(t *testing.T, servers map[string]convoypb.ConvoyServiceServer, cfg RPCConfig) *RPC {
+	t.Helper()
+	listeners := make(map[string]*bufconn.Listener, len(servers))
+	for endpoint, srv := range servers {
+		lis := bufconn.Listen(1 << 20)
+		server := grpc.NewServer()
+		convoypb.RegisterConvoyServiceServer(server, srv)
+		go func() {
+			_ = server.Serve(lis)
+		}()
+		t.Cleanup(server.Stop)
+		listeners[endpoint] = lis
+	}
+
+	cfg.Dialer = func(ctx context.Context, endpoint string) (net.Conn, error) {
+		lis, ok := listeners[endpoint]
+		if !ok {
+			return nil, &net.OpError{Op: "dial", Net: "bufconn", Err: net.ErrClosed}
+		}
+		return lis.DialContext(ctx)
+	}
+	rpc := NewRPC(cfg)
+	t.Cleanup(func() {
+		_ = rpc.Close()
+	})
+	return rpc
+}
+
+func TestProbeHealth(t *testing.T) {
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{
+		"ok":   statusServer{status: convoypb.HealthResponse_STATUS_HEALTHY},
+		"sick": statusServer{status: convoypb.HealthResponse_STATUS_DEGRADED},
+	})
+
+	tests := []struct {
+		endpoint  string
+		healthy   bool
+		reachable bool
+	}{
+		{endpoint: "ok", healthy: true, reachable: true},
+		{endpoint: "sick", healthy: false, reachable: true},
+		{endpoint: "gone", healthy: false, reachable: false},
+		{endpoint: "", healthy: false, reachable: false},
+	}
+
+	for _, tt := range tests {
+		result := ProbeHealth(context.Background(), rpc, "label", tt.endpoint)
+		if result.Healthy != tt.healthy || result.Reachable != tt.reachable {
+			t.Fatalf("%q: got healthy=%v reachable=%v (%s)", tt.endpoint, result.Healthy, result.Reachable, result.Message)
+		}
+		if (result.Err() == nil) != tt.healthy {
+			t.Fatalf("%q: Err() = %v", tt.endpoint, result.Err())
+		}
+		if !tt.healthy && result.Message == "" {
+			t.Fatalf("%q: expected a failure message", tt.endpoint)
+		}
+		if tt.reachable && result.Latency <= 0 {
+			t.Fatalf("%q: expected latency to be recorded", tt.endpoint)
+		}
+	}
+}
+
+// toggleServer reports healthy until its flag is cleared.
+type toggleServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	healthy *atomic.Bool
+}
+
+func (s toggleServer) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	if s.healthy.Load() {
+		return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_HEALTHY}, nil
+	}
+	return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_DEGRADED}, nil
+}
+
+func TestRefreshBalancer_TracksHealth(t *testing.T) {
+	var flaky atomic.Bool
+	flaky.Store(true)
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{
+		"a": statusServer{status: convoypb.HealthResponse_STATUS_HEALTHY},
+		"b": toggleServer{healthy: &flaky},
+	})
+	balancer, err := NewBalancer(loadbalancer.NewRoundRobin())
+	if err != nil {
+		t.Fatalf("NewBalancer: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	RefreshBalancerOnce(ctx, rpc, balancer, []string{"a", "b"})
+	go RefreshBalancer(ctx, rpc, balancer, []string{"a", "b"}, 10*time.Millisecond)
+
+	picks := func() map[string]bool {
+		seen := make(map[string]bool)
+		for i := 0; i < 4; i++ {
+			seen[balancer.Next()] = true
+		}
+		return seen
+	}
+	waitFor := func(what string, ok func(map[string]bool) bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !ok(picks()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	if seen := picks(); !seen["a"] || !seen["b"] {
+		t.Fatalf("expected both endpoints picked initially, got %v", seen)
+	}
+
+	flaky.Store(false)
+	waitFor("b to drop out", func(seen map[string]bool) bool { return seen["a"] && !seen["b"] })
+	for i := 0; i < 3; i++ {
+		if seen := picks(); seen["b"] {
+			t.Fatalf("unhealthy endpoint picked: %v", seen)
+		}
+	}
+
+	flaky.Store(true)
+	waitFor("b to return", func(seen map[string]bool) bool { return seen["a"] && seen["b"] })
+}
+
+func TestReconcileBalancer_EvictsStoppedContainers(t *testing.T) {
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{
+		"a": statusServer{status: convoypb.HealthResponse_STATUS_HEALTHY},
+		"b": statusServer{status: convoypb.HealthResponse_STATUS_HEALTHY},
+	})
+	balancer, err := NewBalancer(loadbalancer.NewRoundRobin())
+	if err != nil {
+		t.Fatalf("NewBalancer: %v", err)
+	}
+
+	var bRunning atomic.Bool
+	bRunning.Store(true)
+	source := func() ([]string, error) {
+		return RunningEndpoints([]*Container{
+			{ID: "a", Endpoint: "a", Running: true},
+			{ID: "b", Endpoint: "b", Running: bRunning.Load()},
+		}), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ReconcileBalancer(ctx, rpc, balancer, source, 10*time.Millisecond)
+
+	waitMembers := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for fmt.Sprint(balancer.Members()) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("members = %v, want %s", balancer.Members(), want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitMembers("[a b]")
+	bRunning.Store(false)
+	waitMembers("[a]")
+	bRunning.Store(true)
+	waitMembers("[a b]")
+}
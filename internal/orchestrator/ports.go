@@ -0,0 +1,69 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortMapping publishes a container port on the host, in addition to the
+// agent's gRPC port which is always published.
+type PortMapping struct {
+	// HostPort is the port on the host; zero lets the runtime pick a free one.
+	HostPort      int
+	ContainerPort int
+	// Protocol is tcp, udp or sctp; empty means tcp.
+	Protocol string
+}
+
+// ParsePortMapping parses a mapping in host:container[/protocol] form, such
+// as "8080:80" or "0:53/udp". A host port of 0 publishes on a random port.
+func ParsePortMapping(s string) (PortMapping, error) {
+	ports, protocol, _ := strings.Cut(strings.TrimSpace(s), "/")
+	hostPort, containerPort, ok := strings.Cut(ports, ":")
+	if !ok {
+		return PortMapping{}, fmt.Errorf("invalid port mapping %q (want host:container[/protocol])", s)
+	}
+
+	var mapping PortMapping
+	var err error
+	if mapping.HostPort, err = strconv.Atoi(hostPort); err != nil {
+		return PortMapping{}, fmt.Errorf("invalid port mapping %q: host port must be a number", s)
+	}
+	if mapping.ContainerPort, err = strconv.Atoi(containerPort); err != nil {
+		return PortMapping{}, fmt.Errorf("invalid port mapping %q: container port must be a number", s)
+	}
+	mapping.Protocol = strings.ToLower(protocol)
+	if err := mapping.validate(); err != nil {
+		return PortMapping{}, err
+	}
+	return mapping, nil
+}
+
+// String formats the mapping in the form ParsePortMapping accepts.
+func (p PortMapping) String() string {
+	return fmt.Sprintf("%d:%d/%s", p.HostPort, p.ContainerPort, p.protocol())
+}
+
+// protocol returns the mapping's protocol, defaulting to tcp.
+func (p PortMapping) protocol() string {
+	if p.Protocol == "" {
+		return "tcp"
+	}
+	return p.Protocol
+}
+
+func (p PortMapping) validate() error {
+	if p.ContainerPort < 1 || p.ContainerPort > 65535 {
+		return fmt.Errorf("invalid port mapping %q: container port must be between 1 and 65535", p.String())
+	}
+	if p.HostPort < 0 || p.HostPort > 65535 {
+		return fmt.Errorf("invalid port mapping %q: host port must be between 0 and 65535", p.String())
+	}
+	switch p.protocol() {
+	case "tcp", "udp", "sctp":
+	default:
+		return fmt.Errorf("invalid port mapping %q: protocol must be tcp, udp or sctp", p.String())
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package orchestrator
+
+import "testing"
+
+func TestParsePortMapping(t *testing.T) {
+	for in, want := range map[string]PortMapping{
+		"8080:80":         {HostPort: 8080, ContainerPort: 80},
+		"0:80":            {HostPort: 0, ContainerPort: 80},
+		"5353:53/udp":     {HostPort: 5353, ContainerPort: 53, Protocol: "udp"},
+		" 9000:9000/TCP ": {HostPort: 9000, ContainerPort: 9000, Protocol: "tcp"},
+		"0:3868/sctp":     {HostPort: 0, ContainerPort: 3868, Protocol: "sctp"},
+	} {
+		got, err := ParsePortMapping(in)
+		if err != nil || got != want {
+			t.Errorf("ParsePortMapping(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "80", "8080:", ":80", "a:80", "8080:0", "70000:80", "-1:80", "8080:80/icmp"} {
+		if got, err := ParsePortMapping(in); err == nil {
+			t.Errorf("ParsePortMapping(%q) = %+v, want an error", in, got)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	convoypb "convoy/api"
+)
+
+// legacyProtocolVersion is what an agent that reports no protocol version speaks.
+const legacyProtocolVersion = "1.0"
+
+// protocolVersion is a parsed major.minor protocol version.
+type protocolVersion struct {
+	major, minor int
+}
+
+func parseProtocolVersion(v string) (protocolVersion, error) {
+	if v == "" {
+		v = legacyProtocolVersion
+	}
+	majorText, minorText, ok := strings.Cut(v, ".")
+	major, majorErr := strconv.Atoi(majorText)
+	minor, minorErr := strconv.Atoi(minorText)
+	if !ok || majorErr != nil || minorErr != nil || major < 0 || minor < 0 {
+		return protocolVersion{}, fmt.Errorf("invalid protocol version %q (want major.minor)", v)
+	}
+	return protocolVersion{major: major, minor: minor}, nil
+}
+
+func (v protocolVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+// NegotiateProtocol compares the agent's protocol version with the CLI's and
+// returns the version both sides speak: the lower of the two. Differing minor
+// versions still work together, minus the newer side's additions, and are
+// reported as a warning; differing major versions are an error.
+func NegotiateProtocol(agent string) (negotiated, warning string, err error) {
+	return negotiateProtocol(convoypb.ProtocolVersion, agent)
+}
+
+func negotiateProtocol(local, agent string) (negotiated, warning string, err error) {
+	ours, err := parseProtocolVersion(local)
+	if err != nil {
+		return "", "", err
+	}
+	theirs, err := parseProtocolVersion(agent)
+	if err != nil {
+		return "", "", fmt.Errorf("agent reports an %w", err)
+	}
+
+	switch {
+	case ours.major != theirs.major:
+		return "", "", fmt.Errorf("agent speaks protocol %s, incompatible with convoy's %s; upgrade the older side", theirs, ours)
+	case ours.minor < theirs.minor:
+		return ours.String(), fmt.Sprintf("agent speaks newer protocol %s than convoy's %s; upgrade convoy for its new features", theirs, ours), nil
+	case ours.minor > theirs.minor:
+		return theirs.String(), fmt.Sprintf("agent speaks older protocol %s than convoy's %s; some features may be unavailable", theirs, ours), nil
+	default:
+		return ours.String(), "", nil
+	}
+}
@@ -0,0 +1,122 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	convoypb "convoy/api"
+)
+
+func TestNegotiateProtocol(t *testing.T) {
+	tests := []struct {
+		name           string
+		local, agent   string
+		wantNegotiated string
+		wantWarning    bool
+		wantErr        string
+	}{
+		{name: "matching", local: "1.2", agent: "1.2", wantNegotiated: "1.2"},
+		{name: "agent before versioning", local: "1.0", agent: "", wantNegotiated: "1.0"},
+		{name: "newer agent minor", local: "1.2", agent: "1.4", wantNegotiated: "1.2", wantWarning: true},
+		{name: "older agent minor", local: "1.2", agent: "1.0", wantNegotiated: "1.0", wantWarning: true},
+		{name: "newer agent major", local: "1.2", agent: "2.0", wantErr: "incompatible"},
+		{name: "older agent major", local: "2.0", agent: "1.9", wantErr: "incompatible"},
+		{name: "garbage", local: "1.0", agent: "one", wantErr: "invalid protocol version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			negotiated, warning, err := negotiateProtocol(tt.local, tt.agent)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if negotiated != tt.wantNegotiated || (warning != "") != tt.wantWarning {
+				t.Fatalf("negotiated %q warning %q, want %q with warning=%v", negotiated, warning, tt.wantNegotiated, tt.wantWarning)
+			}
+		})
+	}
+}
+
+// protocolServer is a healthy agent reporting a fixed protocol version.
+type protocolServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	version string
+}
+
+func (s protocolServer) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_HEALTHY, ProtocolVersion: s.version}, nil
+}
+
+func TestProbeHealth_ProtocolSkew(t *testing.T) {
+	ours, err := parseProtocolVersion(convoypb.ProtocolVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newerMinor := protocolVersion{major: ours.major, minor: ours.minor + 1}.String()
+	newerMajor := protocolVersion{major: ours.major + 1}.String()
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{
+		"same":  protocolServer{version: convoypb.ProtocolVersion},
+		"minor": protocolServer{version: newerMinor},
+		"major": protocolServer{version: newerMajor},
+	})
+
+	same := ProbeHealth(context.Background(), rpc, "same", "same")
+	if !same.Healthy || same.Warning != "" || same.Protocol != convoypb.ProtocolVersion {
+		t.Fatalf("matching agent = %+v", same)
+	}
+
+	minor := ProbeHealth(context.Background(), rpc, "minor", "minor")
+	if !minor.Healthy || minor.Warning == "" || minor.Protocol != convoypb.ProtocolVersion || minor.AgentProtocol != newerMinor {
+		t.Fatalf("minor skew = %+v, want healthy with a warning", minor)
+	}
+
+	major := ProbeHealth(context.Background(), rpc, "major", "major")
+	if major.Healthy || !major.Reachable || !strings.Contains(major.Message, "incompatible") {
+		t.Fatalf("major skew = %+v, want reachable but unhealthy", major)
+	}
+}
+
+// countingProtocolServer is a protocolServer that counts health checks and answers GetInfo.
+type countingProtocolServer struct {
+	protocolServer
+	checks atomic.Int32
+}
+
+func (s *countingProtocolServer) CheckHealth(ctx context.Context, req *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	s.checks.Add(1)
+	return s.protocolServer.CheckHealth(ctx, req)
+}
+
+func (s *countingProtocolServer) GetInfo(context.Context, *convoypb.InfoRequest) (*convoypb.InfoResponse, error) {
+	return &convoypb.InfoResponse{AgentId: "agent"}, nil
+}
+
+func TestRPC_NegotiatesProtocolOncePerConnection(t *testing.T) {
+	ours, err := parseProtocolVersion(convoypb.ProtocolVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same := &countingProtocolServer{protocolServer: protocolServer{version: convoypb.ProtocolVersion}}
+	skewed := &countingProtocolServer{protocolServer: protocolServer{version: protocolVersion{major: ours.major + 1}.String()}}
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{"same": same, "skewed": skewed})
+
+	for range 3 {
+		if _, err := rpc.GetInfo(context.Background(), "same"); err != nil {
+			t.Fatalf("GetInfo on a matching agent: %v", err)
+		}
+		if _, err := rpc.GetInfo(context.Background(), "skewed"); err == nil || !strings.Contains(err.Error(), "incompatible") {
+			t.Fatalf("GetInfo on a skewed agent = %v, want the major skew reported", err)
+		}
+	}
+	if same.checks.Load() != 1 || skewed.checks.Load() != 1 {
+		t.Fatalf("health checks = %d and %d, want one per connection", same.checks.Load(), skewed.checks.Load())
+	}
+}
@@ -0,0 +1,109 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// PullProgress is a snapshot of an image pull, reported each time a layer advances.
+type PullProgress struct {
+	Image string
+	// Layers are in the order the registry first mentioned them.
+	Layers []LayerProgress
+	// Done is set on the final report, sent once the pull has finished or failed.
+	Done bool
+}
+
+// LayerProgress is the state of one image layer during a pull.
+type LayerProgress struct {
+	ID     string
+	Status string
+	// Current and Total count downloaded bytes; Total is zero until known.
+	Current int64
+	Total   int64
+	// Complete is set once the layer is downloaded and extracted, or was
+	// already present locally.
+	Complete bool
+}
+
+// Bytes sums the downloaded and total bytes over all layers.
+func (p PullProgress) Bytes() (current, total int64) {
+	for _, layer := range p.Layers {
+		current += layer.Current
+		total += layer.Total
+	}
+	return current, total
+}
+
+// CompleteLayers counts the layers that are fully pulled.
+func (p PullProgress) CompleteLayers() int {
+	n := 0
+	for _, layer := range p.Layers {
+		if layer.Complete {
+			n++
+		}
+	}
+	return n
+}
+
+// readPullProgress decodes the JSON message stream of a pull of image,
+// calling report after every layer update and once more, with Done set, when
+// the stream ends. Failures reported in the stream are returned as errors.
+func readPullProgress(r io.Reader, image string, report func(PullProgress)) error {
+	progress := PullProgress{Image: image}
+	index := make(map[string]int)
+	snapshot := func() PullProgress {
+		p := progress
+		p.Layers = append([]LayerProgress(nil), progress.Layers...)
+		return p
+	}
+	defer func() {
+		progress.Done = true
+		report(snapshot())
+	}()
+
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		// Messages about the image as a whole, such as "Pulling from
+		// library/alpine" (whose ID is the tag) and the final digest, carry
+		// no layer progress.
+		if msg.ID == "" || strings.HasPrefix(msg.Status, "Pulling from ") {
+			continue
+		}
+
+		i, ok := index[msg.ID]
+		if !ok {
+			i = len(progress.Layers)
+			index[msg.ID] = i
+			progress.Layers = append(progress.Layers, LayerProgress{ID: msg.ID})
+		}
+		layer := &progress.Layers[i]
+		layer.Status = msg.Status
+		switch msg.Status {
+		case "Downloading":
+			if msg.Progress != nil {
+				layer.Current, layer.Total = msg.Progress.Current, msg.Progress.Total
+			}
+		case "Download complete", "Verifying Checksum":
+			layer.Current = layer.Total
+		case "Pull complete", "Already exists":
+			layer.Current = layer.Total
+			layer.Complete = true
+		}
+		report(snapshot())
+	}
+}
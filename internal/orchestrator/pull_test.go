@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+)
+
+// cannedPull is a trimmed progress stream of a two-layer pull, one layer of
+// which was already present.
+const cannedPull = `{"status":"Pulling from library/app","id":"1.0"}
+{"status":"Already exists","progressDetail":{},"id":"aaa"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"bbb"}
+{"status":"Downloading","progressDetail":{"current":512,"total":2048},"progress":"[==>  ]","id":"bbb"}
+{"status":"Downloading","progressDetail":{"current":1536,"total":2048},"progress":"[====>]","id":"bbb"}
+{"status":"Download complete","progressDetail":{},"id":"bbb"}
+{"status":"Extracting","progressDetail":{"current":100,"total":4096},"id":"bbb"}
+{"status":"Pull complete","progressDetail":{},"id":"bbb"}
+{"status":"Digest: sha256:0123"}
+{"status":"Status: Downloaded newer image for app:1.0"}
+`
+
+func TestReadPullProgress(t *testing.T) {
+	var updates []PullProgress
+	if err := readPullProgress(strings.NewReader(cannedPull), "app:1.0", func(p PullProgress) {
+		updates = append(updates, p)
+	}); err != nil {
+		t.Fatalf("readPullProgress: %v", err)
+	}
+
+	// One update per layer message plus the final report.
+	if len(updates) != 8 {
+		t.Fatalf("got %d updates, want 8: %+v", len(updates), updates)
+	}
+	downloading := updates[3]
+	if current, total := downloading.Bytes(); current != 1536 || total != 2048 {
+		t.Fatalf("mid-download bytes = %d/%d, want 1536/2048", current, total)
+	}
+	if downloading.CompleteLayers() != 1 || downloading.Done {
+		t.Fatalf("mid-download progress = %+v", downloading)
+	}
+	extracting := updates[5]
+	if current, _ := extracting.Bytes(); current != 2048 {
+		t.Fatalf("extraction must not count as download bytes, got %d", current)
+	}
+
+	last := updates[len(updates)-1]
+	if !last.Done || last.Image != "app:1.0" || last.CompleteLayers() != 2 || len(last.Layers) != 2 {
+		t.Fatalf("final progress = %+v", last)
+	}
+	if last.Layers[0].ID != "aaa" || last.Layers[1].ID != "bbb" || last.Layers[1].Status != "Pull complete" {
+		t.Fatalf("layers = %+v, want aaa then bbb in stream order", last.Layers)
+	}
+}
+
+func TestReadPullProgress_StreamError(t *testing.T) {
+	stream := `{"status":"Pulling fs layer","progressDetail":{},"id":"bbb"}
+{"errorDetail":{"message":"unauthorized: authentication required"},"error":"unauthorized: authentication required"}
+`
+	var last PullProgress
+	err := readPullProgress(strings.NewReader(stream), "private/app", func(p PullProgress) { last = p })
+	if err == nil || !strings.Contains(err.Error(), "authentication required") {
+		t.Fatalf("err = %v, want the stream's error", err)
+	}
+	if !last.Done {
+		t.Fatalf("a failed pull must still send its final report, got %+v", last)
+	}
+}
@@ -0,0 +1,130 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ServiceInfo describes a gRPC service an agent exposes through reflection.
+type ServiceInfo struct {
+	Name    string
+	File    string
+	Methods []MethodInfo
+}
+
+// MethodInfo describes a single RPC of a service.
+type MethodInfo struct {
+	Name            string
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+// Services lists the services and methods the agent at endpoint exposes. It
+// requires the agent to have gRPC reflection enabled; otherwise the call fails
+// with codes.Unimplemented.
+func (r *RPC) Services(ctx context.Context, endpoint string) ([]ServiceInfo, error) {
+	if endpoint == "" {
+		return nil, errors.New("endpoint is required")
+	}
+
+	pc, err := r.connection(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer r.releaseConn(pc)
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
+	defer cancel()
+
+	stream, err := reflectionpb.NewServerReflectionClient(pc.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stream.CloseSend()
+	}()
+
+	ask := func(req *reflectionpb.ServerReflectionRequest) (*reflectionpb.ServerReflectionResponse, error) {
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return nil, fmt.Errorf("reflection: %s", errResp.GetErrorMessage())
+		}
+		return resp, nil
+	}
+
+	resp, err := ask(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var services []ServiceInfo
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		name := svc.GetName()
+		if strings.HasPrefix(name, "grpc.reflection.") {
+			continue
+		}
+
+		resp, err := ask(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: name},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("describe %s: %w", name, err)
+		}
+
+		info, err := describeService(name, resp.GetFileDescriptorResponse().GetFileDescriptorProto())
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, info)
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// describeService finds the named service among the serialized file descriptors.
+func describeService(name string, files [][]byte) (ServiceInfo, error) {
+	for _, raw := range files {
+		var file descriptorpb.FileDescriptorProto
+		if err := proto.Unmarshal(raw, &file); err != nil {
+			return ServiceInfo{}, fmt.Errorf("decode descriptor for %s: %w", name, err)
+		}
+
+		for _, svc := range file.GetService() {
+			fullName := svc.GetName()
+			if pkg := file.GetPackage(); pkg != "" {
+				fullName = pkg + "." + fullName
+			}
+			if fullName != name {
+				continue
+			}
+
+			info := ServiceInfo{Name: name, File: file.GetName()}
+			for _, method := range svc.GetMethod() {
+				info.Methods = append(info.Methods, MethodInfo{
+					Name:            method.GetName(),
+					ClientStreaming: method.GetClientStreaming(),
+					ServerStreaming: method.GetServerStreaming(),
+				})
+			}
+			return info, nil
+		}
+	}
+
+	return ServiceInfo{}, fmt.Errorf("no descriptor found for %s", name)
+}
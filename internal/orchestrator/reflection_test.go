@@ -0,0 +1,74 @@
+package orchestrator
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func reflectionRPC(t *testing.T, enabled bool) *RPC {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	convoypb.RegisterConvoyServiceServer(server, healthyServer{})
+	if enabled {
+		reflection.Register(server)
+	}
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	rpc := NewRPC(RPCConfig{
+		DialTimeout: time.Second,
+		Dialer: func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		},
+	})
+	t.Cleanup(func() {
+		_ = rpc.Close()
+	})
+	return rpc
+}
+
+func TestRPC_ServicesListsConvoyMethods(t *testing.T) {
+	services, err := reflectionRPC(t, true).Services(context.Background(), "agent")
+	if err != nil {
+		t.Fatalf("Services: %v", err)
+	}
+	if len(services) != 1 || services[0].Name != "convoy.ConvoyService" {
+		t.Fatalf("unexpected services: %+v", services)
+	}
+
+	methods := make(map[string]MethodInfo)
+	for _, m := range services[0].Methods {
+		methods[m.Name] = m
+	}
+	for _, name := range []string{"ExecuteCommand", "ExecuteShell", "CheckHealth", "Copy", "GetInfo"} {
+		if _, ok := methods[name]; !ok {
+			t.Fatalf("method %s missing from %+v", name, services[0].Methods)
+		}
+	}
+	if shell := methods["ExecuteShell"]; !shell.ClientStreaming || !shell.ServerStreaming {
+		t.Fatalf("ExecuteShell should be bidi streaming: %+v", shell)
+	}
+	if unary := methods["CheckHealth"]; unary.ClientStreaming || unary.ServerStreaming {
+		t.Fatalf("CheckHealth should be unary: %+v", unary)
+	}
+}
+
+func TestRPC_ServicesWithoutReflection(t *testing.T) {
+	_, err := reflectionRPC(t, false).Services(context.Background(), "agent")
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected Unimplemented, got %v", err)
+	}
+}
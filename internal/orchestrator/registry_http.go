@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPRegistrySource lists agents that report themselves to an HTTP registry
+// with heartbeats, for fleets the container runtime cannot enumerate. A GET
+// on the registry URL returns a JSON array of the latest heartbeat per agent.
+type HTTPRegistrySource struct {
+	url    string
+	client *http.Client
+}
+
+// registeredAgent is one heartbeat as returned by the registry.
+type registeredAgent struct {
+	AgentID   string    `json:"agent_id"`
+	Endpoint  string    `json:"endpoint"`
+	Health    string    `json:"health"`
+	Hostname  string    `json:"hostname"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewHTTPRegistrySource queries the registry at url. A nil client uses one
+// with a 10 second timeout.
+func NewHTTPRegistrySource(url string, client *http.Client) *HTTPRegistrySource {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &HTTPRegistrySource{url: url, client: client}
+}
+
+// ListContainers returns an entry per registered agent, named after its agent
+// id. Running reports whether the agent's last heartbeat said it was healthy,
+// or degraded because all its slots were busy; an unhealthy agent is shutting down.
+func (s *HTTPRegistrySource) ListContainers(ctx context.Context) ([]*Container, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("query agent registry: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query agent registry: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("query agent registry %s: %s", s.url, resp.Status)
+	}
+
+	var agents []registeredAgent
+	if err := json.NewDecoder(resp.Body).Decode(&agents); err != nil {
+		return nil, fmt.Errorf("decode agent registry response: %w", err)
+	}
+
+	containers := make([]*Container, 0, len(agents))
+	for _, a := range agents {
+		if a.AgentID == "" || a.Endpoint == "" {
+			continue
+		}
+		containers = append(containers, &Container{
+			ID:        a.AgentID,
+			Name:      a.AgentID,
+			Endpoint:  a.Endpoint,
+			Labels:    map[string]string{},
+			Running:   a.Health == "healthy" || a.Health == "degraded",
+			UpdatedAt: a.Timestamp,
+		})
+	}
+	return containers, nil
+}
@@ -0,0 +1,47 @@
+package orchestrator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPRegistrySource_ListContainers(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("method = %s, want GET", r.Method)
+		}
+		_, _ = w.Write([]byte(`[
+			{"agent_id": "edge-1", "endpoint": "10.0.0.1:6000", "health": "healthy", "timestamp": "2026-01-02T03:04:05Z"},
+			{"agent_id": "edge-2", "endpoint": "10.0.0.2:6000", "health": "unhealthy"},
+			{"agent_id": "", "endpoint": "10.0.0.3:6000"}
+		]`))
+	}))
+	defer registry.Close()
+
+	containers, err := NewHTTPRegistrySource(registry.URL, registry.Client()).ListContainers(context.Background())
+	if err != nil {
+		t.Fatalf("ListContainers: %v", err)
+	}
+	if len(containers) != 2 {
+		t.Fatalf("got %d agents, want 2 (entries without an id are skipped)", len(containers))
+	}
+	if c := containers[0]; c.Name != "edge-1" || c.Endpoint != "10.0.0.1:6000" || !c.Running || c.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected first agent %+v", c)
+	}
+	if containers[1].Running {
+		t.Fatalf("unhealthy agent reported as running")
+	}
+}
+
+func TestHTTPRegistrySource_ErrorStatus(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer registry.Close()
+
+	if _, err := NewHTTPRegistrySource(registry.URL, nil).ListContainers(context.Background()); err == nil {
+		t.Fatalf("expected an error for a 502 answer")
+	}
+}
@@ -0,0 +1,80 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// WithRequestID makes calls made with ctx carry id as their request ID instead
+// of a generated one, e.g. to reuse an ID from an outer system.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, convoypb.RequestIDMetadataKey, id)
+}
+
+// outgoingRequestID returns ctx carrying a request ID, adding a generated one when
+// the caller did not set one with WithRequestID.
+func outgoingRequestID(ctx context.Context) (context.Context, string) {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(convoypb.RequestIDMetadataKey); len(values) > 0 {
+			return ctx, values[0]
+		}
+	}
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	id := hex.EncodeToString(buf)
+	return WithRequestID(ctx, id), id
+}
+
+// withRequestIDError appends the request ID to err so it can be looked up in the
+// agent's logs, keeping the gRPC status code intact.
+func withRequestIDError(err error, id string) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+	if st, ok := status.FromError(err); ok {
+		return status.Errorf(st.Code(), "%s (request id %s)", st.Message(), id)
+	}
+	return fmt.Errorf("%w (request id %s)", err, id)
+}
+
+// requestIDDialOptions tag every call and stream with a request ID.
+func requestIDDialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			ctx, id := outgoingRequestID(ctx)
+			return withRequestIDError(invoker(ctx, method, req, reply, cc, opts...), id)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			ctx, id := outgoingRequestID(ctx)
+			stream, err := streamer(ctx, desc, cc, method, opts...)
+			if err != nil {
+				return nil, withRequestIDError(err, id)
+			}
+			return &requestIDClientStream{ClientStream: stream, id: id}, nil
+		}),
+	}
+}
+
+// requestIDClientStream adds the request ID to errors surfacing mid-stream.
+type requestIDClientStream struct {
+	grpc.ClientStream
+	id string
+}
+
+func (s *requestIDClientStream) SendMsg(m any) error {
+	return withRequestIDError(s.ClientStream.SendMsg(m), s.id)
+}
+
+func (s *requestIDClientStream) RecvMsg(m any) error {
+	return withRequestIDError(s.ClientStream.RecvMsg(m), s.id)
+}
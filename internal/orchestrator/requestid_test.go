@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDServer records the request IDs it receives and fails every call.
+type requestIDServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+
+	mu  sync.Mutex
+	ids []string
+}
+
+func (s *requestIDServer) record(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids = append(s.ids, strings.Join(md.Get(convoypb.RequestIDMetadataKey), ","))
+}
+
+func (s *requestIDServer) GetInfo(ctx context.Context, _ *convoypb.InfoRequest) (*convoypb.InfoResponse, error) {
+	s.record(ctx)
+	return nil, status.Error(codes.NotFound, "no such thing")
+}
+
+func (s *requestIDServer) ExecuteCommandStream(_ *convoypb.CommandRequest, stream convoypb.ConvoyService_ExecuteCommandStreamServer) error {
+	s.record(stream.Context())
+	return status.Error(codes.PermissionDenied, "nope")
+}
+
+func (s *requestIDServer) last() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ids[len(s.ids)-1]
+}
+
+func TestRPC_PropagatesRequestID(t *testing.T) {
+	srv := &requestIDServer{}
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{"agent": srv})
+	ctx := context.Background()
+
+	_, err := rpc.GetInfo(ctx, "agent")
+	id := srv.last()
+	if id == "" || strings.Contains(id, ",") {
+		t.Fatalf("agent received request ids %q, want exactly one", id)
+	}
+	if status.Code(err) != codes.NotFound || !strings.Contains(err.Error(), "(request id "+id+")") {
+		t.Fatalf("error %v should keep its code and name request id %s", err, id)
+	}
+
+	if _, err := rpc.GetInfo(ctx, "agent"); err == nil || srv.last() == id {
+		t.Fatalf("each call should get a fresh request id, got %q twice", id)
+	}
+
+	_, err = rpc.GetInfo(WithRequestID(ctx, "deploy-7"), "agent")
+	if srv.last() != "deploy-7" || !strings.Contains(err.Error(), "request id deploy-7") {
+		t.Fatalf("caller-supplied id not used: server saw %q, error %v", srv.last(), err)
+	}
+
+	stream, err := rpc.ExecuteCommandStream(ctx, "agent", &convoypb.CommandRequest{Args: []string{"true"}})
+	if err != nil {
+		t.Fatalf("ExecuteCommandStream: %v", err)
+	}
+	_, err = stream.Recv()
+	if status.Code(err) != codes.PermissionDenied || !strings.Contains(err.Error(), "(request id "+srv.last()+")") {
+		t.Fatalf("stream error %v should name request id %s", err, srv.last())
+	}
+}
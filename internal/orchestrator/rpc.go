@@ -4,27 +4,96 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
 	convoypb "convoy/api"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
+// UnixEndpointPrefix marks an endpoint as the path of a Unix domain socket,
+// e.g. "unix:///run/convoy/agent.sock". Such endpoints are dialed by gRPC's
+// unix resolver; anything else is a host:port dialed over TCP.
+const UnixEndpointPrefix = "unix://"
+
 // RPCConfig configures the RPC client behavior.
 type RPCConfig struct {
 	DialTimeout time.Duration
 	CallTimeout time.Duration
+	// Dialer, when set, replaces the default TCP dialer (e.g. for bufconn, UDS, or SSH transports).
+	Dialer func(ctx context.Context, endpoint string) (net.Conn, error)
+	// DialOptions are appended to the default dial options.
+	DialOptions []grpc.DialOption
+	// MaxConns caps the number of cached connections; when the cap is hit the
+	// least recently used idle connection is closed. Zero means no limit.
+	MaxConns int
+	// IdleTimeout closes connections that have not been used for this long.
+	// Zero keeps connections open until Close.
+	IdleTimeout time.Duration
+	// KeepaliveTime is how often an idle connection is pinged so NAT and load
+	// balancers don't drop long shell sessions. Zero uses the default (30s);
+	// negative disables keepalive pings.
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is how long to wait for a ping ack before closing the connection.
+	KeepaliveTimeout time.Duration
+}
+
+const (
+	defaultKeepaliveTime    = 30 * time.Second
+	defaultKeepaliveTimeout = 10 * time.Second
+)
+
+// keepaliveParams returns the client keepalive settings for cfg and whether they are enabled.
+func (cfg RPCConfig) keepaliveParams() (keepalive.ClientParameters, bool) {
+	if cfg.KeepaliveTime < 0 {
+		return keepalive.ClientParameters{}, false
+	}
+
+	params := keepalive.ClientParameters{
+		Time:                cfg.KeepaliveTime,
+		Timeout:             cfg.KeepaliveTimeout,
+		PermitWithoutStream: true,
+	}
+	if params.Time == 0 {
+		params.Time = defaultKeepaliveTime
+	}
+	if params.Timeout <= 0 {
+		params.Timeout = defaultKeepaliveTimeout
+	}
+	return params, true
 }
 
 // RPC handles gRPC communication with containers.
 type RPC struct {
 	cfg      RPCConfig
 	mu       sync.Mutex
-	conns    map[string]*grpc.ClientConn
+	conns    map[string]*pooledConn
 	dialOpts []grpc.DialOption
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	janitor   sync.WaitGroup
+}
+
+// pooledConn is a cached connection with the bookkeeping used for eviction.
+type pooledConn struct {
+	conn     *grpc.ClientConn
+	lastUsed time.Time
+	// active counts calls and streams currently using the connection; busy
+	// connections are never evicted.
+	active int
+
+	// protocolMu guards the protocol check made once per connection;
+	// protocolErr is set when the agent's major version differs from ours.
+	protocolMu      sync.Mutex
+	protocolChecked bool
+	protocolErr     error
 }
 
 // NewRPC creates a new RPC helper with sensible defaults.
@@ -36,24 +105,47 @@ func NewRPC(cfg RPCConfig) *RPC {
 		cfg.CallTimeout = 30 * time.Second
 	}
 
-	return &RPC{
-		cfg:   cfg,
-		conns: make(map[string]*grpc.ClientConn),
-		dialOpts: []grpc.DialOption{
-			grpc.WithTransportCredentials(insecure.NewCredentials()),
-			grpc.WithBlock(),
-		},
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+	}
+	if cfg.Dialer != nil {
+		dialOpts = append(dialOpts, grpc.WithContextDialer(cfg.Dialer))
 	}
+	if params, ok := cfg.keepaliveParams(); ok {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(params))
+	}
+	dialOpts = append(dialOpts, requestIDDialOptions()...)
+	dialOpts = append(dialOpts, cfg.DialOptions...)
+
+	r := &RPC{
+		cfg:      cfg,
+		conns:    make(map[string]*pooledConn),
+		dialOpts: dialOpts,
+		stop:     make(chan struct{}),
+	}
+
+	if cfg.IdleTimeout > 0 {
+		r.janitor.Add(1)
+		go r.evictIdleLoop()
+	}
+
+	return r
 }
 
-// Close shuts down all open connections.
+// Close stops idle eviction and shuts down all open connections.
 func (r *RPC) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	r.janitor.Wait()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	var firstErr error
-	for endpoint, conn := range r.conns {
-		if err := conn.Close(); err != nil && firstErr == nil {
+	for endpoint, pc := range r.conns {
+		if err := pc.conn.Close(); err != nil && firstErr == nil {
 			firstErr = err
 		}
 		delete(r.conns, endpoint)
@@ -62,12 +154,64 @@ func (r *RPC) Close() error {
 	return firstErr
 }
 
+// evictIdleLoop closes idle connections until Close is called.
+func (r *RPC) evictIdleLoop() {
+	defer r.janitor.Done()
+
+	ticker := time.NewTicker(r.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-ticker.C:
+			r.evictIdle(now)
+		}
+	}
+}
+
+// evictIdle closes connections that have been unused for longer than IdleTimeout.
+func (r *RPC) evictIdle(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for endpoint, pc := range r.conns {
+		if pc.active == 0 && now.Sub(pc.lastUsed) >= r.cfg.IdleTimeout {
+			_ = pc.conn.Close()
+			delete(r.conns, endpoint)
+		}
+	}
+}
+
+// evictLRULocked closes the least recently used idle connection. r.mu must be held.
+func (r *RPC) evictLRULocked() {
+	var (
+		oldest   string
+		oldestAt time.Time
+	)
+	for endpoint, pc := range r.conns {
+		if pc.active > 0 {
+			continue
+		}
+		if oldest == "" || pc.lastUsed.Before(oldestAt) {
+			oldest, oldestAt = endpoint, pc.lastUsed
+		}
+	}
+	if oldest == "" {
+		return
+	}
+	_ = r.conns[oldest].conn.Close()
+	delete(r.conns, oldest)
+}
+
 // ExecuteCommand calls ExecuteCommand on the target endpoint.
 func (r *RPC) ExecuteCommand(ctx context.Context, endpoint string, req *convoypb.CommandRequest) (*convoypb.CommandResponse, error) {
-	client, err := r.client(ctx, endpoint)
+	client, release, err := r.client(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 
 	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
 	defer cancel()
@@ -75,30 +219,66 @@ func (r *RPC) ExecuteCommand(ctx context.Context, endpoint string, req *convoypb
 	return client.ExecuteCommand(ctx, req)
 }
 
-// ExecuteShell opens a bidirectional shell stream.
-func (r *RPC) ExecuteShell(ctx context.Context, endpoint string) (convoypb.ConvoyService_ExecuteShellClient, error) {
-	client, err := r.client(ctx, endpoint)
+// ExecuteCommandStream runs a command and returns a stream of its output followed by an exit message.
+func (r *RPC) ExecuteCommandStream(ctx context.Context, endpoint string, req *convoypb.CommandRequest) (convoypb.ConvoyService_ExecuteCommandStreamClient, error) {
+	client, release, err := r.client(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
-	stream, err := client.ExecuteShell(ctx)
+	stream, err := client.ExecuteCommandStream(ctx, req)
 	if err != nil {
 		cancel()
+		release()
 		return nil, err
 	}
 
-	// The caller is responsible for canceling via stream.Context().Done when finished.
+	// Release the timeout and the connection once the server closes the stream.
+	go func() {
+		<-stream.Context().Done()
+		cancel()
+		release()
+	}()
+
+	return stream, nil
+}
+
+// ExecuteShell opens a bidirectional shell stream. Shells are interactive, so
+// no call timeout applies; the agent's exec timeout bounds them instead.
+func (r *RPC) ExecuteShell(ctx context.Context, endpoint string) (convoypb.ConvoyService_ExecuteShellClient, error) {
+	client, release, err := r.client(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := client.ExecuteShell(ctx)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	// Release the connection once the stream finishes; the caller ends it via CloseSend or the parent context.
+	go func() {
+		<-stream.Context().Done()
+		release()
+	}()
+
 	return stream, nil
 }
 
 // CheckHealth queries the agent health endpoint.
 func (r *RPC) CheckHealth(ctx context.Context, endpoint string, req *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
-	client, err := r.client(ctx, endpoint)
+	// Health probes report protocol skew themselves, so they skip checkProtocol.
+	if endpoint == "" {
+		return nil, errors.New("endpoint is required")
+	}
+	pc, err := r.connection(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
+	defer r.releaseConn(pc)
+	client := convoypb.NewConvoyServiceClient(pc.conn)
 
 	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
 	defer cancel()
@@ -106,39 +286,171 @@ func (r *RPC) CheckHealth(ctx context.Context, endpoint string, req *convoypb.He
 	return client.CheckHealth(ctx, req)
 }
 
+// GetInfo queries the agent identity.
+func (r *RPC) GetInfo(ctx context.Context, endpoint string) (*convoypb.InfoResponse, error) {
+	client, release, err := r.client(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
+	defer cancel()
+
+	return client.GetInfo(ctx, &convoypb.InfoRequest{})
+}
+
+// StartJob starts a command in the background on the agent and returns its initial status.
+func (r *RPC) StartJob(ctx context.Context, endpoint string, req *convoypb.CommandRequest) (*convoypb.JobStatus, error) {
+	client, release, err := r.client(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
+	defer cancel()
+
+	return client.StartJob(ctx, req)
+}
+
+// GetJob reports the status of a background job, with its output when includeOutput is set.
+func (r *RPC) GetJob(ctx context.Context, endpoint, jobID string, includeOutput bool) (*convoypb.JobStatus, error) {
+	client, release, err := r.client(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
+	defer cancel()
+
+	return client.GetJob(ctx, &convoypb.JobRequest{JobId: jobID, IncludeOutput: includeOutput})
+}
+
+// ListJobs lists the background jobs the agent remembers.
+func (r *RPC) ListJobs(ctx context.Context, endpoint string) ([]*convoypb.JobStatus, error) {
+	client, release, err := r.client(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
+	defer cancel()
+
+	resp, err := client.ListJobs(ctx, &convoypb.JobListRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetJobs(), nil
+}
+
+// CancelJob stops the background job or shell session with the given id.
+func (r *RPC) CancelJob(ctx context.Context, endpoint, id string) (*convoypb.CancelResponse, error) {
+	client, release, err := r.client(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
+	defer cancel()
+
+	return client.CancelJob(ctx, &convoypb.CancelRequest{Id: id})
+}
+
 // Copy opens a bidirectional stream for file transfer operations.
 func (r *RPC) Copy(ctx context.Context, endpoint string) (convoypb.ConvoyService_CopyClient, error) {
-	client, err := r.client(ctx, endpoint)
+	client, release, err := r.client(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 
 	stream, err := client.Copy(ctx)
 	if err != nil {
+		release()
 		return nil, err
 	}
 
+	// Keep the connection from being evicted while the transfer runs.
+	go func() {
+		<-stream.Context().Done()
+		release()
+	}()
+
 	return stream, nil
 }
 
-func (r *RPC) client(ctx context.Context, endpoint string) (convoypb.ConvoyServiceClient, error) {
+// client returns a client for endpoint together with a release func the caller
+// must invoke once it is done with the connection.
+func (r *RPC) client(ctx context.Context, endpoint string) (convoypb.ConvoyServiceClient, func(), error) {
 	if endpoint == "" {
-		return nil, errors.New("endpoint is required")
+		return nil, nil, errors.New("endpoint is required")
 	}
 
-	conn, err := r.connection(ctx, endpoint)
+	pc, err := r.connection(ctx, endpoint)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			r.releaseConn(pc)
+		})
 	}
 
-	return convoypb.NewConvoyServiceClient(conn), nil
+	if err := r.checkProtocol(ctx, pc); err != nil {
+		release()
+		return nil, nil, fmt.Errorf("%s: %w", endpoint, err)
+	}
+
+	return convoypb.NewConvoyServiceClient(pc.conn), release, nil
+}
+
+// checkProtocol asks the agent behind pc for its protocol version the first
+// time the connection is used and fails every call on it when the major
+// versions differ. Agents that cannot be asked are left to the call itself:
+// one without CheckHealth predates versioning, and an unreachable one is
+// asked again on the next call.
+func (r *RPC) checkProtocol(ctx context.Context, pc *pooledConn) error {
+	pc.protocolMu.Lock()
+	defer pc.protocolMu.Unlock()
+	if pc.protocolChecked {
+		return pc.protocolErr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.CallTimeout)
+	defer cancel()
+	resp, err := convoypb.NewConvoyServiceClient(pc.conn).CheckHealth(ctx, &convoypb.HealthRequest{ProtocolVersion: convoypb.ProtocolVersion})
+	if err != nil {
+		pc.protocolChecked = status.Code(err) == codes.Unimplemented
+		return nil
+	}
+
+	pc.protocolChecked = true
+	_, _, pc.protocolErr = NegotiateProtocol(resp.GetProtocolVersion())
+	return pc.protocolErr
+}
+
+// releaseConn marks one use of pc as finished.
+func (r *RPC) releaseConn(pc *pooledConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pc.active--
+	pc.lastUsed = time.Now()
 }
 
-func (r *RPC) connection(ctx context.Context, endpoint string) (*grpc.ClientConn, error) {
+// connection returns the cached connection for endpoint, dialing one if needed,
+// and marks it active.
+func (r *RPC) connection(ctx context.Context, endpoint string) (*pooledConn, error) {
 	r.mu.Lock()
-	if conn, ok := r.conns[endpoint]; ok {
+	if pc, ok := r.conns[endpoint]; ok {
+		pc.active++
+		pc.lastUsed = time.Now()
 		r.mu.Unlock()
-		return conn, nil
+		return pc, nil
 	}
 	r.mu.Unlock()
 
@@ -159,9 +471,16 @@ func (r *RPC) connection(ctx context.Context, endpoint string) (*grpc.ClientConn
 		if err != nil {
 			return nil, err
 		}
+		existing.active++
+		existing.lastUsed = time.Now()
 		return existing, nil
 	}
 
-	r.conns[endpoint] = conn
-	return conn, nil
+	if r.cfg.MaxConns > 0 && len(r.conns) >= r.cfg.MaxConns {
+		r.evictLRULocked()
+	}
+
+	pc := &pooledConn{conn: conn, lastUsed: time.Now(), active: 1}
+	r.conns[endpoint] = pc
+	return pc, nil
 }
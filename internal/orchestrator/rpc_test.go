@@ -0,0 +1,162 @@
+package orchestrator
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type healthyServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+}
+
+func (healthyServer) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	return &convoypb.HealthResponse{Status: convoypb.HealthResponse_STATUS_HEALTHY}, nil
+}
+
+func TestRPC_UsesInjectedDialer(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	convoypb.RegisterConvoyServiceServer(server, healthyServer{})
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	var dialed []string
+	rpc := NewRPC(RPCConfig{
+		DialTimeout: 5 * time.Second,
+		Dialer: func(ctx context.Context, endpoint string) (net.Conn, error) {
+			dialed = append(dialed, endpoint)
+			return lis.DialContext(ctx)
+		},
+	})
+	defer func() {
+		_ = rpc.Close()
+	}()
+
+	resp, err := rpc.CheckHealth(context.Background(), "agent-under-test", &convoypb.HealthRequest{})
+	if err != nil {
+		t.Fatalf("CheckHealth: %v", err)
+	}
+	if resp.GetStatus() != convoypb.HealthResponse_STATUS_HEALTHY {
+		t.Fatalf("unexpected status %s", resp.GetStatus())
+	}
+	if len(dialed) != 1 || dialed[0] != "agent-under-test" {
+		t.Fatalf("injected dialer not used, dialed %v", dialed)
+	}
+}
+
+func TestRPC_EvictsIdleConnections(t *testing.T) {
+	rpc := bufconnRPCWithConfig(t, map[string]convoypb.ConvoyServiceServer{"a": healthyServer{}}, RPCConfig{IdleTimeout: 20 * time.Millisecond})
+
+	if _, err := rpc.CheckHealth(context.Background(), "a", &convoypb.HealthRequest{}); err != nil {
+		t.Fatalf("CheckHealth: %v", err)
+	}
+	rpc.mu.Lock()
+	conn := rpc.conns["a"].conn
+	rpc.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rpc.mu.Lock()
+		n := len(rpc.conns)
+		rpc.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("idle connection was not evicted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if state := conn.GetState(); state != connectivity.Shutdown {
+		t.Fatalf("evicted connection not closed, state %s", state)
+	}
+
+	// A later call transparently redials.
+	if _, err := rpc.CheckHealth(context.Background(), "a", &convoypb.HealthRequest{}); err != nil {
+		t.Fatalf("CheckHealth after eviction: %v", err)
+	}
+}
+
+func TestRPC_EvictsLeastRecentlyUsedAtCap(t *testing.T) {
+	rpc := bufconnRPCWithConfig(t, map[string]convoypb.ConvoyServiceServer{
+		"a": healthyServer{},
+		"b": healthyServer{},
+		"c": healthyServer{},
+	}, RPCConfig{MaxConns: 2})
+
+	for _, endpoint := range []string{"a", "b", "a", "c"} {
+		if _, err := rpc.CheckHealth(context.Background(), endpoint, &convoypb.HealthRequest{}); err != nil {
+			t.Fatalf("CheckHealth %s: %v", endpoint, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	rpc.mu.Lock()
+	defer rpc.mu.Unlock()
+	if len(rpc.conns) != 2 {
+		t.Fatalf("expected 2 cached connections, got %d", len(rpc.conns))
+	}
+	if _, ok := rpc.conns["b"]; ok {
+		t.Fatalf("least recently used connection b was not evicted")
+	}
+	for _, endpoint := range []string{"a", "c"} {
+		if _, ok := rpc.conns[endpoint]; !ok {
+			t.Fatalf("expected %s to stay cached", endpoint)
+		}
+	}
+}
+
+func TestRPC_KeepsActiveConnectionsAtCap(t *testing.T) {
+	rpc := bufconnRPCWithConfig(t, map[string]convoypb.ConvoyServiceServer{"a": healthyServer{}, "b": healthyServer{}}, RPCConfig{MaxConns: 1})
+
+	_, release, err := rpc.client(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("client a: %v", err)
+	}
+	defer release()
+	if _, err := rpc.CheckHealth(context.Background(), "b", &convoypb.HealthRequest{}); err != nil {
+		t.Fatalf("CheckHealth b: %v", err)
+	}
+
+	rpc.mu.Lock()
+	defer rpc.mu.Unlock()
+	if _, ok := rpc.conns["a"]; !ok {
+		t.Fatalf("in-use connection was evicted")
+	}
+}
+
+func TestRPCConfig_KeepaliveParams(t *testing.T) {
+	params, ok := RPCConfig{}.keepaliveParams()
+	if !ok || params.Time != defaultKeepaliveTime || params.Timeout != defaultKeepaliveTimeout || !params.PermitWithoutStream {
+		t.Fatalf("unexpected default keepalive params: %+v (enabled %v)", params, ok)
+	}
+
+	params, ok = RPCConfig{KeepaliveTime: time.Minute, KeepaliveTimeout: 5 * time.Second}.keepaliveParams()
+	if !ok || params.Time != time.Minute || params.Timeout != 5*time.Second {
+		t.Fatalf("custom keepalive params not applied: %+v", params)
+	}
+
+	if _, ok := (RPCConfig{KeepaliveTime: -1}).keepaliveParams(); ok {
+		t.Fatalf("negative KeepaliveTime should disable keepalive")
+	}
+
+	enabled := NewRPC(RPCConfig{})
+	disabled := NewRPC(RPCConfig{KeepaliveTime: -1})
+	defer func() {
+		_ = enabled.Close()
+		_ = disabled.Close()
+	}()
+	if len(enabled.dialOpts) != len(disabled.dialOpts)+1 {
+		t.Fatalf("keepalive dial option not added: %d vs %d options", len(enabled.dialOpts), len(disabled.dialOpts))
+	}
+}
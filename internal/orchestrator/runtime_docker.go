@@ -1,11 +1,13 @@
 package orchestrator
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,19 +19,35 @@ import (
 	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/go-connections/nat"
 )
 
 const defaultShell = "/bin/bash"
 
+// imageAPI is the part of the Docker client that finds and pulls images.
+type imageAPI interface {
+	ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error)
+	ImagePull(ctx context.Context, ref string, options imagetypes.PullOptions) (io.ReadCloser, error)
+}
+
 // DockerRuntime implements Runtime using the Docker Engine API.
 type DockerRuntime struct {
 	client        *client.Client
+	images        imageAPI
 	image         string
 	agentGRPCPort int
 	network       string
+	preferNetwork bool
 	pullAlways    bool
+	verifyDigest  bool
 	pullTimeout   time.Duration
+	// pullRef maps an image to the reference pulled when it is not present
+	// locally; nil pulls the image as given.
+	pullRef func(image string) string
+	// pullProgress receives the progress of image pulls; nil discards it.
+	pullProgress func(PullProgress)
 }
 
 // NewDockerRuntime constructs a Docker-backed runtime.
@@ -49,10 +67,13 @@ func NewDockerRuntime(cfg *app.Config) (*DockerRuntime, error) {
 
 	return &DockerRuntime{
 		client:        cli,
+		images:        cli,
 		image:         cfg.Image,
 		agentGRPCPort: cfg.AgentGRPCPort,
 		network:       cfg.DockerNetwork,
+		preferNetwork: cfg.PreferNetwork,
 		pullAlways:    cfg.PullAlways,
+		verifyDigest:  cfg.VerifyImageDigest,
 		pullTimeout:   pullTimeout,
 	}, nil
 }
@@ -68,44 +89,48 @@ func (d *DockerRuntime) CreateContainer(spec ContainerSpec) (*Container, error)
 	}
 
 	name := strings.TrimSpace(spec.Name)
-	labels := copyStringMap(spec.Labels)
-	labels[CLINameLabel] = name
+	labels := managementLabels(spec, time.Now())
 	envVars := mapToEnv(spec.Environment)
 	ctx, cancel := context.WithTimeout(context.Background(), d.pullTimeout)
 	defer cancel()
 
-	if err := d.ensureImage(ctx, image); err != nil {
+	image, err := d.ensureImage(ctx, image, spec.PullPolicy)
+	if err != nil {
 		return nil, fmt.Errorf("ensure image %s: %w", image, err)
 	}
+	digest, err := d.imageDigest(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	if d.verifyDigest {
+		if err := verifyDigest(image, digest); err != nil {
+			return nil, err
+		}
+	}
+	if digest != "" {
+		labels[ImageDigestLabel] = digest
+	}
 
 	portKey := nat.Port(fmt.Sprintf("%d/tcp", d.agentGRPCPort))
+	exposedPorts, portBindings := publishedPorts(portKey, spec.Ports)
 	containerConfig := &container.Config{
 		Image:        image,
 		Labels:       labels,
 		Env:          envVars,
-		ExposedPorts: nat.PortSet{portKey: struct{}{}},
+		ExposedPorts: exposedPorts,
 	}
 	if len(spec.Command) > 0 {
 		containerConfig.Cmd = spec.Command
 	}
 
-	hostConfig := &container.HostConfig{
-		PortBindings: nat.PortMap{
-			portKey: {{HostIP: "", HostPort: ""}},
-		},
-		RestartPolicy: container.RestartPolicy{
-			Name: "unless-stopped",
-		},
-	}
-
-	var networkingConfig *network.NetworkingConfig
-	if strings.TrimSpace(d.network) != "" {
-		networkingConfig = &network.NetworkingConfig{
-			EndpointsConfig: map[string]*network.EndpointSettings{
-				d.network: {},
-			},
+	networkName := cmp.Or(strings.TrimSpace(spec.Network), strings.TrimSpace(d.network))
+	if spec.CreateNetwork {
+		if err := d.ensureNetwork(ctx, networkName); err != nil {
+			return nil, err
 		}
 	}
+	networkMode, networkingConfig := networkSettings(networkName)
+	hostConfig := newHostConfig(spec, networkMode, portBindings)
 
 	resp, err := d.client.ContainerCreate(ctx, containerConfig, hostConfig, networkingConfig, nil, name)
 	if err != nil {
@@ -118,19 +143,100 @@ func (d *DockerRuntime) CreateContainer(spec ContainerSpec) (*Container, error)
 	}
 
 	createdAt, _ := time.Parse(time.RFC3339Nano, inspect.Created)
-	endpoint := deriveEndpoint(inspect, portKey, d.network, d.agentGRPCPort)
+	endpoint := deriveEndpoint(inspect, portKey, networkName, d.agentGRPCPort, d.preferNetwork)
 
 	return &Container{
-		ID:        resp.ID,
-		Name:      name,
-		Image:     image,
-		Endpoint:  endpoint,
-		Labels:    labels,
-		CreatedAt: createdAt,
-		UpdatedAt: createdAt,
+		ID:          resp.ID,
+		Name:        name,
+		Image:       image,
+		ImageDigest: digest,
+		Endpoint:    endpoint,
+		Labels:      labels,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
 	}, nil
 }
 
+// publishedPorts exposes and publishes the agent port on a random host port,
+// followed by the spec's own port mappings. The agent's binding comes first so
+// deriveEndpoint finds it even when a mapping publishes the same port again.
+func publishedPorts(agentPort nat.Port, mappings []PortMapping) (nat.PortSet, nat.PortMap) {
+	exposed := nat.PortSet{agentPort: struct{}{}}
+	bindings := nat.PortMap{agentPort: {{HostIP: "", HostPort: ""}}}
+	for _, m := range mappings {
+		port := nat.Port(fmt.Sprintf("%d/%s", m.ContainerPort, m.protocol()))
+		hostPort := ""
+		if m.HostPort != 0 {
+			hostPort = strconv.Itoa(m.HostPort)
+		}
+		exposed[port] = struct{}{}
+		bindings[port] = append(bindings[port], nat.PortBinding{HostPort: hostPort})
+	}
+	return exposed, bindings
+}
+
+// networkSettings translates the network a container joins into Docker's
+// network mode and, for a named network, its endpoint config. An empty name
+// leaves Docker's default bridge in place.
+func networkSettings(name string) (container.NetworkMode, *network.NetworkingConfig) {
+	mode := container.NetworkMode(name)
+	if name == "" || mode.IsHost() || mode.IsNone() {
+		return mode, nil
+	}
+	return mode, &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			name: {},
+		},
+	}
+}
+
+// ensureNetwork creates the named bridge network unless it already exists.
+func (d *DockerRuntime) ensureNetwork(ctx context.Context, name string) error {
+	_, err := d.client.NetworkInspect(ctx, name, network.InspectOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errdefs.IsNotFound(err) {
+		return fmt.Errorf("inspect network %s: %w", name, err)
+	}
+
+	_, err = d.client.NetworkCreate(ctx, name, network.CreateOptions{
+		Driver: "bridge",
+		Labels: map[string]string{ManagedLabel: "true"},
+	})
+	// Another create may have made the network since it was inspected.
+	if err != nil && !errdefs.IsConflict(err) {
+		return fmt.Errorf("create network %s: %w", name, err)
+	}
+	return nil
+}
+
+// newHostConfig builds the host config for spec on the given network with the
+// given port bindings. Containers are restarted unless stopped when spec sets
+// no policy.
+func newHostConfig(spec ContainerSpec, networkMode container.NetworkMode, portBindings nat.PortMap) *container.HostConfig {
+	restart := container.RestartPolicy{Name: container.RestartPolicyUnlessStopped}
+	if spec.RestartPolicy.Name != "" {
+		restart = container.RestartPolicy{
+			Name:              container.RestartPolicyMode(spec.RestartPolicy.Name),
+			MaximumRetryCount: spec.RestartPolicy.MaxRetries,
+		}
+	}
+
+	// A host network container uses the host's ports directly and one
+	// without a network has none, so neither publishes anything.
+	if networkMode.IsHost() || networkMode.IsNone() {
+		portBindings = nil
+	}
+
+	return &container.HostConfig{
+		Binds:         spec.Volumes,
+		NetworkMode:   networkMode,
+		PortBindings:  portBindings,
+		RestartPolicy: restart,
+	}
+}
+
 // StartContainer starts the container by ID.
 func (d *DockerRuntime) StartContainer(id string) error {
 	ctx := context.Background()
@@ -150,6 +256,75 @@ func (d *DockerRuntime) StopContainer(id string) error {
 	return nil
 }
 
+// KillContainer sends signal to the container's main process.
+func (d *DockerRuntime) KillContainer(id, signal string) error {
+	if err := d.client.ContainerKill(context.Background(), id, signal); err != nil {
+		return fmt.Errorf("kill container %s with %s: %w", id, signal, err)
+	}
+	return nil
+}
+
+// RestartContainer restarts the container by ID in a single Docker call.
+func (d *DockerRuntime) RestartContainer(id string) error {
+	ctx := context.Background()
+	timeoutSec := 10
+	if err := d.client.ContainerRestart(ctx, id, container.StopOptions{Timeout: &timeoutSec}); err != nil {
+		return fmt.Errorf("restart container %s: %w", id, err)
+	}
+	return nil
+}
+
+// Inspect reports the container's state and Docker HEALTHCHECK status.
+func (d *DockerRuntime) Inspect(id string) (*ContainerState, error) {
+	inspect, err := d.client.ContainerInspect(context.Background(), id)
+	if errdefs.IsNotFound(err) {
+		return nil, fmt.Errorf("inspect container %s: %w", id, ErrContainerNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", id, err)
+	}
+
+	state := &ContainerState{Health: healthFromInspect(inspect)}
+	if inspect.ContainerJSONBase != nil && inspect.State != nil {
+		state.Running = inspect.State.Running
+		state.Status = inspect.State.Status
+	}
+	if inspect.Config != nil {
+		state.StopSignal = inspect.Config.StopSignal
+		if inspect.Config.StopTimeout != nil {
+			state.StopTimeout = time.Duration(*inspect.Config.StopTimeout) * time.Second
+		}
+	}
+	return state, nil
+}
+
+// HealthStatus reports the container's Docker HEALTHCHECK status.
+func (d *DockerRuntime) HealthStatus(id string) (ContainerHealth, error) {
+	state, err := d.Inspect(id)
+	if err != nil {
+		return "", err
+	}
+	return state.Health, nil
+}
+
+// healthFromInspect reads the healthcheck status from an inspect result.
+// Containers without a healthcheck have no State.Health at all.
+func healthFromInspect(inspect types.ContainerJSON) ContainerHealth {
+	if inspect.ContainerJSONBase == nil || inspect.State == nil || inspect.State.Health == nil {
+		return ContainerHealthNone
+	}
+	switch inspect.State.Health.Status {
+	case types.Starting:
+		return ContainerHealthStarting
+	case types.Healthy:
+		return ContainerHealthHealthy
+	case types.Unhealthy:
+		return ContainerHealthUnhealthy
+	default:
+		return ContainerHealthNone
+	}
+}
+
 // RemoveContainer removes the container and associated resources.
 func (d *DockerRuntime) RemoveContainer(id string) error {
 	ctx := context.Background()
@@ -165,17 +340,20 @@ func (d *DockerRuntime) ListContainers() ([]*Container, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	args := filters.NewArgs()
-	args.Add("label", "convoy.cli.name")
-
-	opts := container.ListOptions{
-		All:     true,
-		Filters: args,
-	}
-
-	summaries, err := d.client.ContainerList(ctx, opts)
-	if err != nil {
-		return nil, fmt.Errorf("list containers: %w", err)
+	// Docker ANDs label filters, so each ownership label needs its own query.
+	var summaries []types.Container
+	seen := make(map[string]bool)
+	for _, filter := range managedFilters() {
+		found, err := d.client.ContainerList(ctx, container.ListOptions{All: true, Filters: filter})
+		if err != nil {
+			return nil, fmt.Errorf("list containers: %w", err)
+		}
+		for _, summary := range found {
+			if !seen[summary.ID] {
+				seen[summary.ID] = true
+				summaries = append(summaries, summary)
+			}
+		}
 	}
 
 	containers := make([]*Container, 0, len(summaries))
@@ -189,16 +367,18 @@ func (d *DockerRuntime) ListContainers() ([]*Container, error) {
 		}
 
 		createdAt, _ := time.Parse(time.RFC3339Nano, inspect.Created)
-		endpoint := deriveEndpoint(inspect, portKey, d.network, d.agentGRPCPort)
+		endpoint := deriveEndpoint(inspect, portKey, d.network, d.agentGRPCPort, d.preferNetwork)
 
 		containers = append(containers, &Container{
-			ID:        inspect.ID,
-			Name:      deriveCLIName(inspect.Config.Labels),
-			Image:     inspect.Config.Image,
-			Endpoint:  endpoint,
-			Labels:    inspect.Config.Labels,
-			CreatedAt: createdAt,
-			UpdatedAt: createdAt,
+			ID:          inspect.ID,
+			Name:        deriveCLIName(inspect.Config.Labels),
+			Image:       inspect.Config.Image,
+			ImageDigest: inspect.Config.Labels[ImageDigestLabel],
+			Endpoint:    endpoint,
+			Labels:      inspect.Config.Labels,
+			Running:     inspect.State != nil && inspect.State.Running,
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
 		})
 
 	}
@@ -211,16 +391,37 @@ func (d *DockerRuntime) Close() error {
 	return d.client.Close()
 }
 
-func (d *DockerRuntime) ensureImage(ctx context.Context, image string) error {
-	if !d.pullAlways {
-		if _, _, err := d.client.ImageInspectWithRaw(ctx, image); err == nil {
-			return nil
+// ensureImage makes image available locally as policy allows, defaulting to
+// the configured pull_always, and returns the reference to create containers
+// from: image itself when already present, otherwise what was pulled.
+func (d *DockerRuntime) ensureImage(ctx context.Context, image string, policy PullPolicy) (string, error) {
+	if policy == "" {
+		policy = PullMissing
+		if d.pullAlways {
+			policy = PullAlways
+		}
+	}
+
+	if policy != PullAlways {
+		_, _, err := d.images.ImageInspectWithRaw(ctx, image)
+		switch {
+		case err == nil:
+			return image, nil
+		case policy == PullNever && errdefs.IsNotFound(err):
+			return image, fmt.Errorf("image is not present locally and the pull policy is %s", PullNever)
+		case policy == PullNever:
+			return image, err
 		}
 	}
 
-	reader, err := d.client.ImagePull(ctx, image, imagetypes.PullOptions{})
+	ref := image
+	if d.pullRef != nil {
+		ref = d.pullRef(image)
+	}
+
+	reader, err := d.images.ImagePull(ctx, ref, imagetypes.PullOptions{})
 	if err != nil {
-		return err
+		return image, err
 	}
 	defer func(reader io.ReadCloser) {
 		err := reader.Close()
@@ -228,8 +429,32 @@ func (d *DockerRuntime) ensureImage(ctx context.Context, image string) error {
 			return
 		}
 	}(reader)
-	_, _ = io.Copy(io.Discard, reader)
-	return nil
+
+	// Failures that happen mid-pull are reported in the progress stream rather
+	// than as an HTTP error.
+	if d.pullProgress != nil {
+		err = readPullProgress(reader, ref, d.pullProgress)
+	} else {
+		err = jsonmessage.DisplayJSONMessagesStream(reader, io.Discard, 0, false, nil)
+	}
+	if err != nil {
+		return image, fmt.Errorf("pull %s: %w", ref, err)
+	}
+	return ref, nil
+}
+
+// imageDigest looks up the repository digest of a local image.
+func (d *DockerRuntime) imageDigest(ctx context.Context, image string) (string, error) {
+	inspect, _, err := d.images.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("inspect image %s: %w", image, err)
+	}
+	return repoDigest(inspect, image), nil
+}
+
+// SetPullProgress has fn called with the progress of later image pulls.
+func (d *DockerRuntime) SetPullProgress(fn func(PullProgress)) {
+	d.pullProgress = fn
 }
 
 func mapToEnv(env map[string]string) []string {
@@ -261,43 +486,119 @@ func copyStringMap(input map[string]string) map[string]string {
 	return out
 }
 
-func deriveEndpoint(inspect types.ContainerJSON, port nat.Port, preferredNetwork string, agentPort int) string {
+// deriveEndpoint picks the address the CLI dials for a container's agent. By
+// default a published host port wins over the container's network IP; with
+// preferNetwork the IP on the configured network (or the default bridge) is
+// tried first, which avoids NAT hairpinning when the CLI shares that network.
+func deriveEndpoint(inspect types.ContainerJSON, port nat.Port, preferredNetwork string, agentPort int, preferNetwork bool) string {
+	var networkMode container.NetworkMode
+	if inspect.ContainerJSONBase != nil && inspect.HostConfig != nil {
+		networkMode = inspect.HostConfig.NetworkMode
+	}
+	// The agent of a host network container listens on the host itself.
+	if networkMode.IsHost() {
+		return net.JoinHostPort(dialableHost(""), strconv.Itoa(agentPort))
+	}
 	if inspect.NetworkSettings == nil {
 		return ""
 	}
 
-	if bindings, ok := inspect.NetworkSettings.Ports[port]; ok {
-		for _, binding := range bindings {
+	fromBindings := func() string {
+		for _, binding := range inspect.NetworkSettings.Ports[port] {
 			if binding.HostPort == "" {
 				continue
 			}
-			host := binding.HostIP
-			if host == "" || host == "0.0.0.0" {
-				host = "127.0.0.1"
-			}
-			return net.JoinHostPort(host, binding.HostPort)
+			return net.JoinHostPort(dialableHost(binding.HostIP), binding.HostPort)
 		}
+		return ""
 	}
 
-	if preferredNetwork != "" {
-		if netConf, ok := inspect.NetworkSettings.Networks[preferredNetwork]; ok {
-			if ip := strings.TrimSpace(netConf.IPAddress); ip != "" {
-				return fmt.Sprintf("%s:%d", ip, agentPort)
+	// IPv6-only networks leave IPAddress empty and set only the global IPv6 address.
+	fromNetwork := func() string {
+		ip := ""
+		// Containers given a network of their own are looked up on it when
+		// they are not on the configured one.
+		candidates := []string{preferredNetwork}
+		if networkMode.IsUserDefined() {
+			candidates = append(candidates, networkMode.NetworkName())
+		}
+		for _, name := range candidates {
+			if netConf := inspect.NetworkSettings.Networks[name]; name != "" && ip == "" && netConf != nil {
+				ip = firstNonEmpty(netConf.IPAddress, netConf.GlobalIPv6Address)
 			}
 		}
+		if ip == "" {
+			ip = firstNonEmpty(inspect.NetworkSettings.IPAddress, inspect.NetworkSettings.GlobalIPv6Address)
+		}
+		if ip == "" {
+			return ""
+		}
+		return net.JoinHostPort(ip, strconv.Itoa(agentPort))
 	}
 
-	if ip := strings.TrimSpace(inspect.NetworkSettings.IPAddress); ip != "" {
-		return fmt.Sprintf("%s:%d", ip, agentPort)
+	order := []func() string{fromBindings, fromNetwork}
+	if preferNetwork {
+		order = []func() string{fromNetwork, fromBindings}
+	}
+	for _, candidate := range order {
+		if endpoint := candidate(); endpoint != "" {
+			return endpoint
+		}
 	}
+	return ""
+}
 
+// dialableHost maps the wildcard addresses Docker reports for a published port
+// to the loopback address of the same family.
+func dialableHost(hostIP string) string {
+	switch strings.TrimSpace(hostIP) {
+	case "", "0.0.0.0":
+		return "127.0.0.1"
+	case "::":
+		return "::1"
+	default:
+		return strings.TrimSpace(hostIP)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
 	return ""
 }
 
+// managementLabels returns the spec labels plus the ownership labels Convoy always sets.
+// The ownership labels win over user-supplied values with the same key.
+func managementLabels(spec ContainerSpec, now time.Time) map[string]string {
+	name := strings.TrimSpace(spec.Name)
+	labels := copyStringMap(spec.Labels)
+	labels[ManagedLabel] = "true"
+	labels[NameLabel] = name
+	labels[CreatedLabel] = now.UTC().Format(time.RFC3339)
+	labels[CLINameLabel] = name
+	return labels
+}
+
+// managedFilters select the containers created by Convoy: those carrying the
+// managed label, and those from older releases that only set CLINameLabel.
+func managedFilters() []filters.Args {
+	return []filters.Args{
+		filters.NewArgs(filters.Arg("label", ManagedLabel+"=true")),
+		filters.NewArgs(filters.Arg("label", CLINameLabel)),
+	}
+}
+
 func deriveCLIName(labels map[string]string) string {
 	if len(labels) == 0 {
 		return ""
 	}
 
+	if name := strings.TrimSpace(labels[NameLabel]); name != "" {
+		return name
+	}
+
 	return strings.TrimSpace(labels[CLINameLabel])
 }
@@ -0,0 +1,353 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestManagementLabelsAlwaysPresent(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	spec := ContainerSpec{
+		Name:   " web ",
+		Labels: map[string]string{"team": "infra", ManagedLabel: "false"},
+	}
+
+	labels := managementLabels(spec, now)
+
+	want := map[string]string{
+		ManagedLabel: "true",
+		NameLabel:    "web",
+		CreatedLabel: "2026-01-02T03:04:05Z",
+		CLINameLabel: "web",
+		"team":       "infra",
+	}
+	for key, value := range want {
+		if labels[key] != value {
+			t.Fatalf("label %s = %q, want %q", key, labels[key], value)
+		}
+	}
+	if spec.Labels[ManagedLabel] != "false" {
+		t.Fatalf("spec labels must not be mutated")
+	}
+
+	if bare := managementLabels(ContainerSpec{Name: "db"}, now); bare[ManagedLabel] != "true" || bare[NameLabel] != "db" {
+		t.Fatalf("management labels missing without custom labels: %v", bare)
+	}
+
+	var got []string
+	for _, filter := range managedFilters() {
+		got = append(got, filter.Get("label")...)
+	}
+	if want := []string{ManagedLabel + "=true", CLINameLabel}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("list filters = %v, want %v", got, want)
+	}
+}
+
+func TestNewHostConfig_RestartPolicy(t *testing.T) {
+	port := nat.Port("6000/tcp")
+	for _, tc := range []struct {
+		policy RestartPolicy
+		want   container.RestartPolicy
+	}{
+		{RestartPolicy{}, container.RestartPolicy{Name: container.RestartPolicyUnlessStopped}},
+		{RestartPolicy{Name: RestartNo}, container.RestartPolicy{Name: container.RestartPolicyDisabled}},
+		{RestartPolicy{Name: RestartAlways}, container.RestartPolicy{Name: container.RestartPolicyAlways}},
+		{RestartPolicy{Name: RestartOnFailure, MaxRetries: 5}, container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 5}},
+	} {
+		_, bindings := publishedPorts(port, nil)
+		hostConfig := newHostConfig(ContainerSpec{Name: "web", RestartPolicy: tc.policy}, "", bindings)
+		if hostConfig.RestartPolicy != tc.want {
+			t.Errorf("policy %+v: host config restart policy = %+v, want %+v", tc.policy, hostConfig.RestartPolicy, tc.want)
+		}
+		if err := container.ValidateRestartPolicy(hostConfig.RestartPolicy); err != nil {
+			t.Errorf("policy %+v: Docker rejects %+v: %v", tc.policy, hostConfig.RestartPolicy, err)
+		}
+	}
+}
+
+func TestPublishedPorts(t *testing.T) {
+	agent := nat.Port("6000/tcp")
+	exposed, bindings := publishedPorts(agent, []PortMapping{
+		{HostPort: 8080, ContainerPort: 80},
+		{HostPort: 0, ContainerPort: 53, Protocol: "udp"},
+		{HostPort: 6001, ContainerPort: 6000},
+	})
+
+	wantExposed := nat.PortSet{"6000/tcp": {}, "80/tcp": {}, "53/udp": {}}
+	if !reflect.DeepEqual(exposed, wantExposed) {
+		t.Fatalf("exposed = %v, want %v", exposed, wantExposed)
+	}
+	wantBindings := nat.PortMap{
+		"6000/tcp": {{HostPort: ""}, {HostPort: "6001"}},
+		"80/tcp":   {{HostPort: "8080"}},
+		"53/udp":   {{HostPort: ""}},
+	}
+	if !reflect.DeepEqual(bindings, wantBindings) {
+		t.Fatalf("bindings = %v, want %v", bindings, wantBindings)
+	}
+}
+
+func TestNetworkSettings(t *testing.T) {
+	tests := []struct {
+		network  string
+		mode     container.NetworkMode
+		networks []string
+	}{
+		{network: "", mode: ""},
+		{network: NetworkHost, mode: "host"},
+		{network: NetworkNone, mode: "none"},
+		{network: "team-a", mode: "team-a", networks: []string{"team-a"}},
+	}
+	for _, tt := range tests {
+		mode, config := networkSettings(tt.network)
+		if mode != tt.mode {
+			t.Errorf("network %q: mode = %q, want %q", tt.network, mode, tt.mode)
+		}
+		var networks []string
+		if config != nil {
+			for name := range config.EndpointsConfig {
+				networks = append(networks, name)
+			}
+		}
+		if !reflect.DeepEqual(networks, tt.networks) {
+			t.Errorf("network %q: endpoints config for %v, want %v", tt.network, networks, tt.networks)
+		}
+	}
+
+	_, bindings := publishedPorts("6000/tcp", []PortMapping{{HostPort: 8080, ContainerPort: 80}})
+	if hostConfig := newHostConfig(ContainerSpec{}, "team-a", bindings); hostConfig.NetworkMode != "team-a" || len(hostConfig.PortBindings) != 2 {
+		t.Errorf("user network host config = %+v", hostConfig)
+	}
+	if hostConfig := newHostConfig(ContainerSpec{}, "host", bindings); hostConfig.PortBindings != nil {
+		t.Errorf("host network must not publish ports, got %v", hostConfig.PortBindings)
+	}
+}
+
+func TestDeriveEndpoint_OwnNetwork(t *testing.T) {
+	port := nat.Port("6000/tcp")
+	onNetwork := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{NetworkMode: "team-a"}},
+		NetworkSettings: &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{
+			"team-a": {IPAddress: "10.20.0.7"},
+		}},
+	}
+	if got := deriveEndpoint(onNetwork, port, "convoy-net", 6000, true); got != "10.20.0.7:6000" {
+		t.Errorf("container on its own network: got %q", got)
+	}
+
+	onHost := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{NetworkMode: "host"}},
+		NetworkSettings:   &types.NetworkSettings{},
+	}
+	if got := deriveEndpoint(onHost, port, "", 6000, false); got != "127.0.0.1:6000" {
+		t.Errorf("container on the host network: got %q", got)
+	}
+}
+
+func TestDeriveEndpoint_Precedence(t *testing.T) {
+	port := nat.Port("6000/tcp")
+	inspect := types.ContainerJSON{NetworkSettings: &types.NetworkSettings{
+		NetworkSettingsBase: types.NetworkSettingsBase{
+			Ports: nat.PortMap{port: {{HostIP: "0.0.0.0", HostPort: "32768"}}},
+		},
+		DefaultNetworkSettings: types.DefaultNetworkSettings{IPAddress: "172.17.0.2"},
+		Networks: map[string]*network.EndpointSettings{
+			"convoy-net": {IPAddress: "10.10.0.5"},
+		},
+	}}
+
+	tests := []struct {
+		name          string
+		network       string
+		preferNetwork bool
+		want          string
+	}{
+		{name: "host port first by default", network: "convoy-net", want: "127.0.0.1:32768"},
+		{name: "configured network first", network: "convoy-net", preferNetwork: true, want: "10.10.0.5:6000"},
+		{name: "default bridge when network unknown", network: "other", preferNetwork: true, want: "172.17.0.2:6000"},
+	}
+	for _, tt := range tests {
+		if got := deriveEndpoint(inspect, port, tt.network, 6000, tt.preferNetwork); got != tt.want {
+			t.Errorf("%s: deriveEndpoint = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Without a network address the published port is still used when preferring the network.
+	noIP := types.ContainerJSON{NetworkSettings: &types.NetworkSettings{
+		NetworkSettingsBase: types.NetworkSettingsBase{
+			Ports: nat.PortMap{port: {{HostIP: "", HostPort: "32768"}}},
+		},
+	}}
+	if got := deriveEndpoint(noIP, port, "convoy-net", 6000, true); got != "127.0.0.1:32768" {
+		t.Errorf("fallback to host port: got %q", got)
+	}
+}
+
+func TestDeriveEndpoint_IPv6(t *testing.T) {
+	port := nat.Port("6000/tcp")
+
+	tests := []struct {
+		name          string
+		settings      *types.NetworkSettings
+		preferNetwork bool
+		want          string
+	}{
+		{
+			name: "IPv6 wildcard binding",
+			settings: &types.NetworkSettings{NetworkSettingsBase: types.NetworkSettingsBase{
+				Ports: nat.PortMap{port: {{HostIP: "::", HostPort: "32768"}}},
+			}},
+			want: "[::1]:32768",
+		},
+		{
+			name: "IPv6 binding address",
+			settings: &types.NetworkSettings{NetworkSettingsBase: types.NetworkSettingsBase{
+				Ports: nat.PortMap{port: {{HostIP: "fd00::10", HostPort: "32768"}}},
+			}},
+			want: "[fd00::10]:32768",
+		},
+		{
+			name: "IPv6-only configured network",
+			settings: &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{
+				"convoy-net": {GlobalIPv6Address: "fd00:1::5"},
+			}},
+			preferNetwork: true,
+			want:          "[fd00:1::5]:6000",
+		},
+		{
+			name: "IPv6-only default network",
+			settings: &types.NetworkSettings{DefaultNetworkSettings: types.DefaultNetworkSettings{
+				GlobalIPv6Address: "2001:db8::2",
+			}},
+			want: "[2001:db8::2]:6000",
+		},
+	}
+	for _, tt := range tests {
+		got := deriveEndpoint(types.ContainerJSON{NetworkSettings: tt.settings}, port, "convoy-net", 6000, tt.preferNetwork)
+		if got != tt.want {
+			t.Errorf("%s: deriveEndpoint = %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		if _, _, err := net.SplitHostPort(got); err != nil {
+			t.Errorf("%s: %q is not dialable: %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestQualifyImage(t *testing.T) {
+	cases := map[string]string{
+		"alpine":                       "docker.io/library/alpine",
+		"convoy:latest":                "docker.io/library/convoy:latest",
+		"acme/agent:1.2":               "docker.io/acme/agent:1.2",
+		"quay.io/acme/agent":           "quay.io/acme/agent",
+		"localhost/convoy:dev":         "localhost/convoy:dev",
+		"registry.local:5000/agent:v1": "registry.local:5000/agent:v1",
+		"Not A Valid Reference":        "Not A Valid Reference",
+	}
+	for in, want := range cases {
+		if got := qualifyImage(in); got != want {
+			t.Errorf("qualifyImage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHealthFromInspect(t *testing.T) {
+	withHealth := func(health *types.Health) types.ContainerJSON {
+		return types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{Running: true, Health: health}}}
+	}
+
+	tests := []struct {
+		name    string
+		inspect types.ContainerJSON
+		want    ContainerHealth
+	}{
+		{name: "starting", inspect: withHealth(&types.Health{Status: types.Starting}), want: ContainerHealthStarting},
+		{name: "healthy", inspect: withHealth(&types.Health{Status: types.Healthy}), want: ContainerHealthHealthy},
+		{name: "unhealthy", inspect: withHealth(&types.Health{Status: types.Unhealthy, FailingStreak: 3}), want: ContainerHealthUnhealthy},
+		{name: "healthcheck disabled", inspect: withHealth(&types.Health{Status: types.NoHealthcheck}), want: ContainerHealthNone},
+		{name: "no healthcheck", inspect: withHealth(nil), want: ContainerHealthNone},
+		{name: "no state", inspect: types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{}}, want: ContainerHealthNone},
+		{name: "empty inspect", want: ContainerHealthNone},
+	}
+	for _, tt := range tests {
+		if got := healthFromInspect(tt.inspect); got != tt.want {
+			t.Errorf("%s: health = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// fakeImages is an imageAPI holding the images in present.
+type fakeImages struct {
+	present map[string]bool
+	pulls   []string
+}
+
+func (f *fakeImages) ImageInspectWithRaw(_ context.Context, image string) (types.ImageInspect, []byte, error) {
+	if !f.present[image] {
+		return types.ImageInspect{}, nil, errdefs.NotFound(fmt.Errorf("No such image: %s", image))
+	}
+	return types.ImageInspect{ID: "sha256:" + image}, nil, nil
+}
+
+func (f *fakeImages) ImagePull(_ context.Context, ref string, _ imagetypes.PullOptions) (io.ReadCloser, error) {
+	f.pulls = append(f.pulls, ref)
+	return io.NopCloser(strings.NewReader(`{"status":"Status: Downloaded newer image for ` + ref + `"}`)), nil
+}
+
+func TestEnsureImage_PullPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     PullPolicy
+		pullAlways bool
+		present    bool
+		wantPull   bool
+		wantErr    string
+	}{
+		{name: "always pulls a present image", policy: PullAlways, present: true, wantPull: true},
+		{name: "missing skips a present image", policy: PullMissing, present: true},
+		{name: "missing pulls an absent image", policy: PullMissing, wantPull: true},
+		{name: "never uses a present image", policy: PullNever, present: true},
+		{name: "never fails on an absent image", policy: PullNever, wantErr: "pull policy is never"},
+		{name: "default follows pull_always", pullAlways: true, present: true, wantPull: true},
+		{name: "flag overrides pull_always", policy: PullMissing, pullAlways: true, present: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			images := &fakeImages{present: map[string]bool{"alpine:3.20": tt.present}}
+			d := &DockerRuntime{images: images, pullAlways: tt.pullAlways}
+
+			ref, err := d.ensureImage(context.Background(), "alpine:3.20", tt.policy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if len(images.pulls) != 0 {
+					t.Fatalf("pulled %v despite the never policy", images.pulls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ensureImage: %v", err)
+			}
+			if ref != "alpine:3.20" {
+				t.Fatalf("ref = %q", ref)
+			}
+			if pulled := len(images.pulls) > 0; pulled != tt.wantPull {
+				t.Fatalf("pulled = %v, want %v", pulled, tt.wantPull)
+			}
+		})
+	}
+}
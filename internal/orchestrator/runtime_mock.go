@@ -0,0 +1,272 @@
+package orchestrator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"convoy/internal/app"
+
+	"google.golang.org/grpc"
+)
+
+// mockStateFile is the file in the temp directory holding MockRuntime state.
+const mockStateFile = "convoy-mock-runtime.json"
+
+// MockRuntime implements Runtime without a container engine. Containers only
+// exist as records in a JSON state file, locked across processes, so separate
+// CLI invocations see the same fake fleet. Every container's endpoint is a
+// mock agent the runtime serves in-process, which answers health checks and
+// simulates exec by echoing the command back.
+type MockRuntime struct {
+	mu        sync.Mutex
+	statePath string
+	image     string
+
+	agentOnce sync.Once
+	agent     *grpc.Server
+	endpoint  string
+	agentErr  error
+}
+
+// NewMockRuntime constructs a mock runtime keeping its state in the temp directory.
+func NewMockRuntime(cfg *app.Config) *MockRuntime {
+	return NewMockRuntimeAt(filepath.Join(os.TempDir(), mockStateFile), cfg)
+}
+
+// NewMockRuntimeAt constructs a mock runtime keeping its state at statePath.
+func NewMockRuntimeAt(statePath string, cfg *app.Config) *MockRuntime {
+	return &MockRuntime{
+		statePath: statePath,
+		image:     cfg.Image,
+	}
+}
+
+// agentEndpoint starts the mock agent on first use and returns its address.
+func (m *MockRuntime) agentEndpoint() (string, error) {
+	m.agentOnce.Do(func() {
+		m.agent, m.endpoint, m.agentErr = startMockAgent()
+		if m.agentErr != nil {
+			m.agentErr = fmt.Errorf("start mock agent: %w", m.agentErr)
+		}
+	})
+	return m.endpoint, m.agentErr
+}
+
+// Close stops the mock agent if it was started.
+func (m *MockRuntime) Close() error {
+	if _, err := m.agentEndpoint(); err == nil {
+		m.agent.Stop()
+	}
+	return nil
+}
+
+// CreateContainer records a new stopped container.
+func (m *MockRuntime) CreateContainer(spec ContainerSpec) (*Container, error) {
+	image := strings.TrimSpace(spec.Image)
+	if image == "" {
+		image = strings.TrimSpace(m.image)
+	}
+	if image == "" {
+		return nil, errors.New("image is required")
+	}
+
+	endpoint, err := m.agentEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	var created *Container
+	err = m.update(func(containers []*Container) ([]*Container, error) {
+		now := time.Now().UTC()
+		labels := managementLabels(spec, now)
+		created = &Container{
+			ID:        newMockID(),
+			Name:      deriveCLIName(labels),
+			Image:     image,
+			Endpoint:  endpoint,
+			Labels:    labels,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		return append(containers, created), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// StartContainer marks the container as running.
+func (m *MockRuntime) StartContainer(id string) error {
+	return m.setRunning(id, true)
+}
+
+// StopContainer marks the container as stopped.
+func (m *MockRuntime) StopContainer(id string) error {
+	return m.setRunning(id, false)
+}
+
+// KillContainer marks the container as stopped, as if it exited on the signal.
+func (m *MockRuntime) KillContainer(id, _ string) error {
+	return m.setRunning(id, false)
+}
+
+// Inspect reports the recorded state of the container. Mock containers have no healthcheck.
+func (m *MockRuntime) Inspect(id string) (*ContainerState, error) {
+	containers, err := m.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		if c.ID == id {
+			state := &ContainerState{Running: c.Running, Status: "exited", Health: ContainerHealthNone}
+			if c.Running {
+				state.Status = "running"
+			}
+			return state, nil
+		}
+	}
+	return nil, fmt.Errorf("inspect container %s: %w", id, ErrContainerNotFound)
+}
+
+// HealthStatus reports that mock containers have no healthcheck.
+func (m *MockRuntime) HealthStatus(id string) (ContainerHealth, error) {
+	state, err := m.Inspect(id)
+	if err != nil {
+		return "", err
+	}
+	return state.Health, nil
+}
+
+// RemoveContainer deletes the container record.
+func (m *MockRuntime) RemoveContainer(id string) error {
+	return m.update(func(containers []*Container) ([]*Container, error) {
+		for i, c := range containers {
+			if c.ID == id {
+				return append(containers[:i], containers[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("remove container %s: no such container", id)
+	})
+}
+
+// ListContainers returns every recorded container, reached through this
+// process's mock agent.
+func (m *MockRuntime) ListContainers() ([]*Container, error) {
+	endpoint, err := m.agentEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	unlock, err := lockMockState(m.statePath, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	containers, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		c.Endpoint = endpoint
+	}
+	return containers, nil
+}
+
+func (m *MockRuntime) setRunning(id string, running bool) error {
+	return m.update(func(containers []*Container) ([]*Container, error) {
+		for _, c := range containers {
+			if c.ID == id {
+				c.Running = running
+				c.UpdatedAt = time.Now().UTC()
+				return containers, nil
+			}
+		}
+		return nil, fmt.Errorf("container %s: no such container", id)
+	})
+}
+
+// update applies fn to the stored containers and saves the result, holding
+// the state lock throughout so no other process writes in between.
+func (m *MockRuntime) update(fn func([]*Container) ([]*Container, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	unlock, err := lockMockState(m.statePath, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	containers, err := m.load()
+	if err != nil {
+		return err
+	}
+	containers, err = fn(containers)
+	if err != nil {
+		return err
+	}
+	return m.save(containers)
+}
+
+func (m *MockRuntime) load() ([]*Container, error) {
+	data, err := os.ReadFile(m.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read mock state: %w", err)
+	}
+
+	var containers []*Container
+	if err := json.Unmarshal(data, &containers); err != nil {
+		return nil, fmt.Errorf("parse mock state %q: %w", m.statePath, err)
+	}
+	return containers, nil
+}
+
+// save writes containers to a temp file renamed over the state file, so a
+// concurrent reader never sees a partial write.
+func (m *MockRuntime) save(containers []*Container) error {
+	data, err := json.MarshalIndent(containers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode mock state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.statePath), filepath.Base(m.statePath)+".*")
+	if err != nil {
+		return fmt.Errorf("write mock state: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write mock state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write mock state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.statePath); err != nil {
+		return fmt.Errorf("write mock state: %w", err)
+	}
+	return nil
+}
+
+func newMockID() string {
+	buf := make([]byte, 32)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,66 @@
+package orchestrator
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mockAgent stands in for the agent of every MockRuntime container. It
+// reports itself healthy and simulates exec by echoing the command line it
+// was asked to run instead of running anything; other calls are unimplemented.
+type mockAgent struct {
+	convoypb.UnimplementedConvoyServiceServer
+}
+
+// startMockAgent serves a mockAgent on a free localhost port.
+func startMockAgent() (*grpc.Server, string, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, "", err
+	}
+	srv := grpc.NewServer()
+	convoypb.RegisterConvoyServiceServer(srv, mockAgent{})
+	go func() { _ = srv.Serve(lis) }()
+	return srv, lis.Addr().String(), nil
+}
+
+func (mockAgent) CheckHealth(context.Context, *convoypb.HealthRequest) (*convoypb.HealthResponse, error) {
+	return &convoypb.HealthResponse{
+		Status:          convoypb.HealthResponse_STATUS_HEALTHY,
+		Message:         "mock agent",
+		ProtocolVersion: convoypb.ProtocolVersion,
+	}, nil
+}
+
+func (mockAgent) ExecuteCommand(_ context.Context, req *convoypb.CommandRequest) (*convoypb.CommandResponse, error) {
+	if len(req.GetArgs()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "args required")
+	}
+	return &convoypb.CommandResponse{Stdout: mockCommandOutput(req)}, nil
+}
+
+func (mockAgent) ExecuteCommandStream(req *convoypb.CommandRequest, stream convoypb.ConvoyService_ExecuteCommandStreamServer) error {
+	if len(req.GetArgs()) == 0 {
+		return status.Error(codes.InvalidArgument, "args required")
+	}
+	err := stream.Send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Output{Output: &convoypb.ShellOutput{
+		Stream: convoypb.ShellOutput_STDOUT,
+		Data:   []byte(mockCommandOutput(req)),
+	}}})
+	if err != nil {
+		return err
+	}
+	return stream.Send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Exit{Exit: &convoypb.ShellExit{}}})
+}
+
+// mockCommandOutput is what a simulated command prints: its command line.
+func mockCommandOutput(req *convoypb.CommandRequest) string {
+	return strings.Join(req.GetArgs(), " ") + "\n"
+}
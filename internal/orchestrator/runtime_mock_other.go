@@ -0,0 +1,9 @@
+//go:build !unix
+
+package orchestrator
+
+// lockMockState does nothing; only Unix has flock, so elsewhere concurrent
+// CLI invocations can still lose each other's updates.
+func lockMockState(string, bool) (func(), error) {
+	return func() {}, nil
+}
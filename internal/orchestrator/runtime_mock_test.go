@@ -0,0 +1,148 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+	"convoy/internal/app"
+)
+
+func TestMockRuntime_StartListStop(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	cfg := &app.Config{Image: "alpine", AgentGRPCPort: 6000}
+
+	mgr, err := NewManager(NewMockRuntimeAt(statePath, cfg))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	created, err := mgr.Create(ContainerSpec{Name: "web", Image: "alpine"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Name != "web" || created.Image != "alpine" || created.Endpoint == "" {
+		t.Fatalf("unexpected container: %+v", created)
+	}
+	if err := mgr.Start(created.ID); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// A second runtime on the same file stands in for a later CLI invocation.
+	again, err := NewManager(NewMockRuntimeAt(statePath, cfg))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	listed, err := again.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != created.ID || !listed[0].Running {
+		t.Fatalf("listed %+v, want running %s", listed, created.ID)
+	}
+	if listed[0].Labels[ManagedLabel] != "true" {
+		t.Fatalf("labels not persisted: %v", listed[0].Labels)
+	}
+
+	if err := again.Stop(created.ID); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	listed, err = mgr.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(listed) != 1 || listed[0].Running {
+		t.Fatalf("container still running after stop: %+v", listed)
+	}
+
+	if err := mgr.Start("missing"); err == nil {
+		t.Fatalf("expected an error starting an unknown container")
+	}
+}
+
+func TestMockRuntime_Remove(t *testing.T) {
+	rt := NewMockRuntimeAt(filepath.Join(t.TempDir(), "state.json"), &app.Config{Image: "alpine"})
+
+	a, err := rt.CreateContainer(ContainerSpec{Name: "a"})
+	if err != nil {
+		t.Fatalf("create a: %v", err)
+	}
+	b, err := rt.CreateContainer(ContainerSpec{Name: "b"})
+	if err != nil {
+		t.Fatalf("create b: %v", err)
+	}
+	if err := rt.RemoveContainer(a.ID); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	listed, err := rt.ListContainers()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != b.ID {
+		t.Fatalf("listed %+v, want only %s", listed, b.ID)
+	}
+	if err := rt.RemoveContainer(a.ID); err == nil {
+		t.Fatalf("expected an error removing a container twice")
+	}
+}
+
+func TestMockRuntime_SimulatesExec(t *testing.T) {
+	rt := NewMockRuntimeAt(filepath.Join(t.TempDir(), "state.json"), &app.Config{Image: "alpine"})
+	t.Cleanup(func() { _ = rt.Close() })
+	created, err := rt.CreateContainer(ContainerSpec{Name: "web"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	rpc := NewRPC(RPCConfig{DialTimeout: 5 * time.Second, CallTimeout: 5 * time.Second})
+	t.Cleanup(func() { _ = rpc.Close() })
+	health, err := rpc.CheckHealth(context.Background(), created.Endpoint, &convoypb.HealthRequest{})
+	if err != nil || health.GetStatus() != convoypb.HealthResponse_STATUS_HEALTHY {
+		t.Fatalf("CheckHealth = %v, %v; want healthy", health, err)
+	}
+	resp, err := rpc.ExecuteCommand(context.Background(), created.Endpoint, &convoypb.CommandRequest{Args: []string{"echo", "hi"}})
+	if err != nil {
+		t.Fatalf("ExecuteCommand: %v", err)
+	}
+	if resp.GetStdout() != "echo hi\n" || resp.GetExitCode() != 0 {
+		t.Fatalf("ExecuteCommand = %+v, want the command line echoed", resp)
+	}
+}
+
+func TestMockRuntime_ConcurrentUpdatesAreNotLost(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	// Separate runtimes stand in for CLI invocations running at once. Their
+	// agents start up front so the creates below overlap.
+	const n = 20
+	runtimes := make([]*MockRuntime, n)
+	for i := range runtimes {
+		runtimes[i] = NewMockRuntimeAt(statePath, &app.Config{Image: "alpine"})
+		t.Cleanup(func() { _ = runtimes[i].Close() })
+		_, _ = runtimes[i].agentEndpoint()
+	}
+	var wg sync.WaitGroup
+	for i, rt := range runtimes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := rt.CreateContainer(ContainerSpec{Name: fmt.Sprintf("c%d", i)}); err != nil {
+				t.Errorf("create: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	listed, err := runtimes[0].ListContainers()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(listed) != n {
+		t.Fatalf("listed %d containers, want %d", len(listed), n)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockMockState takes an flock on the lock file beside statePath, shared for
+// readers and exclusive for writers, so CLI invocations running at the same
+// time do not lose each other's updates. The returned func releases it.
+func lockMockState(statePath string, exclusive bool) (func(), error) {
+	f, err := os.OpenFile(statePath+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("lock mock state: %w", err)
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("lock mock state: %w", err)
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
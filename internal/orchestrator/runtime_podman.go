@@ -0,0 +1,38 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"convoy/internal/app"
+
+	"github.com/distribution/reference"
+)
+
+// PodmanRuntime implements Runtime using Podman's Docker-compatible API. It
+// behaves like DockerRuntime except that images are pulled by their fully
+// qualified names, since Podman does not assume docker.io for short names.
+type PodmanRuntime struct {
+	*DockerRuntime
+}
+
+// NewPodmanRuntime constructs a Podman-backed runtime talking to cfg.DockerHost.
+func NewPodmanRuntime(cfg *app.Config) (*PodmanRuntime, error) {
+	d, err := NewDockerRuntime(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("podman: %w", err)
+	}
+	d.pullRef = qualifyImage
+
+	return &PodmanRuntime{DockerRuntime: d}, nil
+}
+
+// qualifyImage expands a short image name the way Docker does, so "alpine"
+// becomes "docker.io/library/alpine". Unparseable names are returned unchanged
+// for the API to reject.
+func qualifyImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return named.String()
+}
@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Selector matches containers by their labels. Every requirement must hold for
+// a container to match; the empty selector matches every container.
+type Selector []LabelRequirement
+
+// LabelRequirement is one comma-separated term of a selector.
+type LabelRequirement struct {
+	Key   string
+	Value string
+	// Op is "=", "!=", "exists" for a bare key, or "!exists" for a !key term.
+	Op string
+}
+
+// ParseSelector parses a label selector such as "app=web,tier!=db,canary" into
+// requirements: key=value (or key==value) needs the label set to value,
+// key!=value needs it unset or set to something else, key needs it set and
+// !key needs it unset.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	if strings.TrimSpace(s) == "" {
+		return sel, nil
+	}
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		var req LabelRequirement
+		switch {
+		case strings.Contains(term, "!="):
+			req.Key, req.Value, _ = strings.Cut(term, "!=")
+			req.Op = "!="
+		case strings.Contains(term, "="):
+			req.Key, req.Value, _ = strings.Cut(term, "=")
+			req.Value = strings.TrimPrefix(req.Value, "=")
+			req.Op = "="
+		case strings.HasPrefix(term, "!"):
+			req.Key = strings.TrimPrefix(term, "!")
+			req.Op = "!exists"
+		default:
+			req.Key = term
+			req.Op = "exists"
+		}
+		req.Key = strings.TrimSpace(req.Key)
+		req.Value = strings.TrimSpace(req.Value)
+		if req.Key == "" {
+			return nil, fmt.Errorf("invalid selector %q: empty label key", s)
+		}
+		if strings.ContainsAny(req.Key, "=! ") || strings.ContainsAny(req.Value, "=!") {
+			return nil, fmt.Errorf("invalid selector %q: malformed term %q", s, term)
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// Matches reports whether labels satisfy every requirement of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		if !req.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the selector in the form ParseSelector accepts.
+func (s Selector) String() string {
+	terms := make([]string, len(s))
+	for i, req := range s {
+		switch req.Op {
+		case "exists":
+			terms[i] = req.Key
+		case "!exists":
+			terms[i] = "!" + req.Key
+		default:
+			terms[i] = req.Key + req.Op + req.Value
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+func (r LabelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Op {
+	case "=":
+		return ok && value == r.Value
+	case "!=":
+		return !ok || value != r.Value
+	case "exists":
+		return ok
+	case "!exists":
+		return !ok
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package orchestrator
+
+import "testing"
+
+func TestParseSelector_Matches(t *testing.T) {
+	sel, err := ParseSelector(" app=web, tier!=db ,canary,!legacy")
+	if err != nil {
+		t.Fatalf("ParseSelector: %v", err)
+	}
+	if got, want := sel.String(), "app=web,tier!=db,canary,!legacy"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+
+	for _, tc := range []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{map[string]string{"app": "web", "canary": ""}, true},
+		{map[string]string{"app": "web", "canary": "1", "tier": "front"}, true},
+		{map[string]string{"app": "web", "canary": "1", "tier": "db"}, false},
+		{map[string]string{"app": "api", "canary": "1"}, false},
+		{map[string]string{"app": "web"}, false},
+		{map[string]string{"app": "web", "canary": "1", "legacy": "yes"}, false},
+		{nil, false},
+	} {
+		if got := sel.Matches(tc.labels); got != tc.want {
+			t.Errorf("Matches(%v) = %v, want %v", tc.labels, got, tc.want)
+		}
+	}
+
+	empty, err := ParseSelector("")
+	if err != nil || !empty.Matches(nil) {
+		t.Fatalf("empty selector = %v, %v; want one matching everything", empty, err)
+	}
+	if sel, err := ParseSelector("app==web"); err != nil || !sel.Matches(map[string]string{"app": "web"}) {
+		t.Fatalf("ParseSelector(app==web) = %v, %v", sel, err)
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	for _, in := range []string{"=web", "app=web,", "!", "a b=c", "app=w=eb", "app!=!x"} {
+		if sel, err := ParseSelector(in); err == nil {
+			t.Errorf("ParseSelector(%q) = %v, want an error", in, sel)
+		}
+	}
+}
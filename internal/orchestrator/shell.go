@@ -0,0 +1,180 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultResumeBackoff is the wait before the first attempt to resume a dropped shell.
+const defaultResumeBackoff = 500 * time.Millisecond
+
+// ShellIO connects a shell session to local streams. A nil Stdin sends no input.
+type ShellIO struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ShellOptions controls how RunShell recovers from dropped connections.
+type ShellOptions struct {
+	// ResumeAttempts is how many times in a row RunShell tries to reattach to
+	// the session after its stream fails with Unavailable. Zero disables resuming.
+	ResumeAttempts int
+	// ResumeBackoff is the wait before the first attempt; it doubles on each
+	// further attempt. Zero means half a second.
+	ResumeBackoff time.Duration
+	// OnResume, when set, is called before each attempt with the error that
+	// broke the stream.
+	OnResume func(attempt int, err error)
+}
+
+// RunShell runs a shell on the agent at endpoint, relaying stdio until it exits,
+// and returns its exit status. When the stream drops with Unavailable the same
+// session is resumed, so the shell and its state survive transient network
+// failures. Input or output in flight at the moment of the drop may be lost.
+func (r *RPC) RunShell(ctx context.Context, endpoint string, start *convoypb.ShellStart, stdio ShellIO, opts ShellOptions) (*convoypb.ShellExit, error) {
+	input := readShellInput(stdio.Stdin)
+	backoff := opts.ResumeBackoff
+	if backoff <= 0 {
+		backoff = defaultResumeBackoff
+	}
+
+	var (
+		sessionID string
+		failures  int
+	)
+	for {
+		msg := start
+		if sessionID != "" {
+			msg = &convoypb.ShellStart{ResumeSessionId: sessionID}
+		}
+
+		exit, attached, err := r.runShellStream(ctx, endpoint, msg, input, stdio, &sessionID)
+		if err == nil {
+			return exit, nil
+		}
+		if attached {
+			failures = 0
+		}
+
+		switch {
+		case sessionID == "" || opts.ResumeAttempts <= 0:
+			return nil, err
+		case status.Code(err) == codes.NotFound:
+			return nil, fmt.Errorf("shell session %s could not be resumed; it expired or the agent restarted: %w", sessionID, err)
+		case status.Code(err) != codes.Unavailable:
+			return nil, err
+		case failures >= opts.ResumeAttempts:
+			return nil, fmt.Errorf("shell session %s lost after %d resume attempts: %w", sessionID, failures, err)
+		}
+
+		failures++
+		if opts.OnResume != nil {
+			opts.OnResume(failures, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff << (failures - 1)):
+		}
+	}
+}
+
+// runShellStream runs one stream of a shell session. It records the session id
+// the agent assigns and reports whether the stream got attached to the session.
+func (r *RPC) runShellStream(ctx context.Context, endpoint string, start *convoypb.ShellStart, input <-chan []byte, stdio ShellIO, sessionID *string) (*convoypb.ShellExit, bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := r.ExecuteShell(ctx, endpoint)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := stream.Send(&convoypb.ShellRequest{
+		Payload: &convoypb.ShellRequest_Start{Start: start},
+	}); err != nil {
+		return nil, false, fmt.Errorf("failed to send start message: %w", err)
+	}
+
+	go func() {
+		for {
+			select {
+			case data, ok := <-input:
+				msg := &convoypb.ShellInput{Data: data}
+				if !ok {
+					msg = &convoypb.ShellInput{Eof: true}
+				}
+				if err := stream.Send(&convoypb.ShellRequest{
+					Payload: &convoypb.ShellRequest_Input{Input: msg},
+				}); err != nil || !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	attached := false
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil, attached, errors.New("shell stream ended without an exit status")
+		}
+		if err != nil {
+			return nil, attached, err
+		}
+
+		switch payload := resp.GetPayload().(type) {
+		case *convoypb.ShellResponse_Session:
+			*sessionID = payload.Session.GetSessionId()
+			attached = true
+		case *convoypb.ShellResponse_Output:
+			w := stdio.Stdout
+			if payload.Output.GetStream() == convoypb.ShellOutput_STDERR {
+				w = stdio.Stderr
+			}
+			if w != nil {
+				if _, err := w.Write(payload.Output.GetData()); err != nil {
+					return nil, attached, fmt.Errorf("write shell output: %w", err)
+				}
+			}
+		case *convoypb.ShellResponse_Exit:
+			return payload.Exit, attached, nil
+		}
+	}
+}
+
+// readShellInput forwards r in chunks until it ends, then closes the channel.
+// The reader outlives any single stream so input continues after a resume.
+func readShellInput(r io.Reader) <-chan []byte {
+	ch := make(chan []byte)
+	if r == nil {
+		close(ch)
+		return ch
+	}
+
+	go func() {
+		defer close(ch)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				ch <- append([]byte(nil), buf[:n]...)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
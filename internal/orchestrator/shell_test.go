@@ -0,0 +1,102 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	convoypb "convoy/api"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// droppingShellServer breaks the first shell stream with Unavailable and
+// answers the resume on the second one.
+type droppingShellServer struct {
+	convoypb.UnimplementedConvoyServiceServer
+	expired bool
+
+	mu      sync.Mutex
+	resumes []string
+}
+
+func (s *droppingShellServer) ExecuteShell(stream convoypb.ConvoyService_ExecuteShellServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	send := func(resp *convoypb.ShellResponse) {
+		_ = stream.Send(resp)
+	}
+	output := func(data string) *convoypb.ShellResponse {
+		return &convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Output{
+			Output: &convoypb.ShellOutput{Stream: convoypb.ShellOutput_STDOUT, Data: []byte(data)},
+		}}
+	}
+
+	id := req.GetStart().GetResumeSessionId()
+	if id == "" {
+		send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Session{Session: &convoypb.ShellSession{SessionId: "s1"}}})
+		send(output("before "))
+		return status.Error(codes.Unavailable, "connection reset")
+	}
+
+	s.mu.Lock()
+	s.resumes = append(s.resumes, id)
+	s.mu.Unlock()
+	if s.expired {
+		return status.Errorf(codes.NotFound, "shell session %s not found", id)
+	}
+	send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Session{Session: &convoypb.ShellSession{SessionId: id, Resumed: true}}})
+	send(output("after\n"))
+	send(&convoypb.ShellResponse{Payload: &convoypb.ShellResponse_Exit{Exit: &convoypb.ShellExit{ExitCode: 7}}})
+	return nil
+}
+
+func TestRunShell_ResumesAfterUnavailable(t *testing.T) {
+	srv := &droppingShellServer{}
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{"agent": srv})
+
+	var stdout strings.Builder
+	var resumed []int
+	exit, err := rpc.RunShell(context.Background(), "agent", &convoypb.ShellStart{Args: []string{"sh"}}, ShellIO{Stdout: &stdout}, ShellOptions{
+		ResumeAttempts: 3,
+		ResumeBackoff:  time.Millisecond,
+		OnResume:       func(attempt int, _ error) { resumed = append(resumed, attempt) },
+	})
+	if err != nil {
+		t.Fatalf("RunShell: %v", err)
+	}
+	if exit.GetExitCode() != 7 {
+		t.Fatalf("exit code = %d, want 7", exit.GetExitCode())
+	}
+	if stdout.String() != "before after\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	if len(srv.resumes) != 1 || srv.resumes[0] != "s1" || len(resumed) != 1 {
+		t.Fatalf("resumes = %q, OnResume calls = %v", srv.resumes, resumed)
+	}
+}
+
+func TestRunShell_ReportsExpiredSession(t *testing.T) {
+	srv := &droppingShellServer{expired: true}
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{"agent": srv})
+
+	_, err := rpc.RunShell(context.Background(), "agent", &convoypb.ShellStart{}, ShellIO{}, ShellOptions{ResumeAttempts: 3, ResumeBackoff: time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "could not be resumed") {
+		t.Fatalf("expected a could-not-resume error, got %v", err)
+	}
+}
+
+func TestRunShell_NoResumeWhenDisabled(t *testing.T) {
+	srv := &droppingShellServer{}
+	rpc := bufconnRPC(t, map[string]convoypb.ConvoyServiceServer{"agent": srv})
+
+	_, err := rpc.RunShell(context.Background(), "agent", &convoypb.ShellStart{}, ShellIO{}, ShellOptions{})
+	if status.Code(err) != codes.Unavailable || len(srv.resumes) != 0 {
+		t.Fatalf("expected the Unavailable error without resuming, got %v after %d resumes", err, len(srv.resumes))
+	}
+}
@@ -0,0 +1,45 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// signalNames lists the signals a container can be sent by name, as
+// understood by Docker and the Linux kernel.
+var signalNames = map[string]bool{
+	"SIGABRT": true, "SIGALRM": true, "SIGBUS": true, "SIGCHLD": true,
+	"SIGCONT": true, "SIGFPE": true, "SIGHUP": true, "SIGILL": true,
+	"SIGINT": true, "SIGIO": true, "SIGKILL": true, "SIGPIPE": true,
+	"SIGPROF": true, "SIGPWR": true, "SIGQUIT": true, "SIGSEGV": true,
+	"SIGSTOP": true, "SIGSYS": true, "SIGTERM": true, "SIGTRAP": true,
+	"SIGTSTP": true, "SIGTTIN": true, "SIGTTOU": true, "SIGURG": true,
+	"SIGUSR1": true, "SIGUSR2": true, "SIGVTALRM": true, "SIGWINCH": true,
+	"SIGXCPU": true, "SIGXFSZ": true,
+}
+
+// maxSignal is the highest signal number Linux accepts, counting real-time signals.
+const maxSignal = 64
+
+// ParseSignal validates a signal given by name, with or without its SIG
+// prefix and in any case, or by number, and returns it in the canonical form
+// passed to the runtime: "SIGTERM" for names, the digits for numbers.
+func ParseSignal(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 || n > maxSignal {
+			return "", fmt.Errorf("invalid signal %q: number must be between 1 and %d", s, maxSignal)
+		}
+		return strconv.Itoa(n), nil
+	}
+
+	name := strings.ToUpper(s)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if !signalNames[name] {
+		return "", fmt.Errorf("invalid signal %q", s)
+	}
+	return name, nil
+}
@@ -0,0 +1,35 @@
+package orchestrator
+
+import "testing"
+
+func TestParseSignal(t *testing.T) {
+	for in, want := range map[string]string{
+		"SIGTERM": "SIGTERM",
+		"term":    "SIGTERM",
+		" Hup ":   "SIGHUP",
+		"sigusr1": "SIGUSR1",
+		"9":       "9",
+		"64":      "64",
+	} {
+		got, err := ParseSignal(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSignal(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "SIG", "BOGUS", "0", "65", "-1"} {
+		if got, err := ParseSignal(in); err == nil {
+			t.Errorf("ParseSignal(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestManagerKill_RequiresKiller(t *testing.T) {
+	mgr, err := NewManager(&fakeRuntime{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := mgr.Kill("c1", "SIGTERM"); err == nil {
+		t.Fatalf("expected an error from a runtime that cannot send signals")
+	}
+}
@@ -0,0 +1,51 @@
+package transfer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InsideRoot reports whether the cleaned path is root or below it.
+func InsideRoot(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// LinkEscapes reports whether a symlink created at path with target linkname
+// points outside root, either because it is absolute or because it climbs out
+// with "..".
+func LinkEscapes(root, path, linkname string) bool {
+	target := linkname
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return !InsideRoot(root, filepath.Clean(target))
+}
+
+// CheckParents fails when a symlink among the existing parents of path
+// resolves outside root, so an archive cannot plant a link to, say, /etc and
+// then write files through it.
+func CheckParents(root, path string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fmt.Errorf("resolve destination: %w", err)
+	}
+
+	// Parents that do not exist yet will be created as plain directories, so
+	// only the deepest existing one can lead elsewhere.
+	parent := filepath.Dir(path)
+	for InsideRoot(root, parent) {
+		if _, err := os.Lstat(parent); err == nil {
+			break
+		}
+		parent = filepath.Dir(parent)
+	}
+
+	resolved, err := filepath.EvalSymlinks(parent)
+	if err != nil || !InsideRoot(realRoot, resolved) {
+		return fmt.Errorf("refusing to write %s through a symlink outside the destination", path)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package transfer
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkEscapes(t *testing.T) {
+	root := filepath.FromSlash("/srv/dest")
+	tests := []struct {
+		path, link string
+		want       bool
+	}{
+		{"/srv/dest/a", "b", false},
+		{"/srv/dest/a/b", "../c", false},
+		{"/srv/dest/a", "../../etc", true},
+		{"/srv/dest/a", "/etc/passwd", true},
+		{"/srv/dest/a", "/srv/dest/b", false},
+		{"/srv/dest/a", "../dest-evil", true},
+	}
+	for _, tt := range tests {
+		if got := LinkEscapes(root, filepath.FromSlash(tt.path), tt.link); got != tt.want {
+			t.Errorf("LinkEscapes(%s -> %s) = %v, want %v", tt.path, tt.link, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package transfer
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+)
+
+// Metadata selects what ApplyTarMetadata restores besides a file's mode.
+type Metadata struct {
+	// Times restores access and modification times.
+	Times bool
+	// Owner restores uid and gid; it only takes effect when running as root.
+	Owner bool
+}
+
+// ApplyTarMetadata restores what header records onto the extracted path. A
+// regular file always gets the header's permission bits, since opening an
+// existing file for writing keeps its old mode; ownership and timestamps are
+// restored as keep asks.
+func ApplyTarMetadata(path string, header *tar.Header, keep Metadata) error {
+	if header.Typeflag == tar.TypeReg {
+		if err := os.Chmod(path, os.FileMode(header.Mode).Perm()); err != nil {
+			return fmt.Errorf("failed to set mode of %s: %w", path, err)
+		}
+	}
+
+	if keep.Owner && os.Geteuid() == 0 {
+		if err := os.Lchown(path, header.Uid, header.Gid); err != nil {
+			return fmt.Errorf("failed to set owner of %s: %w", path, err)
+		}
+	}
+
+	if keep.Times && !header.ModTime.IsZero() {
+		atime := header.AccessTime
+		if atime.IsZero() {
+			atime = header.ModTime
+		}
+		if err := os.Chtimes(path, atime, header.ModTime); err != nil {
+			return fmt.Errorf("failed to set times on %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
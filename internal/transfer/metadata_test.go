@@ -0,0 +1,36 @@
+package transfer
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyTarMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: "file", Mode: 0o755, ModTime: mtime}
+
+	if err := ApplyTarMetadata(path, header, Metadata{}); err != nil {
+		t.Fatalf("ApplyTarMetadata: %v", err)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0o755 {
+		t.Fatalf("mode = %v, want the header's 0755 on an existing file", info.Mode().Perm())
+	}
+	if info.ModTime().Equal(mtime) {
+		t.Fatalf("times restored without being asked to")
+	}
+
+	if err := ApplyTarMetadata(path, header, Metadata{Times: true}); err != nil {
+		t.Fatalf("ApplyTarMetadata: %v", err)
+	}
+	if info, _ := os.Stat(path); !info.ModTime().Equal(mtime) {
+		t.Fatalf("mtime = %v, want %v", info.ModTime(), mtime)
+	}
+}
@@ -0,0 +1,53 @@
+// Package transfer holds the file-transfer rules the convoy CLI and agent
+// share, so both ends of a copy pack, filter and unpack entries the same way.
+package transfer
+
+import (
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+)
+
+// IsExcluded reports whether the slash-separated tar name matches any gitignore-style
+// pattern. Patterns without a slash match any path element's base name; patterns with
+// a slash match the full relative path. A trailing slash restricts a pattern to directories.
+func IsExcluded(patterns []string, name string, isDir bool) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		dirOnly := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+		if dirOnly && !isDir {
+			continue
+		}
+
+		target := pathpkg.Base(name)
+		if strings.Contains(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+			target = name
+		}
+
+		if matched, err := pathpkg.Match(pattern, target); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasGlobMeta reports whether path contains any filepath.Match metacharacters.
+func HasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// GlobRoot returns the directory preceding the first wildcard element of pattern.
+func GlobRoot(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for HasGlobMeta(dir) {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
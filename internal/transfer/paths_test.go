@@ -0,0 +1,47 @@
+package transfer
+
+import "testing"
+
+func TestIsExcluded(t *testing.T) {
+	patterns := []string{"*.log", "# a comment", "", "build/", "/docs/*.md", " node_modules "}
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{name: "app.log", want: true},
+		{name: "src/deep/app.log", want: true},
+		{name: "app.go", want: false},
+		{name: "build", isDir: true, want: true},
+		{name: "src/build", isDir: true, want: true},
+		{name: "build", want: false},
+		{name: "docs/readme.md", want: true},
+		{name: "src/docs/readme.md", want: false},
+		{name: "node_modules", isDir: true, want: true},
+		{name: "# a comment", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsExcluded(patterns, tt.name, tt.isDir); got != tt.want {
+			t.Errorf("IsExcluded(%q, dir=%v) = %v, want %v", tt.name, tt.isDir, got, tt.want)
+		}
+	}
+	if IsExcluded(nil, "anything", false) {
+		t.Errorf("no patterns should exclude nothing")
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"/var/log/*.log":      "/var/log",
+		"/srv/*/conf/app.yml": "/srv",
+		"logs/2024-??/*.gz":   "logs",
+		"*.txt":               ".",
+	} {
+		if got := GlobRoot(pattern); got != want {
+			t.Errorf("GlobRoot(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+	if HasGlobMeta("/plain/path") || !HasGlobMeta("/a/[ab]") {
+		t.Errorf("HasGlobMeta misreports metacharacters")
+	}
+}
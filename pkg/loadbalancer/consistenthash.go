@@ -0,0 +1,126 @@
+package loadbalancer
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// DefaultReplicas is the number of virtual nodes per server on the hash ring.
+const DefaultReplicas = 160
+
+// ConsistentHash implements the KeyedBalancer interface with a hash ring.
+// Adding or removing a server only remaps the keys that land on its virtual
+// nodes, about 1/n of them. Keyless calls to Next go round robin.
+type ConsistentHash struct {
+	replicas int
+	ring     []uint32
+	owners   map[uint32]string
+	servers  []string
+	index    int
+	mu       sync.Mutex
+}
+
+var _ KeyedBalancer = (*ConsistentHash)(nil)
+
+// NewConsistentHash creates a new ConsistentHash balancer with replicas
+// virtual nodes per server; zero or less uses DefaultReplicas.
+func NewConsistentHash(replicas int) *ConsistentHash {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	return &ConsistentHash{replicas: replicas, owners: make(map[uint32]string)}
+}
+
+// Next returns the next server in round robin order
+func (ch *ConsistentHash) Next() string {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.servers) == 0 {
+		return ""
+	}
+	server := ch.servers[ch.index]
+	ch.index = (ch.index + 1) % len(ch.servers)
+	return server
+}
+
+// NextFor returns the server owning key: the first virtual node clockwise from its hash
+func (ch *ConsistentHash) NextFor(key string) string {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.ring) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	i, _ := slices.BinarySearch(ch.ring, h)
+	if i == len(ch.ring) {
+		i = 0
+	}
+	return ch.owners[ch.ring[i]]
+}
+
+// AddServer adds a server and its virtual nodes to the ring, reporting false if it was already on it
+func (ch *ConsistentHash) AddServer(server string) bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if slices.Contains(ch.servers, server) {
+		return false
+	}
+	ch.servers = append(ch.servers, server)
+	ch.addNodes(server)
+	slices.Sort(ch.ring)
+	return true
+}
+
+// RemoveServer removes a server and its virtual nodes from the ring, reporting whether it was on it
+func (ch *ConsistentHash) RemoveServer(server string) bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	i := slices.Index(ch.servers, server)
+	if i < 0 {
+		return false
+	}
+	ch.servers = append(ch.servers[:i], ch.servers[i+1:]...)
+	if i < ch.index {
+		ch.index--
+	}
+	if ch.index >= len(ch.servers) {
+		ch.index = 0
+	}
+
+	// A surviving server may have lost a node to this one on a collision, so
+	// the ring is rebuilt rather than trimmed.
+	ch.ring = ch.ring[:0]
+	clear(ch.owners)
+	for _, s := range ch.servers {
+		ch.addNodes(s)
+	}
+	slices.Sort(ch.ring)
+	return true
+}
+
+// addNodes places server's virtual nodes on the ring, leaving it unsorted.
+// On the rare collision the server added first keeps the node.
+func (ch *ConsistentHash) addNodes(server string) {
+	for i := 0; i < ch.replicas; i++ {
+		h := hashKey(server + "#" + strconv.Itoa(i))
+		if _, taken := ch.owners[h]; taken {
+			continue
+		}
+		ch.owners[h] = server
+		ch.ring = append(ch.ring, h)
+	}
+}
+
+// hashKey places key on the ring. FNV-1a alone clusters similar strings such
+// as "server#1" and "server#2", so its output is run through a finalizer.
+func hashKey(key string) uint32 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return uint32(x)
+}
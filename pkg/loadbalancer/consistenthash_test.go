@@ -0,0 +1,157 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ownership(ch *ConsistentHash, keys []string) map[string]string {
+	owners := make(map[string]string, len(keys))
+	for _, key := range keys {
+		owners[key] = ch.NextFor(key)
+	}
+	return owners
+}
+
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("cache-key-%d", i)
+	}
+	return keys
+}
+
+func TestConsistentHash_StickyKeys(t *testing.T) {
+	ch := NewConsistentHash(0)
+	if got := ch.NextFor("k"); got != "" {
+		t.Fatalf("empty ring returned %q", got)
+	}
+	for i := 0; i < 3; i++ {
+		ch.AddServer(fmt.Sprintf("10.0.0.%d:6000", i))
+	}
+
+	for _, key := range testKeys(100) {
+		first := ch.NextFor(key)
+		for j := 0; j < 3; j++ {
+			if got := ch.NextFor(key); got != first {
+				t.Fatalf("key %s moved from %s to %s without a membership change", key, first, got)
+			}
+		}
+	}
+}
+
+func TestConsistentHash_AddServerRemapsOnlyItsShare(t *testing.T) {
+	ch := NewConsistentHash(0)
+	for i := 0; i < 10; i++ {
+		ch.AddServer(fmt.Sprintf("10.0.0.%d:6000", i))
+	}
+	keys := testKeys(20000)
+	before := ownership(ch, keys)
+
+	const added = "10.0.0.10:6000"
+	ch.AddServer(added)
+	after := ownership(ch, keys)
+
+	moved := 0
+	for _, key := range keys {
+		if before[key] == after[key] {
+			continue
+		}
+		moved++
+		if after[key] != added {
+			t.Fatalf("key %s moved between existing servers %s -> %s", key, before[key], after[key])
+		}
+	}
+
+	// Ideally 1/11 of the keys move to the new server; allow for ring imbalance.
+	fraction := float64(moved) / float64(len(keys))
+	t.Logf("adding an 11th server remapped %.1f%% of keys", fraction*100)
+	if fraction < 0.04 || fraction > 0.15 {
+		t.Fatalf("adding a server remapped %.1f%% of keys, want about 9%%", fraction*100)
+	}
+}
+
+func TestConsistentHash_RemoveServerRemapsOnlyItsKeys(t *testing.T) {
+	ch := NewConsistentHash(0)
+	for i := 0; i < 10; i++ {
+		ch.AddServer(fmt.Sprintf("10.0.0.%d:6000", i))
+	}
+	keys := testKeys(20000)
+	before := ownership(ch, keys)
+
+	const removed = "10.0.0.3:6000"
+	ch.RemoveServer(removed)
+	after := ownership(ch, keys)
+
+	moved := 0
+	for _, key := range keys {
+		if before[key] == after[key] {
+			continue
+		}
+		moved++
+		if before[key] != removed {
+			t.Fatalf("key %s moved off %s, which is still present", key, before[key])
+		}
+	}
+	for _, owner := range after {
+		if owner == removed {
+			t.Fatalf("removed server still owns keys")
+		}
+	}
+
+	fraction := float64(moved) / float64(len(keys))
+	t.Logf("removing one of 10 servers remapped %.1f%% of keys", fraction*100)
+	if fraction < 0.04 || fraction > 0.17 {
+		t.Fatalf("removing a server remapped %.1f%% of keys, want about 10%%", fraction*100)
+	}
+}
+
+// collidingServers finds two server names whose single virtual nodes hash
+// to the same point on the ring.
+func collidingServers(t *testing.T) (string, string) {
+	t.Helper()
+	seen := make(map[uint32]string)
+	for i := 0; i < 1<<22; i++ {
+		server := fmt.Sprintf("srv-%d", i)
+		h := hashKey(server + "#0")
+		if other, ok := seen[h]; ok {
+			return other, server
+		}
+		seen[h] = server
+	}
+	t.Fatal("no hash collision found")
+	return "", ""
+}
+
+func TestConsistentHash_RemoveServerRestoresCollidedNodes(t *testing.T) {
+	first, second := collidingServers(t)
+	ch := NewConsistentHash(1)
+	ch.AddServer(first)
+	ch.AddServer(second)
+	if got := len(ch.ring); got != 1 {
+		t.Fatalf("ring has %d nodes, want the collided node once", got)
+	}
+
+	ch.RemoveServer(first)
+	if got := len(ch.ring); got != 1 {
+		t.Fatalf("ring has %d nodes after removal, want %s's node back", got, second)
+	}
+	if got := ch.NextFor("any-key"); got != second {
+		t.Fatalf("NextFor = %q, want %q", got, second)
+	}
+}
+
+func TestConsistentHash_NextRoundRobins(t *testing.T) {
+	ch := NewConsistentHash(4)
+	ch.AddServer("a")
+	ch.AddServer("b")
+	ch.AddServer("a")
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, ch.Next())
+	}
+	if fmt.Sprint(got) != "[a b a b]" {
+		t.Fatalf("Next sequence = %v", got)
+	}
+}
@@ -1,8 +1,17 @@
 package loadbalancer
 
-// Balancer interface for load distribution
+// Balancer interface for load distribution. AddServer and RemoveServer report
+// whether they changed the membership: adding a known server or removing an
+// unknown one does nothing and returns false.
 type Balancer interface {
 	Next() string
-	AddServer(server string)
-	RemoveServer(server string)
+	AddServer(server string) bool
+	RemoveServer(server string) bool
+}
+
+// KeyedBalancer is a Balancer that can also pick a server for a key, returning
+// the same server for the same key while membership is unchanged.
+type KeyedBalancer interface {
+	Balancer
+	NextFor(key string) string
 }
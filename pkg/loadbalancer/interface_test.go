@@ -0,0 +1,162 @@
+package loadbalancer
+
+import (
+	"slices"
+	"testing"
+)
+
+// balancers returns a fresh instance of every Balancer implementation.
+func balancers() map[string]Balancer {
+	return map[string]Balancer{
+		"RoundRobin":     NewRoundRobin(),
+		"ConsistentHash": NewConsistentHash(0),
+	}
+}
+
+// drain collects n picks from b.
+func drain(b Balancer, n int) []string {
+	picks := make([]string, n)
+	for i := range picks {
+		picks[i] = b.Next()
+	}
+	return picks
+}
+
+func TestBalancer_EmptyReturnsNothing(t *testing.T) {
+	for name, b := range balancers() {
+		if got := b.Next(); got != "" {
+			t.Errorf("%s: empty balancer returned %q", name, got)
+		}
+		b.AddServer("a")
+		b.RemoveServer("a")
+		if got := b.Next(); got != "" {
+			t.Errorf("%s: balancer emptied by RemoveServer returned %q", name, got)
+		}
+	}
+}
+
+func TestBalancer_CyclesThroughEveryServer(t *testing.T) {
+	servers := []string{"a", "b", "c"}
+	for name, b := range balancers() {
+		for _, s := range servers {
+			b.AddServer(s)
+		}
+		picks := drain(b, 2*len(servers))
+		if want := append(slices.Clone(servers), servers...); !slices.Equal(picks, want) {
+			t.Errorf("%s: picks = %q, want %q", name, picks, want)
+		}
+	}
+}
+
+func TestBalancer_RemovedServerIsNeverPicked(t *testing.T) {
+	for name, b := range balancers() {
+		for _, s := range []string{"a", "b", "c"} {
+			b.AddServer(s)
+		}
+		b.Next()
+		b.RemoveServer("b")
+		picks := drain(b, 6)
+		if slices.Contains(picks, "b") {
+			t.Errorf("%s: removed server picked in %q", name, picks)
+		}
+		if !slices.Contains(picks, "a") || !slices.Contains(picks, "c") {
+			t.Errorf("%s: remaining servers not all picked in %q", name, picks)
+		}
+	}
+}
+
+func TestBalancer_AddAndRemoveReportChanges(t *testing.T) {
+	for name, b := range balancers() {
+		if !b.AddServer("a") || !b.AddServer("b") {
+			t.Errorf("%s: adding new servers reported no change", name)
+		}
+		if b.AddServer("a") {
+			t.Errorf("%s: adding a duplicate reported a change", name)
+		}
+		if picks := drain(b, 4); !slices.Equal(picks, []string{"a", "b", "a", "b"}) {
+			t.Errorf("%s: picks after a duplicate add = %q, want an even rotation", name, picks)
+		}
+
+		if b.RemoveServer("nope") {
+			t.Errorf("%s: removing an unknown server reported a change", name)
+		}
+		if !b.RemoveServer("a") {
+			t.Errorf("%s: removing a known server reported no change", name)
+		}
+		if b.RemoveServer("a") {
+			t.Errorf("%s: removing a server twice reported a change", name)
+		}
+	}
+}
+
+func TestBalancer_RemoveCurrentServer(t *testing.T) {
+	for name, b := range balancers() {
+		for _, s := range []string{"a", "b", "c"} {
+			b.AddServer(s)
+		}
+		// "b" is up next; removing it moves the rotation on to "c".
+		b.Next()
+		b.RemoveServer("b")
+		if picks := drain(b, 4); !slices.Equal(picks, []string{"c", "a", "c", "a"}) {
+			t.Errorf("%s: picks = %q, want the rotation to continue at c", name, picks)
+		}
+	}
+}
+
+func TestBalancer_RemoveLastServerInRotation(t *testing.T) {
+	for name, b := range balancers() {
+		for _, s := range []string{"a", "b", "c"} {
+			b.AddServer(s)
+		}
+		// "c" is up next and last in the list; removing it wraps to "a".
+		drain(b, 2)
+		b.RemoveServer("c")
+		if picks := drain(b, 3); !slices.Equal(picks, []string{"a", "b", "a"}) {
+			t.Errorf("%s: picks = %q, want the rotation to wrap to a", name, picks)
+		}
+
+		b.RemoveServer("a")
+		b.RemoveServer("b")
+		if got := b.Next(); got != "" {
+			t.Errorf("%s: balancer without servers returned %q", name, got)
+		}
+		b.AddServer("d")
+		if picks := drain(b, 2); !slices.Equal(picks, []string{"d", "d"}) {
+			t.Errorf("%s: picks after refilling = %q", name, picks)
+		}
+	}
+}
+
+func TestBalancer_RemovalMidRotationKeepsOrder(t *testing.T) {
+	servers := []string{"a", "b", "c", "d", "e"}
+	for name := range balancers() {
+		for served := range len(servers) {
+			for _, removed := range servers {
+				b := balancers()[name]
+				for _, s := range servers {
+					b.AddServer(s)
+				}
+				drain(b, served)
+				b.RemoveServer(removed)
+
+				// The rotation carries on with the server that was due next,
+				// or its successor if that one was removed, and then visits
+				// every remaining server exactly once per round.
+				remaining := slices.DeleteFunc(slices.Clone(servers), func(s string) bool { return s == removed })
+				start := served % len(servers)
+				for servers[start] == removed {
+					start = (start + 1) % len(servers)
+				}
+				first := slices.Index(remaining, servers[start])
+				want := make([]string, 2*len(remaining))
+				for i := range want {
+					want[i] = remaining[(first+i)%len(remaining)]
+				}
+
+				if picks := drain(b, len(want)); !slices.Equal(picks, want) {
+					t.Errorf("%s: after %d picks and removing %s, picks = %q, want %q", name, served, removed, picks, want)
+				}
+			}
+		}
+	}
+}
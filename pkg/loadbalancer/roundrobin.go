@@ -1,6 +1,9 @@
 package loadbalancer
 
-import "sync"
+import (
+	"slices"
+	"sync"
+)
 
 // RoundRobin implements the Balancer interface
 type RoundRobin struct {
@@ -9,6 +12,8 @@ type RoundRobin struct {
 	mu      sync.Mutex
 }
 
+var _ Balancer = (*RoundRobin)(nil)
+
 // NewRoundRobin creates a new RoundRobin balancer
 func NewRoundRobin() *RoundRobin {
 	return &RoundRobin{}
@@ -26,21 +31,31 @@ func (rr *RoundRobin) Next() string {
 	return server
 }
 
-// AddServer adds a server to the list
-func (rr *RoundRobin) AddServer(server string) {
+// AddServer adds a server to the end of the rotation, reporting false if it was already in it
+func (rr *RoundRobin) AddServer(server string) bool {
 	rr.mu.Lock()
 	defer rr.mu.Unlock()
+	if slices.Contains(rr.servers, server) {
+		return false
+	}
 	rr.servers = append(rr.servers, server)
+	return true
 }
 
-// RemoveServer removes a server from the list
-func (rr *RoundRobin) RemoveServer(server string) {
+// RemoveServer removes a server from the rotation, reporting whether it was in it
+func (rr *RoundRobin) RemoveServer(server string) bool {
 	rr.mu.Lock()
 	defer rr.mu.Unlock()
-	for i, s := range rr.servers {
-		if s == server {
-			rr.servers = append(rr.servers[:i], rr.servers[i+1:]...)
-			break
-		}
+	i := slices.Index(rr.servers, server)
+	if i < 0 {
+		return false
+	}
+	rr.servers = append(rr.servers[:i], rr.servers[i+1:]...)
+	if i < rr.index {
+		rr.index--
+	}
+	if rr.index >= len(rr.servers) {
+		rr.index = 0
 	}
+	return true
 }
@@ -0,0 +1,42 @@
+package loadbalancer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRoundRobin_RemoveServerAtCursor(t *testing.T) {
+	rr := NewRoundRobin()
+	for _, s := range []string{"a", "b", "c", "d"} {
+		rr.AddServer(s)
+	}
+	if got := drain(rr, 2); !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("picks = %v", got)
+	}
+
+	// The cursor is on c; removing it must hand the turn to d, not skip it.
+	rr.RemoveServer("c")
+	if got := drain(rr, 3); !slices.Equal(got, []string{"d", "a", "b"}) {
+		t.Fatalf("picks after removing the cursor's server = %v, want [d a b]", got)
+	}
+
+	// With the cursor on the last server, removing it wraps to the first.
+	rr.RemoveServer("d")
+	if got := drain(rr, 3); !slices.Equal(got, []string{"a", "b", "a"}) {
+		t.Fatalf("picks after removing the last server = %v, want [a b a]", got)
+	}
+}
+
+func TestRoundRobin_RemoveServerBeforeCursor(t *testing.T) {
+	rr := NewRoundRobin()
+	for _, s := range []string{"a", "b", "c"} {
+		rr.AddServer(s)
+	}
+	drain(rr, 2)
+
+	// Removing a server already passed keeps c as the next pick.
+	rr.RemoveServer("a")
+	if got := drain(rr, 3); !slices.Equal(got, []string{"c", "b", "c"}) {
+		t.Fatalf("picks = %v, want [c b c]", got)
+	}
+}